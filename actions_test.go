@@ -5,8 +5,6 @@ import (
 
 	"time"
 
-	. "code.cloudfoundry.org/vizzini/matchers"
-
 	"code.cloudfoundry.org/bbs/models"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -33,8 +31,8 @@ var _ = Describe("Actions", func() {
 		})
 
 		It("should fail the Task within the timeout window", func() {
-			Eventually(TaskGetter(logger, guid)).Should(HaveTaskState(models.Task_Running))
-			Eventually(TaskGetter(logger, guid), 10).Should(HaveTaskState(models.Task_Completed))
+			WaitForTaskState(guid, models.Task_Running)
+			WaitForTaskState(guid, models.Task_Completed, 10*time.Second)
 			task, err := bbsClient.TaskByGuid(logger, traceID, guid)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(task.GetFailed()).To(BeTrue())
@@ -59,7 +57,7 @@ var _ = Describe("Actions", func() {
 		})
 
 		It("should be possible to specify a working directory", func() {
-			Eventually(TaskGetter(logger, guid)).Should(HaveTaskState(models.Task_Completed))
+			WaitForTaskState(guid, models.Task_Completed)
 			task, err := bbsClient.TaskByGuid(logger, traceID, guid)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(task.GetFailed()).To(BeFalse())
@@ -89,7 +87,7 @@ var _ = Describe("Actions", func() {
 		})
 
 		It("is possible to limit the number of processes", func() {
-			Eventually(TaskGetter(logger, guid)).Should(HaveTaskState(models.Task_Completed))
+			WaitForTaskState(guid, models.Task_Completed)
 			task, err := bbsClient.TaskByGuid(logger, traceID, guid)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(task.GetFailed()).To(BeFalse())
@@ -123,9 +121,9 @@ var _ = Describe("Actions", func() {
 			}
 
 			Expect(bbsClient.DesireLRP(logger, traceID, desiredLRP)).To(Succeed())
-			Eventually(ActualGetter(logger, desiredLRP.ProcessGuid, 0)).Should(BeActualLRPWithState(desiredLRP.ProcessGuid, 0, "RUNNING"))
+			WaitForActualLRPState(desiredLRP.ProcessGuid, 0, "RUNNING")
 			Expect(bbsClient.RemoveDesiredLRP(logger, traceID, desiredLRP.ProcessGuid)).To(Succeed())
-			Eventually(ActualByProcessGuidGetter(logger, desiredLRP.ProcessGuid), 5).Should(BeEmpty())
+			WaitForDesiredLRPRemoved(desiredLRP.ProcessGuid, 5*time.Second)
 		})
 	})
 })
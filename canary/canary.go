@@ -0,0 +1,176 @@
+package canary
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/vizzini/checks"
+	"code.cloudfoundry.org/vizzini/tracker"
+	uuid "github.com/nu7hatch/gouuid"
+)
+
+// Scenario is a check the canary runs over and over.
+type Scenario struct {
+	Name  string
+	Check checks.Check
+}
+
+// Scenarios are the checks the canary knows by name.
+var Scenarios = []Scenario{
+	{Name: "task", Check: checks.TaskRoundTrip},
+	{Name: "lrp", Check: checks.LRPCycle},
+	{Name: "ssh", Check: checks.SSHExec},
+}
+
+const DefaultScenarios = "task,lrp,ssh"
+
+// ParseScenarios looks up a comma-separated list of scenario names.
+func ParseScenarios(value string) ([]Scenario, error) {
+	byName := map[string]Scenario{}
+	for _, scenario := range Scenarios {
+		byName[scenario.Name] = scenario
+	}
+
+	scenarios := []Scenario{}
+	seen := map[string]bool{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		scenario, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown canary scenario %q", name)
+		}
+		seen[name] = true
+		scenarios = append(scenarios, scenario)
+	}
+	if len(scenarios) == 0 {
+		return nil, fmt.Errorf("no canary scenarios in %q", value)
+	}
+	return scenarios, nil
+}
+
+type Options struct {
+	// Env is what every check runs with.  Its Client is wrapped in a fresh
+	// tracker for each run, and its Fixtures.Domain is where everything the
+	// canary desires lives.
+	Env checks.Env
+
+	Scenarios []Scenario
+
+	// Interval is how long each scenario waits between runs, give or take
+	// up to Jitter so that scenarios do not fall into lockstep.
+	Interval time.Duration
+	Jitter   time.Duration
+
+	// CleanupTimeout bounds how long cleanup after a failed run waits for
+	// each Task or DesiredLRP to go away.
+	CleanupTimeout time.Duration
+}
+
+func (o Options) validate() error {
+	problems := []string{}
+	if len(o.Scenarios) == 0 {
+		problems = append(problems, "at least one scenario is required")
+	}
+	if o.Interval <= 0 {
+		problems = append(problems, "interval must be positive")
+	}
+	if o.Jitter < 0 || o.Jitter >= o.Interval {
+		problems = append(problems, "jitter must be between zero and the interval")
+	}
+	if o.Env.Fixtures.Domain == "" {
+		problems = append(problems, "a domain is required")
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid canary options: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// Run runs every scenario in its own loop, recording each run in metrics,
+// until ctx is done.  A run in progress when ctx is done is allowed to
+// finish, so that it does not leave anything behind.
+func Run(ctx context.Context, options Options, metrics *Metrics) error {
+	err := options.validate()
+	if err != nil {
+		return err
+	}
+
+	wg := sync.WaitGroup{}
+	for _, scenario := range options.Scenarios {
+		wg.Add(1)
+		go func(scenario Scenario) {
+			defer wg.Done()
+			loop(ctx, options, metrics, scenario)
+		}(scenario)
+	}
+	wg.Wait()
+	return nil
+}
+
+func loop(ctx context.Context, options Options, metrics *Metrics, scenario Scenario) {
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for {
+		RunOnce(options, metrics, scenario)
+
+		wait := options.Interval
+		if options.Jitter > 0 {
+			wait += time.Duration(random.Int63n(int64(2*options.Jitter))) - options.Jitter
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// RunOnce runs scenario's check once, records the result in metrics and
+// removes anything the check left behind.  It returns the check's error.
+func RunOnce(options Options, metrics *Metrics, scenario Scenario) error {
+	u, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	guid := fmt.Sprintf("%s-%s-%s", options.Env.Fixtures.Domain, scenario.Name, u.String())
+	logger := options.Env.Logger.Session("canary", lager.Data{"scenario": scenario.Name, "guid": guid})
+
+	resourceTracker := tracker.New(options.Env.Client)
+	if options.CleanupTimeout > 0 {
+		resourceTracker.Timeout = options.CleanupTimeout
+	}
+	env := options.Env
+	env.Logger = logger
+	env.Client = resourceTracker.Client()
+
+	start := time.Now()
+	checkErr := scenario.Check(env, guid)
+	duration := time.Since(start)
+
+	result := ResultSuccess
+	if checkErr != nil {
+		result = ResultFailure
+		logger.Error("failed", checkErr, lager.Data{"duration": duration.String()})
+	} else {
+		logger.Info("succeeded", lager.Data{"duration": duration.String()})
+		metrics.LastSuccess.WithLabelValues(scenario.Name).Set(float64(time.Now().Unix()))
+	}
+	metrics.Runs.WithLabelValues(scenario.Name, result).Inc()
+	metrics.Duration.WithLabelValues(scenario.Name, result).Observe(duration.Seconds())
+
+	if len(resourceTracker.Tracked()) > 0 {
+		err = resourceTracker.Cleanup(logger, env.TraceID)
+		if err != nil {
+			logger.Error("failed-to-clean-up", err)
+			metrics.CleanupFailures.WithLabelValues(scenario.Name).Inc()
+		}
+	}
+	return checkErr
+}
@@ -0,0 +1,166 @@
+package canary_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/vizzini/canary"
+	"code.cloudfoundry.org/vizzini/checks"
+	"code.cloudfoundry.org/vizzini/fakebbs"
+	"code.cloudfoundry.org/vizzini/fixtures"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Canary", func() {
+	var (
+		server   *fakebbs.Server
+		client   bbs.InternalClient
+		registry *prometheus.Registry
+		metrics  *canary.Metrics
+		options  canary.Options
+		task     canary.Scenario
+		leaky    canary.Scenario
+	)
+
+	BeforeEach(func() {
+		var err error
+		server = fakebbs.NewServerWithStepInterval(10 * time.Millisecond)
+		server.Start()
+		client, err = server.Client()
+		Expect(err).NotTo(HaveOccurred())
+
+		registry = prometheus.NewRegistry()
+		metrics, err = canary.NewMetrics(registry)
+		Expect(err).NotTo(HaveOccurred())
+
+		scenarios, err := canary.ParseScenarios("task")
+		Expect(err).NotTo(HaveOccurred())
+		task = scenarios[0]
+
+		// desires a task it never deletes, then fails
+		leaky = canary.Scenario{
+			Name: "leaky",
+			Check: func(env checks.Env, guid string) error {
+				defer GinkgoRecover()
+				definition, err := env.Fixtures.Task(guid)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(env.Client.DesireTask(env.Logger, env.TraceID, guid, env.Fixtures.Domain, definition)).To(Succeed())
+				return errors.New("boom")
+			},
+		}
+
+		options = canary.Options{
+			Env: checks.Env{
+				Logger:  lagertest.NewTestLogger("canary"),
+				Client:  client,
+				TraceID: "trace-id",
+				Fixtures: fixtures.Defaults{
+					Domain:               "vizzini-canary",
+					RootFS:               models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot),
+					GraceTarballURL:      "http://example.com/grace.tgz",
+					RoutableDomainSuffix: "example.com",
+				},
+				Timeout:      5 * time.Second,
+				PollInterval: 10 * time.Millisecond,
+			},
+			Interval:       50 * time.Millisecond,
+			Jitter:         10 * time.Millisecond,
+			CleanupTimeout: 5 * time.Second,
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("RunOnce", func() {
+		It("counts a successful run and when it happened", func() {
+			before := time.Now().Unix()
+			Expect(canary.RunOnce(options, metrics, task)).To(Succeed())
+
+			Expect(testutil.ToFloat64(metrics.Runs.WithLabelValues("task", canary.ResultSuccess))).To(Equal(1.0))
+			Expect(testutil.ToFloat64(metrics.Runs.WithLabelValues("task", canary.ResultFailure))).To(BeZero())
+			Expect(testutil.ToFloat64(metrics.LastSuccess.WithLabelValues("task"))).To(BeNumerically(">=", before))
+			Expect(testutil.CollectAndCount(metrics.Duration)).To(Equal(1))
+		})
+
+		It("counts a failed run and cleans up what it left behind", func() {
+			Expect(canary.RunOnce(options, metrics, leaky)).To(MatchError("boom"))
+
+			Expect(testutil.ToFloat64(metrics.Runs.WithLabelValues("leaky", canary.ResultFailure))).To(Equal(1.0))
+			Expect(testutil.ToFloat64(metrics.CleanupFailures.WithLabelValues("leaky"))).To(BeZero())
+			Expect(client.TasksByDomain(options.Env.Logger, "trace-id", "vizzini-canary")).To(BeEmpty())
+		})
+
+		It("counts runs it could not clean up after", func() {
+			options.CleanupTimeout = 50 * time.Millisecond
+			leaky.Check = func(env checks.Env, guid string) error {
+				definition, err := env.Fixtures.Task(guid)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(env.Client.DesireTask(env.Logger, env.TraceID, guid, env.Fixtures.Domain, definition)).To(Succeed())
+				server.Close()
+				return errors.New("boom")
+			}
+
+			Expect(canary.RunOnce(options, metrics, leaky)).To(MatchError("boom"))
+			Expect(testutil.ToFloat64(metrics.CleanupFailures.WithLabelValues("leaky"))).To(Equal(1.0))
+		})
+	})
+
+	Describe("Run", func() {
+		It("runs every scenario repeatedly until the context is done", func() {
+			options.Scenarios = []canary.Scenario{task, leaky}
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() {
+				done <- canary.Run(ctx, options, metrics)
+			}()
+
+			Eventually(func() float64 {
+				return testutil.ToFloat64(metrics.Runs.WithLabelValues("leaky", canary.ResultFailure))
+			}).Should(BeNumerically(">=", 2))
+			Eventually(func() float64 {
+				return testutil.ToFloat64(metrics.Runs.WithLabelValues("task", canary.ResultSuccess))
+			}).Should(BeNumerically(">=", 2))
+
+			cancel()
+			Eventually(done).Should(Receive(BeNil()))
+			Expect(client.TasksByDomain(options.Env.Logger, "trace-id", "vizzini-canary")).To(BeEmpty())
+		})
+
+		It("rejects invalid options", func() {
+			options.Jitter = options.Interval
+			options.Env.Fixtures.Domain = ""
+			err := canary.Run(context.Background(), options, metrics)
+			Expect(err).To(MatchError("invalid canary options: at least one scenario is required, jitter must be between zero and the interval, a domain is required"))
+		})
+	})
+
+	Describe("ParseScenarios", func() {
+		It("looks up scenarios by name", func() {
+			scenarios, err := canary.ParseScenarios(canary.DefaultScenarios + ",task")
+			Expect(err).NotTo(HaveOccurred())
+			names := []string{}
+			for _, scenario := range scenarios {
+				names = append(names, scenario.Name)
+			}
+			Expect(names).To(Equal([]string{"task", "lrp", "ssh"}))
+		})
+
+		It("rejects unknown and empty lists", func() {
+			_, err := canary.ParseScenarios("task,dns")
+			Expect(err).To(MatchError(`unknown canary scenario "dns"`))
+
+			_, err = canary.ParseScenarios(" , ")
+			Expect(err).To(MatchError(`no canary scenarios in " , "`))
+		})
+	})
+})
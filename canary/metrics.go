@@ -0,0 +1,69 @@
+package canary
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "vizzini_canary"
+
+// DurationBuckets are the upper bounds, in seconds, of the run duration
+// histogram.  An LRP cycle takes tens of seconds on a healthy deployment, so
+// the buckets reach well past Prometheus's defaults.
+var DurationBuckets = []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 90, 120, 180, 300}
+
+// Metrics are what the canary exports, labelled by scenario.
+type Metrics struct {
+	// Runs counts finished runs by scenario and result, "success" or
+	// "failure".
+	Runs *prometheus.CounterVec
+
+	// Duration observes how long each run took, whether or not it succeeded.
+	Duration *prometheus.HistogramVec
+
+	// LastSuccess is the Unix time of the last successful run.
+	LastSuccess *prometheus.GaugeVec
+
+	// CleanupFailures counts runs that left resources the canary could not
+	// remove afterwards.
+	CleanupFailures *prometheus.CounterVec
+}
+
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// NewMetrics creates the canary's metrics and registers them with registerer.
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	metrics := &Metrics{
+		Runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "runs_total",
+			Help:      "Canary runs by scenario and result.",
+		}, []string{"scenario", "result"}),
+		Duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "duration_seconds",
+			Help:      "How long each canary run took.",
+			Buckets:   DurationBuckets,
+		}, []string{"scenario", "result"}),
+		LastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_success_timestamp_seconds",
+			Help:      "Unix time of the last successful run of each scenario.",
+		}, []string{"scenario"}),
+		CleanupFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cleanup_failures_total",
+			Help:      "Canary runs whose leftover resources could not be removed.",
+		}, []string{"scenario"}),
+	}
+
+	for _, collector := range []prometheus.Collector{metrics.Runs, metrics.Duration, metrics.LastSuccess, metrics.CleanupFailures} {
+		err := registerer.Register(collector)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return metrics, nil
+}
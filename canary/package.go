@@ -0,0 +1 @@
+package canary // import "code.cloudfoundry.org/vizzini/canary"
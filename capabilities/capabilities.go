@@ -0,0 +1,124 @@
+package capabilities
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Capability names something a spec needs from the target deployment.
+type Capability string
+
+const (
+	Docker         Capability = "docker"
+	OCIImages      Capability = "oci-images"
+	ContainerProxy Capability = "container-proxy"
+	Privileged     Capability = "privileged"
+)
+
+const stackPrefix = "stack:"
+
+// Stack is the capability of running on the named preloaded rootfs.
+func Stack(name string) Capability {
+	return Capability(stackPrefix + name)
+}
+
+// Set records which capabilities the deployment has and, for each one it
+// lacks, why.
+type Set struct {
+	supported map[Capability]bool
+	reasons   map[Capability]string
+}
+
+func NewSet() *Set {
+	return &Set{
+		supported: map[Capability]bool{},
+		reasons:   map[Capability]string{},
+	}
+}
+
+// setJSON is how a Set is shared between parallel processes.
+type setJSON struct {
+	Supported []Capability          `json:"supported"`
+	Missing   map[Capability]string `json:"missing"`
+}
+
+func (s *Set) MarshalJSON() ([]byte, error) {
+	encoded := setJSON{Supported: []Capability{}, Missing: s.reasons}
+	for capability := range s.supported {
+		encoded.Supported = append(encoded.Supported, capability)
+	}
+	sort.Slice(encoded.Supported, func(i, j int) bool { return encoded.Supported[i] < encoded.Supported[j] })
+	return json.Marshal(encoded)
+}
+
+func (s *Set) UnmarshalJSON(data []byte) error {
+	decoded := setJSON{}
+	err := json.Unmarshal(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*s = *NewSet()
+	for _, capability := range decoded.Supported {
+		s.Add(capability)
+	}
+	for capability, reason := range decoded.Missing {
+		s.Remove(capability, reason)
+	}
+	return nil
+}
+
+func (s *Set) Add(capability Capability) {
+	s.supported[capability] = true
+	delete(s.reasons, capability)
+}
+
+func (s *Set) Remove(capability Capability, reason string) {
+	delete(s.supported, capability)
+	s.reasons[capability] = reason
+}
+
+func (s *Set) Supports(capability Capability) bool {
+	return s.supported[capability]
+}
+
+// Missing returns the reason the first unsupported capability in needs is
+// unavailable, and false if every capability is supported.
+func (s *Set) Missing(needs ...Capability) (string, bool) {
+	for _, need := range needs {
+		if s.supported[need] {
+			continue
+		}
+		reason, ok := s.reasons[need]
+		if !ok {
+			reason = "not detected"
+		}
+		return fmt.Sprintf("deployment does not support %s: %s", need, reason), true
+	}
+	return "", false
+}
+
+func (s *Set) Report(w io.Writer) {
+	all := map[Capability]bool{}
+	for capability := range s.supported {
+		all[capability] = true
+	}
+	for capability := range s.reasons {
+		all[capability] = true
+	}
+
+	capabilities := make([]string, 0, len(all))
+	for capability := range all {
+		capabilities = append(capabilities, string(capability))
+	}
+	sort.Strings(capabilities)
+
+	for _, capability := range capabilities {
+		if s.supported[Capability(capability)] {
+			fmt.Fprintf(w, "%-24s yes\n", capability)
+		} else {
+			fmt.Fprintf(w, "%-24s no (%s)\n", capability, s.reasons[Capability(capability)])
+		}
+	}
+}
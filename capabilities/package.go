@@ -0,0 +1 @@
+package capabilities // import "code.cloudfoundry.org/vizzini/capabilities"
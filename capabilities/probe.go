@@ -0,0 +1,229 @@
+package capabilities
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+type ProbeOptions struct {
+	// Domain and GuidPrefix name the probe LRP and task, which are removed
+	// before Probe returns.
+	Domain     string
+	GuidPrefix string
+
+	// RootFS is the rootfs the probe LRP and task run on.
+	RootFS string
+
+	// OCIImageURL is the image the OCI specs run; OCIImages is unsupported
+	// without one.
+	OCIImageURL string
+
+	// Overrides skip probing for the given capabilities and take the supplied
+	// value instead, e.g. when the operator has set enable_* in the config.
+	Overrides map[Capability]bool
+
+	Timeout      time.Duration
+	PollInterval time.Duration
+}
+
+var errProbeTimedOut = errors.New("timed out")
+
+// Probe asks the BBS what the target deployment can do.  Rootfs providers
+// come from the registered cells; container proxy and privileged support are
+// detected by running a short-lived LRP and task.
+func Probe(logger lager.Logger, client bbs.InternalClient, traceID string, options ProbeOptions) (*Set, error) {
+	logger = logger.Session("probe-capabilities")
+	if options.PollInterval == 0 {
+		options.PollInterval = 500 * time.Millisecond
+	}
+
+	cells, err := client.Cells(logger, traceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch cells: %s", err.Error())
+	}
+
+	set := NewSet()
+	set.Remove(Docker, "no cell has a docker rootfs provider")
+	for _, cell := range cells {
+		for _, provider := range cell.RootfsProviders {
+			switch provider.Name {
+			case models.DockerRootFSScheme:
+				set.Add(Docker)
+			case models.PreloadedRootFSScheme:
+				for _, stack := range provider.Properties {
+					set.Add(Stack(stack))
+				}
+			}
+		}
+	}
+
+	switch {
+	case !set.Supports(Docker):
+		set.Remove(OCIImages, "no cell has a docker rootfs provider")
+	case options.OCIImageURL == "":
+		set.Remove(OCIImages, "diego_docker_oci_image_url is not configured")
+	default:
+		set.Add(OCIImages)
+	}
+
+	probes := []struct {
+		capability Capability
+		probe      func() (string, error)
+	}{
+		{ContainerProxy, func() (string, error) { return probeContainerProxy(logger, client, traceID, options) }},
+		{Privileged, func() (string, error) { return probePrivileged(logger, client, traceID, options) }},
+	}
+
+	for _, p := range probes {
+		if supported, ok := options.Overrides[p.capability]; ok {
+			if supported {
+				set.Add(p.capability)
+			} else {
+				set.Remove(p.capability, "disabled in the Vizzini config")
+			}
+			continue
+		}
+
+		reason, err := p.probe()
+		if err != nil {
+			set.Remove(p.capability, fmt.Sprintf("probe failed: %s", err.Error()))
+		} else if reason != "" {
+			set.Remove(p.capability, reason)
+		} else {
+			set.Add(p.capability)
+		}
+	}
+
+	return set, nil
+}
+
+func probeContainerProxy(logger lager.Logger, client bbs.InternalClient, traceID string, options ProbeOptions) (string, error) {
+	processGuid := options.GuidPrefix + "-container-proxy"
+	lrp := &models.DesiredLRP{
+		ProcessGuid: processGuid,
+		Domain:      options.Domain,
+		Instances:   1,
+		RootFs:      options.RootFS,
+		MemoryMb:    32,
+		DiskMb:      32,
+		Ports:       []uint32{8080},
+		Action: models.WrapAction(&models.RunAction{
+			Path: "sh",
+			Args: []string{"-c", "sleep 3600"},
+			User: "vcap",
+		}),
+	}
+	err := client.DesireLRP(logger, traceID, lrp)
+	if err != nil {
+		return "", err
+	}
+	defer removeLRP(logger, client, traceID, processGuid, options)
+
+	var running *models.ActualLRP
+	err = poll(options, func() (bool, error) {
+		lrps, err := client.ActualLRPs(logger, traceID, models.ActualLRPFilter{ProcessGuid: processGuid})
+		if err != nil {
+			return false, err
+		}
+		for _, lrp := range lrps {
+			if lrp.State == models.ActualLRPStateRunning {
+				running = lrp
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, port := range running.Ports {
+		if port.ContainerPort == 8080 && port.HostTlsProxyPort != 0 {
+			return "", nil
+		}
+	}
+	return "probe LRP has no TLS proxy port", nil
+}
+
+// removeLRP removes the probe LRP and waits for its instances to stop, so
+// that they do not take up room while the first specs run.
+func removeLRP(logger lager.Logger, client bbs.InternalClient, traceID, processGuid string, options ProbeOptions) {
+	err := client.RemoveDesiredLRP(logger, traceID, processGuid)
+	if err != nil {
+		logger.Error("failed-to-remove-probe-lrp", err)
+		return
+	}
+	err = poll(options, func() (bool, error) {
+		lrps, err := client.ActualLRPs(logger, traceID, models.ActualLRPFilter{ProcessGuid: processGuid})
+		return len(lrps) == 0, err
+	})
+	if err != nil {
+		logger.Error("failed-to-wait-for-probe-lrp-removal", err)
+	}
+}
+
+func probePrivileged(logger lager.Logger, client bbs.InternalClient, traceID string, options ProbeOptions) (string, error) {
+	taskGuid := options.GuidPrefix + "-privileged"
+	definition := &models.TaskDefinition{
+		RootFs:     options.RootFS,
+		MemoryMb:   32,
+		DiskMb:     32,
+		Privileged: true,
+		Action: models.WrapAction(&models.RunAction{
+			Path: "true",
+			User: "root",
+		}),
+	}
+	err := client.DesireTask(logger, traceID, taskGuid, options.Domain, definition)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		client.CancelTask(logger, traceID, taskGuid)
+		client.ResolvingTask(logger, traceID, taskGuid)
+		client.DeleteTask(logger, traceID, taskGuid)
+	}()
+
+	var completed *models.Task
+	err = poll(options, func() (bool, error) {
+		task, err := client.TaskByGuid(logger, traceID, taskGuid)
+		if err != nil {
+			return false, err
+		}
+		if task.State == models.Task_Completed {
+			completed = task
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if completed.Failed {
+		return fmt.Sprintf("privileged probe task failed: %s", completed.FailureReason), nil
+	}
+	return "", nil
+}
+
+func poll(options ProbeOptions, done func() (bool, error)) error {
+	deadline := time.Now().Add(options.Timeout)
+	for {
+		ok, err := done()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errProbeTimedOut
+		}
+		time.Sleep(options.PollInterval)
+	}
+}
@@ -0,0 +1,147 @@
+package capabilities_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/vizzini/capabilities"
+	"code.cloudfoundry.org/vizzini/fakebbs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Probe", func() {
+	var (
+		server  *fakebbs.Server
+		client  bbs.InternalClient
+		logger  *lagertest.TestLogger
+		options capabilities.ProbeOptions
+	)
+
+	BeforeEach(func() {
+		var err error
+		logger = lagertest.NewTestLogger("capabilities")
+		server = fakebbs.NewServerWithStepInterval(10 * time.Millisecond)
+		server.Start()
+		client, err = server.Client()
+		Expect(err).NotTo(HaveOccurred())
+
+		options = capabilities.ProbeOptions{
+			Domain:       "capabilities",
+			GuidPrefix:   "probe",
+			RootFS:       models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot),
+			OCIImageURL:  "docker:///cloudfoundry/diego-docker-app",
+			Timeout:      5 * time.Second,
+			PollInterval: 10 * time.Millisecond,
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("detects rootfs providers, the container proxy and privileged support", func() {
+		set, err := capabilities.Probe(logger, client, "trace-id", options)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(set.Supports(capabilities.Docker)).To(BeTrue())
+		Expect(set.Supports(capabilities.OCIImages)).To(BeTrue())
+		Expect(set.Supports(capabilities.Stack(fakebbs.DefaultPreloadedRoot))).To(BeTrue())
+		Expect(set.Supports(capabilities.ContainerProxy)).To(BeTrue())
+		Expect(set.Supports(capabilities.Privileged)).To(BeTrue())
+
+		_, missing := set.Missing(capabilities.Docker, capabilities.Privileged)
+		Expect(missing).To(BeFalse())
+	})
+
+	It("cleans up after itself", func() {
+		_, err := capabilities.Probe(logger, client, "trace-id", options)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(client.DesiredLRPs(logger, "trace-id", models.DesiredLRPFilter{Domain: "capabilities"})).To(BeEmpty())
+		Expect(client.ActualLRPs(logger, "trace-id", models.ActualLRPFilter{Domain: "capabilities"})).To(BeEmpty())
+		Expect(client.TasksByDomain(logger, "trace-id", "capabilities")).To(BeEmpty())
+	})
+
+	It("explains why docker and OCI images are unavailable", func() {
+		cells := fakebbs.DefaultCells()
+		cells[0].RootfsProviders = cells[0].RootfsProviders[:1]
+		server.SetCells(cells)
+
+		set, err := capabilities.Probe(logger, client, "trace-id", options)
+		Expect(err).NotTo(HaveOccurred())
+
+		reason, missing := set.Missing(capabilities.OCIImages)
+		Expect(missing).To(BeTrue())
+		Expect(reason).To(Equal("deployment does not support oci-images: no cell has a docker rootfs provider"))
+	})
+
+	It("requires an OCI image for OCI support", func() {
+		options.OCIImageURL = ""
+		set, err := capabilities.Probe(logger, client, "trace-id", options)
+		Expect(err).NotTo(HaveOccurred())
+
+		reason, missing := set.Missing(capabilities.Docker, capabilities.OCIImages)
+		Expect(missing).To(BeTrue())
+		Expect(reason).To(ContainSubstring("diego_docker_oci_image_url is not configured"))
+	})
+
+	It("reports probes that cannot run", func() {
+		options.RootFS = models.PreloadedRootFS("fruitfs")
+		options.Timeout = 200 * time.Millisecond
+
+		set, err := capabilities.Probe(logger, client, "trace-id", options)
+		Expect(err).NotTo(HaveOccurred())
+
+		reason, missing := set.Missing(capabilities.ContainerProxy)
+		Expect(missing).To(BeTrue())
+		Expect(reason).To(ContainSubstring("probe failed: timed out"))
+
+		reason, missing = set.Missing(capabilities.Privileged)
+		Expect(missing).To(BeTrue())
+		Expect(reason).To(ContainSubstring("found no compatible cell"))
+	})
+
+	It("survives being shared between parallel processes as JSON", func() {
+		options.OCIImageURL = ""
+		set, err := capabilities.Probe(logger, client, "trace-id", options)
+		Expect(err).NotTo(HaveOccurred())
+
+		encoded, err := json.Marshal(set)
+		Expect(err).NotTo(HaveOccurred())
+		decoded := capabilities.NewSet()
+		Expect(json.Unmarshal(encoded, decoded)).To(Succeed())
+
+		Expect(decoded.Supports(capabilities.Privileged)).To(BeTrue())
+		reason, missing := decoded.Missing(capabilities.OCIImages)
+		Expect(missing).To(BeTrue())
+		Expect(reason).To(ContainSubstring("diego_docker_oci_image_url is not configured"))
+		Expect(decoded).To(Equal(set))
+	})
+
+	It("takes overrides instead of probing", func() {
+		options.RootFS = models.PreloadedRootFS("fruitfs")
+		options.Overrides = map[capabilities.Capability]bool{
+			capabilities.ContainerProxy: true,
+			capabilities.Privileged:     false,
+		}
+
+		set, err := capabilities.Probe(logger, client, "trace-id", options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(set.Supports(capabilities.ContainerProxy)).To(BeTrue())
+
+		reason, missing := set.Missing(capabilities.Privileged)
+		Expect(missing).To(BeTrue())
+		Expect(reason).To(ContainSubstring("disabled in the Vizzini config"))
+
+		report := &bytes.Buffer{}
+		set.Report(report)
+		Expect(report.String()).To(ContainSubstring("container-proxy"))
+		Expect(report.String()).To(ContainSubstring("privileged               no (disabled in the Vizzini config)"))
+	})
+})
@@ -0,0 +1,264 @@
+package checks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/vizzini/fixtures"
+	"code.cloudfoundry.org/vizzini/grace"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	DefaultTimeout      = 2 * time.Minute
+	DefaultPollInterval = time.Second
+)
+
+// Env is everything a check needs to reach the deployment.
+type Env struct {
+	Logger  lager.Logger
+	Client  bbs.InternalClient
+	TraceID string
+
+	// Fixtures describes what the checks desire.  Everything lives in its
+	// Domain.
+	Fixtures fixtures.Defaults
+
+	// Timeout bounds each wait within a check, not the check as a whole.
+	Timeout      time.Duration
+	PollInterval time.Duration
+
+	// HTTPClient reaches routes through the router; nil uses
+	// http.DefaultClient.
+	HTTPClient *http.Client
+
+	SSHAddress   string
+	SSHPassword  string
+	LifecycleURL string
+}
+
+// Check runs one round trip against the deployment using guid for whatever it
+// desires.  A check that succeeds has removed everything it desired; one that
+// fails may leave resources behind, so run checks with a tracker's client.
+type Check func(env Env, guid string) error
+
+// TaskRoundTrip desires a Task, waits for it to complete successfully, then
+// resolves and deletes it.
+func TaskRoundTrip(env Env, taskGuid string) error {
+	env = env.withDefaults()
+	task, err := env.Fixtures.Task(taskGuid)
+	if err != nil {
+		return err
+	}
+	err = env.Client.DesireTask(env.Logger, env.TraceID, taskGuid, env.Fixtures.Domain, task)
+	if err != nil {
+		return fmt.Errorf("failed to desire task: %s", err.Error())
+	}
+
+	var completed *models.Task
+	err = env.poll("task to complete", func() (bool, error) {
+		completed, err = env.Client.TaskByGuid(env.Logger, env.TraceID, taskGuid)
+		if err != nil {
+			return false, err
+		}
+		return completed.State == models.Task_Completed, nil
+	})
+	if err != nil {
+		return err
+	}
+	if completed.Failed {
+		return fmt.Errorf("task failed: %s", completed.FailureReason)
+	}
+
+	err = env.Client.ResolvingTask(env.Logger, env.TraceID, taskGuid)
+	if err != nil {
+		return fmt.Errorf("failed to resolve task: %s", err.Error())
+	}
+	err = env.Client.DeleteTask(env.Logger, env.TraceID, taskGuid)
+	if err != nil {
+		return fmt.Errorf("failed to delete task: %s", err.Error())
+	}
+	return nil
+}
+
+// LRPCycle desires a routed DesiredLRP, waits for it to run and serve on its
+// route, scales it to two instances, then removes it and waits for its
+// ActualLRPs to go away.
+func LRPCycle(env Env, processGuid string) error {
+	env = env.withDefaults()
+	lrp, err := env.Fixtures.DesiredLRP(processGuid)
+	if err != nil {
+		return err
+	}
+	err = env.Client.DesireLRP(env.Logger, env.TraceID, lrp)
+	if err != nil {
+		return fmt.Errorf("failed to desire LRP: %s", err.Error())
+	}
+
+	err = env.waitForRunning(processGuid, 1)
+	if err != nil {
+		return err
+	}
+
+	route := env.Fixtures.RouteFor(processGuid, fixtures.GracePort)
+	graceClient := grace.ForRoute(route, grace.WithHTTPClient(env.HTTPClient))
+	err = env.poll("route "+route+" to serve", func() (bool, error) {
+		return graceClient.Ready(), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	update := &models.DesiredLRPUpdate{}
+	update.SetInstances(2)
+	err = env.Client.UpdateDesiredLRP(env.Logger, env.TraceID, processGuid, update)
+	if err != nil {
+		return fmt.Errorf("failed to scale LRP: %s", err.Error())
+	}
+	err = env.waitForRunning(processGuid, 2)
+	if err != nil {
+		return err
+	}
+
+	return env.removeLRP(processGuid)
+}
+
+// SSHExec desires a DesiredLRP running diego-sshd, runs a command in its
+// first instance through the ssh proxy, then removes it.
+func SSHExec(env Env, processGuid string) error {
+	env = env.withDefaults()
+	if env.SSHAddress == "" {
+		return errors.New("no ssh proxy address configured")
+	}
+	lrp, err := env.Fixtures.DesiredLRP(processGuid, fixtures.WithSSH(env.LifecycleURL))
+	if err != nil {
+		return err
+	}
+	err = env.Client.DesireLRP(env.Logger, env.TraceID, lrp)
+	if err != nil {
+		return fmt.Errorf("failed to desire LRP: %s", err.Error())
+	}
+
+	err = env.waitForRunning(processGuid, 1)
+	if err != nil {
+		return err
+	}
+
+	// diego-sshd may still be starting once the instance is running
+	expected := "vizzini-" + processGuid
+	var output string
+	var lastErr error
+	err = env.poll("ssh command to run", func() (bool, error) {
+		output, lastErr = env.sshExec(processGuid, "echo "+expected)
+		return lastErr == nil, nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %s", err.Error(), lastErr.Error())
+	}
+	if strings.TrimSpace(output) != expected {
+		return fmt.Errorf("ssh command printed %q, expected %q", output, expected)
+	}
+
+	return env.removeLRP(processGuid)
+}
+
+func (e Env) sshExec(processGuid, command string) (string, error) {
+	client, err := ssh.Dial("tcp", e.SSHAddress, &ssh.ClientConfig{
+		User:            fmt.Sprintf("diego:%s/0", processGuid),
+		Auth:            []ssh.AuthMethod{ssh.Password(e.SSHPassword)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         e.Timeout,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	output, err := session.CombinedOutput(command)
+	return string(output), err
+}
+
+func (e Env) waitForRunning(processGuid string, instances int) error {
+	return e.poll(fmt.Sprintf("%d instances to be running", instances), func() (bool, error) {
+		actuals, err := e.Client.ActualLRPs(e.Logger, e.TraceID, models.ActualLRPFilter{ProcessGuid: processGuid})
+		if err != nil {
+			return false, err
+		}
+		running := 0
+		for _, actual := range actuals {
+			if actual.State == models.ActualLRPStateRunning {
+				running++
+			}
+		}
+		return running == instances, nil
+	})
+}
+
+func (e Env) removeLRP(processGuid string) error {
+	err := e.Client.RemoveDesiredLRP(e.Logger, e.TraceID, processGuid)
+	if err != nil {
+		return fmt.Errorf("failed to remove LRP: %s", err.Error())
+	}
+	return e.poll("actual LRPs to be removed", func() (bool, error) {
+		actuals, err := e.Client.ActualLRPs(e.Logger, e.TraceID, models.ActualLRPFilter{ProcessGuid: processGuid})
+		if err != nil {
+			return false, err
+		}
+		return len(actuals) == 0, nil
+	})
+}
+
+func (e Env) poll(description string, done func() (bool, error)) error {
+	return Poll(e.Timeout, e.PollInterval, description, func() (bool, string, error) {
+		ok, err := done()
+		return ok, "", err
+	})
+}
+
+// Poll calls done every interval until it succeeds, fails or timeout passes.
+// done also says what it saw, if anything, which is included in the error
+// when the wait times out.
+func Poll(timeout, interval time.Duration, description string, done func() (bool, string, error)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, seen, err := done()
+		if err != nil {
+			return fmt.Errorf("failed waiting for %s: %s", description, err.Error())
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			if seen != "" {
+				return fmt.Errorf("timed out after %s waiting for %s (last saw %s)", timeout, description, seen)
+			}
+			return fmt.Errorf("timed out after %s waiting for %s", timeout, description)
+		}
+		time.Sleep(interval)
+	}
+}
+
+func (e Env) withDefaults() Env {
+	if e.Timeout == 0 {
+		e.Timeout = DefaultTimeout
+	}
+	if e.PollInterval == 0 {
+		e.PollInterval = DefaultPollInterval
+	}
+	if e.HTTPClient == nil {
+		e.HTTPClient = http.DefaultClient
+	}
+	return e
+}
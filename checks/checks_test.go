@@ -0,0 +1,123 @@
+package checks_test
+
+import (
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/vizzini/checks"
+	"code.cloudfoundry.org/vizzini/fakebbs"
+	"code.cloudfoundry.org/vizzini/fixtures"
+	"code.cloudfoundry.org/vizzini/grace"
+	"code.cloudfoundry.org/vizzini/grace/fakegrace"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Checks", func() {
+	var (
+		server      *fakebbs.Server
+		client      bbs.InternalClient
+		graceServer *fakegrace.Server
+		transport   *fakegrace.RouterTransport
+		env         checks.Env
+	)
+
+	BeforeEach(func() {
+		var err error
+		server = fakebbs.NewServerWithStepInterval(10 * time.Millisecond)
+		server.Start()
+		client, err = server.Client()
+		Expect(err).NotTo(HaveOccurred())
+
+		graceServer = fakegrace.NewServer(0, grace.Env{})
+		transport = graceServer.RouterTransport()
+
+		env = checks.Env{
+			Logger:  lagertest.NewTestLogger("checks"),
+			Client:  client,
+			TraceID: "trace-id",
+			Fixtures: fixtures.Defaults{
+				Domain:               "vizzini-checks",
+				RootFS:               models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot),
+				GraceTarballURL:      "http://example.com/grace.tgz",
+				RoutableDomainSuffix: "example.com",
+			},
+			Timeout:      5 * time.Second,
+			PollInterval: 10 * time.Millisecond,
+			HTTPClient:   &http.Client{Transport: transport},
+		}
+	})
+
+	AfterEach(func() {
+		graceServer.Close()
+		server.Close()
+	})
+
+	expectNothingLeft := func() {
+		Expect(client.TasksByDomain(env.Logger, "trace-id", "vizzini-checks")).To(BeEmpty())
+		Expect(client.DesiredLRPs(env.Logger, "trace-id", models.DesiredLRPFilter{Domain: "vizzini-checks"})).To(BeEmpty())
+		Expect(client.ActualLRPs(env.Logger, "trace-id", models.ActualLRPFilter{Domain: "vizzini-checks"})).To(BeEmpty())
+	}
+
+	Describe("TaskRoundTrip", func() {
+		It("runs a task to completion and deletes it", func() {
+			Expect(checks.TaskRoundTrip(env, "some-task")).To(Succeed())
+			expectNothingLeft()
+		})
+
+		It("fails when the task fails", func() {
+			env.Fixtures.RootFS = models.PreloadedRootFS("unsupported")
+			Expect(checks.TaskRoundTrip(env, "some-task")).To(MatchError(ContainSubstring("task failed: found no compatible cell")))
+		})
+	})
+
+	Describe("LRPCycle", func() {
+		It("desires, routes, scales and removes an LRP", func() {
+			graceServer.FailNextRequests(2)
+
+			Expect(checks.LRPCycle(env, "some-lrp")).To(Succeed())
+			Expect(transport.Hosts()).To(ContainElement("some-lrp.example.com"))
+			expectNothingLeft()
+		})
+
+		It("fails when the route never serves", func() {
+			graceServer.Close()
+			env.Timeout = 200 * time.Millisecond
+
+			err := checks.LRPCycle(env, "some-lrp")
+			Expect(err).To(MatchError("timed out after 200ms waiting for route some-lrp.example.com to serve"))
+		})
+
+		It("fails when the LRP cannot be placed", func() {
+			env.Fixtures.RootFS = models.PreloadedRootFS("unsupported")
+			env.Timeout = 200 * time.Millisecond
+
+			err := checks.LRPCycle(env, "some-lrp")
+			Expect(err).To(MatchError("timed out after 200ms waiting for 1 instances to be running"))
+		})
+	})
+
+	Describe("SSHExec", func() {
+		It("requires an ssh proxy address", func() {
+			Expect(checks.SSHExec(env, "some-lrp")).To(MatchError("no ssh proxy address configured"))
+			expectNothingLeft()
+		})
+
+		It("reports why the ssh proxy could not run the command", func() {
+			env.SSHAddress = "127.0.0.1:1"
+			env.LifecycleURL = "http://example.com/lifecycle.tgz"
+			env.Timeout = 200 * time.Millisecond
+
+			err := checks.SSHExec(env, "some-lrp")
+			Expect(err).To(MatchError(ContainSubstring("timed out after 200ms waiting for ssh command to run: dial tcp 127.0.0.1:1")))
+
+			lrp, err := client.DesiredLRPByProcessGuid(env.Logger, "trace-id", "some-lrp")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lrp.Ports).To(ContainElement(fixtures.SSHPort))
+		})
+	})
+})
@@ -0,0 +1 @@
+package checks // import "code.cloudfoundry.org/vizzini/checks"
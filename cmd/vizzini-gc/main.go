@@ -0,0 +1,79 @@
+// vizzini-gc removes the Tasks and DesiredLRPs that crashed or aborted
+// Vizzini runs left in stale vizzini-* domains.
+//
+// It reads the BBS address and client certificates from the same config as
+// the suite (VIZZINI_CONFIG_PATH, VIZZINI_* environment variables or flags):
+//
+//	VIZZINI_CONFIG_PATH=/path/to/vizzini.json vizzini-gc --min-age=2h --dry-run
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	vizziniconfig "code.cloudfoundry.org/vizzini/config"
+	"code.cloudfoundry.org/vizzini/tracker"
+)
+
+const traceID = "vizzini-gc"
+
+func main() {
+	flagSet := flag.NewFlagSet("vizzini-gc", flag.ExitOnError)
+	configFlags := vizziniconfig.RegisterFlags(flagSet)
+	domainPrefix := flagSet.String("domain-prefix", tracker.DefaultDomainPrefix, "only sweep domains with this prefix")
+	minAge := flagSet.Duration("min-age", tracker.DefaultMinAge, "only sweep domains with no task or actual LRP activity for this long")
+	timeout := flagSet.Duration("timeout", tracker.DefaultTimeout, "how long to wait for each task or LRP to go away")
+	dryRun := flagSet.Bool("dry-run", false, "list stale domains without sweeping them")
+	flagSet.Parse(os.Args[1:])
+
+	config, _, err := vizziniconfig.Load(os.Getenv("VIZZINI_CONFIG_PATH"), os.LookupEnv, configFlags)
+	if err != nil {
+		fail(err)
+	}
+
+	logger := lager.NewLogger("vizzini-gc")
+	logger.RegisterSink(lager.NewWriterSink(os.Stderr, lager.INFO))
+
+	client, err := config.BBSClient()
+	if err != nil {
+		fail(err)
+	}
+
+	options := tracker.GCOptions{
+		DomainPrefix: *domainPrefix,
+		MinAge:       *minAge,
+		Timeout:      *timeout,
+		PollInterval: time.Second,
+	}
+	staleDomains, err := tracker.FindStaleDomains(logger, client, traceID, options)
+	if err != nil {
+		fail(err)
+	}
+
+	failed := false
+	for _, stale := range staleDomains {
+		fmt.Println(stale)
+		if *dryRun {
+			continue
+		}
+		err := tracker.Sweep(logger, client, traceID, stale, options)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+	if len(staleDomains) == 0 {
+		fmt.Println("no stale domains")
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "vizzini-gc:", err)
+	os.Exit(1)
+}
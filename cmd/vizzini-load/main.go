@@ -0,0 +1,101 @@
+// vizzini-load measures how the BBS copes with a steady stream of DesireTask,
+// DesireLRP and UpdateDesiredLRP requests.  Everything it desires lives in its
+// own vizzini-load-* domain and is removed again when the run ends.
+//
+// It reads the BBS address, client certificates and fixture settings from the
+// same config as the suite (VIZZINI_CONFIG_PATH, VIZZINI_* environment
+// variables or flags):
+//
+//	VIZZINI_CONFIG_PATH=/path/to/vizzini.json vizzini-load --rate=50 --concurrency=8 --duration=2m \
+//	    --mix=desire_task=2,desire_lrp=1,update_desired_lrp=1
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	vizziniconfig "code.cloudfoundry.org/vizzini/config"
+	"code.cloudfoundry.org/vizzini/load"
+	"code.cloudfoundry.org/vizzini/tracker"
+	uuid "github.com/nu7hatch/gouuid"
+)
+
+const traceID = "vizzini-load"
+
+func main() {
+	flagSet := flag.NewFlagSet("vizzini-load", flag.ExitOnError)
+	configFlags := vizziniconfig.RegisterFlags(flagSet)
+	rate := flagSet.Float64("rate", 10, "target requests per second; 0 sends as fast as the workers can")
+	concurrency := flagSet.Int("concurrency", 4, "number of requests in flight at once")
+	duration := flagSet.Duration("duration", time.Minute, "how long to send requests for")
+	mixFlag := flagSet.String("mix", load.DefaultMix, "comma-separated operation=weight pairs")
+	lrpInstances := flagSet.Int("lrp-instances", 0, "instances per desired LRP; 0 keeps the cells out of the measurement")
+	domain := flagSet.String("domain", "", "domain to desire everything in (default vizzini-load-<random>)")
+	jsonOutput := flagSet.Bool("json", false, "print the report as JSON")
+	flagSet.Parse(os.Args[1:])
+
+	config, _, err := vizziniconfig.Load(os.Getenv("VIZZINI_CONFIG_PATH"), os.LookupEnv, configFlags)
+	if err != nil {
+		fail(err)
+	}
+
+	mix, err := load.ParseMix(*mixFlag)
+	if err != nil {
+		fail(err)
+	}
+	if *domain == "" {
+		u, err := uuid.NewV4()
+		if err != nil {
+			fail(err)
+		}
+		*domain = tracker.DefaultDomainPrefix + "load-" + u.String()[:8]
+	}
+
+	logger := lager.NewLogger("vizzini-load")
+	logger.RegisterSink(lager.NewWriterSink(os.Stderr, lager.INFO))
+
+	client, err := config.BBSClient()
+	if err != nil {
+		fail(err)
+	}
+
+	// stop sending on ^C but still clean up
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report, err := load.Run(ctx, logger, client, traceID, load.Options{
+		Rate:           *rate,
+		Concurrency:    *concurrency,
+		Duration:       *duration,
+		Mix:            mix,
+		LRPInstances:   int32(*lrpInstances),
+		Fixtures:       config.Fixtures(*domain),
+		CleanupTimeout: tracker.DefaultTimeout,
+	})
+	if report.Endpoints != nil {
+		if *jsonOutput {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			encoder.Encode(report)
+		} else {
+			fmt.Println(report)
+		}
+	}
+	if err != nil {
+		fail(err)
+	}
+	if report.CleanupError != "" || len(report.Leaks) > 0 {
+		os.Exit(1)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "vizzini-load:", err)
+	os.Exit(1)
+}
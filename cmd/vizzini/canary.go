@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/vizzini/canary"
+	"code.cloudfoundry.org/vizzini/checks"
+	vizziniconfig "code.cloudfoundry.org/vizzini/config"
+	"code.cloudfoundry.org/vizzini/tracker"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// runCanary runs each scenario every --interval until interrupted and serves
+// the results on /metrics.
+func (c *cli) runCanary(args []string) int {
+	flagSet := c.newFlagSet("vizzini canary")
+	configFlags := vizziniconfig.RegisterFlags(flagSet)
+	scenariosFlag := flagSet.String("scenarios", canary.DefaultScenarios, "comma-separated scenarios to run: task, lrp, ssh")
+	interval := flagSet.Duration("interval", time.Minute, "how long each scenario waits between runs")
+	jitter := flagSet.Duration("jitter", 10*time.Second, "randomize each interval by up to this much either way")
+	listen := flagSet.String("listen", ":9090", "address to serve /metrics on")
+	domain := flagSet.String("domain", "", "domain to desire everything in (default vizzini-canary-<random>)")
+	config, err := c.loadConfig(flagSet, configFlags, args)
+	if err != nil {
+		return c.fail(err)
+	}
+	client, err := c.newClient(config)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	scenarios, err := canary.ParseScenarios(*scenariosFlag)
+	if err != nil {
+		return c.fail(err)
+	}
+	if *domain == "" {
+		*domain, err = newDomain("canary")
+		if err != nil {
+			return c.fail(err)
+		}
+	}
+
+	logger := c.newLogger("vizzini-canary")
+	registry := prometheus.NewRegistry()
+	metrics, err := canary.NewMetrics(registry)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	// listen up front so that a bad --listen fails the command
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return c.fail(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Handler: mux}
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("failed-to-serve-metrics", err)
+		}
+	}()
+	logger.Info("serving-metrics", lager.Data{"address": listener.Addr().String(), "domain": *domain})
+
+	// finish the runs in progress on ^C so nothing is left behind
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err = canary.Run(ctx, canary.Options{
+		Env: checks.Env{
+			Logger:       logger,
+			Client:       client,
+			TraceID:      "vizzini-canary",
+			Fixtures:     config.Fixtures(*domain),
+			Timeout:      time.Duration(config.DefaultEventuallyTimeout),
+			SSHAddress:   config.SSHAddress,
+			SSHPassword:  config.SSHPassword,
+			LifecycleURL: lifecycleURL(config),
+		},
+		Scenarios:      scenarios,
+		Interval:       *interval,
+		Jitter:         *jitter,
+		CleanupTimeout: tracker.DefaultTimeout,
+	}, metrics)
+	server.Close()
+	if err != nil {
+		return c.fail(err)
+	}
+	return 0
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/vizzini/checks"
+	vizziniconfig "code.cloudfoundry.org/vizzini/config"
+	"code.cloudfoundry.org/vizzini/tracker"
+	uuid "github.com/nu7hatch/gouuid"
+)
+
+// checkResult is what a one-off check prints.
+type checkResult struct {
+	Check        string        `json:"check"`
+	Guid         string        `json:"guid"`
+	Domain       string        `json:"domain"`
+	Succeeded    bool          `json:"succeeded"`
+	Duration     time.Duration `json:"duration"`
+	Error        string        `json:"error,omitempty"`
+	CleanupError string        `json:"cleanup_error,omitempty"`
+}
+
+func (r checkResult) String() string {
+	line := fmt.Sprintf("%s: succeeded in %s", r.Check, r.Duration.Round(time.Millisecond))
+	if !r.Succeeded {
+		line = fmt.Sprintf("%s: failed after %s: %s", r.Check, r.Duration.Round(time.Millisecond), r.Error)
+	}
+	line += fmt.Sprintf("\n  guid %s in domain %s", r.Guid, r.Domain)
+	if r.CleanupError != "" {
+		line += "\n  cleanup failed, vizzini-gc will sweep the domain later: " + r.CleanupError
+	}
+	return line
+}
+
+func (c *cli) runTask(args []string) int {
+	return c.runCheck("task run", "task", checks.TaskRoundTrip, args)
+}
+
+func (c *cli) runLRPSmoke(args []string) int {
+	return c.runCheck("lrp smoke", "lrp", checks.LRPCycle, args)
+}
+
+func (c *cli) runSSHCheck(args []string) int {
+	return c.runCheck("ssh check", "ssh", checks.SSHExec, args)
+}
+
+// runCheck runs check once in a fresh domain, removes anything it left
+// behind, and exits non-zero if either failed.
+func (c *cli) runCheck(name, kind string, check checks.Check, args []string) int {
+	flagSet := c.newFlagSet("vizzini " + name)
+	configFlags := vizziniconfig.RegisterFlags(flagSet)
+	domain := flagSet.String("domain", "", "domain to desire everything in (default vizzini-cli-<random>)")
+	timeout := flagSet.Duration("timeout", 0, "how long to wait for each step (default default_eventually_timeout)")
+	jsonOutput := flagSet.Bool("json", false, "print the result as JSON")
+	config, err := c.loadConfig(flagSet, configFlags, args)
+	if err != nil {
+		return c.fail(err)
+	}
+	client, err := c.newClient(config)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	if *domain == "" {
+		*domain, err = newDomain("cli")
+		if err != nil {
+			return c.fail(err)
+		}
+	}
+	if *timeout == 0 {
+		*timeout = time.Duration(config.DefaultEventuallyTimeout)
+	}
+	u, err := uuid.NewV4()
+	if err != nil {
+		return c.fail(err)
+	}
+	guid := fmt.Sprintf("%s-%s-%s", *domain, kind, u.String()[:8])
+
+	logger := c.newLogger("vizzini")
+	resourceTracker := tracker.New(client)
+	env := checks.Env{
+		Logger:       logger,
+		Client:       resourceTracker.Client(),
+		TraceID:      traceID,
+		Fixtures:     config.Fixtures(*domain),
+		Timeout:      *timeout,
+		SSHAddress:   config.SSHAddress,
+		SSHPassword:  config.SSHPassword,
+		LifecycleURL: lifecycleURL(config),
+	}
+
+	result := checkResult{Check: name, Guid: guid, Domain: *domain, Succeeded: true}
+	start := time.Now()
+	err = check(env, guid)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Succeeded = false
+		result.Error = err.Error()
+	}
+	err = resourceTracker.Cleanup(logger, env.TraceID)
+	if err != nil {
+		result.CleanupError = err.Error()
+	}
+
+	if *jsonOutput {
+		err = c.printJSON(result)
+		if err != nil {
+			return c.fail(err)
+		}
+	} else {
+		fmt.Fprintln(c.stdout, result)
+	}
+	if !result.Succeeded || result.CleanupError != "" {
+		return 1
+	}
+	return 0
+}
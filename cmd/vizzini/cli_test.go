@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	vizziniconfig "code.cloudfoundry.org/vizzini/config"
+	"code.cloudfoundry.org/vizzini/fakebbs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("vizzini", func() {
+	var (
+		server         *fakebbs.Server
+		client         bbs.InternalClient
+		stdout, stderr *gbytes.Buffer
+		c              *cli
+	)
+
+	BeforeEach(func() {
+		var err error
+		server = fakebbs.NewServerWithStepInterval(10 * time.Millisecond)
+		server.Start()
+		client, err = server.Client()
+		Expect(err).NotTo(HaveOccurred())
+
+		stdout, stderr = gbytes.NewBuffer(), gbytes.NewBuffer()
+		c = &cli{
+			stdout:    stdout,
+			stderr:    stderr,
+			lookupEnv: func(string) (string, bool) { return "", false },
+			newClient: func(vizziniconfig.VizziniConfig) (bbs.InternalClient, error) { return client, nil },
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("exits 2 with the usage for an unknown command", func() {
+		Expect(c.run([]string{"tasks", "run"})).To(Equal(2))
+		Expect(stderr).To(gbytes.Say(`unknown command "tasks run"`))
+		Expect(stderr).To(gbytes.Say("task run"))
+	})
+
+	It("exits 2 for an unknown flag", func() {
+		Expect(c.run([]string{"cells", "list", "--no-such-flag"})).To(Equal(2))
+		Expect(stderr).To(gbytes.Say("no-such-flag"))
+	})
+
+	It("exits 1 when it cannot connect", func() {
+		c.newClient = vizziniconfig.VizziniConfig.BBSClient
+		Expect(c.run([]string{"domains", "list"})).To(Equal(1))
+		Expect(stderr).To(gbytes.Say("vizzini: .*bbs_address"))
+	})
+
+	Describe("task run", func() {
+		args := func(extra ...string) []string {
+			return append([]string{"task", "run", "--domain=vizzini-cli-test", "--timeout=5s", "--json"}, extra...)
+		}
+
+		It("exits 0 once the task has completed and been deleted", func() {
+			Expect(c.run(args("--default-rootfs=" + models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot)))).To(Equal(0))
+
+			result := checkResult{}
+			Expect(json.Unmarshal(stdout.Contents(), &result)).To(Succeed())
+			Expect(result.Check).To(Equal("task run"))
+			Expect(result.Domain).To(Equal("vizzini-cli-test"))
+			Expect(result.Guid).To(HavePrefix("vizzini-cli-test-task-"))
+			Expect(result.Succeeded).To(BeTrue())
+			Expect(result.CleanupError).To(BeEmpty())
+
+			Expect(client.TasksByDomain(lagertest.NewTestLogger("test"), traceID, "vizzini-cli-test")).To(BeEmpty())
+		})
+
+		It("exits 1 when the task fails", func() {
+			Expect(c.run(args("--default-rootfs=" + models.PreloadedRootFS("unsupported")))).To(Equal(1))
+
+			result := checkResult{}
+			Expect(json.Unmarshal(stdout.Contents(), &result)).To(Succeed())
+			Expect(result.Succeeded).To(BeFalse())
+			Expect(result.Error).To(ContainSubstring("found no compatible cell"))
+		})
+	})
+
+	Describe("cells list", func() {
+		BeforeEach(func() {
+			lrp := &models.DesiredLRP{
+				ProcessGuid: "some-guid",
+				Domain:      "vizzini-cli-test",
+				Instances:   1,
+				Action:      models.WrapAction(&models.RunAction{Path: "/tmp/grace/grace", User: "vcap"}),
+				RootFs:      models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot),
+				MemoryMb:    128,
+				DiskMb:      256,
+			}
+			logger := lagertest.NewTestLogger("test")
+			Expect(client.DesireLRP(logger, traceID, lrp)).To(Succeed())
+			Eventually(func() ([]*models.ActualLRP, error) {
+				return client.ActualLRPs(logger, traceID, models.ActualLRPFilter{CellID: fakebbs.FakeCellID})
+			}).Should(HaveLen(1))
+		})
+
+		It("prints each cell with what is placed on it", func() {
+			Expect(c.run([]string{"cells", "list"})).To(Equal(0))
+			Expect(stdout).To(gbytes.Say(`CELL\s+ZONE`))
+			Expect(stdout).To(gbytes.Say(fakebbs.FakeCellID + `\s+z1\s+16384\s+65536\s+250\s+1\s+0\s`))
+		})
+
+		It("prints the cells as JSON with --json", func() {
+			Expect(c.run([]string{"cells", "list", "--json"})).To(Equal(0))
+
+			cells := []cell{}
+			Expect(json.Unmarshal(stdout.Contents(), &cells)).To(Succeed())
+			Expect(cells).To(HaveLen(1))
+			Expect(cells[0].CellId).To(Equal(fakebbs.FakeCellID))
+			Expect(cells[0].ActualLRPs).To(Equal(1))
+			Expect(cells[0].Tasks).To(Equal(0))
+		})
+
+		It("says so when there are no cells", func() {
+			server.SetCells(nil)
+			Expect(c.run([]string{"cells", "list"})).To(Equal(0))
+			Expect(stdout).To(gbytes.Say("no cells"))
+		})
+	})
+})
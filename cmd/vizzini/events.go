@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	vizziniconfig "code.cloudfoundry.org/vizzini/config"
+	"code.cloudfoundry.org/vizzini/eventstream"
+)
+
+// listFlag collects a flag that may be repeated or given as a comma-separated
+// list.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// runEvents tails the LRP instance and task event streams until interrupted,
+// printing each event with what changed.
+func (c *cli) runEvents(args []string) int {
+	flagSet := c.newFlagSet("vizzini events")
+	configFlags := vizziniconfig.RegisterFlags(flagSet)
+	filter := eventstream.Filter{}
+	flagSet.Var((*listFlag)(&filter.Domains), "domain", "only show events in this domain (repeatable)")
+	flagSet.Var((*listFlag)(&filter.Guids), "guid", "only show events about this task or process GUID (repeatable)")
+	flagSet.Var((*listFlag)(&filter.Types), "type", "only show events of this type, e.g. actual_lrp_crashed (repeatable)")
+	jsonOutput := flagSet.Bool("json", false, "print one JSON object per event")
+	config, err := c.loadConfig(flagSet, configFlags, args)
+	if err != nil {
+		return c.fail(err)
+	}
+	client, err := c.newClient(config)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Tail hands over one event at a time, so printErr needs no lock
+	var printErr error
+	eventstream.Tail(ctx, c.newLogger("vizzini"), client, eventstream.TailOptions{
+		OnDisconnect: func(stream string, err error) {
+			fmt.Fprintf(c.stderr, "vizzini: %s event stream dropped (%s), reconnecting\n", stream, err)
+		},
+	}, func(event models.Event) {
+		if printErr != nil || !filter.Matches(event) {
+			return
+		}
+		line := eventstream.Describe(event, time.Now())
+		if *jsonOutput {
+			printErr = c.printJSON(line)
+		} else {
+			_, printErr = fmt.Fprintln(c.stdout, line)
+		}
+		if printErr != nil {
+			stop()
+		}
+	})
+	if printErr != nil {
+		return c.fail(printErr)
+	}
+	return 0
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	vizziniconfig "code.cloudfoundry.org/vizzini/config"
+	"code.cloudfoundry.org/vizzini/inspect"
+)
+
+// runInspect prints what the BBS knows about a task GUID, process GUID or
+// domain, e.g. one a failed spec referenced.
+func (c *cli) runInspect(args []string) int {
+	flagSet := c.newFlagSet("vizzini inspect")
+	configFlags := vizziniconfig.RegisterFlags(flagSet)
+	jsonOutput := flagSet.Bool("json", false, "print the report as JSON")
+	flagSet.Usage = func() {
+		fmt.Fprintln(flagSet.Output(), "usage: vizzini inspect [flags] <task guid|process guid|domain>")
+		flagSet.PrintDefaults()
+	}
+	config, err := c.loadConfig(flagSet, configFlags, args)
+	if err != nil {
+		return c.fail(err)
+	}
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		return 2
+	}
+	client, err := c.newClient(config)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	report := inspect.Inspect(c.newLogger("vizzini"), client, traceID, flagSet.Arg(0))
+	if *jsonOutput {
+		err = c.printJSON(report)
+		if err != nil {
+			return c.fail(err)
+		}
+	} else {
+		fmt.Fprintln(c.stdout, report)
+	}
+	if !report.Found() || len(report.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"code.cloudfoundry.org/bbs/models"
+	vizziniconfig "code.cloudfoundry.org/vizzini/config"
+	"code.cloudfoundry.org/vizzini/inspect"
+)
+
+// cell is what cells list prints for each cell: its presence plus how much
+// of the deployment is currently placed on it.
+type cell struct {
+	*models.CellPresence
+	ActualLRPs int `json:"actual_lrps"`
+	Tasks      int `json:"tasks"`
+}
+
+func (c *cli) listCells(args []string) int {
+	flagSet := c.newFlagSet("vizzini cells list")
+	configFlags := vizziniconfig.RegisterFlags(flagSet)
+	jsonOutput := flagSet.Bool("json", false, "print the cells as JSON")
+	config, err := c.loadConfig(flagSet, configFlags, args)
+	if err != nil {
+		return c.fail(err)
+	}
+	client, err := c.newClient(config)
+	if err != nil {
+		return c.fail(err)
+	}
+	logger := c.newLogger("vizzini")
+
+	presences, err := client.Cells(logger, traceID)
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to fetch cells: %s", err.Error()))
+	}
+	actualLRPs, err := client.ActualLRPs(logger, traceID, models.ActualLRPFilter{})
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to fetch actual LRPs: %s", err.Error()))
+	}
+	tasks, err := client.Tasks(logger, traceID)
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to fetch tasks: %s", err.Error()))
+	}
+
+	cells := make([]cell, len(presences))
+	byID := map[string]*cell{}
+	for i, presence := range presences {
+		cells[i] = cell{CellPresence: presence}
+		byID[presence.CellId] = &cells[i]
+	}
+	for _, actualLRP := range actualLRPs {
+		if placed, ok := byID[actualLRP.CellId]; ok {
+			placed.ActualLRPs++
+		}
+	}
+	for _, task := range tasks {
+		if placed, ok := byID[task.CellId]; ok {
+			placed.Tasks++
+		}
+	}
+	sort.Slice(cells, func(i, j int) bool { return cells[i].CellId < cells[j].CellId })
+
+	if *jsonOutput {
+		err = c.printJSON(cells)
+		if err != nil {
+			return c.fail(err)
+		}
+		return 0
+	}
+	if len(cells) == 0 {
+		fmt.Fprintln(c.stdout, "no cells")
+		return 0
+	}
+	writer := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "CELL\tZONE\tMEMORY MB\tDISK MB\tCONTAINERS\tLRPS\tTASKS\tROOTFS\tPLACEMENT TAGS")
+	for _, placed := range cells {
+		memory, disk, containers := int32(0), int32(0), int32(0)
+		if placed.Capacity != nil {
+			memory, disk, containers = placed.Capacity.MemoryMb, placed.Capacity.DiskMb, placed.Capacity.Containers
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%s\t%s\n",
+			placed.CellId, placed.Zone, memory, disk, containers, placed.ActualLRPs, placed.Tasks,
+			inspect.RootFSProviders(placed.RootfsProviders), strings.Join(placed.PlacementTags, ","))
+	}
+	err = writer.Flush()
+	if err != nil {
+		return c.fail(err)
+	}
+	return 0
+}
+
+func (c *cli) listDomains(args []string) int {
+	flagSet := c.newFlagSet("vizzini domains list")
+	configFlags := vizziniconfig.RegisterFlags(flagSet)
+	jsonOutput := flagSet.Bool("json", false, "print the domains as JSON")
+	config, err := c.loadConfig(flagSet, configFlags, args)
+	if err != nil {
+		return c.fail(err)
+	}
+	client, err := c.newClient(config)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	domains, err := client.Domains(c.newLogger("vizzini"), traceID)
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to fetch domains: %s", err.Error()))
+	}
+	sort.Strings(domains)
+
+	if *jsonOutput {
+		err = c.printJSON(domains)
+		if err != nil {
+			return c.fail(err)
+		}
+		return 0
+	}
+	if len(domains) == 0 {
+		fmt.Fprintln(c.stdout, "no fresh domains")
+		return 0
+	}
+	for _, domain := range domains {
+		fmt.Fprintln(c.stdout, domain)
+	}
+	return 0
+}
@@ -0,0 +1,159 @@
+// vizzini runs Vizzini's checks against a Diego deployment outside of the
+// Ginkgo suite.
+//
+// It reads the BBS address, client certificates and fixture settings from the
+// same config as the suite (VIZZINI_CONFIG_PATH, VIZZINI_* environment
+// variables or flags):
+//
+//	VIZZINI_CONFIG_PATH=/path/to/vizzini.json vizzini task run
+//	VIZZINI_CONFIG_PATH=/path/to/vizzini.json vizzini cells list --json
+//	VIZZINI_CONFIG_PATH=/path/to/vizzini.json vizzini events --domain=cf-apps
+//	VIZZINI_CONFIG_PATH=/path/to/vizzini.json vizzini canary --interval=1m --listen=:9090
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/lager/v3"
+	vizziniconfig "code.cloudfoundry.org/vizzini/config"
+	"code.cloudfoundry.org/vizzini/tracker"
+	uuid "github.com/nu7hatch/gouuid"
+)
+
+// traceID is what the one-off commands pass to the BBS; the canary uses its
+// own.
+const traceID = "vizzini-cli"
+
+type command struct {
+	name    string
+	summary string
+	run     func(c *cli, args []string) int
+}
+
+var commands = []command{
+	{"task run", "desire a task, wait for it to complete and delete it", (*cli).runTask},
+	{"lrp smoke", "desire a routed LRP, curl it, scale it and remove it", (*cli).runLRPSmoke},
+	{"ssh check", "run a command in an LRP through the ssh proxy", (*cli).runSSHCheck},
+	{"cells list", "list the cells and their capacity", (*cli).listCells},
+	{"domains list", "list the fresh domains", (*cli).listDomains},
+	{"events", "tail LRP instance and task events, with what changed", (*cli).runEvents},
+	{"inspect", "show everything the BBS knows about a task GUID, process GUID or domain", (*cli).runInspect},
+	{"canary", "run checks continuously and export the results as Prometheus metrics", (*cli).runCanary},
+}
+
+// cli is what the commands read their config from, connect with and write
+// to, so that they can be run against a fake BBS.
+type cli struct {
+	stdout    io.Writer
+	stderr    io.Writer
+	lookupEnv func(string) (string, bool)
+	newClient func(vizziniconfig.VizziniConfig) (bbs.InternalClient, error)
+}
+
+func main() {
+	c := &cli{
+		stdout:    os.Stdout,
+		stderr:    os.Stderr,
+		lookupEnv: os.LookupEnv,
+		newClient: vizziniconfig.VizziniConfig.BBSClient,
+	}
+	os.Exit(c.run(os.Args[1:]))
+}
+
+// run runs the command args names and returns the exit code: 1 if the
+// command failed, 2 if it was used incorrectly.
+func (c *cli) run(args []string) int {
+	for _, command := range commands {
+		words := strings.Fields(command.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == command.name {
+			return command.run(c, args[len(words):])
+		}
+	}
+
+	if len(args) > 0 && args[0] != "-h" && args[0] != "help" {
+		fmt.Fprintf(c.stderr, "vizzini: unknown command %q\n\n", strings.Join(args, " "))
+	}
+	fmt.Fprintln(c.stderr, "usage: vizzini <command> [flags]\n\ncommands:")
+	for _, command := range commands {
+		fmt.Fprintf(c.stderr, "  %-14s %s\n", command.name, command.summary)
+	}
+	fmt.Fprintln(c.stderr, "\nrun \"vizzini <command> -h\" for the flags of each command")
+	return 2
+}
+
+// usageError is a bad flag or argument; the usage has already been printed.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+func (c *cli) newFlagSet(name string) *flag.FlagSet {
+	flagSet := flag.NewFlagSet(name, flag.ContinueOnError)
+	flagSet.SetOutput(c.stderr)
+	return flagSet
+}
+
+// loadConfig parses args with flagSet, which must have had the config flags
+// registered by vizziniconfig.RegisterFlags.
+func (c *cli) loadConfig(flagSet *flag.FlagSet, configFlags *vizziniconfig.Flags, args []string) (vizziniconfig.VizziniConfig, error) {
+	err := flagSet.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return vizziniconfig.VizziniConfig{}, err
+	}
+	if err != nil {
+		return vizziniconfig.VizziniConfig{}, usageError{err: err}
+	}
+
+	configPath, _ := c.lookupEnv("VIZZINI_CONFIG_PATH")
+	config, _, err := vizziniconfig.Load(configPath, c.lookupEnv, configFlags)
+	return config, err
+}
+
+func (c *cli) newLogger(component string) lager.Logger {
+	logger := lager.NewLogger(component)
+	logger.RegisterSink(lager.NewWriterSink(c.stderr, lager.INFO))
+	return logger
+}
+
+// newDomain returns a fresh vizzini-<kind>-* domain, which vizzini-gc sweeps
+// if a run dies before cleaning up.
+func newDomain(kind string) (string, error) {
+	u, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	return tracker.DefaultDomainPrefix + kind + "-" + u.String()[:8], nil
+}
+
+func lifecycleURL(config vizziniconfig.VizziniConfig) string {
+	return config.FileServerAddress + "/v1/static/buildpack_app_lifecycle/buildpack_app_lifecycle.tgz"
+}
+
+func (c *cli) printJSON(value interface{}) error {
+	encoder := json.NewEncoder(c.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// fail reports err and returns the exit code for it.
+func (c *cli) fail(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	var usage usageError
+	if errors.As(err, &usage) {
+		return 2
+	}
+	fmt.Fprintln(c.stderr, "vizzini:", err)
+	return 1
+}
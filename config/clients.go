@@ -0,0 +1,28 @@
+package config
+
+import (
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/vizzini/fixtures"
+)
+
+// BBSClient connects to bbs_address with the configured client certificate.
+// The BBS's own certificate is not verified.
+func (c VizziniConfig) BBSClient() (bbs.InternalClient, error) {
+	if c.BBSAddress == "" {
+		return nil, MissingValueError{Key: "bbs_address"}
+	}
+	return bbs.NewSecureSkipVerifyClient(c.BBSAddress, c.BBSClientCertPath, c.BBSClientKeyPath, 0, 0)
+}
+
+// Fixtures builds DesiredLRPs and Tasks in domain for the configured
+// deployment.
+func (c VizziniConfig) Fixtures(domain string) fixtures.Defaults {
+	return fixtures.Defaults{
+		Domain:               domain,
+		RootFS:               c.DefaultRootFS,
+		GraceTarballURL:      c.GraceTarballURL,
+		GraceTarballChecksum: c.GraceTarballChecksum,
+		RoutableDomainSuffix: c.RoutableDomainSuffix,
+		PlacementTags:        c.RepPlacementTags,
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 )
 
 type VizziniConfig struct {
@@ -13,7 +14,7 @@ type VizziniConfig struct {
 	SSHAddress                     string   `json:"ssh_address"`
 	SSHPassword                    string   `json:"ssh_password"`
 	RoutableDomainSuffix           string   `json:"routable_domain_suffix"`
-	HostAddress                    string   `json:"host_addresss"`
+	HostAddress                    string   `json:"host_address"`
 	EnableContainerProxyTests      bool     `json:"enable_container_proxy_tests"`
 	ProxyCAPath                    string   `json:"proxy_ca_path"`
 	ProxyClientCertPath            string   `json:"proxy_client_cert_path"`
@@ -27,26 +28,57 @@ type VizziniConfig struct {
 	GraceBusyboxImageURL           string   `json:"grace_busybox_image_url"`
 	DiegoDockerOCIImageURL         string   `json:"diego_docker_oci_image_url"`
 	FileServerAddress              string   `json:"file_server_address"`
+	FakeBBS                        bool     `json:"fake_bbs"`
+	DefaultEventuallyTimeout       Duration `json:"default_eventually_timeout"`
+	DockerTimeout                  Duration `json:"docker_timeout"`
+	ReportDir                      string   `json:"report_dir"`
+	PerfSamples                    int      `json:"perf_samples"`
+	ScenarioDir                    string   `json:"scenario_dir"`
+	Profile                        string   `json:"profile"`
+	ProfilesPath                   string   `json:"profiles_path"`
+	TraceOTLPEndpoint              string   `json:"trace_otlp_endpoint"`
+	TraceFile                      string   `json:"trace_file"`
+	LogDir                         string   `json:"log_dir"`
+	TCPRouterAddress               string   `json:"tcp_router_address"`
+	TCPRouterGroupGuid             string   `json:"tcp_router_group_guid"`
+	TCPExternalPort                int      `json:"tcp_external_port"`
+	NetworkPolicySourceGroup       string   `json:"network_policy_source_group"`
+	NetworkPolicyDestinationGroup  string   `json:"network_policy_destination_group"`
+	InternalRouteDomain            string   `json:"internal_route_domain"`
+
+	unknownKeys []string
 }
 
-func NewVizziniConfig() (VizziniConfig, error) {
-	configPath, ok := os.LookupEnv("VIZZINI_CONFIG_PATH")
-	if !ok {
-		return VizziniConfig{}, fmt.Errorf("error loading Vizzini config: VIZZINI_CONFIG_PATH env var not set")
-	}
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
 
-	configFile, err := os.Open(configPath)
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	err := json.Unmarshal(data, &value)
 	if err != nil {
-		return VizziniConfig{}, fmt.Errorf("error loading Vizzini config: %s", err.Error())
+		return fmt.Errorf("durations must be strings such as \"90s\": %s", err.Error())
 	}
-	defer configFile.Close()
-
-	vizziniConfig := VizziniConfig{}
-	decoder := json.NewDecoder(configFile)
-	err = decoder.Decode(&vizziniConfig)
+	parsed, err := time.ParseDuration(value)
 	if err != nil {
-		return VizziniConfig{}, fmt.Errorf("error unmarshalling Vizzini config: %s", err.Error())
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func NewVizziniConfig() (VizziniConfig, error) {
+	configPath, ok := os.LookupEnv("VIZZINI_CONFIG_PATH")
+	if !ok {
+		return VizziniConfig{}, fmt.Errorf("error loading Vizzini config: VIZZINI_CONFIG_PATH env var not set")
 	}
 
-	return vizziniConfig, nil
+	config, _, err := Load(configPath, os.LookupEnv, nil)
+	return config, err
 }
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const EnvPrefix = "VIZZINI_"
+
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Sources records which layer supplied each config key, keyed by JSON name.
+type Sources map[string]Source
+
+var secretKeys = map[string]bool{
+	"ssh_password": true,
+}
+
+func (s Sources) Report(w io.Writer, config VizziniConfig) {
+	values := fieldsByKey(&config)
+	keys := make([]string, 0, len(s))
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := fmt.Sprintf("%v", values[key].Interface())
+		if secretKeys[key] && value != "" {
+			value = "[REDACTED]"
+		}
+		fmt.Fprintf(w, "%-36s %-8s %s\n", key, s[key], value)
+	}
+}
+
+func Defaults() VizziniConfig {
+	return VizziniConfig{
+		DefaultEventuallyTimeout: Duration(120 * time.Second),
+		DockerTimeout:            Duration(120 * time.Second),
+		PerfSamples:              10,
+		ScenarioDir:              "scenarios",
+		ProfilesPath:             "profiles.yml",
+	}
+}
+
+// Flags holds the command-line overrides registered by RegisterFlags.  Every
+// config key is exposed as --<json-key-with-dashes>, e.g. --bbs-address.
+type Flags struct {
+	flagSet *flag.FlagSet
+	values  map[string]*string
+}
+
+func RegisterFlags(flagSet *flag.FlagSet) *Flags {
+	flags := &Flags{flagSet: flagSet, values: map[string]*string{}}
+	for key := range fieldsByKey(&VizziniConfig{}) {
+		flags.values[key] = flagSet.String(FlagName(key), "", fmt.Sprintf("overrides %q from the Vizzini config file", key))
+	}
+	return flags
+}
+
+func (f *Flags) set() map[string]string {
+	set := map[string]string{}
+	if f == nil || !f.flagSet.Parsed() {
+		return set
+	}
+	names := map[string]string{}
+	for key := range f.values {
+		names[FlagName(key)] = key
+	}
+	f.flagSet.Visit(func(fl *flag.Flag) {
+		if key, ok := names[fl.Name]; ok {
+			set[key] = *f.values[key]
+		}
+	})
+	return set
+}
+
+func FlagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(key)
+}
+
+// legacyEnv maps environment variables that predate the VIZZINI_ prefix onto
+// their config keys.  The prefixed variable wins if both are set.
+var legacyEnv = map[string]string{
+	"default_eventually_timeout": "DEFAULT_EVENTUALLY_TIMEOUT",
+}
+
+// Load merges, in increasing order of precedence, the defaults, the JSON file
+// at configPath (if any), VIZZINI_* environment variables and any flags that
+// were set on the command line.
+func Load(configPath string, lookupEnv func(string) (string, bool), flags *Flags) (VizziniConfig, Sources, error) {
+	config := Defaults()
+	sources := Sources{}
+	fields := fieldsByKey(&config)
+	for key := range fields {
+		sources[key] = SourceDefault
+	}
+
+	if configPath != "" {
+		fileKeys, err := loadFile(configPath, &config)
+		if err != nil {
+			return VizziniConfig{}, nil, err
+		}
+		for _, key := range fileKeys {
+			sources[key] = SourceFile
+		}
+	}
+
+	for key, field := range fields {
+		value, ok := lookupEnv(EnvName(key))
+		if !ok {
+			if legacyName, hasLegacy := legacyEnv[key]; hasLegacy {
+				value, ok = lookupEnv(legacyName)
+			}
+		}
+		if !ok {
+			continue
+		}
+		if err := setField(field, value); err != nil {
+			return VizziniConfig{}, nil, fmt.Errorf("error loading Vizzini config: invalid value %q for %s: %s", value, EnvName(key), err.Error())
+		}
+		sources[key] = SourceEnv
+	}
+
+	for key, value := range flags.set() {
+		if err := setField(fields[key], value); err != nil {
+			return VizziniConfig{}, nil, fmt.Errorf("error loading Vizzini config: invalid value %q for --%s: %s", value, FlagName(key), err.Error())
+		}
+		sources[key] = SourceFlag
+	}
+
+	return config, sources, nil
+}
+
+// deprecatedKeys are accepted in config files for compatibility with
+// existing manifests and applied to the key they were renamed to.
+var deprecatedKeys = map[string]string{
+	"host_addresss": "host_address",
+}
+
+func loadFile(configPath string, config *VizziniConfig) ([]string, error) {
+	contents, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("error loading Vizzini config: %s", err.Error())
+	}
+
+	raw := map[string]json.RawMessage{}
+	err = json.Unmarshal(contents, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling Vizzini config: %s", err.Error())
+	}
+
+	fields := fieldsByKey(config)
+	keys := make([]string, 0, len(raw))
+	for key, value := range raw {
+		if newKey, ok := deprecatedKeys[key]; ok {
+			if _, set := raw[newKey]; set {
+				continue
+			}
+			key = newKey
+		}
+
+		field, ok := fields[key]
+		if !ok {
+			config.unknownKeys = append(config.unknownKeys, key)
+			continue
+		}
+		err = json.Unmarshal(value, field.Addr().Interface())
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshalling Vizzini config: %s: %s", key, err.Error())
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(config.unknownKeys)
+
+	return keys, nil
+}
+
+func fieldsByKey(config *VizziniConfig) map[string]reflect.Value {
+	fields := map[string]reflect.Value{}
+	value := reflect.ValueOf(config).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		key := strings.Split(value.Type().Field(i).Tag.Get("json"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		fields[key] = value.Field(i)
+	}
+	return fields
+}
+
+func setField(field reflect.Value, value string) error {
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(value)
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case int:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(i))
+	case []string:
+		list := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		field.Set(reflect.ValueOf(list))
+	case Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(Duration(d)))
+	default:
+		return fmt.Errorf("unsupported config type %s", field.Type())
+	}
+	return nil
+}
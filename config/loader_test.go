@@ -0,0 +1,102 @@
+package config_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/vizzini/config"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Load", func() {
+	var (
+		configPath string
+		env        map[string]string
+		flagSet    *flag.FlagSet
+		flags      *config.Flags
+	)
+
+	lookupEnv := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+
+	BeforeEach(func() {
+		dir := GinkgoT().TempDir()
+		configPath = filepath.Join(dir, "vizzini.json")
+		Expect(os.WriteFile(configPath, []byte(`{
+			"bbs_address": "https://file.example.com:8889",
+			"ssh_address": "ssh.example.com:2222",
+			"routable_domain_suffix": "file.example.com",
+			"max_task_retries": 2
+		}`), 0644)).To(Succeed())
+
+		env = map[string]string{}
+		flagSet = flag.NewFlagSet("vizzini", flag.ContinueOnError)
+		flags = config.RegisterFlags(flagSet)
+	})
+
+	It("applies defaults, file, env and flags in increasing order of precedence", func() {
+		env["VIZZINI_ROUTABLE_DOMAIN_SUFFIX"] = "env.example.com"
+		env["VIZZINI_BBS_ADDRESS"] = "https://env.example.com:8889"
+		env["VIZZINI_REP_PLACEMENT_TAGS"] = "a, b"
+		Expect(flagSet.Parse([]string{"--bbs-address=https://flag.example.com:8889"})).To(Succeed())
+
+		loaded, sources, err := config.Load(configPath, lookupEnv, flags)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(loaded.BBSAddress).To(Equal("https://flag.example.com:8889"))
+		Expect(loaded.RoutableDomainSuffix).To(Equal("env.example.com"))
+		Expect(loaded.SSHAddress).To(Equal("ssh.example.com:2222"))
+		Expect(loaded.MaxTaskRetries).To(Equal(2))
+		Expect(loaded.RepPlacementTags).To(Equal([]string{"a", "b"}))
+		Expect(time.Duration(loaded.DefaultEventuallyTimeout)).To(Equal(120 * time.Second))
+
+		Expect(sources["bbs_address"]).To(Equal(config.SourceFlag))
+		Expect(sources["routable_domain_suffix"]).To(Equal(config.SourceEnv))
+		Expect(sources["ssh_address"]).To(Equal(config.SourceFile))
+		Expect(sources["default_eventually_timeout"]).To(Equal(config.SourceDefault))
+	})
+
+	It("honours the legacy DEFAULT_EVENTUALLY_TIMEOUT variable", func() {
+		env["DEFAULT_EVENTUALLY_TIMEOUT"] = "3m"
+
+		loaded, sources, err := config.Load(configPath, lookupEnv, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(time.Duration(loaded.DefaultEventuallyTimeout)).To(Equal(3 * time.Minute))
+		Expect(sources["default_eventually_timeout"]).To(Equal(config.SourceEnv))
+	})
+
+	It("works without a config file", func() {
+		env["VIZZINI_FAKE_BBS"] = "true"
+
+		loaded, sources, err := config.Load("", lookupEnv, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.FakeBBS).To(BeTrue())
+		Expect(sources["bbs_address"]).To(Equal(config.SourceDefault))
+	})
+
+	It("rejects values that do not parse", func() {
+		env["VIZZINI_MAX_TASK_RETRIES"] = "lots"
+
+		_, _, err := config.Load(configPath, lookupEnv, nil)
+		Expect(err).To(MatchError(ContainSubstring("VIZZINI_MAX_TASK_RETRIES")))
+	})
+
+	It("redacts secrets when reporting", func() {
+		env["VIZZINI_SSH_PASSWORD"] = "hunter2"
+
+		loaded, sources, err := config.Load(configPath, lookupEnv, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		report := &bytes.Buffer{}
+		sources.Report(report, loaded)
+		Expect(report.String()).To(MatchRegexp(`ssh_password\s+env\s+\[REDACTED\]`))
+		Expect(report.String()).NotTo(ContainSubstring("hunter2"))
+	})
+})
@@ -0,0 +1,329 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+type UnknownKeyError struct {
+	Key string
+}
+
+func (e UnknownKeyError) Error() string {
+	return fmt.Sprintf("unknown key %q", e.Key)
+}
+
+type MissingValueError struct {
+	Key string
+}
+
+func (e MissingValueError) Error() string {
+	return fmt.Sprintf("%s is required", e.Key)
+}
+
+type InvalidAddressError struct {
+	Key   string
+	Value string
+	Err   error
+}
+
+func (e InvalidAddressError) Error() string {
+	return fmt.Sprintf("%s %q is not a valid address: %s", e.Key, e.Value, e.Err.Error())
+}
+
+func (e InvalidAddressError) Unwrap() error {
+	return e.Err
+}
+
+type UnsupportedSchemeError struct {
+	Key       string
+	Value     string
+	Supported []string
+}
+
+func (e UnsupportedSchemeError) Error() string {
+	return fmt.Sprintf("%s %q must use one of the schemes: %s", e.Key, e.Value, strings.Join(e.Supported, ", "))
+}
+
+type MissingFileError struct {
+	Key  string
+	Path string
+	Err  error
+}
+
+func (e MissingFileError) Error() string {
+	return fmt.Sprintf("%s %q cannot be read: %s", e.Key, e.Path, e.Err.Error())
+}
+
+func (e MissingFileError) Unwrap() error {
+	return e.Err
+}
+
+type KeyPairError struct {
+	CertKey string
+	KeyKey  string
+	Err     error
+}
+
+func (e KeyPairError) Error() string {
+	return fmt.Sprintf("%s and %s do not form a valid key pair: %s", e.CertKey, e.KeyKey, e.Err.Error())
+}
+
+func (e KeyPairError) Unwrap() error {
+	return e.Err
+}
+
+type InvalidChecksumError struct {
+	Key       string
+	Value     string
+	Algorithm string
+}
+
+func (e InvalidChecksumError) Error() string {
+	return fmt.Sprintf("%s %q is not a well-formed %s checksum", e.Key, e.Value, e.Algorithm)
+}
+
+type InvalidValueError struct {
+	Key    string
+	Value  string
+	Reason string
+}
+
+func (e InvalidValueError) Error() string {
+	return fmt.Sprintf("%s %q %s", e.Key, e.Value, e.Reason)
+}
+
+// ValidationErrors aggregates every problem Validate finds so that a broken
+// config can be fixed in one pass.
+type ValidationErrors []error
+
+func (e ValidationErrors) Error() string {
+	lines := []string{fmt.Sprintf("invalid Vizzini config (%d problems):", len(e))}
+	for _, err := range e {
+		lines = append(lines, "  - "+err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e ValidationErrors) Unwrap() []error {
+	return e
+}
+
+// sha1 is the only algorithm DesiredLRPWithGuid asks Diego to verify
+const graceChecksumAlgorithm = "sha1"
+const graceChecksumLength = 40
+
+func (c VizziniConfig) Validate() error {
+	errs := ValidationErrors{}
+	add := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, key := range c.unknownKeys {
+		add(UnknownKeyError{Key: key})
+	}
+
+	if !c.FakeBBS {
+		add(required("bbs_address", c.BBSAddress))
+		add(required("ssh_address", c.SSHAddress))
+	}
+
+	add(validateURL("bbs_address", c.BBSAddress, "https"))
+	add(validateHostPort("ssh_address", c.SSHAddress))
+	add(validateHost("host_address", c.HostAddress))
+	add(validateURL("file_server_address", c.FileServerAddress, "http", "https"))
+	add(validateURL("grace_tarball_url", c.GraceTarballURL, "http", "https"))
+	add(validateURL("grace_busybox_image_url", c.GraceBusyboxImageURL, "docker"))
+	add(validateURL("diego_docker_oci_image_url", c.DiegoDockerOCIImageURL, "docker"))
+	add(validateURL("trace_otlp_endpoint", c.TraceOTLPEndpoint, "http", "https"))
+	add(validateRootFS("default_rootfs", c.DefaultRootFS))
+	add(validateChecksum("grace_tarball_checksum", c.GraceTarballChecksum))
+
+	errs = append(errs, validateKeyPair("bbs_client_cert_path", c.BBSClientCertPath, "bbs_client_key_path", c.BBSClientKeyPath)...)
+	errs = append(errs, validateKeyPair("proxy_client_cert_path", c.ProxyClientCertPath, "proxy_client_key_path", c.ProxyClientKeyPath)...)
+	if c.EnableContainerProxyTests {
+		add(required("proxy_ca_path", c.ProxyCAPath))
+	}
+	add(validateCA("proxy_ca_path", c.ProxyCAPath))
+
+	add(validateHost("tcp_router_address", c.TCPRouterAddress))
+	if c.TCPRouterAddress != "" {
+		add(required("tcp_router_group_guid", c.TCPRouterGroupGuid))
+		add(c.ValidateTCPExternalPorts(1))
+	}
+
+	// the policy names both ends, and internal routes only resolve to
+	// instances the policy lets the caller reach
+	if c.NetworkPolicySourceGroup != "" || c.InternalRouteDomain != "" {
+		add(required("network_policy_destination_group", c.NetworkPolicyDestinationGroup))
+	}
+	if c.NetworkPolicyDestinationGroup != "" || c.InternalRouteDomain != "" {
+		add(required("network_policy_source_group", c.NetworkPolicySourceGroup))
+	}
+	add(validateHost("internal_route_domain", c.InternalRouteDomain))
+
+	if c.MaxTaskRetries < 0 {
+		add(InvalidValueError{Key: "max_task_retries", Value: fmt.Sprint(c.MaxTaskRetries), Reason: "must not be negative"})
+	}
+	if c.DefaultEventuallyTimeout <= 0 {
+		add(InvalidValueError{Key: "default_eventually_timeout", Value: c.DefaultEventuallyTimeout.String(), Reason: "must be positive"})
+	}
+	if c.DockerTimeout <= 0 {
+		add(InvalidValueError{Key: "docker_timeout", Value: c.DockerTimeout.String(), Reason: "must be positive"})
+	}
+	if c.PerfSamples <= 0 {
+		add(InvalidValueError{Key: "perf_samples", Value: fmt.Sprint(c.PerfSamples), Reason: "must be positive"})
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// ValidateTCPExternalPorts checks that the TCP routing specs' external ports
+// fit below 65536 when run in processes parallel Ginkgo processes.  Process n
+// routes through tcp_external_port + 2(n-1) and the port after it, so the
+// router group must reserve tcp_external_port up to
+// tcp_external_port + 2*processes - 1.
+func (c VizziniConfig) ValidateTCPExternalPorts(processes int) error {
+	if c.TCPRouterAddress == "" {
+		return nil
+	}
+	below := 65537 - 2*processes
+	if c.TCPExternalPort > 0 && c.TCPExternalPort < below {
+		return nil
+	}
+	reason := fmt.Sprintf("must be a port below %d", below)
+	if processes > 1 {
+		reason += fmt.Sprintf(" to leave two ports for each of %d parallel processes", processes)
+	}
+	return InvalidValueError{Key: "tcp_external_port", Value: fmt.Sprint(c.TCPExternalPort), Reason: reason}
+}
+
+func required(key, value string) error {
+	if value == "" {
+		return MissingValueError{Key: key}
+	}
+	return nil
+}
+
+func validateURL(key, value string, schemes ...string) error {
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return InvalidAddressError{Key: key, Value: value, Err: err}
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			if u.Host == "" && u.Path == "" {
+				return InvalidAddressError{Key: key, Value: value, Err: fmt.Errorf("missing host")}
+			}
+			return nil
+		}
+	}
+	return UnsupportedSchemeError{Key: key, Value: value, Supported: schemes}
+}
+
+func validateHostPort(key, value string) error {
+	if value == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(value); err != nil {
+		return InvalidAddressError{Key: key, Value: value, Err: err}
+	}
+	return nil
+}
+
+func validateHost(key, value string) error {
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse("//" + value)
+	if err != nil {
+		return InvalidAddressError{Key: key, Value: value, Err: err}
+	}
+	if u.Host != value || u.Port() != "" {
+		return InvalidAddressError{Key: key, Value: value, Err: fmt.Errorf("expected a bare host or IP")}
+	}
+	return nil
+}
+
+func validateRootFS(key, value string) error {
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return InvalidAddressError{Key: key, Value: value, Err: err}
+	}
+	if u.Scheme != "preloaded" {
+		return UnsupportedSchemeError{Key: key, Value: value, Supported: []string{"preloaded"}}
+	}
+	if u.Opaque == "" {
+		return InvalidValueError{Key: key, Value: value, Reason: "must name a stack, e.g. preloaded:cflinuxfs4"}
+	}
+	return nil
+}
+
+func validateChecksum(key, value string) error {
+	if value == "" {
+		return nil
+	}
+	if _, err := hex.DecodeString(value); err != nil || len(value) != graceChecksumLength {
+		return InvalidChecksumError{Key: key, Value: value, Algorithm: graceChecksumAlgorithm}
+	}
+	return nil
+}
+
+func validateKeyPair(certKey, certPath, keyKey, keyPath string) []error {
+	if certPath == "" && keyPath == "" {
+		return nil
+	}
+	if certPath == "" {
+		return []error{MissingValueError{Key: certKey}}
+	}
+	if keyPath == "" {
+		return []error{MissingValueError{Key: keyKey}}
+	}
+
+	errs := []error{}
+	if _, err := os.Stat(certPath); err != nil {
+		errs = append(errs, MissingFileError{Key: certKey, Path: certPath, Err: err})
+	}
+	if _, err := os.Stat(keyPath); err != nil {
+		errs = append(errs, MissingFileError{Key: keyKey, Path: keyPath, Err: err})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	if _, err := tls.LoadX509KeyPair(certPath, keyPath); err != nil {
+		return []error{KeyPairError{CertKey: certKey, KeyKey: keyKey, Err: err}}
+	}
+	return nil
+}
+
+func validateCA(key, path string) error {
+	if path == "" {
+		return nil
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return MissingFileError{Key: key, Path: path, Err: err}
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(contents) {
+		return InvalidValueError{Key: key, Value: path, Reason: "does not contain any PEM certificates"}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package config_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/vizzini/config"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validate", func() {
+	var (
+		dir       string
+		validJSON string
+	)
+
+	writeKeyPair := func(name string) (string, string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+		certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		Expect(err).NotTo(HaveOccurred())
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		Expect(err).NotTo(HaveOccurred())
+
+		certPath := filepath.Join(dir, name+".crt")
+		keyPath := filepath.Join(dir, name+".key")
+		Expect(os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0600)).To(Succeed())
+		Expect(os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)).To(Succeed())
+		return certPath, keyPath
+	}
+
+	load := func(contents string) config.VizziniConfig {
+		path := filepath.Join(dir, "vizzini.json")
+		Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+		loaded, _, err := config.Load(path, func(string) (string, bool) { return "", false }, nil)
+		Expect(err).NotTo(HaveOccurred())
+		return loaded
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		certPath, keyPath := writeKeyPair("bbs")
+		validJSON = `{
+			"bbs_address": "https://bbs.service.cf.internal:8889",
+			"bbs_client_cert_path": "` + certPath + `",
+			"bbs_client_key_path": "` + keyPath + `",
+			"ssh_address": "ssh-proxy.service.cf.internal:2222",
+			"host_address": "10.0.0.5",
+			"default_rootfs": "preloaded:cflinuxfs4",
+			"grace_tarball_url": "https://example.com/grace.tgz",
+			"grace_tarball_checksum": "0123456789abcdef0123456789abcdef01234567",
+			"grace_busybox_image_url": "docker:///cloudfoundry/grace"
+		}`
+	})
+
+	It("accepts a well-formed config", func() {
+		Expect(load(validJSON).Validate()).To(Succeed())
+	})
+
+	It("accepts the misspelled host_addresss key that older manifests use", func() {
+		loaded := load(`{"fake_bbs": true, "host_addresss": "10.0.0.5"}`)
+		Expect(loaded.Validate()).To(Succeed())
+		Expect(loaded.HostAddress).To(Equal("10.0.0.5"))
+	})
+
+	It("reports every problem at once", func() {
+		certPath, _ := writeKeyPair("bbs")
+		_, otherKeyPath := writeKeyPair("other")
+
+		err := load(`{
+			"bbs_address": "http://bbs.service.cf.internal:8889",
+			"bbs_client_cert_path": "` + certPath + `",
+			"bbs_client_key_path": "` + otherKeyPath + `",
+			"ssh_address": "ssh-proxy.service.cf.internal",
+			"default_rootfs": "docker:///cloudfoundry/cflinuxfs4",
+			"grace_tarball_checksum": "not-hex",
+			"proxy_ca_path": "/does/not/exist",
+			"bbs_adress": "typo"
+		}`).Validate()
+		Expect(err).To(HaveOccurred())
+
+		var validationErrors config.ValidationErrors
+		Expect(errors.As(err, &validationErrors)).To(BeTrue())
+		Expect(validationErrors).To(HaveLen(7))
+
+		var unknownKey config.UnknownKeyError
+		Expect(errors.As(err, &unknownKey)).To(BeTrue())
+		Expect(unknownKey.Key).To(Equal("bbs_adress"))
+
+		var unsupportedScheme config.UnsupportedSchemeError
+		Expect(errors.As(err, &unsupportedScheme)).To(BeTrue())
+
+		var invalidAddress config.InvalidAddressError
+		Expect(errors.As(err, &invalidAddress)).To(BeTrue())
+		Expect(invalidAddress.Key).To(Equal("ssh_address"))
+
+		var keyPair config.KeyPairError
+		Expect(errors.As(err, &keyPair)).To(BeTrue())
+
+		var checksum config.InvalidChecksumError
+		Expect(errors.As(err, &checksum)).To(BeTrue())
+
+		var missingFile config.MissingFileError
+		Expect(errors.As(err, &missingFile)).To(BeTrue())
+		Expect(missingFile.Key).To(Equal("proxy_ca_path"))
+
+		Expect(err.Error()).To(ContainSubstring("invalid Vizzini config (7 problems)"))
+	})
+
+	It("requires the BBS and SSH addresses unless running against the fake BBS", func() {
+		err := load(`{}`).Validate()
+		Expect(err).To(MatchError(ContainSubstring("bbs_address is required")))
+		Expect(err).To(MatchError(ContainSubstring("ssh_address is required")))
+
+		Expect(load(`{"fake_bbs": true}`).Validate()).To(Succeed())
+	})
+
+	It("requires at least one perf sample", func() {
+		Expect(load(`{"fake_bbs": true}`).PerfSamples).To(Equal(10))
+
+		err := load(`{"fake_bbs": true, "perf_samples": 0}`).Validate()
+		Expect(err).To(MatchError(ContainSubstring(`perf_samples "0" must be positive`)))
+	})
+
+	It("requires the OTLP endpoint to be an HTTP URL", func() {
+		Expect(load(`{"fake_bbs": true, "trace_otlp_endpoint": "http://localhost:4318"}`).Validate()).To(Succeed())
+
+		err := load(`{"fake_bbs": true, "trace_otlp_endpoint": "localhost:4317"}`).Validate()
+		Expect(err).To(MatchError(ContainSubstring(`trace_otlp_endpoint "localhost:4317" must use one of the schemes: http, https`)))
+	})
+
+	It("requires a router group and external port along with the TCP router address", func() {
+		Expect(load(`{"fake_bbs": true, "tcp_router_address": "tcp.example.com", "tcp_router_group_guid": "default-tcp", "tcp_external_port": 61000}`).Validate()).To(Succeed())
+
+		err := load(`{"fake_bbs": true, "tcp_router_address": "tcp.example.com:1024"}`).Validate()
+		Expect(err).To(MatchError(ContainSubstring(`tcp_router_address "tcp.example.com:1024"`)))
+		Expect(err).To(MatchError(ContainSubstring("tcp_router_group_guid is required")))
+		Expect(err).To(MatchError(ContainSubstring(`tcp_external_port "0" must be a port below 65535`)))
+	})
+
+	It("leaves two external ports for each parallel process", func() {
+		config := load(`{"fake_bbs": true, "tcp_router_address": "tcp.example.com", "tcp_router_group_guid": "default-tcp", "tcp_external_port": 65528}`)
+		Expect(config.ValidateTCPExternalPorts(1)).To(Succeed())
+		Expect(config.ValidateTCPExternalPorts(4)).To(Succeed())
+		Expect(config.ValidateTCPExternalPorts(5)).To(MatchError(`tcp_external_port "65528" must be a port below 65527 to leave two ports for each of 5 parallel processes`))
+
+		Expect(load(`{"fake_bbs": true}`).ValidateTCPExternalPorts(5)).To(Succeed())
+	})
+
+	It("requires both ends of the network policy", func() {
+		Expect(load(`{"fake_bbs": true, "network_policy_source_group": "vizzini-source", "network_policy_destination_group": "vizzini-destination", "internal_route_domain": "apps.internal"}`).Validate()).To(Succeed())
+
+		err := load(`{"fake_bbs": true, "network_policy_source_group": "vizzini-source"}`).Validate()
+		Expect(err).To(MatchError(ContainSubstring("network_policy_destination_group is required")))
+
+		err = load(`{"fake_bbs": true, "internal_route_domain": "apps.internal"}`).Validate()
+		Expect(err).To(MatchError(ContainSubstring("network_policy_source_group is required")))
+		Expect(err).To(MatchError(ContainSubstring("network_policy_destination_group is required")))
+	})
+
+	It("requires both halves of a key pair", func() {
+		certPath, _ := writeKeyPair("proxy")
+		err := load(`{"fake_bbs": true, "proxy_client_cert_path": "` + certPath + `"}`).Validate()
+		Expect(err).To(MatchError(ContainSubstring("proxy_client_key_path is required")))
+	})
+})
@@ -2,34 +2,27 @@ package vizzini_test
 
 import (
 	"encoding/json"
-	"net/http"
 
 	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/vizzini/grace"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
 
 var _ = Describe("The container environment", func() {
 	var lrp *models.DesiredLRP
-	var url string
 
 	BeforeEach(func() {
-		url = "http://" + RouteForGuid(guid) + "/env?json=true"
 		lrp = DesiredLRPWithGuid(guid)
 		lrp.Ports = []uint32{8080, 5000}
 
 		Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-		Eventually(EndpointCurler(url)).Should(Equal(http.StatusOK))
+		Eventually(GraceClient(guid).Ready).Should(BeTrue())
 	})
 
-	getEnvs := func(url string) [][]string {
-		response, err := http.Get(url)
+	getEnvs := func() grace.Env {
+		envs, err := GraceClient(guid).Env()
 		Expect(err).NotTo(HaveOccurred())
-		Expect(response.StatusCode).To(Equal(http.StatusOK))
-		envs := [][]string{}
-		err = json.NewDecoder(response.Body).Decode(&envs)
-		Expect(err).NotTo(HaveOccurred())
-		response.Body.Close()
 		return envs
 	}
 
@@ -38,7 +31,7 @@ var _ = Describe("The container environment", func() {
 			actualLRP, err := ActualLRPByProcessGuidAndIndex(logger, guid, 0)
 			Expect(err).NotTo(HaveOccurred())
 
-			envs := getEnvs(url)
+			envs := getEnvs()
 
 			Expect(envs).To(ContainElement([]string{"INSTANCE_INDEX", "0"}))
 			Expect(envs).To(ContainElement([]string{"INSTANCE_GUID", actualLRP.InstanceGuid}))
@@ -64,13 +57,13 @@ var _ = Describe("The container environment", func() {
 			})
 			Expect(err).NotTo(HaveOccurred())
 
-			envs := getEnvs(url)
+			envs := getEnvs()
 			Expect(envs).To(ContainElement([]string{"CF_INSTANCE_IP", actualLRP.Address}), "If this fails, then your executor may not be configured to expose ip:port to the container")
 			Expect(envs).To(ContainElement([]string{"CF_INSTANCE_PORTS", string(cfPortMappingPayload)}))
 		})
 
 		It("includes CF_INSTANCE_INTERNAL_IP", func() {
-			envs := getEnvs(url)
+			envs := getEnvs()
 			Expect(envs).To(ContainElement(ContainElement("CF_INSTANCE_INTERNAL_IP")))
 		})
 	})
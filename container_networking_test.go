@@ -0,0 +1,101 @@
+package vizzini_test
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/vizzini/fixtures"
+	"code.cloudfoundry.org/vizzini/profiles"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// These specs need a network policy, created ahead of time with the policy
+// server, that lets network_policy_source_group reach
+// network_policy_destination_group on the Grace port.
+var _ = Describe("Container networking", Label(profiles.Networking), func() {
+	var sourceGuid, destinationGuid, strangerGuid string
+
+	curl := func(callerGuid, target string) func() (int, error) {
+		return func() (int, error) {
+			return GraceClient(callerGuid).Curl(target)
+		}
+	}
+
+	BeforeEach(func() {
+		if config.NetworkPolicySourceGroup == "" {
+			Skip("network_policy_source_group is not configured")
+		}
+		// derived from guid, so that a failed spec's forensics cover all three
+		sourceGuid, destinationGuid, strangerGuid = guid+"-source", guid, guid+"-stranger"
+
+		destinationOptions := []fixtures.LRPOption{fixtures.WithPolicyGroup(config.NetworkPolicyDestinationGroup)}
+		if config.InternalRouteDomain != "" {
+			destinationOptions = append(destinationOptions, fixtures.WithInternalRoute(destinationGuid+"."+config.InternalRouteDomain))
+		}
+
+		for _, lrp := range []*models.DesiredLRP{
+			DesiredLRPWithGuid(sourceGuid, fixtures.WithPolicyGroup(config.NetworkPolicySourceGroup)),
+			DesiredLRPWithGuid(destinationGuid, destinationOptions...),
+			// no policy names a fresh policy group
+			DesiredLRPWithGuid(strangerGuid, fixtures.WithPolicyGroup(strangerGuid)),
+		} {
+			Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
+		}
+		for _, lrpGuid := range []string{sourceGuid, destinationGuid, strangerGuid} {
+			WaitForActualLRPState(lrpGuid, 0, models.ActualLRPStateRunning)
+			Eventually(GraceClient(lrpGuid).Ready).Should(BeTrue())
+		}
+	})
+
+	It("gives each instance an overlay address", func() {
+		for _, lrpGuid := range []string{sourceGuid, destinationGuid, strangerGuid} {
+			actualLRP, err := ActualGetter(logger, lrpGuid, 0)()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actualLRP.InstanceAddress).NotTo(BeEmpty())
+			Expect(actualLRP.InstanceAddress).NotTo(Equal(actualLRP.Address), "the overlay address should not be the cell's")
+		}
+	})
+
+	It("refuses connections that no policy allows", func() {
+		target := OverlayURLFor(destinationGuid, 0, fixtures.GracePort) + "/env"
+
+		By("first reaching the destination from the source, so a refusal means the policy is in force")
+		// policies reach the cells' firewalls a few seconds after the containers start
+		Eventually(curl(sourceGuid, target)).Should(Equal(http.StatusOK))
+
+		By("calling the destination from a container in another policy group")
+		Consistently(curl(strangerGuid, target), 5*time.Second).Should(Equal(http.StatusInternalServerError))
+
+		By("calling the source from the destination, against the direction of the policy")
+		target = OverlayURLFor(sourceGuid, 0, fixtures.GracePort) + "/env"
+		Consistently(curl(destinationGuid, target), 5*time.Second).Should(Equal(http.StatusInternalServerError))
+	})
+
+	It("allows connections that the policy permits", func() {
+		target := OverlayURLFor(destinationGuid, 0, fixtures.GracePort) + "/env"
+		// policies reach the cells' firewalls a few seconds after the containers start
+		Eventually(curl(sourceGuid, target)).Should(Equal(http.StatusOK))
+	})
+
+	Context("with an internal route", func() {
+		var target string
+
+		BeforeEach(func() {
+			if config.InternalRouteDomain == "" {
+				Skip("internal_route_domain is not configured")
+			}
+			target = fmt.Sprintf("http://%s.%s:%d/env", destinationGuid, config.InternalRouteDomain, fixtures.GracePort)
+		})
+
+		It("reaches the destination through its internal hostname", func() {
+			Eventually(curl(sourceGuid, target)).Should(Equal(http.StatusOK))
+
+			By("still refusing callers that no policy allows")
+			Expect(curl(strangerGuid, target)()).To(Equal(http.StatusInternalServerError))
+		})
+	})
+})
@@ -1,56 +1,34 @@
 package vizzini_test
 
 import (
-	"fmt"
 	"net/http"
 	"time"
 
-	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/vizzini/grace"
 	. "code.cloudfoundry.org/vizzini/matchers"
+	"code.cloudfoundry.org/vizzini/profiles"
 
 	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
 
-func MakeGraceExit(baseURL string, status int) {
+func MakeGraceExit(graceClient *grace.Client, status int) {
+	logger.Info("making-grace-exit", lager.Data{"url": graceClient.URL(), "status": status})
+
 	//make sure Grace is up first
-	Eventually(EndpointCurler(baseURL + "/env")).Should(Equal(http.StatusOK))
-
-	//make Grace exit
-	for i := 0; i < 10; i++ {
-		url := fmt.Sprintf("%s/exit/%d", baseURL, status)
-		resp, err := http.Post(url, "application/octet-stream", nil)
-		Expect(err).NotTo(HaveOccurred())
-		resp.Body.Close()
-		if resp.StatusCode == http.StatusOK {
-			return
-		}
-		logger.Info(
-			"grace-exit-request-failed",
-			lager.Data{"response-status-code": resp.StatusCode, "attempt": i, "desired-exit-status": status},
-		)
-		time.Sleep(10 * time.Millisecond)
-	}
-	Fail("failed to make grace exit")
-}
+	Eventually(graceClient.Ready).Should(BeTrue())
 
-func TellGraceToDeleteFile(baseURL string, filename string) {
-	url := fmt.Sprintf("%s/file/%s", baseURL, filename)
-	req, err := http.NewRequest("DELETE", url, nil)
-	Expect(err).NotTo(HaveOccurred())
-	resp, err := http.DefaultClient.Do(req)
-	Expect(err).NotTo(HaveOccurred())
-	resp.Body.Close()
-	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	Expect(graceClient.Exit(status)).To(Succeed(), "failed to make grace exit")
 }
 
 var _ = Describe("Crashes", func() {
 	var lrp *models.DesiredLRP
-	var url string
+	var graceClient *grace.Client
 
 	BeforeEach(func() {
-		url = fmt.Sprintf("http://%s", RouteForGuid(guid))
+		graceClient = GraceClient(guid, grace.WithRetries(10, 10*time.Millisecond))
 		lrp = DesiredLRPWithGuid(guid)
 	})
 
@@ -60,11 +38,11 @@ var _ = Describe("Crashes", func() {
 		})
 
 		It("adds the crash reason to the application", func() {
-			MakeGraceExit(url, 17)
-			Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateRunning, 1))
-			actualLRP, err := ActualLRPByProcessGuidAndIndex(logger, guid, 0)
-			Expect(err).NotTo(HaveOccurred())
-			Expect(actualLRP.CrashReason).To(ContainSubstring("Exited with status 17"))
+			MakeGraceExit(graceClient, 17)
+			WaitForActualLRP(MatchActualLRP(guid, 0).
+				WithState(models.ActualLRPStateRunning).
+				WithCrashCount(1).
+				WithCrashReason("Exited with status 17"))
 		})
 	})
 
@@ -73,49 +51,50 @@ var _ = Describe("Crashes", func() {
 			Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
 		})
 
-		It("{SLOW} restarts the application immediately twice, and then starts backing it off, and updates the modification tag as it goes", func() {
+		It("restarts the application immediately twice, and then starts backing it off, and updates the modification tag as it goes", Label(profiles.Slow), func() {
 			actualLRP, err := ActualLRPByProcessGuidAndIndex(logger, guid, 0)
 			Expect(err).NotTo(HaveOccurred())
 			tag := actualLRP.ModificationTag
 
 			By("immediately restarting #1")
-			MakeGraceExit(url, 1)
-			Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateRunning, 1))
+			MakeGraceExit(graceClient, 1)
+			WaitForActualLRP(MatchActualLRP(guid, 0).
+				WithState(models.ActualLRPStateRunning).
+				WithCrashCount(1).
+				WithModificationTagAfter(tag))
 
 			restartedActualLRP, err := ActualLRPByProcessGuidAndIndex(logger, guid, 0)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(restartedActualLRP.InstanceGuid).NotTo(Equal(actualLRP.InstanceGuid))
-			Expect(restartedActualLRP.ModificationTag.Epoch).To(Equal(tag.Epoch))
-			Expect(restartedActualLRP.ModificationTag.Index).To(BeNumerically(">", tag.Index))
 
 			By("immediately restarting #2")
-			MakeGraceExit(url, 1)
-			Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateRunning, 2))
+			MakeGraceExit(graceClient, 1)
+			WaitForActualLRP(MatchActualLRP(guid, 0).WithState(models.ActualLRPStateRunning).WithCrashCount(2))
 
 			By("eventually restarting #3 (slow)")
-			MakeGraceExit(url, 1)
-			Eventually(ActualGetter(logger, guid, 0), ConvergerInterval).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateCrashed, 3))
+			MakeGraceExit(graceClient, 1)
+			WaitForActualLRP(MatchActualLRP(guid, 0).WithState(models.ActualLRPStateCrashed).WithCrashCount(3), ConvergerInterval)
 			Consistently(ActualGetter(logger, guid, 0), CrashRestartTimeout-5*time.Second).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateCrashed, 3))
-			Eventually(ActualGetter(logger, guid, 0), ConvergerInterval*2).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateRunning, 3))
-			Eventually(EndpointCurler(url + "/env")).Should(Equal(http.StatusOK))
+			WaitForActualLRP(MatchActualLRP(guid, 0).WithState(models.ActualLRPStateRunning).WithCrashCount(3), ConvergerInterval*2)
+			Eventually(graceClient.Ready).Should(BeTrue())
 		})
 
 		It("deletes the crashed ActualLRP when scaling down", func() {
 			By("immediately restarting #1")
-			MakeGraceExit(url, 1)
-			Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateRunning, 1))
+			MakeGraceExit(graceClient, 1)
+			WaitForActualLRP(MatchActualLRP(guid, 0).WithState(models.ActualLRPStateRunning).WithCrashCount(1))
 
 			By("immediately restarting #2")
-			MakeGraceExit(url, 1)
-			Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateRunning, 2))
+			MakeGraceExit(graceClient, 1)
+			WaitForActualLRP(MatchActualLRP(guid, 0).WithState(models.ActualLRPStateRunning).WithCrashCount(2))
 
 			By("eventually restarting #3")
-			MakeGraceExit(url, 1)
-			Eventually(ActualGetter(logger, guid, 0), ConvergerInterval).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateCrashed, 3))
+			MakeGraceExit(graceClient, 1)
+			WaitForActualLRP(MatchActualLRP(guid, 0).WithState(models.ActualLRPStateCrashed).WithCrashCount(3), ConvergerInterval)
 
 			By("deleting the DesiredLRP")
 			Expect(bbsClient.RemoveDesiredLRP(logger, traceID, guid)).To(Succeed())
-			Eventually(ActualByProcessGuidGetter(logger, guid)).Should(BeEmpty())
+			WaitForDesiredLRPRemoved(guid)
 		})
 	})
 
@@ -126,16 +105,16 @@ var _ = Describe("Crashes", func() {
 
 		It("should delete the Crashed ActualLRP succesfully", func() {
 			By("immediately restarting #1")
-			MakeGraceExit(url, 1)
-			Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateRunning, 1))
+			MakeGraceExit(graceClient, 1)
+			WaitForActualLRP(MatchActualLRP(guid, 0).WithState(models.ActualLRPStateRunning).WithCrashCount(1))
 
 			By("immediately restarting #2")
-			MakeGraceExit(url, 1)
-			Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateRunning, 2))
+			MakeGraceExit(graceClient, 1)
+			WaitForActualLRP(MatchActualLRP(guid, 0).WithState(models.ActualLRPStateRunning).WithCrashCount(2))
 
 			By("eventually restarting #3")
-			MakeGraceExit(url, 1)
-			Eventually(ActualGetter(logger, guid, 0), ConvergerInterval).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateCrashed, 3))
+			MakeGraceExit(graceClient, 1)
+			WaitForActualLRP(MatchActualLRP(guid, 0).WithState(models.ActualLRPStateCrashed).WithCrashCount(3), ConvergerInterval)
 
 			actualLRPKey := models.NewActualLRPKey(guid, 0, domain)
 			Expect(bbsClient.RetireActualLRP(logger, traceID, &actualLRPKey)).To(Succeed())
@@ -151,32 +130,32 @@ var _ = Describe("Crashes", func() {
 		Context("when running a single action", func() {
 			BeforeEach(func() {
 				Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-				Eventually(EndpointCurler(url + "/env")).Should(Equal(http.StatusOK))
+				Eventually(graceClient.Ready).Should(BeTrue())
 			})
 
 			It("comes up as soon as the process starts", func() {
-				Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithState(guid, 0, models.ActualLRPStateRunning))
+				WaitForActualLRPState(guid, 0, models.ActualLRPStateRunning)
 			})
 
 			Context("when the process dies with exit code 0", func() {
 				BeforeEach(func() {
-					MakeGraceExit(url, 0)
+					MakeGraceExit(graceClient, 0)
 				})
 
 				It("gets restarted immediately", func() {
-					Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateRunning, 1))
-					Eventually(EndpointCurler(url + "/env")).Should(Equal(http.StatusOK))
+					WaitForActualLRP(MatchActualLRP(guid, 0).WithState(models.ActualLRPStateRunning).WithCrashCount(1))
+					Eventually(graceClient.Ready).Should(BeTrue())
 				})
 			})
 
 			Context("when the process dies with exit code 1", func() {
 				BeforeEach(func() {
-					MakeGraceExit(url, 1)
+					MakeGraceExit(graceClient, 1)
 				})
 
 				It("gets restarted immediately", func() {
-					Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateRunning, 1))
-					Eventually(EndpointCurler(url + "/env")).Should(Equal(http.StatusOK))
+					WaitForActualLRP(MatchActualLRP(guid, 0).WithState(models.ActualLRPStateRunning).WithCrashCount(1))
+					Eventually(graceClient.Ready).Should(BeTrue())
 				})
 			})
 		})
@@ -204,13 +183,13 @@ var _ = Describe("Crashes", func() {
 
 				Context("when one of the actions finishes", func() {
 					JustBeforeEach(func() {
-						Eventually(EndpointCurler(url + "/env")).Should(Equal(http.StatusOK))
-						MakeGraceExit(url, 0)
+						Eventually(graceClient.Ready).Should(BeTrue())
+						MakeGraceExit(graceClient, 0)
 					})
 
 					It("gets restarted immediately", func() {
-						Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateRunning, 1))
-						Eventually(EndpointCurler(url + "/env")).Should(Equal(http.StatusOK))
+						WaitForActualLRP(MatchActualLRP(guid, 0).WithState(models.ActualLRPStateRunning).WithCrashCount(1))
+						Eventually(graceClient.Ready).Should(BeTrue())
 					})
 				})
 			})
@@ -255,14 +234,10 @@ var _ = Describe("Crashes", func() {
 
 						It("shows the monitor crash reasons", func() {
 
-							Eventually(ActualGetter(logger, guid, 0), HealthyCheckInterval+5*time.Second).Should(BeActualLRPWithCrashCount(guid, 0, 1))
-
-							actualLRP, err := ActualGetter(logger, guid, 0)()
-							Expect(err).NotTo(HaveOccurred())
-
-							Expect(actualLRP.CrashReason).To(ContainSubstring("Instance never healthy after"))
-							Expect(actualLRP.CrashReason).To(MatchRegexp("failed to make TCP connection to .*:9090: dial tcp .*:9090: connect: connection refused"))
-							Expect(actualLRP.CrashReason).To(ContainSubstring("failed to make HTTP request to '/ping' on port 9090: connection refused"))
+							WaitForActualLRP(MatchActualLRP(guid, 0).
+								WithCrashCount(1).
+								WithCrashReason("Instance never healthy after", "failed to make HTTP request to '/ping' on port 9090: connection refused").
+								WithCrashReasonMatching("failed to make TCP connection to .*:9090: dial tcp .*:9090: connect: connection refused"), HealthyCheckInterval+5*time.Second)
 						})
 					})
 				})
@@ -301,14 +276,11 @@ var _ = Describe("Crashes", func() {
 
 						It("shows the monitor crash reasons", func() {
 
-							Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithState(guid, 0, models.ActualLRPStateRunning))
-							MakeGraceExit(url, 0)
-							Eventually(ActualGetter(logger, guid, 0), HealthyCheckInterval+10*time.Second).Should(BeActualLRPWithCrashCount(guid, 0, 1))
-
-							actualLRP, err := ActualGetter(logger, guid, 0)()
-							Expect(err).NotTo(HaveOccurred())
-
-							Expect(actualLRP.CrashReason).To(ContainSubstring("Instance became unhealthy: Liveness check unsuccessful: failed to make HTTP request to '/ping' on port 8080: connection refused"))
+							WaitForActualLRPState(guid, 0, models.ActualLRPStateRunning)
+							MakeGraceExit(graceClient, 0)
+							WaitForActualLRP(MatchActualLRP(guid, 0).
+								WithCrashCount(1).
+								WithCrashReason("Instance became unhealthy: Liveness check unsuccessful: failed to make HTTP request to '/ping' on port 8080: connection refused"), HealthyCheckInterval+10*time.Second)
 						})
 					})
 
@@ -327,14 +299,11 @@ var _ = Describe("Crashes", func() {
 
 						It("shows the monitor crash reasons", func() {
 
-							Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithState(guid, 0, models.ActualLRPStateRunning))
-							MakeGraceExit(url, 0)
-							Eventually(ActualGetter(logger, guid, 0), HealthyCheckInterval+10*time.Second).Should(BeActualLRPWithCrashCount(guid, 0, 1))
-
-							actualLRP, err := ActualGetter(logger, guid, 0)()
-							Expect(err).NotTo(HaveOccurred())
-
-							Expect(actualLRP.CrashReason).To(MatchRegexp("Instance became unhealthy: Liveness check unsuccessful: failed to make TCP connection to .*:8080: dial tcp .*:8080: connect: connection refused"))
+							WaitForActualLRPState(guid, 0, models.ActualLRPStateRunning)
+							MakeGraceExit(graceClient, 0)
+							WaitForActualLRP(MatchActualLRP(guid, 0).
+								WithCrashCount(1).
+								WithCrashReasonMatching("Instance became unhealthy: Liveness check unsuccessful: failed to make TCP connection to .*:8080: dial tcp .*:8080: connect: connection refused"), HealthyCheckInterval+10*time.Second)
 						})
 					})
 
@@ -359,14 +328,14 @@ var _ = Describe("Crashes", func() {
 
 						It("shows the monitor crash reasons", func() {
 
-							Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithState(guid, 0, models.ActualLRPStateRunning))
-							MakeGraceExit(url, 0)
-							Eventually(ActualGetter(logger, guid, 0), HealthyCheckInterval+10*time.Second).Should(BeActualLRPWithCrashCount(guid, 0, 1))
+							WaitForActualLRPState(guid, 0, models.ActualLRPStateRunning)
+							MakeGraceExit(graceClient, 0)
+							WaitForActualLRP(MatchActualLRP(guid, 0).
+								WithCrashCount(1).
+								WithCrashReason("Instance became unhealthy:"), HealthyCheckInterval+10*time.Second)
 
 							actualLRP, err := ActualGetter(logger, guid, 0)()
 							Expect(err).NotTo(HaveOccurred())
-
-							Expect(actualLRP.CrashReason).To(ContainSubstring("Instance became unhealthy:"))
 							Expect(actualLRP.CrashReason).To(SatisfyAny(
 								MatchRegexp("failed to make TCP connection to .*:8080: dial tcp .*:8080: connect: connection refused"),
 								ContainSubstring("failed to make HTTP request to '/ping' on port 8080: connection refused"),
@@ -391,12 +360,12 @@ var _ = Describe("Crashes", func() {
 						},
 					))
 					Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-					Eventually(EndpointCurler(url + "/env")).Should(Equal(http.StatusOK))
+					Eventually(graceClient.Ready).Should(BeTrue())
 				})
 
 				Context("when one of the actions finishes", func() {
 					BeforeEach(func() {
-						MakeGraceExit(url, 2)
+						MakeGraceExit(graceClient, 2)
 					})
 
 					It("does not crash", func() {
@@ -410,7 +379,6 @@ var _ = Describe("Crashes", func() {
 	Context("with a monitor action", func() {
 		Context("when the monitor eventually succeeds", func() {
 			var directURL string
-			var indirectURL string
 			BeforeEach(func() {
 				lrp.Action = models.WrapAction(&models.RunAction{
 					Path: "/tmp/grace/grace",
@@ -426,10 +394,9 @@ var _ = Describe("Crashes", func() {
 				})
 
 				Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-				Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithState(guid, 0, models.ActualLRPStateRunning))
-				Eventually(EndpointCurler(url + "/env")).Should(Equal(http.StatusOK))
+				WaitForActualLRPState(guid, 0, models.ActualLRPStateRunning)
+				Eventually(graceClient.Ready).Should(BeTrue())
 				directURL = "https://" + TLSDirectAddressFor(guid, 0, 8080)
-				indirectURL = "http://" + RouteForGuid(guid)
 			})
 
 			It("enters the running state", func() {
@@ -438,7 +405,7 @@ var _ = Describe("Crashes", func() {
 
 			Context("when the process dies with exit code 0", func() {
 				BeforeEach(func() {
-					MakeGraceExit(indirectURL, 0)
+					MakeGraceExit(graceClient, 0)
 				})
 
 				It("does not get marked as crashed (may have daemonized)", func() {
@@ -450,54 +417,54 @@ var _ = Describe("Crashes", func() {
 				BeforeEach(func() {
 					//tell grace to delete the file then exit, it's highly unlikely that the health check will run
 					//between these two lines so the test should actually be covering the edge case in question
-					TellGraceToDeleteFile(url, "up")
-					MakeGraceExit(indirectURL, 0)
+					Expect(graceClient.DeleteFile("up")).To(Succeed())
+					MakeGraceExit(graceClient, 0)
 				})
 
-				It("{SLOW} is marked as crashed", func() {
+				It("is marked as crashed", Label(profiles.Slow), func() {
 					Consistently(ActualGetter(logger, guid, 0), 2).Should(BeActualLRPWithState(guid, 0, models.ActualLRPStateRunning), "Banking on the fact that the health check runs every thirty seconds and is unlikely to run immediately")
-					Eventually(ActualGetter(logger, guid, 0), HealthyCheckInterval+5*time.Second).Should(BeActualLRPWithCrashCount(guid, 0, 1))
+					WaitForActualLRP(MatchActualLRP(guid, 0).WithCrashCount(1), HealthyCheckInterval+5*time.Second)
 				})
 			})
 
 			Context("when the process dies with exit code 1", func() {
 				BeforeEach(func() {
-					MakeGraceExit(indirectURL, 1)
+					MakeGraceExit(graceClient, 1)
 				})
 
 				It("is marked as crashed (immediately)", func() {
-					Eventually(ActualGetter(logger, guid, 0), HealthyCheckInterval/3).Should(BeActualLRPWithCrashCount(guid, 0, 1))
+					WaitForActualLRP(MatchActualLRP(guid, 0).WithCrashCount(1), HealthyCheckInterval/3)
 				})
 			})
 
 			Context("when the monitor subsequently fails", func() {
 				BeforeEach(func() {
-					TellGraceToDeleteFile(indirectURL, "up")
+					Expect(graceClient.DeleteFile("up")).To(Succeed())
 				})
 
-				It("{SLOW} is marked as crashed (and reaped)", func() {
+				It("is marked as crashed (and reaped)", Label(profiles.Slow), func() {
 					actualLRP, err := ActualLRPByProcessGuidAndIndex(logger, guid, 0)
 					Expect(err).ToNot(HaveOccurred())
 
 					tlsConfig, err := containerProxyTLSConfig(actualLRP.InstanceGuid)
 					Expect(err).NotTo(HaveOccurred())
 
-					httpClient := &http.Client{
+					directClient := grace.NewClient(directURL, grace.WithHTTPClient(&http.Client{
 						Timeout: time.Second,
 						Transport: &http.Transport{
 							TLSClientConfig: tlsConfig,
 						},
-					}
+					}))
 
 					By("first validate that we can connect to the container directly using " + directURL)
-					_, err = httpClient.Get(directURL + "/env")
+					_, err = directClient.Env()
 					Expect(err).NotTo(HaveOccurred())
 
 					By("being marked as crashed")
-					Eventually(ActualGetter(logger, guid, 0), HealthyCheckInterval+10*time.Second).Should(BeActualLRPWithCrashCount(guid, 0, 1))
+					WaitForActualLRP(MatchActualLRP(guid, 0).WithCrashCount(1), HealthyCheckInterval+10*time.Second)
 
 					By("tearing down the process -- this reaches out to the container's direct address " + directURL + " and ensures we can't reach it")
-					_, err = httpClient.Get(directURL + "/env")
+					_, err = directClient.Env()
 					Expect(err).To(HaveOccurred())
 				})
 			})
@@ -511,7 +478,7 @@ var _ = Describe("Crashes", func() {
 				})
 
 				Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-				Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithState(guid, 0, models.ActualLRPStateClaimed))
+				WaitForActualLRPState(guid, 0, models.ActualLRPStateClaimed)
 			})
 
 			Context("when the process dies with exit code 0", func() {
@@ -540,7 +507,7 @@ var _ = Describe("Crashes", func() {
 				})
 
 				It("gets marked as crashed (immediately)", func() {
-					Eventually(ActualGetter(logger, guid, 0), 30*time.Second).Should(BeActualLRPWithCrashCount(guid, 0, 1))
+					WaitForActualLRP(MatchActualLRP(guid, 0).WithCrashCount(1), 30*time.Second)
 				})
 			})
 
@@ -551,7 +518,7 @@ var _ = Describe("Crashes", func() {
 
 				It("never enters the running state and is marked as crashed after the StartTimeout", func() {
 					Consistently(ActualGetter(logger, guid, 0), 3).Should(BeActualLRPWithState(guid, 0, models.ActualLRPStateClaimed))
-					Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithCrashCount(guid, 0, 1))
+					WaitForActualLRP(MatchActualLRP(guid, 0).WithCrashCount(1))
 				})
 			})
 
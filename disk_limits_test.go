@@ -2,7 +2,10 @@ package vizzini_test
 
 import (
 	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/vizzini/capabilities"
+	"code.cloudfoundry.org/vizzini/fixtures"
 	. "code.cloudfoundry.org/vizzini/matchers"
+	"code.cloudfoundry.org/vizzini/profiles"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -28,7 +31,7 @@ var _ = Describe("DiskLimits", func() {
 			It("should not crash, but should start succesfully", func() {
 				lrp.DiskMb = 64
 				Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-				Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithState(guid, 0, models.ActualLRPStateRunning))
+				WaitForActualLRPState(guid, 0, models.ActualLRPStateRunning)
 			})
 		})
 
@@ -36,33 +39,36 @@ var _ = Describe("DiskLimits", func() {
 			It("should crash", func() {
 				lrp.DiskMb = 4
 				Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-				Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPThatHasCrashed(guid, 0))
+				WaitForActualLRP(MatchActualLRP(guid, 0).ThatHasCrashed())
 
 				//getting all the way helps ensure the tests don't spuriously fail
 				//when we delete the DesiredLRP if the application is in the middle of restarting it looks like we need to wiat for a convergence
 				//loop to eventually clean it up.  This is likely a bug, though it's not crticial.
-				Eventually(ActualGetter(logger, guid, 0), ConvergerInterval).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateCrashed, 3))
+				WaitForActualLRP(MatchActualLRP(guid, 0).WithState(models.ActualLRPStateCrashed).WithCrashCount(3), ConvergerInterval)
 			})
 		})
 	})
 
-	Describe("{DOCKER} with a docker-image rootfs", func() {
+	Describe("with a docker-image rootfs", Label(profiles.Docker), func() {
 		BeforeEach(func() {
-			lrp.RootFs = config.GraceBusyboxImageURL
-			lrp.Setup = nil //note: we copy nothing in, the docker image on its own should cause this failure
-			lrp.Action = models.WrapAction(&models.RunAction{
-				Path: "/grace",
-				User: "root",
-				Env:  []*models.EnvironmentVariable{{Name: "PORT", Value: "8080"}},
-			})
-			lrp.Monitor = nil
+			RequireCapabilities(capabilities.Docker)
+			//note: we copy nothing in, the docker image on its own should cause this failure
+			lrp = DesiredLRPWithGuid(guid,
+				fixtures.WithDockerImage(config.GraceBusyboxImageURL),
+				fixtures.WithAction(&models.RunAction{
+					Path: "/grace",
+					User: "root",
+					Env:  []*models.EnvironmentVariable{{Name: "PORT", Value: "8080"}},
+				}),
+				fixtures.WithoutMonitor(),
+			)
 		})
 
 		Context("when the disk limit exceeds the size of the docker image", func() {
 			It("should not crash, but should start succesfully", func() {
 				lrp.DiskMb = 64
 				Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-				Eventually(ActualGetter(logger, guid, 0), dockerTimeout).Should(BeActualLRPWithState(guid, 0, models.ActualLRPStateRunning))
+				WaitForActualLRPState(guid, 0, models.ActualLRPStateRunning, dockerTimeout)
 			})
 		})
 
@@ -70,12 +76,12 @@ var _ = Describe("DiskLimits", func() {
 			It("should crash", func() {
 				lrp.DiskMb = 4
 				Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-				Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPThatHasCrashed(guid, 0))
+				WaitForActualLRP(MatchActualLRP(guid, 0).ThatHasCrashed())
 
 				//getting all the way helps ensure the tests don't spuriously fail
 				//when we delete the DesiredLRP if the application is in the middle of restarting it looks like we need to wiat for a convergence
 				//loop to eventually clean it up.  This is likely a bug, though it's not crticial.
-				Eventually(ActualGetter(logger, guid, 0), ConvergerInterval).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateCrashed, 3))
+				WaitForActualLRP(MatchActualLRP(guid, 0).WithState(models.ActualLRPStateCrashed).WithCrashCount(3), ConvergerInterval)
 			})
 		})
 	})
@@ -24,13 +24,13 @@ var _ = Describe("Download Checksums", func() {
 				ChecksumValue:     "0123456789abcdef0123456789abcdef01234567",
 			})
 			Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-			Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPThatHasCrashed(guid, 0))
+			WaitForActualLRP(MatchActualLRP(guid, 0).ThatHasCrashed())
 
 			// getting all the way helps ensure the tests don't spuriously fail
 			// when we delete the DesiredLRP if the application is in the middle of
 			// restarting it looks like we need to wait for a convergence loop to
 			// eventually clean it up.  This is likely a bug, though it's not critical.
-			Eventually(ActualGetter(logger, guid, 0), ConvergerInterval).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateCrashed, 3))
+			WaitForActualLRP(MatchActualLRP(guid, 0).WithState(models.ActualLRPStateCrashed).WithCrashCount(3), ConvergerInterval)
 		})
 	})
 })
@@ -0,0 +1,246 @@
+package eventstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/vizzini/forensics"
+)
+
+// Filter selects events by domain, task or process GUID, and event type.
+// Within each field any value matches; an empty field matches everything.
+type Filter struct {
+	Domains []string
+	Guids   []string
+	Types   []string
+}
+
+func (f Filter) Matches(event models.Event) bool {
+	return matchesAny(f.Domains, DomainFor(event)) &&
+		matchesAny(f.Guids, forensics.GuidFor(event)) &&
+		matchesAny(f.Types, event.EventType())
+}
+
+func matchesAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// DomainFor returns the domain of the task or LRP an event is about.
+func DomainFor(event models.Event) string {
+	switch event := event.(type) {
+	case *models.DesiredLRPCreatedEvent:
+		return event.DesiredLrp.Domain
+	case *models.DesiredLRPChangedEvent:
+		return event.After.Domain
+	case *models.DesiredLRPRemovedEvent:
+		return event.DesiredLrp.Domain
+	case *models.ActualLRPInstanceCreatedEvent:
+		return event.ActualLrp.Domain
+	case *models.ActualLRPInstanceChangedEvent:
+		return event.Domain
+	case *models.ActualLRPInstanceRemovedEvent:
+		return event.ActualLrp.Domain
+	case *models.ActualLRPCrashedEvent:
+		return event.Domain
+	case *models.TaskCreatedEvent:
+		return event.Task.Domain
+	case *models.TaskChangedEvent:
+		return event.After.Domain
+	case *models.TaskRemovedEvent:
+		return event.Task.Domain
+	}
+	return ""
+}
+
+// Change is one field that differs between an event's Before and After.
+// Nested fields are named by their JSON path, e.g. modification_tag.index.
+type Change struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Field, c.Before, c.After)
+}
+
+// Line is an event rendered for people to read.
+type Line struct {
+	At     time.Time `json:"at"`
+	Type   string    `json:"type"`
+	Domain string    `json:"domain"`
+	Guid   string    `json:"guid"`
+
+	// Index is the ActualLRP instance index, if the event is about one.
+	Index *int32 `json:"index,omitempty"`
+
+	// Changes lists what changed for events with a Before and After; other
+	// events are summed up in Summary instead.
+	Changes []Change `json:"changes,omitempty"`
+	Summary string   `json:"summary,omitempty"`
+}
+
+// ignoredChanges change on every transition and say nothing a reader of the
+// tail cares about.
+var ignoredChanges = map[string]bool{
+	"since":                  true,
+	"updated_at":             true,
+	"modification_tag.index": true,
+}
+
+// Describe renders event as a Line received at at.
+func Describe(event models.Event, at time.Time) Line {
+	line := Line{
+		At:     at,
+		Type:   event.EventType(),
+		Domain: DomainFor(event),
+		Guid:   forensics.GuidFor(event),
+	}
+
+	switch event := event.(type) {
+	case *models.ActualLRPInstanceChangedEvent:
+		line.Index = &event.Index
+		line.Changes = withEnumName(Diff(event.Before, event.After), "presence", event.Before.Presence.String(), event.After.Presence.String())
+	case *models.DesiredLRPChangedEvent:
+		line.Changes = Diff(event.Before, event.After)
+	case *models.TaskChangedEvent:
+		line.Changes = withEnumName(Diff(event.Before, event.After), "state", event.Before.State.String(), event.After.State.String())
+	case *models.ActualLRPInstanceCreatedEvent:
+		line.Index = &event.ActualLrp.Index
+		line.Summary = forensics.Summarize(event)
+	case *models.ActualLRPInstanceRemovedEvent:
+		line.Index = &event.ActualLrp.Index
+		line.Summary = forensics.Summarize(event)
+	case *models.ActualLRPCrashedEvent:
+		line.Index = &event.Index
+		line.Summary = forensics.Summarize(event)
+	default:
+		line.Summary = forensics.Summarize(event)
+	}
+	return line
+}
+
+func (l Line) String() string {
+	subject := l.Guid
+	if l.Index != nil {
+		subject = fmt.Sprintf("%s/%d", l.Guid, *l.Index)
+	}
+	parts := []string{l.At.Format("15:04:05.000"), fmt.Sprintf("%-28s", l.Type), subject}
+	if l.Domain != "" {
+		parts = append(parts, "["+l.Domain+"]")
+	}
+
+	details := l.Summary
+	if l.Changes != nil {
+		changes := make([]string, len(l.Changes))
+		for i, change := range l.Changes {
+			changes[i] = change.String()
+		}
+		details = strings.Join(changes, ", ")
+		if details == "" {
+			details = "no visible change"
+		}
+	}
+	if details != "" {
+		parts = append(parts, details)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Diff lists the fields that differ between before and after, which are
+// compared by their JSON encodings so that the names match the BBS API.
+func Diff(before, after interface{}) []Change {
+	beforeFields := flatten(before)
+	afterFields := flatten(after)
+
+	names := map[string]bool{}
+	for name := range beforeFields {
+		names[name] = true
+	}
+	for name := range afterFields {
+		names[name] = true
+	}
+
+	changes := []Change{}
+	for name := range names {
+		b, a := beforeFields[name], afterFields[name]
+		if ignoredChanges[name] || reflect.DeepEqual(b, a) {
+			continue
+		}
+		changes = append(changes, Change{Field: name, Before: render(b), After: render(a)})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// withEnumName shows field by its enum name rather than the number it is
+// encoded as.
+func withEnumName(changes []Change, field, before, after string) []Change {
+	for i := range changes {
+		if changes[i].Field == field {
+			changes[i].Before, changes[i].After = before, after
+		}
+	}
+	return changes
+}
+
+func flatten(value interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fields
+	}
+	var decoded interface{}
+	err = json.Unmarshal(encoded, &decoded)
+	if err != nil {
+		return fields
+	}
+	flattenInto(fields, "", decoded)
+	return fields
+}
+
+// flattenInto names nested object fields by their path; arrays are compared
+// as a whole.
+func flattenInto(fields map[string]interface{}, prefix string, value interface{}) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		if prefix != "" {
+			fields[prefix] = value
+		}
+		return
+	}
+	for key, nested := range object {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+		flattenInto(fields, name, nested)
+	}
+}
+
+func render(value interface{}) string {
+	if value == nil {
+		return "<none>"
+	}
+	if str, ok := value.(string); ok && str != "" {
+		return str
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
@@ -0,0 +1 @@
+package eventstream // import "code.cloudfoundry.org/vizzini/eventstream"
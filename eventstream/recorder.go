@@ -0,0 +1,144 @@
+package eventstream
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/vizzini/forensics"
+)
+
+// EventRecorder collects the LRP instance and task events for a set of
+// process and task GUIDs, in the order each stream delivered them, so specs
+// can assert on a whole lifecycle rather than on its final state.
+type EventRecorder struct {
+	// RetryInterval is how long to wait before resubscribing to a stream that
+	// dropped.  It doubles after every failed attempt up to MaxRetryInterval.
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+
+	guids map[string]bool
+
+	lock    sync.Mutex
+	events  []RecordedEvent
+	drops   []forensics.Drop
+	streams *forensics.Streams
+}
+
+// RecordedEvent is an event with the time the recorder received it.
+type RecordedEvent struct {
+	Event models.Event
+	At    time.Time
+}
+
+// NewEventRecorder records events for guids, or for every GUID if none are
+// given.
+func NewEventRecorder(guids ...string) *EventRecorder {
+	recorder := &EventRecorder{
+		RetryInterval:    forensics.DefaultRetryInterval,
+		MaxRetryInterval: forensics.DefaultMaxRetryInterval,
+		guids:            map[string]bool{},
+	}
+	for _, guid := range guids {
+		recorder.guids[guid] = true
+	}
+	return recorder
+}
+
+// Start records the instance and task event streams until Stop is called.
+// A stream that drops is resubscribed to, so polling Events keeps seeing new
+// events; Drops says which were missed.
+func (r *EventRecorder) Start(logger lager.Logger, client bbs.Client) error {
+	streams, err := forensics.Subscribe(logger.Session("event-recorder"), client, forensics.StreamOptions{
+		RetryInterval:    r.RetryInterval,
+		MaxRetryInterval: r.MaxRetryInterval,
+		OnDisconnect: func(stream string, err error) {
+			r.lock.Lock()
+			defer r.lock.Unlock()
+			r.drops = append(r.drops, forensics.Drop{Stream: stream, At: time.Now(), Error: err.Error()})
+		},
+	}, r.Record)
+	if err != nil {
+		return err
+	}
+	r.streams = streams
+	return nil
+}
+
+// Stop closes the event streams and waits for the events already received to
+// be recorded.
+func (r *EventRecorder) Stop() {
+	if r.streams != nil {
+		r.streams.Stop()
+	}
+}
+
+// Drops lists every time a stream dropped while recording.  Events sent
+// while it was down are missing from Events.
+func (r *EventRecorder) Drops() []forensics.Drop {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]forensics.Drop{}, r.drops...)
+}
+
+func (r *EventRecorder) Record(event models.Event) {
+	if len(r.guids) > 0 && !r.guids[forensics.GuidFor(event)] {
+		return
+	}
+	at := time.Now()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = append(r.events, RecordedEvent{Event: event, At: at})
+}
+
+// Events returns everything recorded so far.  It is meant to be polled, e.g.
+// Eventually(recorder.Events).Should(MatchEventSequence(...)).
+func (r *EventRecorder) Events() []models.Event {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	events := make([]models.Event, len(r.events))
+	for i, recorded := range r.events {
+		events[i] = recorded.Event
+	}
+	return events
+}
+
+// EventsFor returns the events recorded for a single GUID.
+func (r *EventRecorder) EventsFor(guid string) []models.Event {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	events := []models.Event{}
+	for _, recorded := range r.events {
+		if forensics.GuidFor(recorded.Event) == guid {
+			events = append(events, recorded.Event)
+		}
+	}
+	return events
+}
+
+// TimelineFor returns the events recorded for a single GUID along with when
+// each arrived, for measuring how long transitions took.
+func (r *EventRecorder) TimelineFor(guid string) []RecordedEvent {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	timeline := []RecordedEvent{}
+	for _, recorded := range r.events {
+		if forensics.GuidFor(recorded.Event) == guid {
+			timeline = append(timeline, recorded)
+		}
+	}
+	return timeline
+}
+
+// EventsForGetter returns EventsFor(guid) as a function for Eventually.
+func (r *EventRecorder) EventsForGetter(guid string) func() []models.Event {
+	return func() []models.Event {
+		return r.EventsFor(guid)
+	}
+}
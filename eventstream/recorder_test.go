@@ -0,0 +1,104 @@
+package eventstream_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/vizzini/eventstream"
+	"code.cloudfoundry.org/vizzini/fakebbs"
+
+	. "code.cloudfoundry.org/vizzini/matchers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EventRecorder", func() {
+	var (
+		server   *fakebbs.Server
+		client   bbs.InternalClient
+		logger   *lagertest.TestLogger
+		recorder *eventstream.EventRecorder
+	)
+
+	BeforeEach(func() {
+		var err error
+		logger = lagertest.NewTestLogger("eventstream")
+		server = fakebbs.NewServerWithStepInterval(10 * time.Millisecond)
+		server.Start()
+		client, err = server.Client()
+		Expect(err).NotTo(HaveOccurred())
+
+		recorder = eventstream.NewEventRecorder("lrp-guid", "task-guid")
+		Expect(recorder.Start(logger, client)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		recorder.Stop()
+		server.Close()
+	})
+
+	desiredLRP := func(processGuid string) *models.DesiredLRP {
+		return &models.DesiredLRP{
+			ProcessGuid: processGuid,
+			Domain:      "eventstream",
+			Instances:   1,
+			RootFs:      models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot),
+			MemoryMb:    128,
+			Action:      models.WrapAction(&models.RunAction{Path: "true", User: "vcap"}),
+		}
+	}
+
+	It("records an LRP's whole lifecycle in order", func() {
+		Expect(client.DesireLRP(logger, "trace-id", desiredLRP("lrp-guid"))).To(Succeed())
+		Eventually(recorder.Events).Should(MatchEventSequence(
+			MatchDesiredLRPCreatedEvent("lrp-guid"),
+			MatchActualLRPInstanceCreatedEvent("lrp-guid", 0),
+			MatchActualLRPInstanceChangedEvent("lrp-guid", 0, models.ActualLRPStateClaimed),
+			MatchActualLRPInstanceChangedEvent("lrp-guid", 0, models.ActualLRPStateRunning),
+		))
+
+		Expect(client.RemoveDesiredLRP(logger, "trace-id", "lrp-guid")).To(Succeed())
+		Eventually(recorder.EventsForGetter("lrp-guid")).Should(ContainEventsInOrder(
+			MatchDesiredLRPCreatedEvent("lrp-guid"),
+			MatchDesiredLRPRemovedEvent("lrp-guid"),
+			MatchActualLRPInstanceRemovedEvent("lrp-guid", 0),
+		))
+	})
+
+	It("records task events and ignores other GUIDs", func() {
+		Expect(client.DesireLRP(logger, "trace-id", desiredLRP("other-guid"))).To(Succeed())
+		Expect(client.DesireTask(logger, "trace-id", "task-guid", "eventstream", &models.TaskDefinition{
+			RootFs:   models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot),
+			MemoryMb: 32,
+			Action:   models.WrapAction(&models.RunAction{Path: "true", User: "vcap"}),
+		})).To(Succeed())
+
+		Eventually(recorder.EventsForGetter("task-guid")).Should(ContainElement(BeAssignableToTypeOf(&models.TaskCreatedEvent{})))
+		Consistently(recorder.EventsForGetter("other-guid"), 100*time.Millisecond).Should(BeEmpty())
+		Expect(recorder.EventsFor("lrp-guid")).To(BeEmpty())
+	})
+
+	It("resubscribes to a stream that drops and reports the drop", func() {
+		droppingClient := fakebbs.NewDroppingClient(client)
+		recorder := eventstream.NewEventRecorder("lrp-guid")
+		recorder.RetryInterval = 10 * time.Millisecond
+		Expect(recorder.Start(logger, droppingClient)).To(Succeed())
+		defer recorder.Stop()
+		Expect(droppingClient.Subscriptions()).To(Equal(2))
+
+		droppingClient.Drop()
+		Eventually(droppingClient.Subscriptions).Should(Equal(4))
+		Expect(recorder.Drops()).To(ConsistOf(HaveField("Stream", "instance"), HaveField("Stream", "task")))
+
+		Expect(client.DesireLRP(logger, "trace-id", desiredLRP("lrp-guid"))).To(Succeed())
+		Eventually(recorder.Events).Should(ContainElement(MatchDesiredLRPCreatedEvent("lrp-guid")))
+	})
+
+	It("records every GUID when none are given", func() {
+		recorder := eventstream.NewEventRecorder()
+		recorder.Record(&models.DesiredLRPCreatedEvent{DesiredLrp: desiredLRP("anything")})
+		Expect(recorder.Events()).To(ConsistOf(MatchDesiredLRPCreatedEvent("anything")))
+	})
+})
@@ -0,0 +1,44 @@
+package eventstream
+
+import (
+	"context"
+	"sync"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/events"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/vizzini/forensics"
+)
+
+type TailOptions = forensics.StreamOptions
+
+// Tail subscribes to the instance and task event streams and hands every
+// event to handle, one at a time, until ctx is done.  A stream that drops is
+// resubscribed to; events sent while it was down are lost.
+func Tail(ctx context.Context, logger lager.Logger, client bbs.Client, options TailOptions, handle func(models.Event)) {
+	logger = logger.Session("tail")
+
+	lock := sync.Mutex{}
+	serialized := func(event models.Event) {
+		lock.Lock()
+		defer lock.Unlock()
+		handle(event)
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		forensics.Follow(ctx, logger, "instance", nil, func() (events.EventSource, error) {
+			return client.SubscribeToInstanceEvents(logger)
+		}, options, serialized)
+	}()
+	go func() {
+		defer wg.Done()
+		forensics.Follow(ctx, logger, "task", nil, func() (events.EventSource, error) {
+			return client.SubscribeToTaskEvents(logger)
+		}, options, serialized)
+	}()
+	wg.Wait()
+}
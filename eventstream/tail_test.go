@@ -0,0 +1,193 @@
+package eventstream_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/vizzini/eventstream"
+	"code.cloudfoundry.org/vizzini/fakebbs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tail", func() {
+	var (
+		server *fakebbs.Server
+		client *fakebbs.DroppingClient
+		logger *lagertest.TestLogger
+
+		ctx    context.Context
+		cancel context.CancelFunc
+		done   chan struct{}
+
+		lock        sync.Mutex
+		received    []models.Event
+		disconnects []string
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("tail")
+		server = fakebbs.NewServerWithStepInterval(10 * time.Millisecond)
+		server.Start()
+		internalClient, err := server.Client()
+		Expect(err).NotTo(HaveOccurred())
+		client = fakebbs.NewDroppingClient(internalClient)
+
+		received = nil
+		disconnects = nil
+		ctx, cancel = context.WithCancel(context.Background())
+		done = make(chan struct{})
+		go func() {
+			defer close(done)
+			eventstream.Tail(ctx, logger, client, eventstream.TailOptions{
+				RetryInterval: 10 * time.Millisecond,
+				OnDisconnect: func(stream string, err error) {
+					lock.Lock()
+					defer lock.Unlock()
+					disconnects = append(disconnects, stream)
+				},
+			}, func(event models.Event) {
+				lock.Lock()
+				defer lock.Unlock()
+				received = append(received, event)
+			})
+		}()
+		Eventually(client.Subscriptions).Should(Equal(2))
+	})
+
+	AfterEach(func() {
+		cancel()
+		Eventually(done).Should(BeClosed())
+		server.Close()
+	})
+
+	guids := func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		guids := []string{}
+		for _, event := range received {
+			guids = append(guids, eventstream.Describe(event, time.Now()).Guid)
+		}
+		return guids
+	}
+
+	desireTask := func(taskGuid string) {
+		definition := &models.TaskDefinition{
+			RootFs:   models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot),
+			MemoryMb: 128,
+			Action:   models.WrapAction(&models.RunAction{Path: "true", User: "vcap"}),
+		}
+		Expect(client.DesireTask(logger, "trace-id", taskGuid, "eventstream", definition)).To(Succeed())
+	}
+
+	It("resubscribes when a stream drops", func() {
+		desireTask("before-drop")
+		Eventually(guids).Should(ContainElement("before-drop"))
+
+		client.Drop()
+		Eventually(client.Subscriptions).Should(Equal(4))
+		lock.Lock()
+		Expect(disconnects).To(ConsistOf("instance", "task"))
+		lock.Unlock()
+
+		desireTask("after-drop")
+		Eventually(guids).Should(ContainElement("after-drop"))
+	})
+
+	It("keeps retrying while the BBS is away and stops when the context is done", func() {
+		server.Close()
+		Eventually(client.Subscriptions).Should(BeNumerically(">", 4))
+		cancel()
+		Eventually(done).Should(BeClosed())
+	})
+})
+
+var _ = Describe("Describe", func() {
+	actualLRP := func(state string) *models.ActualLRPInfo {
+		return &models.ActualLRPInfo{
+			ActualLRPNetInfo: models.ActualLRPNetInfo{},
+			State:            state,
+			Since:            time.Now().UnixNano(),
+			Presence:         models.ActualLRP_Ordinary,
+		}
+	}
+
+	It("lists the fields that changed, without the ones that always do", func() {
+		before := actualLRP(models.ActualLRPStateClaimed)
+		after := actualLRP(models.ActualLRPStateRunning)
+		after.ActualLRPNetInfo = models.ActualLRPNetInfo{Address: "10.0.0.1", InstanceAddress: "10.255.0.2"}
+		after.Since = before.Since + 1
+		after.ModificationTag = models.ModificationTag{Index: 1}
+		event := &models.ActualLRPInstanceChangedEvent{
+			ActualLRPKey: models.NewActualLRPKey("some-guid", 1, "some-domain"),
+			Before:       before,
+			After:        after,
+		}
+
+		line := eventstream.Describe(event, time.Date(2020, 1, 2, 3, 4, 5, 6000000, time.UTC))
+		Expect(line.Guid).To(Equal("some-guid"))
+		Expect(line.Domain).To(Equal("some-domain"))
+		Expect(*line.Index).To(BeEquivalentTo(1))
+		Expect(line.Changes).To(ContainElement(eventstream.Change{Field: "state", Before: "CLAIMED", After: "RUNNING"}))
+		Expect(line.Changes).To(ContainElement(And(HaveField("Field", "address"), HaveField("After", "10.0.0.1"))))
+		Expect(line.Changes).NotTo(ContainElement(HaveField("Field", "since")))
+		Expect(line.Changes).NotTo(ContainElement(HaveField("Field", "modification_tag.index")))
+		Expect(line.String()).To(HavePrefix("03:04:05.006 actual_lrp_instance_changed  some-guid/1 [some-domain] "))
+		Expect(line.String()).To(ContainSubstring("state: CLAIMED -> RUNNING"))
+	})
+
+	It("names task states rather than their numbers", func() {
+		before := &models.Task{TaskGuid: "some-task", Domain: "some-domain", State: models.Task_Pending}
+		after := &models.Task{TaskGuid: "some-task", Domain: "some-domain", State: models.Task_Running, CellId: "cell-1"}
+
+		line := eventstream.Describe(&models.TaskChangedEvent{Before: before, After: after}, time.Now())
+		Expect(line.Changes).To(ContainElement(eventstream.Change{Field: "state", Before: "Pending", After: "Running"}))
+		Expect(line.Changes).To(ContainElement(And(HaveField("Field", "cell_id"), HaveField("After", "cell-1"))))
+	})
+
+	It("nests DesiredLRP fields by their JSON path", func() {
+		before := &models.DesiredLRP{ProcessGuid: "some-lrp", Instances: 1, ModificationTag: &models.ModificationTag{Epoch: "a"}}
+		after := &models.DesiredLRP{ProcessGuid: "some-lrp", Instances: 3, ModificationTag: &models.ModificationTag{Epoch: "b"}}
+
+		line := eventstream.Describe(&models.DesiredLRPChangedEvent{Before: before, After: after}, time.Now())
+		Expect(line.Changes).To(Equal([]eventstream.Change{
+			{Field: "instances", Before: "1", After: "3"},
+			{Field: "modification_tag.epoch", Before: "a", After: "b"},
+		}))
+	})
+
+	It("sums up events without a Before and After", func() {
+		event := &models.ActualLRPCrashedEvent{
+			ActualLRPKey: models.NewActualLRPKey("some-guid", 0, "some-domain"),
+			CrashCount:   2,
+			CrashReason:  "boom",
+		}
+		line := eventstream.Describe(event, time.Now())
+		Expect(line.Changes).To(BeNil())
+		Expect(line.String()).To(HaveSuffix(`some-guid/0 [some-domain] index=0 crash_count=2 crash_reason="boom"`))
+	})
+})
+
+var _ = Describe("Filter", func() {
+	task := &models.TaskCreatedEvent{Task: &models.Task{TaskGuid: "some-task", Domain: "some-domain"}}
+	lrp := &models.DesiredLRPCreatedEvent{DesiredLrp: &models.DesiredLRP{ProcessGuid: "some-lrp", Domain: "other-domain"}}
+
+	It("matches everything when empty", func() {
+		Expect(eventstream.Filter{}.Matches(task)).To(BeTrue())
+		Expect(eventstream.Filter{}.Matches(lrp)).To(BeTrue())
+	})
+
+	It("matches any value within a field and every field", func() {
+		filter := eventstream.Filter{Domains: []string{"some-domain", "other-domain"}, Types: []string{models.EventTypeTaskCreated}}
+		Expect(filter.Matches(task)).To(BeTrue())
+		Expect(filter.Matches(lrp)).To(BeFalse())
+
+		filter = eventstream.Filter{Guids: []string{"some-lrp"}}
+		Expect(filter.Matches(task)).To(BeFalse())
+		Expect(filter.Matches(lrp)).To(BeTrue())
+	})
+})
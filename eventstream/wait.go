@@ -0,0 +1,318 @@
+package eventstream
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/events"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/vizzini/forensics"
+)
+
+const (
+	DefaultWaitTimeout      = 2 * time.Minute
+	DefaultWaitPollInterval = time.Second
+)
+
+type WaitOptions struct {
+	// Timeout bounds the whole wait, including the initial check.
+	Timeout time.Duration
+
+	// PollInterval is how often the BBS is asked directly, which only happens
+	// once the event stream has failed.
+	PollInterval time.Duration
+}
+
+// WaitTimeoutError is returned when a wait runs out of time.  History holds
+// every event the wait received for the GUID, oldest first.
+type WaitTimeoutError struct {
+	Description string
+	Timeout     time.Duration
+	LastSeen    string
+	History     []Line
+
+	// StreamErr is why the event stream failed, if the wait had to fall back
+	// to polling.
+	StreamErr error
+}
+
+func (e *WaitTimeoutError) Error() string {
+	message := fmt.Sprintf("timed out after %s waiting for %s (last saw %s)", e.Timeout, e.Description, e.LastSeen)
+	if e.StreamErr != nil {
+		message += fmt.Sprintf("\nthe event stream failed, so the wait fell back to polling: %s", e.StreamErr.Error())
+	}
+	if len(e.History) == 0 {
+		return message + "\nno events were received"
+	}
+	lines := make([]string, len(e.History))
+	for i, line := range e.History {
+		lines[i] = "  " + line.String()
+	}
+	return message + "\nevents received:\n" + strings.Join(lines, "\n")
+}
+
+// WaitForActualLRPState waits until the ActualLRP at index of processGuid is
+// in state.  Evacuating instances are ignored.
+func WaitForActualLRPState(logger lager.Logger, client bbs.InternalClient, traceID, processGuid string, index int32, state string, options WaitOptions) error {
+	return WaitForActualLRP(logger.Session("wait-for-actual-lrp-state"), client, traceID, processGuid, index,
+		fmt.Sprintf("ActualLRP %s/%d to be %s", processGuid, index, state),
+		func(lrp *models.ActualLRP) bool { return lrp.State == state },
+		options)
+}
+
+// WaitForActualLRP waits until matches accepts the ActualLRP at index of
+// processGuid, e.g. until it has crashed a given number of times.
+// Evacuating instances are ignored, and matches is never given nil.
+func WaitForActualLRP(logger lager.Logger, client bbs.InternalClient, traceID, processGuid string, index int32, description string, matches func(*models.ActualLRP) bool, options WaitOptions) error {
+	var current *models.ActualLRP
+	result := func() (bool, string) {
+		if current == nil {
+			return false, "no ActualLRP"
+		}
+		return matches(current), describeActualLRP(current)
+	}
+
+	return wait(logger.Session("wait-for-actual-lrp"), options, waiter{
+		guid:        processGuid,
+		description: description,
+		subscribe:   client.SubscribeToInstanceEvents,
+		check: func() (bool, string, error) {
+			lrps, err := client.ActualLRPs(logger, traceID, models.ActualLRPFilter{ProcessGuid: processGuid, Index: &index})
+			if err != nil {
+				return false, "", err
+			}
+			current = nil
+			for _, lrp := range lrps {
+				if lrp.Presence != models.ActualLRP_Evacuating {
+					current = lrp
+				}
+			}
+			done, seen := result()
+			return done, seen, nil
+		},
+		apply: func(event models.Event) (bool, string) {
+			switch event := event.(type) {
+			case *models.ActualLRPInstanceCreatedEvent:
+				if event.ActualLrp.Index == index && event.ActualLrp.Presence != models.ActualLRP_Evacuating {
+					current = event.ActualLrp
+				}
+			case *models.ActualLRPInstanceChangedEvent:
+				if event.Index == index && event.After.Presence != models.ActualLRP_Evacuating {
+					current = event.After.ToActualLRP(event.ActualLRPKey, event.ActualLRPInstanceKey)
+				}
+			case *models.ActualLRPInstanceRemovedEvent:
+				if event.ActualLrp.Index == index && event.ActualLrp.Presence != models.ActualLRP_Evacuating {
+					current = nil
+				}
+			}
+			return result()
+		},
+	})
+}
+
+func describeActualLRP(lrp *models.ActualLRP) string {
+	description := fmt.Sprintf("%s with crash count %d", lrp.State, lrp.CrashCount)
+	if lrp.CrashReason != "" {
+		description += fmt.Sprintf(" (%q)", lrp.CrashReason)
+	}
+	return description
+}
+
+// WaitForTaskState waits until the task with taskGuid is in state.
+func WaitForTaskState(logger lager.Logger, client bbs.InternalClient, traceID, taskGuid string, state models.Task_State, options WaitOptions) error {
+	current := "no task"
+	return wait(logger.Session("wait-for-task-state"), options, waiter{
+		guid:        taskGuid,
+		description: fmt.Sprintf("task %s to be %s", taskGuid, state),
+		subscribe:   client.SubscribeToTaskEvents,
+		check: func() (bool, string, error) {
+			task, err := client.TaskByGuid(logger, traceID, taskGuid)
+			if err != nil {
+				return false, "", err
+			}
+			current = task.State.String()
+			return task.State == state, current, nil
+		},
+		apply: func(event models.Event) (bool, string) {
+			switch event := event.(type) {
+			case *models.TaskCreatedEvent:
+				current = event.Task.State.String()
+			case *models.TaskChangedEvent:
+				current = event.After.State.String()
+			case *models.TaskRemovedEvent:
+				current = "no task"
+			}
+			return current == state.String(), current
+		},
+	})
+}
+
+// WaitForDesiredLRPRemoved waits until the DesiredLRP with processGuid is gone
+// and so is every one of its ActualLRPs, which can outlive it while their
+// containers shut down.
+func WaitForDesiredLRPRemoved(logger lager.Logger, client bbs.InternalClient, traceID, processGuid string, options WaitOptions) error {
+	desired := false
+	actuals := map[string]bool{}
+	seen := func() string {
+		state := "no DesiredLRP"
+		if desired {
+			state = "a DesiredLRP"
+		}
+		return fmt.Sprintf("%s and %d ActualLRPs", state, len(actuals))
+	}
+	actualKey := func(index int32, presence models.ActualLRP_Presence) string {
+		return fmt.Sprintf("%d/%s", index, presence)
+	}
+
+	return wait(logger.Session("wait-for-desired-lrp-removed"), options, waiter{
+		guid:        processGuid,
+		description: fmt.Sprintf("DesiredLRP %s and its ActualLRPs to be removed", processGuid),
+		subscribe:   client.SubscribeToInstanceEvents,
+		check: func() (bool, string, error) {
+			_, err := client.DesiredLRPByProcessGuid(logger, traceID, processGuid)
+			switch {
+			case err == nil:
+				desired = true
+			case models.ConvertError(err).Type == models.Error_ResourceNotFound:
+				desired = false
+			default:
+				return false, "", err
+			}
+
+			lrps, err := client.ActualLRPs(logger, traceID, models.ActualLRPFilter{ProcessGuid: processGuid})
+			if err != nil {
+				return false, "", err
+			}
+			actuals = map[string]bool{}
+			for _, lrp := range lrps {
+				actuals[actualKey(lrp.Index, lrp.Presence)] = true
+			}
+			return !desired && len(actuals) == 0, seen(), nil
+		},
+		apply: func(event models.Event) (bool, string) {
+			switch event := event.(type) {
+			case *models.DesiredLRPCreatedEvent:
+				desired = true
+			case *models.DesiredLRPRemovedEvent:
+				desired = false
+			case *models.ActualLRPInstanceCreatedEvent:
+				actuals[actualKey(event.ActualLrp.Index, event.ActualLrp.Presence)] = true
+			case *models.ActualLRPInstanceChangedEvent:
+				delete(actuals, actualKey(event.Index, event.Before.Presence))
+				actuals[actualKey(event.Index, event.After.Presence)] = true
+			case *models.ActualLRPInstanceRemovedEvent:
+				delete(actuals, actualKey(event.ActualLrp.Index, event.ActualLrp.Presence))
+			}
+			return !desired && len(actuals) == 0, seen()
+		},
+	})
+}
+
+// waiter is what a wait is about: check asks the BBS for the current state,
+// and apply updates that state from an event about guid.  Both report
+// whether the wait is over and what they saw.
+type waiter struct {
+	guid        string
+	description string
+	subscribe   func(lager.Logger) (events.EventSource, error)
+	check       func() (bool, string, error)
+	apply       func(models.Event) (bool, string)
+}
+
+// wait subscribes before checking the current state, so that no change can
+// slip in between the two.  If the stream fails the wait falls back to
+// polling with check.
+func wait(logger lager.Logger, options WaitOptions, w waiter) error {
+	if options.Timeout == 0 {
+		options.Timeout = DefaultWaitTimeout
+	}
+	if options.PollInterval == 0 {
+		options.PollInterval = DefaultWaitPollInterval
+	}
+	timer := time.NewTimer(options.Timeout)
+	defer timer.Stop()
+
+	timeoutErr := &WaitTimeoutError{Description: w.description, Timeout: options.Timeout, LastSeen: "nothing", History: []Line{}}
+	check := func() bool {
+		done, seen, err := w.check()
+		if err != nil {
+			logger.Error("failed-to-check", err)
+			timeoutErr.LastSeen = "an error: " + err.Error()
+			return false
+		}
+		timeoutErr.LastSeen = seen
+		return done
+	}
+
+	source, err := w.subscribe(logger)
+	if err != nil {
+		logger.Error("failed-to-subscribe", err)
+		timeoutErr.StreamErr = err
+	} else {
+		defer source.Close()
+	}
+
+	if check() {
+		return nil
+	}
+
+	if timeoutErr.StreamErr == nil {
+		done := make(chan struct{})
+		defer close(done)
+		received, failed := stream(source, done)
+		for timeoutErr.StreamErr == nil {
+			select {
+			case event := <-received:
+				if forensics.GuidFor(event) != w.guid {
+					continue
+				}
+				timeoutErr.History = append(timeoutErr.History, Describe(event, time.Now()))
+				done, seen := w.apply(event)
+				timeoutErr.LastSeen = seen
+				if done {
+					return nil
+				}
+			case err := <-failed:
+				logger.Error("stream-failed", err)
+				timeoutErr.StreamErr = err
+			case <-timer.C:
+				return timeoutErr
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-timer.C:
+			return timeoutErr
+		case <-time.After(options.PollInterval):
+		}
+		if check() {
+			return nil
+		}
+	}
+}
+
+// stream reads source in the background until it fails or done is closed.
+func stream(source events.EventSource, done <-chan struct{}) (<-chan models.Event, <-chan error) {
+	received := make(chan models.Event)
+	failed := make(chan error, 1)
+	go func() {
+		for {
+			event, err := source.Next()
+			if err != nil {
+				failed <- err
+				return
+			}
+			select {
+			case received <- event:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return received, failed
+}
@@ -0,0 +1,204 @@
+package eventstream_test
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/vizzini/eventstream"
+	"code.cloudfoundry.org/vizzini/fakebbs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// countingClient counts how often a task is looked up, to show that waits
+// leave the BBS alone while the stream is up.
+type countingClient struct {
+	*fakebbs.DroppingClient
+
+	lock        sync.Mutex
+	taskLookups int
+}
+
+func (c *countingClient) TaskByGuid(logger lager.Logger, traceID, taskGuid string) (*models.Task, error) {
+	c.lock.Lock()
+	c.taskLookups++
+	c.lock.Unlock()
+	return c.DroppingClient.TaskByGuid(logger, traceID, taskGuid)
+}
+
+func (c *countingClient) TaskLookups() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.taskLookups
+}
+
+var _ = Describe("Waiting", func() {
+	var (
+		stepInterval time.Duration
+		server       *fakebbs.Server
+		client       *countingClient
+		logger       *lagertest.TestLogger
+	)
+
+	BeforeEach(func() {
+		stepInterval = 10 * time.Millisecond
+	})
+
+	JustBeforeEach(func() {
+		logger = lagertest.NewTestLogger("wait")
+		server = fakebbs.NewServerWithStepInterval(stepInterval)
+		server.Start()
+		internalClient, err := server.Client()
+		Expect(err).NotTo(HaveOccurred())
+		client = &countingClient{DroppingClient: fakebbs.NewDroppingClient(internalClient)}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	desireTask := func(taskGuid string) {
+		definition := &models.TaskDefinition{
+			RootFs:   models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot),
+			MemoryMb: 128,
+			Action:   models.WrapAction(&models.RunAction{Path: "true", User: "vcap"}),
+		}
+		Expect(client.DesireTask(logger, "trace-id", taskGuid, "eventstream", definition)).To(Succeed())
+	}
+
+	desireLRP := func(processGuid string, instances int32) {
+		Expect(client.DesireLRP(logger, "trace-id", &models.DesiredLRP{
+			ProcessGuid: processGuid,
+			Domain:      "eventstream",
+			Instances:   instances,
+			RootFs:      models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot),
+			MemoryMb:    128,
+			Action:      models.WrapAction(&models.RunAction{Path: "true", User: "vcap"}),
+		})).To(Succeed())
+	}
+
+	// inBackground runs a wait and hands back where its result will arrive.
+	inBackground := func(wait func() error) <-chan error {
+		result := make(chan error, 1)
+		go func() {
+			defer GinkgoRecover()
+			result <- wait()
+		}()
+		return result
+	}
+
+	noPolling := eventstream.WaitOptions{Timeout: 5 * time.Second, PollInterval: time.Hour}
+
+	It("follows a task on the event stream, asking the BBS only once", func() {
+		desireTask("task-guid")
+		Expect(eventstream.WaitForTaskState(logger, client, "trace-id", "task-guid", models.Task_Completed, noPolling)).To(Succeed())
+		Expect(client.TaskLookups()).To(Equal(1))
+	})
+
+	It("waits for one index of an LRP to reach a state", func() {
+		desireLRP("lrp-guid", 2)
+		Expect(eventstream.WaitForActualLRPState(logger, client, "trace-id", "lrp-guid", 1, models.ActualLRPStateRunning, noPolling)).To(Succeed())
+
+		lrps, err := client.ActualLRPs(logger, "trace-id", models.ActualLRPFilter{ProcessGuid: "lrp-guid"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lrps).To(ContainElement(And(HaveField("Index", BeEquivalentTo(1)), HaveField("State", models.ActualLRPStateRunning))))
+	})
+
+	It("waits for an ActualLRP to satisfy a predicate", func() {
+		desireLRP("lrp-guid", 1)
+		Expect(eventstream.WaitForActualLRPState(logger, client, "trace-id", "lrp-guid", 0, models.ActualLRPStateRunning, noPolling)).To(Succeed())
+
+		result := inBackground(func() error {
+			return eventstream.WaitForActualLRP(logger, client, "trace-id", "lrp-guid", 0, "a second crash", func(lrp *models.ActualLRP) bool {
+				return lrp.CrashCount == 2 && lrp.State == models.ActualLRPStateRunning
+			}, noPolling)
+		})
+		Eventually(client.Subscriptions).Should(Equal(2))
+
+		Expect(server.CrashActualLRP("lrp-guid", 0, "Exited with status 1")).To(Succeed())
+		Consistently(result, 50*time.Millisecond).ShouldNot(Receive())
+		Expect(server.CrashActualLRP("lrp-guid", 0, "Exited with status 1")).To(Succeed())
+		Eventually(result).Should(Receive(BeNil()))
+	})
+
+	It("waits for a DesiredLRP and its ActualLRPs to be removed", func() {
+		desireLRP("lrp-guid", 2)
+		Expect(eventstream.WaitForActualLRPState(logger, client, "trace-id", "lrp-guid", 1, models.ActualLRPStateRunning, noPolling)).To(Succeed())
+
+		result := inBackground(func() error {
+			return eventstream.WaitForDesiredLRPRemoved(logger, client, "trace-id", "lrp-guid", noPolling)
+		})
+		Eventually(client.Subscriptions).Should(Equal(2))
+		Consistently(result, 50*time.Millisecond).ShouldNot(Receive())
+
+		Expect(client.RemoveDesiredLRP(logger, "trace-id", "lrp-guid")).To(Succeed())
+		Eventually(result).Should(Receive(BeNil()))
+
+		lrps, err := client.ActualLRPs(logger, "trace-id", models.ActualLRPFilter{ProcessGuid: "lrp-guid"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lrps).To(BeEmpty())
+	})
+
+	Context("when nothing moves", func() {
+		BeforeEach(func() {
+			stepInterval = time.Hour
+		})
+
+		It("returns at once when the state has already been reached", func() {
+			desireTask("task-guid")
+			Expect(eventstream.WaitForTaskState(logger, client, "trace-id", "task-guid", models.Task_Pending, noPolling)).To(Succeed())
+		})
+
+		It("times out with every event it received", func() {
+			result := inBackground(func() error {
+				return eventstream.WaitForTaskState(logger, client, "trace-id", "task-guid", models.Task_Completed, eventstream.WaitOptions{Timeout: 200 * time.Millisecond})
+			})
+			Eventually(client.Subscriptions).Should(Equal(1))
+			desireTask("task-guid")
+			desireTask("other-guid")
+
+			var err error
+			Eventually(result).Should(Receive(&err))
+			var timeoutErr *eventstream.WaitTimeoutError
+			Expect(errors.As(err, &timeoutErr)).To(BeTrue())
+			Expect(timeoutErr.LastSeen).To(Equal("Pending"))
+			Expect(timeoutErr.StreamErr).To(BeNil())
+			Expect(timeoutErr.History).To(ConsistOf(And(HaveField("Type", "task_created"), HaveField("Guid", "task-guid"))))
+			Expect(err.Error()).To(HavePrefix("timed out after 200ms waiting for task task-guid to be Completed (last saw Pending)\nevents received:\n  "))
+		})
+
+		It("falls back to polling when the stream fails", func() {
+			desireTask("task-guid")
+			result := inBackground(func() error {
+				return eventstream.WaitForTaskState(logger, client, "trace-id", "task-guid", models.Task_Completed, eventstream.WaitOptions{Timeout: 5 * time.Second, PollInterval: 10 * time.Millisecond})
+			})
+			Eventually(client.Subscriptions).Should(Equal(1))
+			Eventually(client.TaskLookups).Should(Equal(1))
+
+			client.Drop()
+			Eventually(client.TaskLookups).Should(BeNumerically(">", 2))
+			Expect(server.CompleteTask("task-guid", false, "", "")).To(Succeed())
+			Eventually(result).Should(Receive(BeNil()))
+		})
+
+		It("says the stream failed when the fallback times out too", func() {
+			desireTask("task-guid")
+			result := inBackground(func() error {
+				return eventstream.WaitForTaskState(logger, client, "trace-id", "task-guid", models.Task_Completed, eventstream.WaitOptions{Timeout: 200 * time.Millisecond, PollInterval: 10 * time.Millisecond})
+			})
+			Eventually(client.Subscriptions).Should(Equal(1))
+			Eventually(client.TaskLookups).Should(Equal(1))
+			client.Drop()
+
+			var err error
+			Eventually(result).Should(Receive(&err))
+			Expect(err).To(MatchError(ContainSubstring("the event stream failed, so the wait fell back to polling")))
+			Expect(err).To(MatchError(HaveSuffix("no events were received")))
+		})
+	})
+})
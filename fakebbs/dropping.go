@@ -0,0 +1,58 @@
+package fakebbs
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/events"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+// DroppingClient hands out event streams that can be dropped on demand, the
+// way a BBS restart or a flaky load balancer would drop them.
+type DroppingClient struct {
+	bbs.InternalClient
+
+	lock          sync.Mutex
+	sources       []events.EventSource
+	subscriptions int
+}
+
+func NewDroppingClient(client bbs.InternalClient) *DroppingClient {
+	return &DroppingClient{InternalClient: client}
+}
+
+func (c *DroppingClient) SubscribeToInstanceEvents(logger lager.Logger) (events.EventSource, error) {
+	return c.track(c.InternalClient.SubscribeToInstanceEvents(logger))
+}
+
+func (c *DroppingClient) SubscribeToTaskEvents(logger lager.Logger) (events.EventSource, error) {
+	return c.track(c.InternalClient.SubscribeToTaskEvents(logger))
+}
+
+func (c *DroppingClient) track(source events.EventSource, err error) (events.EventSource, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.subscriptions++
+	if err == nil {
+		c.sources = append(c.sources, source)
+	}
+	return source, err
+}
+
+// Subscriptions counts the subscriptions made so far, including failed ones.
+func (c *DroppingClient) Subscriptions() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.subscriptions
+}
+
+// Drop closes every stream handed out so far.
+func (c *DroppingClient) Drop() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, source := range c.sources {
+		source.Close()
+	}
+	c.sources = nil
+}
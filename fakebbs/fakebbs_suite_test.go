@@ -0,0 +1,13 @@
+package fakebbs_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestFakeBBS(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "FakeBBS Suite")
+}
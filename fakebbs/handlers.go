@@ -0,0 +1,284 @@
+package fakebbs
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/bbs/events"
+	"code.cloudfoundry.org/bbs/models"
+	"github.com/gogo/protobuf/proto"
+)
+
+const protoContentType = "application/x-protobuf"
+
+func (s *Server) ping(w http.ResponseWriter, req *http.Request) {
+	writeResponse(w, &models.PingResponse{Available: true})
+}
+
+// Domains
+
+func (s *Server) domains(w http.ResponseWriter, req *http.Request) {
+	writeResponse(w, &models.DomainsResponse{Domains: s.state.freshDomains()})
+}
+
+func (s *Server) upsertDomain(w http.ResponseWriter, req *http.Request) {
+	request := &models.UpsertDomainRequest{}
+	response := &models.UpsertDomainResponse{}
+	if err := parseRequest(req, request); err != nil {
+		response.Error = err
+	} else {
+		response.Error = models.ConvertError(s.state.upsertDomain(request.Domain, time.Duration(request.Ttl)*time.Second))
+	}
+	writeResponse(w, response)
+}
+
+// Cells
+
+func (s *Server) cells(w http.ResponseWriter, req *http.Request) {
+	writeResponse(w, &models.CellsResponse{Cells: s.state.allCells()})
+}
+
+// Actual LRPs
+
+func (s *Server) actualLRPs(w http.ResponseWriter, req *http.Request) {
+	request := &models.ActualLRPsRequest{}
+	response := &models.ActualLRPsResponse{}
+	if err := parseRequest(req, request); err != nil {
+		response.Error = err
+		writeResponse(w, response)
+		return
+	}
+
+	filter := models.ActualLRPFilter{
+		Domain:      request.Domain,
+		CellID:      request.CellId,
+		ProcessGuid: request.ProcessGuid,
+	}
+	if request.IndexExists() {
+		index := request.GetIndex()
+		filter.Index = &index
+	}
+	response.ActualLrps = s.state.actualLRPsWhere(filter)
+	writeResponse(w, response)
+}
+
+func (s *Server) retireActualLRP(w http.ResponseWriter, req *http.Request) {
+	request := &models.RetireActualLRPRequest{}
+	response := &models.ActualLRPLifecycleResponse{}
+	if err := parseRequest(req, request); err != nil {
+		response.Error = err
+	} else {
+		response.Error = models.ConvertError(s.state.retireActualLRP(request.ActualLrpKey))
+	}
+	writeResponse(w, response)
+}
+
+// Desired LRPs
+
+func (s *Server) desiredLRPs(w http.ResponseWriter, req *http.Request) {
+	request := &models.DesiredLRPsRequest{}
+	response := &models.DesiredLRPsResponse{}
+	if err := parseRequest(req, request); err != nil {
+		response.Error = err
+	} else {
+		response.DesiredLrps = s.state.desiredLRPsWhere(request.Domain, request.ProcessGuids)
+	}
+	writeResponse(w, response)
+}
+
+func (s *Server) desiredLRPByProcessGuid(w http.ResponseWriter, req *http.Request) {
+	request := &models.DesiredLRPByProcessGuidRequest{}
+	response := &models.DesiredLRPResponse{}
+	if err := parseRequest(req, request); err != nil {
+		response.Error = err
+	} else {
+		lrp, err := s.state.desiredLRPByProcessGuid(request.ProcessGuid)
+		response.DesiredLrp = lrp
+		response.Error = models.ConvertError(err)
+	}
+	writeResponse(w, response)
+}
+
+func (s *Server) desireLRP(w http.ResponseWriter, req *http.Request) {
+	request := &models.DesireLRPRequest{}
+	response := &models.DesiredLRPLifecycleResponse{}
+	if err := parseRequest(req, request); err != nil {
+		response.Error = err
+	} else {
+		response.Error = models.ConvertError(s.state.desireLRP(request.DesiredLrp))
+	}
+	writeResponse(w, response)
+}
+
+func (s *Server) updateDesiredLRP(w http.ResponseWriter, req *http.Request) {
+	request := &models.UpdateDesiredLRPRequest{}
+	response := &models.DesiredLRPLifecycleResponse{}
+	if err := parseRequest(req, request); err != nil {
+		response.Error = err
+	} else {
+		response.Error = models.ConvertError(s.state.updateDesiredLRP(request.ProcessGuid, request.Update))
+	}
+	writeResponse(w, response)
+}
+
+func (s *Server) removeDesiredLRP(w http.ResponseWriter, req *http.Request) {
+	request := &models.RemoveDesiredLRPRequest{}
+	response := &models.DesiredLRPLifecycleResponse{}
+	if err := parseRequest(req, request); err != nil {
+		response.Error = err
+	} else {
+		response.Error = models.ConvertError(s.state.removeDesiredLRP(request.ProcessGuid))
+	}
+	writeResponse(w, response)
+}
+
+// Tasks
+
+func (s *Server) tasks(w http.ResponseWriter, req *http.Request) {
+	request := &models.TasksRequest{}
+	response := &models.TasksResponse{}
+	if err := parseRequest(req, request); err != nil {
+		response.Error = err
+	} else {
+		response.Tasks = s.state.tasksWhere(request.Domain, request.CellId)
+	}
+	writeResponse(w, response)
+}
+
+func (s *Server) taskByGuid(w http.ResponseWriter, req *http.Request) {
+	request := &models.TaskByGuidRequest{}
+	response := &models.TaskResponse{}
+	if err := parseRequest(req, request); err != nil {
+		response.Error = err
+	} else {
+		task, err := s.state.taskByGuid(request.TaskGuid)
+		response.Task = task
+		response.Error = models.ConvertError(err)
+	}
+	writeResponse(w, response)
+}
+
+func (s *Server) desireTask(w http.ResponseWriter, req *http.Request) {
+	request := &models.DesireTaskRequest{}
+	response := &models.TaskLifecycleResponse{}
+	if err := parseRequest(req, request); err != nil {
+		response.Error = err
+	} else {
+		response.Error = models.ConvertError(s.state.desireTask(request.TaskGuid, request.Domain, request.TaskDefinition))
+	}
+	writeResponse(w, response)
+}
+
+func (s *Server) cancelTask(w http.ResponseWriter, req *http.Request) {
+	request := &models.TaskGuidRequest{}
+	response := &models.TaskLifecycleResponse{}
+	if err := parseRequest(req, request); err != nil {
+		response.Error = err
+	} else {
+		response.Error = models.ConvertError(s.state.cancelTask(request.TaskGuid))
+	}
+	writeResponse(w, response)
+}
+
+func (s *Server) resolvingTask(w http.ResponseWriter, req *http.Request) {
+	request := &models.TaskGuidRequest{}
+	response := &models.TaskLifecycleResponse{}
+	if err := parseRequest(req, request); err != nil {
+		response.Error = err
+	} else {
+		response.Error = models.ConvertError(s.state.resolvingTask(request.TaskGuid))
+	}
+	writeResponse(w, response)
+}
+
+func (s *Server) deleteTask(w http.ResponseWriter, req *http.Request) {
+	request := &models.TaskGuidRequest{}
+	response := &models.TaskLifecycleResponse{}
+	if err := parseRequest(req, request); err != nil {
+		response.Error = err
+	} else {
+		response.Error = models.ConvertError(s.state.deleteTask(request.TaskGuid))
+	}
+	writeResponse(w, response)
+}
+
+// Events
+
+func (s *Server) instanceEventStream(w http.ResponseWriter, req *http.Request) {
+	s.streamEvents(w, req, s.hub.subscribeInstance())
+}
+
+func (s *Server) taskEventStream(w http.ResponseWriter, req *http.Request) {
+	s.streamEvents(w, req, s.hub.subscribeTask())
+}
+
+func (s *Server) streamEvents(w http.ResponseWriter, req *http.Request, subscription chan models.Event) {
+	defer s.hub.unsubscribe(subscription)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+	w.Header().Add("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Add("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	eventID := 0
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-s.stop:
+			return
+		case event, ok := <-subscription:
+			if !ok {
+				return
+			}
+			sseEvent, err := events.NewEventFromModelEvent(eventID, event)
+			if err != nil {
+				continue
+			}
+			if err := sseEvent.Write(w); err != nil {
+				return
+			}
+			flusher.Flush()
+			eventID++
+		}
+	}
+}
+
+type validator interface {
+	proto.Message
+	Validate() error
+}
+
+func parseRequest(req *http.Request, request validator) *models.Error {
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return models.ErrUnknownError
+	}
+	if err := proto.Unmarshal(data, request); err != nil {
+		return models.ErrDeserialize
+	}
+	if err := request.Validate(); err != nil {
+		return models.NewError(models.Error_InvalidRequest, err.Error())
+	}
+	return nil
+}
+
+func writeResponse(w http.ResponseWriter, message proto.Message) {
+	responseBytes, err := proto.Marshal(message)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", protoContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseBytes)
+}
@@ -0,0 +1,109 @@
+package fakebbs
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/bbs/models"
+)
+
+const subscriberBufferSize = 1024
+
+type hub struct {
+	lock             sync.Mutex
+	instanceSubs     map[chan models.Event]struct{}
+	taskSubs         map[chan models.Event]struct{}
+	instanceHistory  []models.Event
+	taskHistory      []models.Event
+	historyRetention int
+}
+
+func newHub() *hub {
+	return &hub{
+		instanceSubs:     map[chan models.Event]struct{}{},
+		taskSubs:         map[chan models.Event]struct{}{},
+		historyRetention: 1000,
+	}
+}
+
+func (h *hub) subscribeInstance() chan models.Event {
+	return h.subscribe(h.instanceSubs)
+}
+
+func (h *hub) subscribeTask() chan models.Event {
+	return h.subscribe(h.taskSubs)
+}
+
+func (h *hub) subscribe(subs map[chan models.Event]struct{}) chan models.Event {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	ch := make(chan models.Event, subscriberBufferSize)
+	subs[ch] = struct{}{}
+	return ch
+}
+
+func (h *hub) unsubscribe(ch chan models.Event) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	delete(h.instanceSubs, ch)
+	delete(h.taskSubs, ch)
+}
+
+func (h *hub) publishInstance(event models.Event) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.instanceHistory = appendBounded(h.instanceHistory, event, h.historyRetention)
+	broadcast(h.instanceSubs, event)
+}
+
+func (h *hub) publishTask(event models.Event) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.taskHistory = appendBounded(h.taskHistory, event, h.historyRetention)
+	broadcast(h.taskSubs, event)
+}
+
+func (h *hub) history() []models.Event {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	events := make([]models.Event, 0, len(h.instanceHistory)+len(h.taskHistory))
+	events = append(events, h.instanceHistory...)
+	return append(events, h.taskHistory...)
+}
+
+func (h *hub) closeAll() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for ch := range h.instanceSubs {
+		close(ch)
+		delete(h.instanceSubs, ch)
+	}
+	for ch := range h.taskSubs {
+		close(ch)
+		delete(h.taskSubs, ch)
+	}
+}
+
+// a slow subscriber loses events rather than stalling the state machine; the
+// buffer is large enough that this only happens to abandoned streams
+func broadcast(subs map[chan models.Event]struct{}, event models.Event) {
+	for ch := range subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func appendBounded(events []models.Event, event models.Event, limit int) []models.Event {
+	events = append(events, event)
+	if len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	return events
+}
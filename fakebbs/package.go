@@ -0,0 +1 @@
+package fakebbs // import "code.cloudfoundry.org/vizzini/fakebbs"
@@ -0,0 +1,166 @@
+package fakebbs
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"github.com/tedsuo/rata"
+)
+
+const DefaultStepInterval = 100 * time.Millisecond
+
+type Server struct {
+	state        *state
+	hub          *hub
+	httpServer   *httptest.Server
+	stepInterval time.Duration
+	callbacks    *http.Client
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func NewServer() *Server {
+	return NewServerWithStepInterval(DefaultStepInterval)
+}
+
+func NewServerWithStepInterval(stepInterval time.Duration) *Server {
+	hub := newHub()
+	server := &Server{
+		state:        newState(hub),
+		hub:          hub,
+		stepInterval: stepInterval,
+		callbacks:    &http.Client{Timeout: 5 * time.Second},
+		stop:         make(chan struct{}),
+	}
+
+	handler, err := server.router()
+	if err != nil {
+		panic(err)
+	}
+	server.httpServer = httptest.NewUnstartedServer(handler)
+	return server
+}
+
+func (s *Server) Start() {
+	s.httpServer.Start()
+
+	s.wg.Add(1)
+	go s.run()
+}
+
+func (s *Server) URL() string {
+	return s.httpServer.URL
+}
+
+func (s *Server) Close() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		s.wg.Wait()
+		s.hub.closeAll()
+		s.httpServer.Close()
+	})
+}
+
+func (s *Server) Client() (bbs.InternalClient, error) {
+	return bbs.NewClientWithConfig(bbs.ClientConfig{URL: s.URL()})
+}
+
+// SetCells replaces the cells the fake reports and places work on.
+func (s *Server) SetCells(cells []*models.CellPresence) {
+	s.state.setCells(cells)
+}
+
+// CrashActualLRP simulates the rep reporting a crashed instance.
+func (s *Server) CrashActualLRP(processGuid string, index int, reason string) error {
+	return s.state.crashActualLRP(processGuid, int32(index), reason)
+}
+
+// CompleteTask finishes a PENDING or RUNNING Task with the given outcome
+// instead of waiting for the fake to succeed it.
+func (s *Server) CompleteTask(taskGuid string, failed bool, failureReason, result string) error {
+	return s.state.completeTask(taskGuid, failed, failureReason, result)
+}
+
+// Events returns every event the fake has emitted, oldest first.
+func (s *Server) Events() []models.Event {
+	return s.hub.history()
+}
+
+func (s *Server) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.stepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			for _, task := range s.state.step() {
+				if s.deliverCallback(task) {
+					s.state.resolveCallback(task.TaskGuid)
+				}
+			}
+		}
+	}
+}
+
+func (s *Server) deliverCallback(task *models.Task) bool {
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return false
+	}
+
+	resp, err := s.callbacks.Post(task.CompletionCallbackUrl, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	// the BBS only retries when the callback is unavailable
+	return resp.StatusCode != http.StatusServiceUnavailable && resp.StatusCode != http.StatusGatewayTimeout
+}
+
+func (s *Server) router() (http.Handler, error) {
+	handlers := rata.Handlers{
+		bbs.PingRoute_r0:                    http.HandlerFunc(s.ping),
+		bbs.DomainsRoute_r0:                 http.HandlerFunc(s.domains),
+		bbs.UpsertDomainRoute_r0:            http.HandlerFunc(s.upsertDomain),
+		bbs.CellsRoute_r0:                   http.HandlerFunc(s.cells),
+		bbs.ActualLRPsRoute_r0:              http.HandlerFunc(s.actualLRPs),
+		bbs.RetireActualLRPRoute_r0:         http.HandlerFunc(s.retireActualLRP),
+		bbs.DesiredLRPsRoute_r3:             http.HandlerFunc(s.desiredLRPs),
+		bbs.DesiredLRPByProcessGuidRoute_r3: http.HandlerFunc(s.desiredLRPByProcessGuid),
+		bbs.DesireDesiredLRPRoute_r2:        http.HandlerFunc(s.desireLRP),
+		bbs.UpdateDesiredLRPRoute_r0:        http.HandlerFunc(s.updateDesiredLRP),
+		bbs.RemoveDesiredLRPRoute_r0:        http.HandlerFunc(s.removeDesiredLRP),
+		bbs.TasksRoute_r3:                   http.HandlerFunc(s.tasks),
+		bbs.TaskByGuidRoute_r3:              http.HandlerFunc(s.taskByGuid),
+		bbs.DesireTaskRoute_r2:              http.HandlerFunc(s.desireTask),
+		bbs.CancelTaskRoute_r0:              http.HandlerFunc(s.cancelTask),
+		bbs.ResolvingTaskRoute_r0:           http.HandlerFunc(s.resolvingTask),
+		bbs.DeleteTaskRoute_r0:              http.HandlerFunc(s.deleteTask),
+		bbs.LRPInstanceEventStreamRoute_r1:  http.HandlerFunc(s.instanceEventStream),
+		bbs.TaskEventStreamRoute_r1:         http.HandlerFunc(s.taskEventStream),
+	}
+
+	routes := rata.Routes{}
+	for name := range handlers {
+		route, ok := bbs.Routes.FindRouteByName(name)
+		if !ok {
+			continue
+		}
+		routes = append(routes, route)
+	}
+
+	return rata.NewRouter(routes, handlers)
+}
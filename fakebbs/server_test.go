@@ -0,0 +1,260 @@
+package fakebbs_test
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	vizziniconfig "code.cloudfoundry.org/vizzini/config"
+	"code.cloudfoundry.org/vizzini/fakebbs"
+	"code.cloudfoundry.org/vizzini/fixtures"
+
+	. "code.cloudfoundry.org/vizzini/matchers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const traceID = "fakebbs-trace-id"
+
+var _ = Describe("Server", func() {
+	var (
+		server *fakebbs.Server
+		client bbs.InternalClient
+		logger *lagertest.TestLogger
+	)
+
+	BeforeEach(func() {
+		var err error
+		logger = lagertest.NewTestLogger("fakebbs")
+		server = fakebbs.NewServerWithStepInterval(10 * time.Millisecond)
+		server.Start()
+		client, err = server.Client()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	taskDefinition := func() *models.TaskDefinition {
+		return &models.TaskDefinition{
+			Action: models.WrapAction(&models.RunAction{
+				Path: "bash",
+				User: "vcap",
+			}),
+			RootFs:   models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot),
+			MemoryMb: 128,
+			DiskMb:   256,
+		}
+	}
+
+	desiredLRP := func(processGuid string) *models.DesiredLRP {
+		return &models.DesiredLRP{
+			ProcessGuid: processGuid,
+			Domain:      "some-domain",
+			Instances:   2,
+			Action: models.WrapAction(&models.RunAction{
+				Path: "/tmp/grace/grace",
+				User: "vcap",
+			}),
+			RootFs:   models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot),
+			MemoryMb: 128,
+			DiskMb:   256,
+			Ports:    []uint32{8080},
+		}
+	}
+
+	It("responds to pings", func() {
+		Expect(client.Ping(logger, traceID)).To(BeTrue())
+	})
+
+	It("reports cells with rootfs providers", func() {
+		cells, err := client.Cells(logger, traceID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cells).To(HaveLen(1))
+		Expect(cells[0].CellId).To(Equal(fakebbs.FakeCellID))
+		Expect(cells[0].RootfsProviders).NotTo(BeEmpty())
+	})
+
+	Describe("domains", func() {
+		It("expires domains after their TTL", func() {
+			Expect(client.UpsertDomain(logger, traceID, "forever", 0)).To(Succeed())
+			Expect(client.UpsertDomain(logger, traceID, "fleeting", time.Second)).To(Succeed())
+			Expect(client.Domains(logger, traceID)).To(ConsistOf("forever", "fleeting"))
+			Eventually(func() ([]string, error) {
+				return client.Domains(logger, traceID)
+			}, 3*time.Second).Should(ConsistOf("forever"))
+		})
+
+		It("rejects an empty domain", func() {
+			Expect(client.UpsertDomain(logger, traceID, "", 0)).NotTo(Succeed())
+		})
+	})
+
+	Describe("tasks", func() {
+		It("walks a task through its lifecycle", func() {
+			Expect(client.DesireTask(logger, traceID, "task-guid", "some-domain", taskDefinition())).To(Succeed())
+			Eventually(func() (*models.Task, error) {
+				return client.TaskByGuid(logger, traceID, "task-guid")
+			}).Should(HaveTaskState(models.Task_Completed))
+
+			Expect(client.TasksByDomain(logger, traceID, "some-domain")).To(HaveLen(1))
+			Expect(client.ResolvingTask(logger, traceID, "task-guid")).To(Succeed())
+			Expect(client.DeleteTask(logger, traceID, "task-guid")).To(Succeed())
+			Expect(client.TasksByDomain(logger, traceID, "some-domain")).To(BeEmpty())
+		})
+
+		It("rejects duplicate and invalid tasks", func() {
+			Expect(client.DesireTask(logger, traceID, "task-guid", "some-domain", taskDefinition())).To(Succeed())
+			err := client.DesireTask(logger, traceID, "task-guid", "some-domain", taskDefinition())
+			Expect(models.ConvertError(err).Type).To(Equal(models.Error_ResourceExists))
+
+			err = client.DesireTask(logger, traceID, "abc def", "some-domain", taskDefinition())
+			Expect(models.ConvertError(err).Type).To(Equal(models.Error_InvalidRequest))
+		})
+
+		It("cancels running tasks", func() {
+			Expect(client.DesireTask(logger, traceID, "task-guid", "some-domain", taskDefinition())).To(Succeed())
+			Expect(client.CancelTask(logger, traceID, "task-guid")).To(Succeed())
+
+			task, err := client.TaskByGuid(logger, traceID, "task-guid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(task.Failed).To(BeTrue())
+			Expect(task.FailureReason).To(Equal("task was cancelled"))
+		})
+
+		It("fails tasks that no cell can run", func() {
+			definition := taskDefinition()
+			definition.RootFs = models.PreloadedRootFS("fruitfs")
+			Expect(client.DesireTask(logger, traceID, "task-guid", "some-domain", definition)).To(Succeed())
+			Eventually(func() (*models.Task, error) {
+				return client.TaskByGuid(logger, traceID, "task-guid")
+			}).Should(HaveTaskState(models.Task_Completed))
+
+			task, err := client.TaskByGuid(logger, traceID, "task-guid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(task.FailureReason).To(ContainSubstring("found no compatible cell"))
+		})
+	})
+
+	Describe("LRPs", func() {
+		actuals := func(processGuid string) func() ([]models.ActualLRP, error) {
+			return func() ([]models.ActualLRP, error) {
+				lrps, err := client.ActualLRPs(logger, traceID, models.ActualLRPFilter{ProcessGuid: processGuid})
+				actualLRPs := []models.ActualLRP{}
+				for _, lrp := range lrps {
+					actualLRPs = append(actualLRPs, *lrp)
+				}
+				return actualLRPs, err
+			}
+		}
+
+		It("starts, scales and removes instances", func() {
+			Expect(client.DesireLRP(logger, traceID, desiredLRP("lrp-guid"))).To(Succeed())
+			Eventually(actuals("lrp-guid")).Should(ConsistOf(
+				BeActualLRPWithState("lrp-guid", 0, models.ActualLRPStateRunning),
+				BeActualLRPWithState("lrp-guid", 1, models.ActualLRPStateRunning),
+			))
+
+			update := &models.DesiredLRPUpdate{}
+			update.SetInstances(1)
+			Expect(client.UpdateDesiredLRP(logger, traceID, "lrp-guid", update)).To(Succeed())
+			Eventually(actuals("lrp-guid")).Should(ConsistOf(BeActualLRP("lrp-guid", 0)))
+
+			fetched, err := client.DesiredLRPByProcessGuid(logger, traceID, "lrp-guid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fetched.Instances).To(BeEquivalentTo(1))
+			Expect(fetched.ModificationTag.Index).To(BeEquivalentTo(1))
+
+			Expect(client.RemoveDesiredLRP(logger, traceID, "lrp-guid")).To(Succeed())
+			Expect(actuals("lrp-guid")()).To(BeEmpty())
+		})
+
+		It("records crashes", func() {
+			Expect(client.DesireLRP(logger, traceID, desiredLRP("lrp-guid"))).To(Succeed())
+			Eventually(actuals("lrp-guid")).Should(ContainElement(BeActualLRPWithState("lrp-guid", 0, models.ActualLRPStateRunning)))
+
+			Expect(server.CrashActualLRP("lrp-guid", 0, "Exited with status 17")).To(Succeed())
+			Eventually(actuals("lrp-guid")).Should(ContainElement(BeActualLRPWithStateAndCrashCount("lrp-guid", 0, models.ActualLRPStateRunning, 1)))
+		})
+
+		It("reports placement errors for LRPs that do not fit", func() {
+			lrp := desiredLRP("lrp-guid")
+			lrp.MemoryMb = 1024 * 1024
+			Expect(client.DesireLRP(logger, traceID, lrp)).To(Succeed())
+			Eventually(actuals("lrp-guid")).Should(ContainElement(BeUnclaimedActualLRPWithPlacementError("lrp-guid", 0)))
+		})
+
+		It("runs fixtures built from the placeholder Grace values", func() {
+			// what the suite fills in for a config of just {"fake_bbs": true}
+			defaults := fixtures.Defaults{
+				Domain:               "some-domain",
+				RootFS:               models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot),
+				GraceTarballURL:      fakebbs.PlaceholderGraceTarballURL,
+				GraceTarballChecksum: fakebbs.PlaceholderGraceTarballChecksum,
+			}
+			config := vizziniconfig.Defaults()
+			config.FakeBBS = true
+			config.DefaultRootFS = defaults.RootFS
+			config.GraceTarballURL = defaults.GraceTarballURL
+			config.GraceTarballChecksum = defaults.GraceTarballChecksum
+			Expect(config.Validate()).To(Succeed())
+
+			lrp, err := defaults.DesiredLRP("lrp-guid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.DesireLRP(logger, traceID, lrp)).To(Succeed())
+			Eventually(actuals("lrp-guid")).Should(ContainElement(BeActualLRPWithState("lrp-guid", 0, models.ActualLRPStateRunning)))
+		})
+	})
+
+	Describe("event streams", func() {
+		It("streams LRP instance events", func() {
+			eventSource, err := client.SubscribeToInstanceEvents(logger)
+			Expect(err).NotTo(HaveOccurred())
+			defer eventSource.Close()
+
+			lock := &sync.Mutex{}
+			received := []models.Event{}
+			go func() {
+				for {
+					event, err := eventSource.Next()
+					if err != nil {
+						return
+					}
+					lock.Lock()
+					received = append(received, event)
+					lock.Unlock()
+				}
+			}()
+			getEvents := func() []models.Event {
+				lock.Lock()
+				defer lock.Unlock()
+				return append([]models.Event{}, received...)
+			}
+
+			Expect(client.DesireLRP(logger, traceID, desiredLRP("lrp-guid"))).To(Succeed())
+			Eventually(getEvents).Should(ContainElement(MatchDesiredLRPCreatedEvent("lrp-guid")))
+			Eventually(getEvents).Should(ContainElement(MatchActualLRPInstanceCreatedEvent("lrp-guid", 0)))
+			Eventually(getEvents).Should(ContainElement(MatchActualLRPInstanceChangedEvent("lrp-guid", 0, models.ActualLRPStateClaimed)))
+			Eventually(getEvents).Should(ContainElement(MatchActualLRPInstanceChangedEvent("lrp-guid", 0, models.ActualLRPStateRunning)))
+
+			Expect(client.RemoveDesiredLRP(logger, traceID, "lrp-guid")).To(Succeed())
+			Eventually(getEvents).Should(ContainElement(MatchDesiredLRPRemovedEvent("lrp-guid")))
+			Eventually(getEvents).Should(ContainElement(MatchActualLRPInstanceRemovedEvent("lrp-guid", 0)))
+		})
+
+		It("streams task events", func() {
+			eventSource, err := client.SubscribeToTaskEvents(logger)
+			Expect(err).NotTo(HaveOccurred())
+			defer eventSource.Close()
+
+			Expect(client.DesireTask(logger, traceID, "task-guid", "some-domain", taskDefinition())).To(Succeed())
+
+			event, err := eventSource.Next()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(event).To(BeAssignableToTypeOf(&models.TaskCreatedEvent{}))
+		})
+	})
+})
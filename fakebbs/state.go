@@ -0,0 +1,612 @@
+package fakebbs
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	uuid "github.com/nu7hatch/gouuid"
+)
+
+const (
+	FakeCellID           = "fake-cell-0"
+	FakeCellAddress      = "10.255.0.1"
+	DefaultPreloadedRoot = "cflinuxfs4"
+
+	// PlaceholderGraceTarballURL and PlaceholderGraceTarballChecksum let
+	// fixtures pass validation; the fake never downloads anything.
+	PlaceholderGraceTarballURL      = "http://fake-bbs.invalid/grace.tgz"
+	PlaceholderGraceTarballChecksum = "0000000000000000000000000000000000000000"
+)
+
+func DefaultCells() []*models.CellPresence {
+	return []*models.CellPresence{
+		{
+			CellId:     FakeCellID,
+			RepAddress: "http://" + FakeCellAddress + ":1800",
+			Zone:       "z1",
+			Capacity: &models.CellCapacity{
+				MemoryMb:   16384,
+				DiskMb:     65536,
+				Containers: 250,
+			},
+			RootfsProviders: []*models.Provider{
+				{Name: models.PreloadedRootFSScheme, Properties: []string{DefaultPreloadedRoot}},
+				{Name: models.DockerRootFSScheme},
+			},
+		},
+	}
+}
+
+type state struct {
+	lock sync.Mutex
+
+	domains     map[string]time.Time
+	tasks       map[string]*models.Task
+	desiredLRPs map[string]*models.DesiredLRP
+	actualLRPs  map[string]map[int32]*models.ActualLRP
+	cells       []*models.CellPresence
+
+	hub *hub
+}
+
+func newState(hub *hub) *state {
+	return &state{
+		domains:     map[string]time.Time{},
+		tasks:       map[string]*models.Task{},
+		desiredLRPs: map[string]*models.DesiredLRP{},
+		actualLRPs:  map[string]map[int32]*models.ActualLRP{},
+		cells:       DefaultCells(),
+		hub:         hub,
+	}
+}
+
+// Domains
+
+func (s *state) upsertDomain(domain string, ttl time.Duration) error {
+	if domain == "" {
+		return models.NewError(models.Error_InvalidRequest, "domain is required")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+	s.domains[domain] = expiresAt
+	return nil
+}
+
+func (s *state) freshDomains() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	domains := []string{}
+	for domain, expiresAt := range s.domains {
+		if expiresAt.IsZero() || expiresAt.After(now) {
+			domains = append(domains, domain)
+		}
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// Cells
+
+func (s *state) setCells(cells []*models.CellPresence) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.cells = cells
+}
+
+func (s *state) allCells() []*models.CellPresence {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]*models.CellPresence{}, s.cells...)
+}
+
+// placementError mirrors the auctioneer's messages closely enough for the
+// suite's ContainSubstring assertions to behave the same against the fake.
+func (s *state) placementError(rootFS string, memoryMB, diskMB int32) string {
+	compatible := false
+	for _, cell := range s.cells {
+		if cellSupportsRootFS(cell, rootFS) {
+			compatible = true
+			if cell.Capacity.MemoryMb >= memoryMB && cell.Capacity.DiskMb >= diskMB {
+				return ""
+			}
+		}
+	}
+	if !compatible {
+		return "found no compatible cell"
+	}
+	return "insufficient resources: memory"
+}
+
+func cellSupportsRootFS(cell *models.CellPresence, rootFS string) bool {
+	rootFSURL, err := url.Parse(rootFS)
+	if err != nil {
+		return false
+	}
+	scheme, stack := rootFSURL.Scheme, rootFSURL.Opaque
+	if scheme == models.PreloadedOCIRootFSScheme {
+		scheme = models.PreloadedRootFSScheme
+	}
+	for _, provider := range cell.RootfsProviders {
+		if provider.Name != scheme {
+			continue
+		}
+		if scheme != models.PreloadedRootFSScheme {
+			return true
+		}
+		for _, property := range provider.Properties {
+			if property == stack {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Tasks
+
+func (s *state) desireTask(guid, domain string, definition *models.TaskDefinition) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.tasks[guid]; ok {
+		return models.ErrResourceExists
+	}
+
+	now := time.Now().UnixNano()
+	task := &models.Task{
+		TaskDefinition: definition,
+		TaskGuid:       guid,
+		Domain:         domain,
+		State:          models.Task_Pending,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	s.tasks[guid] = task
+	s.hub.publishTask(&models.TaskCreatedEvent{Task: copyTask(task)})
+	return nil
+}
+
+func (s *state) tasksWhere(domain, cellID string) []*models.Task {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	tasks := []*models.Task{}
+	for _, task := range s.tasks {
+		if domain != "" && task.Domain != domain {
+			continue
+		}
+		if cellID != "" && task.CellId != cellID {
+			continue
+		}
+		tasks = append(tasks, copyTask(task))
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].CreatedAt < tasks[j].CreatedAt })
+	return tasks
+}
+
+func (s *state) taskByGuid(guid string) (*models.Task, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	task, ok := s.tasks[guid]
+	if !ok {
+		return nil, models.ErrResourceNotFound
+	}
+	return copyTask(task), nil
+}
+
+func (s *state) cancelTask(guid string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	task, ok := s.tasks[guid]
+	if !ok {
+		return models.ErrResourceNotFound
+	}
+	if task.State != models.Task_Pending && task.State != models.Task_Running {
+		return transitionError(task.State, models.Task_Completed)
+	}
+	s.completeTaskLocked(task, true, "task was cancelled", "")
+	return nil
+}
+
+func (s *state) resolvingTask(guid string) error {
+	return s.transitionTask(guid, models.Task_Completed, models.Task_Resolving)
+}
+
+func (s *state) deleteTask(guid string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	task, ok := s.tasks[guid]
+	if !ok {
+		return models.ErrResourceNotFound
+	}
+	if task.State != models.Task_Resolving {
+		return transitionError(task.State, models.Task_Resolving)
+	}
+	delete(s.tasks, guid)
+	s.hub.publishTask(&models.TaskRemovedEvent{Task: copyTask(task)})
+	return nil
+}
+
+func (s *state) transitionTask(guid string, from, to models.Task_State) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	task, ok := s.tasks[guid]
+	if !ok {
+		return models.ErrResourceNotFound
+	}
+	if task.State != from {
+		return transitionError(task.State, to)
+	}
+	before := copyTask(task)
+	task.State = to
+	task.UpdatedAt = time.Now().UnixNano()
+	s.hub.publishTask(&models.TaskChangedEvent{Before: before, After: copyTask(task)})
+	return nil
+}
+
+func (s *state) completeTask(guid string, failed bool, failureReason, result string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	task, ok := s.tasks[guid]
+	if !ok {
+		return models.ErrResourceNotFound
+	}
+	if task.State != models.Task_Pending && task.State != models.Task_Running {
+		return transitionError(task.State, models.Task_Completed)
+	}
+	s.completeTaskLocked(task, failed, failureReason, result)
+	return nil
+}
+
+func (s *state) completeTaskLocked(task *models.Task, failed bool, failureReason, result string) {
+	before := copyTask(task)
+	now := time.Now().UnixNano()
+	task.State = models.Task_Completed
+	task.Failed = failed
+	task.FailureReason = failureReason
+	task.Result = result
+	task.UpdatedAt = now
+	task.FirstCompletedAt = now
+	s.hub.publishTask(&models.TaskChangedEvent{Before: before, After: copyTask(task)})
+}
+
+func transitionError(from, to models.Task_State) error {
+	return models.NewError(models.Error_InvalidStateTransition, fmt.Sprintf("Cannot transition from %s to %s", from, to))
+}
+
+// Desired LRPs
+
+func (s *state) desireLRP(lrp *models.DesiredLRP) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.desiredLRPs[lrp.ProcessGuid]; ok {
+		return models.ErrResourceExists
+	}
+
+	lrp.ModificationTag = &models.ModificationTag{Epoch: newGuid(), Index: 0}
+	s.desiredLRPs[lrp.ProcessGuid] = lrp
+	s.actualLRPs[lrp.ProcessGuid] = map[int32]*models.ActualLRP{}
+	s.hub.publishInstance(&models.DesiredLRPCreatedEvent{DesiredLrp: copyDesiredLRP(lrp)})
+
+	for index := int32(0); index < lrp.Instances; index++ {
+		s.createActualLRPLocked(lrp, index)
+	}
+	return nil
+}
+
+func (s *state) desiredLRPsWhere(domain string, processGuids []string) []*models.DesiredLRP {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	wanted := map[string]bool{}
+	for _, processGuid := range processGuids {
+		wanted[processGuid] = true
+	}
+
+	lrps := []*models.DesiredLRP{}
+	for _, lrp := range s.desiredLRPs {
+		if domain != "" && lrp.Domain != domain {
+			continue
+		}
+		if len(wanted) > 0 && !wanted[lrp.ProcessGuid] {
+			continue
+		}
+		lrps = append(lrps, copyDesiredLRP(lrp))
+	}
+	sort.Slice(lrps, func(i, j int) bool { return lrps[i].ProcessGuid < lrps[j].ProcessGuid })
+	return lrps
+}
+
+func (s *state) desiredLRPByProcessGuid(processGuid string) (*models.DesiredLRP, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	lrp, ok := s.desiredLRPs[processGuid]
+	if !ok {
+		return nil, models.ErrResourceNotFound
+	}
+	return copyDesiredLRP(lrp), nil
+}
+
+func (s *state) updateDesiredLRP(processGuid string, update *models.DesiredLRPUpdate) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	lrp, ok := s.desiredLRPs[processGuid]
+	if !ok {
+		return models.ErrResourceNotFound
+	}
+
+	before := copyDesiredLRP(lrp)
+	after := lrp.ApplyUpdate(update)
+	tag := *lrp.ModificationTag
+	tag.Increment()
+	after.ModificationTag = &tag
+	s.desiredLRPs[processGuid] = after
+	s.hub.publishInstance(&models.DesiredLRPChangedEvent{Before: before, After: copyDesiredLRP(after)})
+
+	for index, actual := range s.actualLRPs[processGuid] {
+		if index >= after.Instances {
+			s.removeActualLRPLocked(actual)
+		}
+	}
+	for index := int32(0); index < after.Instances; index++ {
+		if _, ok := s.actualLRPs[processGuid][index]; !ok {
+			s.createActualLRPLocked(after, index)
+		}
+	}
+	return nil
+}
+
+func (s *state) removeDesiredLRP(processGuid string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	lrp, ok := s.desiredLRPs[processGuid]
+	if !ok {
+		return models.ErrResourceNotFound
+	}
+
+	delete(s.desiredLRPs, processGuid)
+	s.hub.publishInstance(&models.DesiredLRPRemovedEvent{DesiredLrp: copyDesiredLRP(lrp)})
+	for _, actual := range s.actualLRPs[processGuid] {
+		s.removeActualLRPLocked(actual)
+	}
+	delete(s.actualLRPs, processGuid)
+	return nil
+}
+
+// Actual LRPs
+
+func (s *state) actualLRPsWhere(filter models.ActualLRPFilter) []*models.ActualLRP {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	lrps := []*models.ActualLRP{}
+	for _, instances := range s.actualLRPs {
+		for _, actual := range instances {
+			if filter.Domain != "" && actual.Domain != filter.Domain {
+				continue
+			}
+			if filter.ProcessGuid != "" && actual.ProcessGuid != filter.ProcessGuid {
+				continue
+			}
+			if filter.CellID != "" && actual.CellId != filter.CellID {
+				continue
+			}
+			if filter.Index != nil && actual.Index != *filter.Index {
+				continue
+			}
+			lrps = append(lrps, copyActualLRP(actual))
+		}
+	}
+	sort.Slice(lrps, func(i, j int) bool {
+		if lrps[i].ProcessGuid == lrps[j].ProcessGuid {
+			return lrps[i].Index < lrps[j].Index
+		}
+		return lrps[i].ProcessGuid < lrps[j].ProcessGuid
+	})
+	return lrps
+}
+
+func (s *state) retireActualLRP(key *models.ActualLRPKey) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	actual, ok := s.actualLRPs[key.ProcessGuid][key.Index]
+	if !ok {
+		return models.ErrResourceNotFound
+	}
+	s.removeActualLRPLocked(actual)
+	return nil
+}
+
+func (s *state) crashActualLRP(processGuid string, index int32, reason string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	actual, ok := s.actualLRPs[processGuid][index]
+	if !ok {
+		return models.ErrResourceNotFound
+	}
+
+	before := copyActualLRP(actual)
+	actual.CrashCount++
+	actual.CrashReason = reason
+	// like the BBS, the first two crashes are restarted immediately and later
+	// ones sit in CRASHED until the fake's next step
+	if actual.CrashCount < 3 {
+		actual.State = models.ActualLRPStateUnclaimed
+	} else {
+		actual.State = models.ActualLRPStateCrashed
+	}
+	actual.ActualLRPInstanceKey = models.ActualLRPInstanceKey{}
+	actual.ActualLRPNetInfo = models.ActualLRPNetInfo{}
+	actual.SetRoutable(false)
+	actual.Since = time.Now().UnixNano()
+	actual.ModificationTag.Increment()
+	s.publishActualChangedLocked(before, actual)
+	return nil
+}
+
+func (s *state) createActualLRPLocked(lrp *models.DesiredLRP, index int32) {
+	actual := &models.ActualLRP{
+		ActualLRPKey:    models.NewActualLRPKey(lrp.ProcessGuid, index, lrp.Domain),
+		State:           models.ActualLRPStateUnclaimed,
+		Since:           time.Now().UnixNano(),
+		ModificationTag: models.ModificationTag{Epoch: newGuid(), Index: 0},
+		Presence:        models.ActualLRP_Ordinary,
+	}
+	actual.SetRoutable(false)
+	s.actualLRPs[lrp.ProcessGuid][index] = actual
+	s.hub.publishInstance(&models.ActualLRPInstanceCreatedEvent{ActualLrp: copyActualLRP(actual)})
+}
+
+func (s *state) removeActualLRPLocked(actual *models.ActualLRP) {
+	delete(s.actualLRPs[actual.ProcessGuid], actual.Index)
+	s.hub.publishInstance(&models.ActualLRPInstanceRemovedEvent{ActualLrp: copyActualLRP(actual)})
+}
+
+func (s *state) publishActualChangedLocked(before, after *models.ActualLRP) {
+	s.hub.publishInstance(&models.ActualLRPInstanceChangedEvent{
+		ActualLRPKey:         after.ActualLRPKey,
+		ActualLRPInstanceKey: after.ActualLRPInstanceKey,
+		Before:               before.ToActualLRPInfo(),
+		After:                after.ToActualLRPInfo(),
+	})
+}
+
+// step advances every Task and ActualLRP by at most one state, which is what
+// lets event-stream consumers observe each transition in order.  It returns
+// the completed Tasks that are waiting on a completion callback.
+func (s *state) step() []*models.Task {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	callbacks := []*models.Task{}
+	for _, task := range s.tasks {
+		switch task.State {
+		case models.Task_Completed:
+			if task.CompletionCallbackUrl != "" {
+				callbacks = append(callbacks, copyTask(task))
+			}
+		case models.Task_Pending:
+			if placementError := s.placementError(task.RootFs, task.MemoryMb, task.DiskMb); placementError != "" {
+				s.completeTaskLocked(task, true, placementError, "")
+				continue
+			}
+			before := copyTask(task)
+			task.State = models.Task_Running
+			task.CellId = FakeCellID
+			task.UpdatedAt = time.Now().UnixNano()
+			s.hub.publishTask(&models.TaskChangedEvent{Before: before, After: copyTask(task)})
+		case models.Task_Running:
+			s.completeTaskLocked(task, false, "", "")
+		}
+	}
+
+	for processGuid, instances := range s.actualLRPs {
+		desired := s.desiredLRPs[processGuid]
+		for _, actual := range instances {
+			before := copyActualLRP(actual)
+			switch actual.State {
+			case models.ActualLRPStateUnclaimed:
+				if placementError := s.placementError(desired.RootFs, desired.MemoryMb, desired.DiskMb); placementError != "" {
+					if actual.PlacementError == placementError {
+						continue
+					}
+					actual.PlacementError = placementError
+				} else {
+					actual.State = models.ActualLRPStateClaimed
+					actual.PlacementError = ""
+					actual.ActualLRPInstanceKey = models.NewActualLRPInstanceKey(newGuid(), FakeCellID)
+				}
+			case models.ActualLRPStateClaimed:
+				actual.State = models.ActualLRPStateRunning
+				actual.ActualLRPNetInfo = netInfoFor(desired, actual.Index)
+				actual.SetRoutable(true)
+			case models.ActualLRPStateCrashed:
+				actual.State = models.ActualLRPStateUnclaimed
+			default:
+				continue
+			}
+			actual.Since = time.Now().UnixNano()
+			actual.ModificationTag.Increment()
+			s.publishActualChangedLocked(before, actual)
+		}
+	}
+
+	return callbacks
+}
+
+// resolveCallback removes a Task whose completion callback was delivered,
+// the same way the BBS's task completion worker does.
+func (s *state) resolveCallback(guid string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	task, ok := s.tasks[guid]
+	if !ok || task.State != models.Task_Completed {
+		return
+	}
+	delete(s.tasks, guid)
+	s.hub.publishTask(&models.TaskRemovedEvent{Task: copyTask(task)})
+}
+
+func netInfoFor(desired *models.DesiredLRP, index int32) models.ActualLRPNetInfo {
+	netInfo := models.ActualLRPNetInfo{
+		Address:         FakeCellAddress,
+		InstanceAddress: fmt.Sprintf("10.255.100.%d", index+1),
+	}
+	for i, port := range desired.Ports {
+		hostPort := uint32(61000 + 10*int(index) + 2*i)
+		netInfo.Ports = append(netInfo.Ports, &models.PortMapping{
+			ContainerPort:         port,
+			HostPort:              hostPort,
+			ContainerTlsProxyPort: 61001 + uint32(i),
+			HostTlsProxyPort:      hostPort + 1,
+		})
+	}
+	return netInfo
+}
+
+func copyTask(task *models.Task) *models.Task {
+	taskCopy := *task
+	return &taskCopy
+}
+
+func copyDesiredLRP(lrp *models.DesiredLRP) *models.DesiredLRP {
+	lrpCopy := *lrp
+	return &lrpCopy
+}
+
+func copyActualLRP(lrp *models.ActualLRP) *models.ActualLRP {
+	lrpCopy := *lrp
+	return &lrpCopy
+}
+
+func newGuid() string {
+	u, err := uuid.NewV4()
+	if err != nil {
+		panic(err)
+	}
+	return u.String()
+}
@@ -0,0 +1,118 @@
+package fixtures
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/routing-info/cfroutes"
+)
+
+// GracePort is the port Grace listens on in every fixture.
+const GracePort uint32 = 8080
+
+// Defaults are the deployment-specific values every fixture is built with,
+// usually taken from the Vizzini config.
+type Defaults struct {
+	Domain               string
+	RootFS               string
+	GraceTarballURL      string
+	GraceTarballChecksum string
+	RoutableDomainSuffix string
+	PlacementTags        []string
+}
+
+// RouteFor returns the hostname that WithRoutes maps port to.  GracePort gets
+// <guid>.<suffix>; any other port gets <guid>-<port>.<suffix>.
+func (d Defaults) RouteFor(guid string, port uint32) string {
+	if port == GracePort {
+		return fmt.Sprintf("%s.%s", guid, d.RoutableDomainSuffix)
+	}
+	return fmt.Sprintf("%s-%d.%s", guid, port, d.RoutableDomainSuffix)
+}
+
+// DesiredLRP returns a single instance of Grace on GracePort, routed and
+// monitored, with options applied in order.  It fails if the result does not
+// pass the same validation the BBS performs.
+func (d Defaults) DesiredLRP(guid string, options ...LRPOption) (*models.DesiredLRP, error) {
+	lrp := &models.DesiredLRP{
+		ProcessGuid:   guid,
+		PlacementTags: d.PlacementTags,
+		Domain:        d.Domain,
+		Instances:     1,
+		CachedDependencies: []*models.CachedDependency{
+			&models.CachedDependency{
+				From:              d.GraceTarballURL,
+				To:                "/tmp/grace",
+				CacheKey:          "grace",
+				ChecksumAlgorithm: "sha1",
+				ChecksumValue:     d.GraceTarballChecksum,
+			},
+		},
+		Action: models.WrapAction(&models.RunAction{
+			Path: "/tmp/grace/grace",
+			User: "vcap",
+			Env: []*models.EnvironmentVariable{
+				{Name: "PORT", Value: fmt.Sprint(GracePort)},
+				{Name: "ACTION_LEVEL", Value: "COYOTE"},
+				{Name: "OVERRIDE", Value: "DAQUIRI"}},
+		}),
+		Monitor: models.WrapAction(&models.RunAction{
+			Path: "nc",
+			Args: []string{"-z", "0.0.0.0", fmt.Sprint(GracePort)},
+			User: "vcap",
+		}),
+		RootFs:     d.RootFS,
+		MemoryMb:   128,
+		DiskMb:     256,
+		CpuWeight:  100,
+		LogGuid:    guid,
+		LogSource:  "VIZ",
+		MetricTags: map[string]*models.MetricTagValue{"source_id": {Static: guid}},
+		Annotation: "arbitrary-data",
+	}
+	options = append([]LRPOption{WithRoutes(GracePort)}, options...)
+	for _, option := range options {
+		err := option.applyToLRP(d, lrp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid desired LRP fixture %q: %s", guid, err.Error())
+		}
+	}
+
+	err := lrp.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid desired LRP fixture %q: %s", guid, err.Error())
+	}
+	return lrp, nil
+}
+
+// Task returns a task that writes "some output" to its result file, with
+// options applied in order.  It fails if the result does not pass the same
+// validation the BBS performs.
+func (d Defaults) Task(logGuid string, options ...TaskOption) (*models.TaskDefinition, error) {
+	task := &models.TaskDefinition{
+		Action: models.WrapAction(&models.RunAction{
+			Path: "bash",
+			Args: []string{"-c", "echo 'some output' > /tmp/bar"},
+			User: "vcap",
+		}),
+		RootFs:        d.RootFS,
+		MemoryMb:      128,
+		DiskMb:        256,
+		CpuWeight:     100,
+		LogGuid:       logGuid,
+		LogSource:     "VIZ",
+		ResultFile:    "/tmp/bar",
+		Annotation:    "arbitrary-data",
+		PlacementTags: d.PlacementTags,
+	}
+
+	for _, option := range options {
+		option.applyToTask(d, task)
+	}
+
+	err := task.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid task fixture for %q: %s", logGuid, err.Error())
+	}
+	return task, nil
+}
@@ -0,0 +1,254 @@
+package fixtures_test
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/routing-info/cfroutes"
+	"code.cloudfoundry.org/routing-info/internalroutes"
+	"code.cloudfoundry.org/routing-info/tcp_routes"
+	"code.cloudfoundry.org/vizzini/fixtures"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fixtures", func() {
+	var defaults fixtures.Defaults
+
+	BeforeEach(func() {
+		defaults = fixtures.Defaults{
+			Domain:               "vizzini-fixtures",
+			RootFS:               models.PreloadedRootFS("cflinuxfs4"),
+			GraceTarballURL:      "https://example.com/grace.tgz",
+			GraceTarballChecksum: "0123456789abcdef0123456789abcdef01234567",
+			RoutableDomainSuffix: "vizzini.example.com",
+			PlacementTags:        []string{"vizzini"},
+		}
+	})
+
+	Describe("DesiredLRP", func() {
+		It("builds a routed Grace LRP", func() {
+			lrp, err := defaults.DesiredLRP("some-guid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lrp.ProcessGuid).To(Equal("some-guid"))
+			Expect(lrp.Domain).To(Equal("vizzini-fixtures"))
+			Expect(lrp.PlacementTags).To(ConsistOf("vizzini"))
+			Expect(lrp.Ports).To(ConsistOf(fixtures.GracePort))
+			Expect(lrp.Monitor).NotTo(BeNil())
+
+			routes, err := cfroutes.CFRoutesFromRoutingInfo(*lrp.Routes)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(routes).To(ConsistOf(cfroutes.CFRoute{Port: 8080, Hostnames: []string{"some-guid.vizzini.example.com"}}))
+		})
+
+		DescribeTable("produces valid LRPs",
+			func(option fixtures.LRPOption, check func(*models.DesiredLRP)) {
+				lrp, err := defaults.DesiredLRP("some-guid", option)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lrp.Validate()).To(Succeed())
+				check(lrp)
+			},
+			Entry("WithDockerImage", fixtures.WithDockerImage("docker:///cloudfoundry/grace"), func(lrp *models.DesiredLRP) {
+				Expect(lrp.RootFs).To(Equal("docker:///cloudfoundry/grace"))
+			}),
+			Entry("WithHTTPCheck", fixtures.WithHTTPCheck(8080, "/ping"), func(lrp *models.DesiredLRP) {
+				Expect(lrp.CheckDefinition.Checks).To(HaveLen(1))
+				Expect(lrp.CheckDefinition.Checks[0].HttpCheck.Path).To(Equal("/ping"))
+			}),
+			Entry("WithSidecar", fixtures.WithSidecar(&models.RunAction{Path: "/tmp/grace/grace", User: "vcap"}), func(lrp *models.DesiredLRP) {
+				Expect(lrp.Sidecars).To(HaveLen(1))
+			}),
+			Entry("WithEgressRule", fixtures.WithEgressRule(&models.SecurityGroupRule{Protocol: models.AllProtocol, Destinations: []string{"0.0.0.0/0"}}), func(lrp *models.DesiredLRP) {
+				Expect(lrp.EgressRules).To(HaveLen(1))
+			}),
+			Entry("WithPolicyGroup", fixtures.WithPolicyGroup("some-group"), func(lrp *models.DesiredLRP) {
+				Expect(lrp.Network.Properties).To(Equal(map[string]string{"policy_group_id": "some-group"}))
+			}),
+			Entry("WithMaxPids", fixtures.WithMaxPids(1024), func(lrp *models.DesiredLRP) {
+				Expect(lrp.MaxPids).To(BeEquivalentTo(1024))
+			}),
+			Entry("WithRoutes", fixtures.WithRoutes(8080, 8090), func(lrp *models.DesiredLRP) {
+				Expect(lrp.Ports).To(Equal([]uint32{8080, 8090}))
+				routes, err := cfroutes.CFRoutesFromRoutingInfo(*lrp.Routes)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(routes).To(ConsistOf(
+					cfroutes.CFRoute{Port: 8080, Hostnames: []string{"some-guid.vizzini.example.com"}},
+					cfroutes.CFRoute{Port: 8090, Hostnames: []string{"some-guid-8090.vizzini.example.com"}},
+				))
+			}),
+			Entry("WithImageLayers", fixtures.WithImageLayers(exclusiveLayer()), func(lrp *models.DesiredLRP) {
+				Expect(lrp.ImageLayers).To(HaveLen(1))
+				Expect(lrp.LegacyDownloadUser).To(Equal("vcap"))
+			}),
+			Entry("WithPrivileged", fixtures.WithPrivileged(), func(lrp *models.DesiredLRP) {
+				Expect(lrp.Privileged).To(BeTrue())
+			}),
+			Entry("WithoutMonitor", fixtures.WithoutMonitor(), func(lrp *models.DesiredLRP) {
+				Expect(lrp.Monitor).To(BeNil())
+			}),
+			Entry("WithInstances", fixtures.WithInstances(3), func(lrp *models.DesiredLRP) {
+				Expect(lrp.Instances).To(BeEquivalentTo(3))
+			}),
+			Entry("WithSSH", fixtures.WithSSH("https://example.com/lifecycle.tgz"), func(lrp *models.DesiredLRP) {
+				Expect(lrp.Sidecars).To(HaveLen(1))
+				Expect(lrp.Ports).To(Equal([]uint32{fixtures.GracePort, fixtures.SSHPort}))
+				Expect(*lrp.Routes).To(HaveKey("diego-ssh"))
+				Expect(*lrp.Routes).To(HaveKey("cf-router"))
+			}),
+			Entry("WithTCPRoute", fixtures.WithTCPRoute("some-router-group", 61000, 9999), func(lrp *models.DesiredLRP) {
+				Expect(lrp.Ports).To(Equal([]uint32{fixtures.GracePort, 9999}))
+				Expect(*lrp.Routes).To(HaveKey("cf-router"))
+				tcpRoutes, err := fixtures.TCPRoutes(lrp.Routes)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(tcpRoutes).To(ConsistOf(tcp_routes.TCPRoute{RouterGroupGuid: "some-router-group", ExternalPort: 61000, ContainerPort: 9999}))
+			}),
+		)
+
+		It("adds internal routes alongside the HTTP routes", func() {
+			lrp, err := defaults.DesiredLRP("some-guid",
+				fixtures.WithInternalRoute("some-guid.apps.internal"),
+				fixtures.WithInternalRoute("other.apps.internal"),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*lrp.Routes).To(HaveKey("cf-router"))
+			Expect(string(*(*lrp.Routes)["internal-router"])).To(MatchJSON(`[{"hostname": "some-guid.apps.internal"}, {"hostname": "other.apps.internal"}]`))
+
+			internalRoutes, err := fixtures.InternalRoutes(lrp.Routes)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(internalRoutes).To(ConsistOf(
+				internalroutes.InternalRoute{Hostname: "some-guid.apps.internal"},
+				internalroutes.InternalRoute{Hostname: "other.apps.internal"},
+			))
+
+			replaced := fixtures.ReplaceInternalRoutes(lrp.Routes, nil)
+			Expect(string(*(*replaced)["internal-router"])).To(Equal("[]"))
+			Expect(*replaced).To(HaveKey("cf-router"))
+		})
+
+		It("fails to decode internal routes that are not a list of routes", func() {
+			payload := json.RawMessage(`{"hostname": "some-guid.apps.internal"}`)
+			_, err := fixtures.InternalRoutes(&models.Routes{"internal-router": &payload})
+			Expect(err).To(MatchError(ContainSubstring("failed to decode internal routes")))
+		})
+
+		It("replaces only the HTTP routes and keeps the ports other routes need", func() {
+			lrp, err := defaults.DesiredLRP("some-guid",
+				fixtures.WithTCPRoute("some-router-group", 61000, 9999),
+				fixtures.WithRoutes(8090),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lrp.Ports).To(Equal([]uint32{fixtures.GracePort, 9999, 8090}))
+
+			routes, err := cfroutes.CFRoutesFromRoutingInfo(*lrp.Routes)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(routes).To(ConsistOf(cfroutes.CFRoute{Port: 8090, Hostnames: []string{"some-guid-8090.vizzini.example.com"}}))
+
+			tcpRoutes, err := fixtures.TCPRoutes(lrp.Routes)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tcpRoutes).To(ConsistOf(tcp_routes.TCPRoute{RouterGroupGuid: "some-router-group", ExternalPort: 61000, ContainerPort: 9999}))
+		})
+
+		It("replaces TCP routes without touching the others", func() {
+			lrp, err := defaults.DesiredLRP("some-guid",
+				fixtures.WithTCPRoute("some-router-group", 61000, fixtures.GracePort),
+				fixtures.WithTCPRoute("some-router-group", 61001, fixtures.GracePort),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lrp.Ports).To(Equal([]uint32{fixtures.GracePort}))
+
+			replaced := fixtures.ReplaceTCPRoutes(lrp.Routes, tcp_routes.TCPRoutes{
+				{RouterGroupGuid: "some-router-group", ExternalPort: 61002, ContainerPort: fixtures.GracePort},
+			})
+			tcpRoutes, err := fixtures.TCPRoutes(replaced)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tcpRoutes).To(HaveLen(1))
+			Expect(tcpRoutes[0].ExternalPort).To(BeEquivalentTo(61002))
+			Expect(*replaced).To(HaveKey("cf-router"))
+
+			tcpRoutes, err = fixtures.TCPRoutes(lrp.Routes)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tcpRoutes).To(HaveLen(2), "the original routes are left alone")
+
+			Expect(string(*(*fixtures.ReplaceTCPRoutes(lrp.Routes, nil))["tcp-router"])).To(Equal("[]"))
+		})
+
+		It("applies options in order", func() {
+			lrp, err := defaults.DesiredLRP("some-guid",
+				fixtures.WithDockerImage("docker:///cloudfoundry/grace"),
+				fixtures.WithDockerImage("docker:///cloudfoundry/busybox"),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lrp.RootFs).To(Equal("docker:///cloudfoundry/busybox"))
+		})
+
+		It("rejects LRPs the BBS would reject", func() {
+			_, err := defaults.DesiredLRP("some-guid", fixtures.WithMaxPids(-1))
+			Expect(err).To(MatchError(ContainSubstring(`invalid desired LRP fixture "some-guid"`)))
+
+			defaults.RootFS = ""
+			_, err = defaults.DesiredLRP("some-guid")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Task", func() {
+		It("builds a task with a result file", func() {
+			task, err := defaults.Task("some-guid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(task.LogGuid).To(Equal("some-guid"))
+			Expect(task.ResultFile).To(Equal("/tmp/bar"))
+			Expect(task.PlacementTags).To(ConsistOf("vizzini"))
+		})
+
+		DescribeTable("produces valid tasks",
+			func(option fixtures.TaskOption, check func(*models.TaskDefinition)) {
+				task, err := defaults.Task("some-guid", option)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(task.Validate()).To(Succeed())
+				check(task)
+			},
+			Entry("WithDockerImage", fixtures.WithDockerImage("docker:///cloudfoundry/busybox-alice"), func(task *models.TaskDefinition) {
+				Expect(task.RootFs).To(Equal("docker:///cloudfoundry/busybox-alice"))
+			}),
+			Entry("WithAction", fixtures.WithAction(&models.RunAction{Path: "true", User: "vcap"}), func(task *models.TaskDefinition) {
+				Expect(task.Action.RunAction.Path).To(Equal("true"))
+			}),
+			Entry("WithEgressRule", fixtures.WithEgressRule(&models.SecurityGroupRule{Protocol: models.AllProtocol, Destinations: []string{"0.0.0.0/0"}}), func(task *models.TaskDefinition) {
+				Expect(task.EgressRules).To(HaveLen(1))
+			}),
+			Entry("WithNetwork", fixtures.WithNetwork(map[string]string{"policy_group_id": "some-group"}), func(task *models.TaskDefinition) {
+				Expect(task.Network.Properties).To(HaveKeyWithValue("policy_group_id", "some-group"))
+			}),
+			Entry("WithMaxPids", fixtures.WithMaxPids(1024), func(task *models.TaskDefinition) {
+				Expect(task.MaxPids).To(BeEquivalentTo(1024))
+			}),
+			Entry("WithImageLayers", fixtures.WithImageLayers(exclusiveLayer()), func(task *models.TaskDefinition) {
+				Expect(task.ImageLayers).To(HaveLen(1))
+			}),
+			Entry("WithPrivileged", fixtures.WithPrivileged(), func(task *models.TaskDefinition) {
+				Expect(task.Privileged).To(BeTrue())
+			}),
+			Entry("WithResultFile", fixtures.WithResultFile(""), func(task *models.TaskDefinition) {
+				Expect(task.ResultFile).To(BeEmpty())
+			}),
+		)
+
+		It("rejects tasks the BBS would reject", func() {
+			_, err := defaults.Task("some-guid", fixtures.WithDockerImage(""))
+			Expect(err).To(MatchError(ContainSubstring(`invalid task fixture for "some-guid"`)))
+		})
+	})
+})
+
+func exclusiveLayer() *models.ImageLayer {
+	return &models.ImageLayer{
+		Name:            "busybox",
+		Url:             "https://example.com/busybox.tgz",
+		DestinationPath: "/tmp/busybox",
+		LayerType:       models.LayerTypeExclusive,
+		MediaType:       models.MediaTypeTgz,
+		DigestAlgorithm: models.DigestAlgorithmSha256,
+		DigestValue:     "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+	}
+}
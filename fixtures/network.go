@@ -0,0 +1,83 @@
+package fixtures
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/routing-info/internalroutes"
+)
+
+// PolicyGroupProperty is the Network property the container networking
+// stack matches policies against; Cloud Foundry sets it to the app GUID.
+const PolicyGroupProperty = "policy_group_id"
+
+// WithNetwork sets the properties the container networking stack is given
+// for the fixture's containers, e.g. PolicyGroupProperty.
+func WithNetwork(properties map[string]string) Option {
+	return Option{
+		lrp:  func(_ Defaults, lrp *models.DesiredLRP) { lrp.Network = &models.Network{Properties: properties} },
+		task: func(_ Defaults, task *models.TaskDefinition) { task.Network = &models.Network{Properties: properties} },
+	}
+}
+
+// WithPolicyGroup puts the fixture's containers in the network policy group
+// policyGroup, so that policies naming it apply to them.
+func WithPolicyGroup(policyGroup string) Option {
+	return WithNetwork(map[string]string{PolicyGroupProperty: policyGroup})
+}
+
+// WithInternalRoute has the service discovery controller resolve hostname,
+// e.g. some-guid.apps.internal, to the overlay addresses of the LRP's
+// instances.  Routes set so far are kept, so it can be applied more than
+// once.
+func WithInternalRoute(hostname string) LRPOption {
+	return fallibleLRPOption(func(_ Defaults, lrp *models.DesiredLRP) error {
+		internalRoutes, err := InternalRoutes(lrp.Routes)
+		if err != nil {
+			return err
+		}
+		internalRoutes = append(internalRoutes, internalroutes.InternalRoute{Hostname: hostname})
+		lrp.Routes = ReplaceInternalRoutes(lrp.Routes, internalRoutes)
+		return nil
+	})
+}
+
+// InternalRoutes decodes the internal-router routing info in routes; there
+// are none if it has no such key.
+func InternalRoutes(routes *models.Routes) (internalroutes.InternalRoutes, error) {
+	internalRoutes := internalroutes.InternalRoutes{}
+	if routes == nil {
+		return internalRoutes, nil
+	}
+	payload := (*routes)[internalroutes.INTERNAL_ROUTER]
+	if payload == nil {
+		return internalRoutes, nil
+	}
+	err := json.Unmarshal(*payload, &internalRoutes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode internal routes: %s", err.Error())
+	}
+	return internalRoutes, nil
+}
+
+// ReplaceInternalRoutes returns a copy of routes whose internal-router
+// routing info is internalRoutes.  Other routes are kept; an empty
+// internalRoutes unregisters every internal route.
+func ReplaceInternalRoutes(routes *models.Routes, internalRoutes internalroutes.InternalRoutes) *models.Routes {
+	if internalRoutes == nil {
+		internalRoutes = internalroutes.InternalRoutes{}
+	}
+	// marshalling a slice of structs of strings cannot fail
+	payload, _ := json.Marshal(internalRoutes)
+	internalRoute := json.RawMessage(payload)
+
+	replaced := models.Routes{}
+	if routes != nil {
+		for key, value := range *routes {
+			replaced[key] = value
+		}
+	}
+	replaced[internalroutes.INTERNAL_ROUTER] = &internalRoute
+	return &replaced
+}
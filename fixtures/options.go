@@ -0,0 +1,195 @@
+package fixtures
+
+import (
+	"slices"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/routing-info/cfroutes"
+)
+
+// LRPOption changes a DesiredLRP fixture.
+type LRPOption interface {
+	applyToLRP(Defaults, *models.DesiredLRP) error
+}
+
+// TaskOption changes a TaskDefinition fixture.
+type TaskOption interface {
+	applyToTask(Defaults, *models.TaskDefinition)
+}
+
+type lrpOption func(Defaults, *models.DesiredLRP)
+
+func (o lrpOption) applyToLRP(d Defaults, lrp *models.DesiredLRP) error {
+	o(d, lrp)
+	return nil
+}
+
+// fallibleLRPOption is for options that build on what earlier options set,
+// e.g. routes, and so can find it unusable.
+type fallibleLRPOption func(Defaults, *models.DesiredLRP) error
+
+func (o fallibleLRPOption) applyToLRP(d Defaults, lrp *models.DesiredLRP) error {
+	return o(d, lrp)
+}
+
+type taskOption func(Defaults, *models.TaskDefinition)
+
+func (o taskOption) applyToTask(d Defaults, task *models.TaskDefinition) {
+	o(d, task)
+}
+
+// Option changes DesiredLRP and TaskDefinition fixtures alike.
+type Option struct {
+	lrp  lrpOption
+	task taskOption
+}
+
+func (o Option) applyToLRP(d Defaults, lrp *models.DesiredLRP) error {
+	return o.lrp.applyToLRP(d, lrp)
+}
+
+func (o Option) applyToTask(d Defaults, task *models.TaskDefinition) {
+	o.task(d, task)
+}
+
+// WithDockerImage runs on imageURL, e.g. docker:///cloudfoundry/grace,
+// instead of the default rootfs.
+func WithDockerImage(imageURL string) Option {
+	return Option{
+		lrp:  func(_ Defaults, lrp *models.DesiredLRP) { lrp.RootFs = imageURL },
+		task: func(_ Defaults, task *models.TaskDefinition) { task.RootFs = imageURL },
+	}
+}
+
+// WithAction replaces the action that runs in the container.
+func WithAction(action models.ActionInterface) Option {
+	return Option{
+		lrp:  func(_ Defaults, lrp *models.DesiredLRP) { lrp.Action = models.WrapAction(action) },
+		task: func(_ Defaults, task *models.TaskDefinition) { task.Action = models.WrapAction(action) },
+	}
+}
+
+// WithEgressRule adds rule to the fixture's security group rules.
+func WithEgressRule(rule *models.SecurityGroupRule) Option {
+	return Option{
+		lrp:  func(_ Defaults, lrp *models.DesiredLRP) { lrp.EgressRules = append(lrp.EgressRules, rule) },
+		task: func(_ Defaults, task *models.TaskDefinition) { task.EgressRules = append(task.EgressRules, rule) },
+	}
+}
+
+// WithMaxPids limits the number of processes in the container; 0 means no
+// limit.
+func WithMaxPids(maxPids int32) Option {
+	return Option{
+		lrp:  func(_ Defaults, lrp *models.DesiredLRP) { lrp.MaxPids = maxPids },
+		task: func(_ Defaults, task *models.TaskDefinition) { task.MaxPids = maxPids },
+	}
+}
+
+// WithImageLayers adds layers to the container's filesystem.  Exclusive
+// layers are downloaded as vcap unless a LegacyDownloadUser is already set.
+func WithImageLayers(layers ...*models.ImageLayer) Option {
+	return Option{
+		lrp: func(_ Defaults, lrp *models.DesiredLRP) {
+			lrp.ImageLayers = append(lrp.ImageLayers, layers...)
+			if lrp.LegacyDownloadUser == "" {
+				lrp.LegacyDownloadUser = "vcap"
+			}
+		},
+		task: func(_ Defaults, task *models.TaskDefinition) {
+			task.ImageLayers = append(task.ImageLayers, layers...)
+			if task.LegacyDownloadUser == "" {
+				task.LegacyDownloadUser = "vcap"
+			}
+		},
+	}
+}
+
+func WithPrivileged() Option {
+	return Option{
+		lrp:  func(_ Defaults, lrp *models.DesiredLRP) { lrp.Privileged = true },
+		task: func(_ Defaults, task *models.TaskDefinition) { task.Privileged = true },
+	}
+}
+
+// WithRoutes exposes ports and routes each of them through the HTTP router at
+// the hostname given by Defaults.RouteFor.  It replaces the HTTP routes set
+// so far, but other routes, and the ports earlier options exposed for them,
+// are kept.
+func WithRoutes(ports ...uint32) LRPOption {
+	return lrpOption(func(d Defaults, lrp *models.DesiredLRP) {
+		cfRoutes := cfroutes.CFRoutes{}
+		for _, port := range ports {
+			cfRoutes = append(cfRoutes, cfroutes.CFRoute{Port: port, Hostnames: []string{d.RouteFor(lrp.ProcessGuid, port)}})
+		}
+
+		routes := models.Routes{}
+		if lrp.Routes != nil {
+			for key, value := range *lrp.Routes {
+				routes[key] = value
+			}
+		}
+		for key, value := range cfRoutes.RoutingInfo() {
+			routes[key] = value
+		}
+		lrp.Routes = &routes
+
+		for _, port := range ports {
+			if !slices.Contains(lrp.Ports, port) {
+				lrp.Ports = append(lrp.Ports, port)
+			}
+		}
+	})
+}
+
+// WithHTTPCheck adds a declarative HTTP health check against port and path.
+// The monitor action is left in place; use WithoutMonitor to drop it.
+func WithHTTPCheck(port uint32, path string) LRPOption {
+	return lrpOption(func(_ Defaults, lrp *models.DesiredLRP) {
+		if lrp.CheckDefinition == nil {
+			lrp.CheckDefinition = &models.CheckDefinition{}
+		}
+		lrp.CheckDefinition.Checks = append(lrp.CheckDefinition.Checks, &models.Check{
+			HttpCheck: &models.HTTPCheck{Port: port, Path: path},
+		})
+	})
+}
+
+// WithSidecar runs action alongside the main action in every instance.
+func WithSidecar(action models.ActionInterface) LRPOption {
+	return lrpOption(func(_ Defaults, lrp *models.DesiredLRP) {
+		lrp.Sidecars = append(lrp.Sidecars, &models.Sidecar{Action: models.WrapAction(action)})
+	})
+}
+
+func WithSetup(action models.ActionInterface) LRPOption {
+	return lrpOption(func(_ Defaults, lrp *models.DesiredLRP) {
+		lrp.Setup = models.WrapAction(action)
+	})
+}
+
+func WithMonitor(action models.ActionInterface) LRPOption {
+	return lrpOption(func(_ Defaults, lrp *models.DesiredLRP) {
+		lrp.Monitor = models.WrapAction(action)
+	})
+}
+
+func WithoutMonitor() LRPOption {
+	return lrpOption(func(_ Defaults, lrp *models.DesiredLRP) {
+		lrp.Monitor = nil
+	})
+}
+
+func WithInstances(instances int32) LRPOption {
+	return lrpOption(func(_ Defaults, lrp *models.DesiredLRP) {
+		lrp.Instances = instances
+	})
+}
+
+// WithResultFile names the file whose contents become the task's result; an
+// empty path means no result.
+func WithResultFile(path string) TaskOption {
+	return taskOption(func(_ Defaults, task *models.TaskDefinition) {
+		task.ResultFile = path
+	})
+}
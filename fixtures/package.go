@@ -0,0 +1 @@
+package fixtures // import "code.cloudfoundry.org/vizzini/fixtures"
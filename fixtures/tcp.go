@@ -0,0 +1,74 @@
+package fixtures
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/routing-info/tcp_routes"
+)
+
+// WithTCPRoute exposes containerPort and has the TCP routers in
+// routerGroupGuid forward externalPort to it.  Routes set so far are kept, so
+// it can be applied more than once.
+func WithTCPRoute(routerGroupGuid string, externalPort, containerPort uint32) LRPOption {
+	return fallibleLRPOption(func(_ Defaults, lrp *models.DesiredLRP) error {
+		tcpRoutes, err := TCPRoutes(lrp.Routes)
+		if err != nil {
+			return err
+		}
+		tcpRoutes = append(tcpRoutes, tcp_routes.TCPRoute{
+			RouterGroupGuid: routerGroupGuid,
+			ExternalPort:    externalPort,
+			ContainerPort:   containerPort,
+		})
+		lrp.Routes = ReplaceTCPRoutes(lrp.Routes, tcpRoutes)
+
+		for _, port := range lrp.Ports {
+			if port == containerPort {
+				return nil
+			}
+		}
+		lrp.Ports = append(lrp.Ports, containerPort)
+		return nil
+	})
+}
+
+// TCPRoutes decodes the tcp-router routing info in routes; there are none if
+// it has no such key.
+func TCPRoutes(routes *models.Routes) (tcp_routes.TCPRoutes, error) {
+	tcpRoutes := tcp_routes.TCPRoutes{}
+	if routes == nil {
+		return tcpRoutes, nil
+	}
+	payload := (*routes)[tcp_routes.TCP_ROUTER]
+	if payload == nil {
+		return tcpRoutes, nil
+	}
+	err := json.Unmarshal(*payload, &tcpRoutes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode tcp routes: %s", err.Error())
+	}
+	return tcpRoutes, nil
+}
+
+// ReplaceTCPRoutes returns a copy of routes whose tcp-router routing info is
+// tcpRoutes, e.g. for a DesiredLRPUpdate.  Other routes are kept; an empty
+// tcpRoutes unregisters every TCP route.
+func ReplaceTCPRoutes(routes *models.Routes, tcpRoutes tcp_routes.TCPRoutes) *models.Routes {
+	if tcpRoutes == nil {
+		tcpRoutes = tcp_routes.TCPRoutes{}
+	}
+	// marshalling a slice of structs of strings and integers cannot fail
+	payload, _ := json.Marshal(tcpRoutes)
+	tcpRoute := json.RawMessage(payload)
+
+	replaced := models.Routes{}
+	if routes != nil {
+		for key, value := range *routes {
+			replaced[key] = value
+		}
+	}
+	replaced[tcp_routes.TCP_ROUTER] = &tcpRoute
+	return &replaced
+}
@@ -0,0 +1,157 @@
+package forensics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+// Report is what the BBS knows about a GUID: the task or DesiredLRP it names,
+// its ActualLRPs and the events recorded for it.
+type Report struct {
+	GUID       string             `json:"guid"`
+	Task       *models.Task       `json:"task,omitempty"`
+	DesiredLRP *models.DesiredLRP `json:"desired_lrp,omitempty"`
+	ActualLRPs []ActualLRP        `json:"actual_lrps"`
+	Events     []Event            `json:"events"`
+	Drops      []Drop             `json:"stream_drops,omitempty"`
+	Errors     []string           `json:"errors,omitempty"`
+}
+
+// ActualLRP holds the fields of an ActualLRP that explain why it is not
+// running where it should be.
+type ActualLRP struct {
+	Index          int32     `json:"index"`
+	State          string    `json:"state"`
+	Presence       string    `json:"presence"`
+	CellID         string    `json:"cell_id"`
+	InstanceGuid   string    `json:"instance_guid"`
+	PlacementError string    `json:"placement_error,omitempty"`
+	CrashCount     int32     `json:"crash_count"`
+	CrashReason    string    `json:"crash_reason,omitempty"`
+	Since          time.Time `json:"since"`
+}
+
+// Collect gathers a Report for guid.  recorder may be nil, in which case the
+// report has no events.
+func Collect(logger lager.Logger, client bbs.InternalClient, traceID, guid string, recorder *Recorder) *Report {
+	logger = logger.Session("forensics", lager.Data{"guid": guid})
+	report := &Report{
+		GUID:       guid,
+		ActualLRPs: []ActualLRP{},
+		Events:     []Event{},
+	}
+	addError := func(what string, err error) {
+		if err != nil && models.ConvertError(err).Type != models.Error_ResourceNotFound {
+			report.Errors = append(report.Errors, what+": "+err.Error())
+		}
+	}
+
+	task, err := client.TaskByGuid(logger, traceID, guid)
+	addError("task", err)
+	report.Task = task
+
+	desiredLRP, err := client.DesiredLRPByProcessGuid(logger, traceID, guid)
+	addError("desired LRP", err)
+	report.DesiredLRP = desiredLRP
+
+	actualLRPs, err := client.ActualLRPs(logger, traceID, models.ActualLRPFilter{ProcessGuid: guid})
+	addError("actual LRPs", err)
+	for _, lrp := range actualLRPs {
+		report.ActualLRPs = append(report.ActualLRPs, ActualLRP{
+			Index:          lrp.Index,
+			State:          lrp.State,
+			Presence:       lrp.Presence.String(),
+			CellID:         lrp.CellId,
+			InstanceGuid:   lrp.InstanceGuid,
+			PlacementError: lrp.PlacementError,
+			CrashCount:     lrp.CrashCount,
+			CrashReason:    lrp.CrashReason,
+			Since:          time.Unix(0, lrp.Since),
+		})
+	}
+	sort.Slice(report.ActualLRPs, func(i, j int) bool {
+		return report.ActualLRPs[i].Index < report.ActualLRPs[j].Index
+	})
+
+	if recorder != nil {
+		report.Events = recorder.EventsFor(guid)
+		report.Drops = recorder.Drops()
+	}
+
+	return report
+}
+
+// Cells lists every cell the task and ActualLRPs were placed on.
+func (r *Report) Cells() []string {
+	cells := map[string]bool{}
+	if r.Task != nil && r.Task.CellId != "" {
+		cells[r.Task.CellId] = true
+	}
+	for _, lrp := range r.ActualLRPs {
+		if lrp.CellID != "" {
+			cells[lrp.CellID] = true
+		}
+	}
+
+	sorted := []string{}
+	for cell := range cells {
+		sorted = append(sorted, cell)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func (r *Report) String() string {
+	lines := []string{fmt.Sprintf("BBS state for %s", r.GUID)}
+
+	if r.Task != nil {
+		lines = append(lines, fmt.Sprintf("  task: state=%s cell=%s failed=%t failure_reason=%q", r.Task.State, r.Task.CellId, r.Task.Failed, r.Task.FailureReason))
+	}
+	if r.DesiredLRP != nil {
+		lines = append(lines, fmt.Sprintf("  desired LRP: instances=%d rootfs=%s", r.DesiredLRP.Instances, r.DesiredLRP.RootFs))
+	}
+	if r.Task == nil && r.DesiredLRP == nil {
+		lines = append(lines, "  no task or desired LRP")
+	}
+
+	for _, lrp := range r.ActualLRPs {
+		line := fmt.Sprintf("  actual LRP %d: state=%s presence=%s cell=%s crash_count=%d", lrp.Index, lrp.State, lrp.Presence, lrp.CellID, lrp.CrashCount)
+		if lrp.CrashReason != "" {
+			line += fmt.Sprintf(" crash_reason=%q", lrp.CrashReason)
+		}
+		if lrp.PlacementError != "" {
+			line += fmt.Sprintf(" placement_error=%q", lrp.PlacementError)
+		}
+		lines = append(lines, line)
+	}
+
+	if cells := r.Cells(); len(cells) > 0 {
+		lines = append(lines, "  cells: "+strings.Join(cells, ", "))
+	}
+
+	if len(r.Events) > 0 {
+		lines = append(lines, "  recent events:")
+		for _, event := range r.Events {
+			lines = append(lines, "    "+event.String())
+		}
+	}
+
+	if len(r.Drops) > 0 {
+		lines = append(lines, "  recent events may be incomplete:")
+		for _, drop := range r.Drops {
+			lines = append(lines, "    "+drop.String())
+		}
+	}
+
+	for _, err := range r.Errors {
+		lines = append(lines, "  error fetching "+err)
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,13 @@
+package forensics_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestForensics(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Forensics Suite")
+}
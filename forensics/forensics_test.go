@@ -0,0 +1,147 @@
+package forensics_test
+
+import (
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/vizzini/fakebbs"
+	"code.cloudfoundry.org/vizzini/forensics"
+	"github.com/onsi/gomega/gbytes"
+
+	. "code.cloudfoundry.org/vizzini/matchers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Forensics", func() {
+	var (
+		server   *fakebbs.Server
+		client   bbs.InternalClient
+		logger   *lagertest.TestLogger
+		recorder *forensics.Recorder
+	)
+
+	BeforeEach(func() {
+		var err error
+		logger = lagertest.NewTestLogger("forensics")
+		server = fakebbs.NewServerWithStepInterval(10 * time.Millisecond)
+		server.Start()
+		client, err = server.Client()
+		Expect(err).NotTo(HaveOccurred())
+
+		recorder = forensics.NewRecorder(forensics.DefaultEventsPerGuid)
+		recorder.Filter = func(guid string) bool { return strings.HasPrefix(guid, "mine-") }
+		Expect(recorder.Start(logger, client)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		recorder.Stop()
+		server.Close()
+	})
+
+	desiredLRP := func(processGuid string, memoryMB int32) *models.DesiredLRP {
+		return &models.DesiredLRP{
+			ProcessGuid: processGuid,
+			Domain:      "forensics",
+			Instances:   2,
+			RootFs:      models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot),
+			MemoryMb:    memoryMB,
+			Action:      models.WrapAction(&models.RunAction{Path: "true", User: "vcap"}),
+		}
+	}
+
+	It("reports crashes and placement errors along with recent events", func() {
+		Expect(client.DesireLRP(logger, "trace-id", desiredLRP("mine-lrp", 128))).To(Succeed())
+		Eventually(func() []forensics.ActualLRP {
+			return forensics.Collect(logger, client, "trace-id", "mine-lrp", recorder).ActualLRPs
+		}).Should(ContainElement(HaveField("State", models.ActualLRPStateRunning)))
+		Expect(server.CrashActualLRP("mine-lrp", 0, "Exited with status 17")).To(Succeed())
+
+		Eventually(func() []forensics.Event {
+			return recorder.EventsFor("mine-lrp")
+		}).Should(ContainElement(HaveField("Summary", ContainSubstring(`crash_reason="Exited with status 17"`))))
+
+		report := forensics.Collect(logger, client, "trace-id", "mine-lrp", recorder)
+		Expect(report.Errors).To(BeEmpty())
+		Expect(report.Task).To(BeNil())
+		Expect(report.DesiredLRP.ProcessGuid).To(Equal("mine-lrp"))
+		Expect(report.ActualLRPs).To(HaveLen(2))
+		Expect(report.ActualLRPs[0].CrashCount).To(BeEquivalentTo(1))
+		Expect(report.ActualLRPs[0].CrashReason).To(Equal("Exited with status 17"))
+		Expect(report.Cells()).To(ConsistOf(fakebbs.FakeCellID))
+		Expect(report.String()).To(ContainSubstring("actual LRP 0:"))
+		Expect(report.String()).To(ContainSubstring("recent events:"))
+
+		Expect(client.DesireLRP(logger, "trace-id", desiredLRP("mine-huge-lrp", 1024*1024))).To(Succeed())
+		Eventually(func() []forensics.ActualLRP {
+			return forensics.Collect(logger, client, "trace-id", "mine-huge-lrp", recorder).ActualLRPs
+		}).Should(ContainElement(HaveField("PlacementError", ContainSubstring("insufficient resources"))))
+	})
+
+	It("reports tasks", func() {
+		Expect(client.DesireTask(logger, "trace-id", "mine-task", "forensics", &models.TaskDefinition{
+			RootFs:   models.PreloadedRootFS("fruitfs"),
+			MemoryMb: 32,
+			Action:   models.WrapAction(&models.RunAction{Path: "true", User: "vcap"}),
+		})).To(Succeed())
+		Eventually(func() *models.Task {
+			return forensics.Collect(logger, client, "trace-id", "mine-task", recorder).Task
+		}).Should(HaveTaskState(models.Task_Completed))
+
+		report := forensics.Collect(logger, client, "trace-id", "mine-task", recorder)
+		Expect(report.DesiredLRP).To(BeNil())
+		Expect(report.String()).To(ContainSubstring("found no compatible cell"))
+		Eventually(func() []forensics.Event {
+			return recorder.EventsFor("mine-task")
+		}).Should(ContainElement(HaveField("Summary", ContainSubstring("failure_reason"))))
+	})
+
+	It("only records GUIDs the filter accepts and forgets them on request", func() {
+		Expect(client.DesireLRP(logger, "trace-id", desiredLRP("mine-lrp", 128))).To(Succeed())
+		Expect(client.DesireLRP(logger, "trace-id", desiredLRP("theirs-lrp", 128))).To(Succeed())
+		Eventually(func() []forensics.Event { return recorder.EventsFor("mine-lrp") }).ShouldNot(BeEmpty())
+		Expect(recorder.EventsFor("theirs-lrp")).To(BeEmpty())
+
+		Expect(client.DesireLRP(logger, "trace-id", desiredLRP("mine-lrp-peer", 128))).To(Succeed())
+		Eventually(func() []string { return recorder.GuidsWithPrefix("mine-lrp-") }).Should(Equal([]string{"mine-lrp-peer"}))
+
+		recorder.Forget("mine-lrp")
+		Expect(recorder.EventsFor("mine-lrp")).To(BeEmpty())
+	})
+
+	It("resubscribes to a stream that drops and notes the drop in reports", func() {
+		droppingClient := fakebbs.NewDroppingClient(client)
+		recorder := forensics.NewRecorder(forensics.DefaultEventsPerGuid)
+		recorder.RetryInterval = 10 * time.Millisecond
+		Expect(recorder.Start(logger, droppingClient)).To(Succeed())
+		defer recorder.Stop()
+		Expect(droppingClient.Subscriptions()).To(Equal(2))
+
+		droppingClient.Drop()
+		Eventually(droppingClient.Subscriptions).Should(Equal(4))
+		Expect(recorder.Drops()).To(ConsistOf(HaveField("Stream", "instance"), HaveField("Stream", "task")))
+		Expect(logger).To(gbytes.Say("stream-dropped"))
+
+		Expect(client.DesireLRP(logger, "trace-id", desiredLRP("mine-lrp", 128))).To(Succeed())
+		Eventually(func() []forensics.Event { return recorder.EventsFor("mine-lrp") }).ShouldNot(BeEmpty())
+
+		report := forensics.Collect(logger, client, "trace-id", "mine-lrp", recorder)
+		Expect(report.Drops).To(HaveLen(2))
+		Expect(report.String()).To(ContainSubstring("recent events may be incomplete:"))
+		Expect(report.String()).To(ContainSubstring("the task event stream dropped"))
+	})
+
+	It("keeps only the most recent events for each GUID", func() {
+		recorder := forensics.NewRecorder(2)
+		for i := int32(1); i <= 3; i++ {
+			recorder.Record(&models.DesiredLRPCreatedEvent{DesiredLrp: &models.DesiredLRP{ProcessGuid: "guid", Instances: i}})
+		}
+		events := recorder.EventsFor("guid")
+		Expect(events).To(HaveLen(2))
+		Expect(events[0].Summary).To(Equal("instances=2"))
+		Expect(events[1].Summary).To(Equal("instances=3"))
+	})
+})
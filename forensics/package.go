@@ -0,0 +1 @@
+package forensics // import "code.cloudfoundry.org/vizzini/forensics"
@@ -0,0 +1,228 @@
+package forensics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+const (
+	DefaultEventsPerGuid    = 50
+	DefaultRetryInterval    = time.Second
+	DefaultMaxRetryInterval = 30 * time.Second
+)
+
+// Event is a BBS event as the Recorder saw it.
+type Event struct {
+	ReceivedAt time.Time `json:"received_at"`
+	Type       string    `json:"type"`
+	Summary    string    `json:"summary"`
+}
+
+func (e Event) String() string {
+	return fmt.Sprintf("%s %-28s %s", e.ReceivedAt.Format("15:04:05.000"), e.Type, e.Summary)
+}
+
+// Drop is a time an event stream dropped, or could not be resubscribed to.
+// Events sent before it was resubscribed to were not recorded.
+type Drop struct {
+	Stream string    `json:"stream"`
+	At     time.Time `json:"at"`
+	Error  string    `json:"error"`
+}
+
+func (d Drop) String() string {
+	return fmt.Sprintf("%s the %s event stream dropped, events sent until it was resubscribed to are missing: %s", d.At.Format("15:04:05.000"), d.Stream, d.Error)
+}
+
+// Recorder keeps the most recent BBS events for each task or process GUID so
+// they are at hand when a spec fails.
+type Recorder struct {
+	// Filter, if set, limits recording to the GUIDs it accepts.
+	Filter func(guid string) bool
+
+	// RetryInterval is how long to wait before resubscribing to a stream that
+	// dropped.  It doubles after every failed attempt up to MaxRetryInterval.
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+
+	limit   int
+	lock    sync.Mutex
+	events  map[string][]Event
+	drops   []Drop
+	streams *Streams
+}
+
+func NewRecorder(eventsPerGuid int) *Recorder {
+	return &Recorder{
+		RetryInterval:    DefaultRetryInterval,
+		MaxRetryInterval: DefaultMaxRetryInterval,
+		limit:            eventsPerGuid,
+		events:           map[string][]Event{},
+	}
+}
+
+// Start subscribes to the instance and task event streams.  Events are
+// recorded until Stop is called; a stream that drops is resubscribed to and
+// the drop is noted in every Report collected afterwards.
+func (r *Recorder) Start(logger lager.Logger, client bbs.Client) error {
+	streams, err := Subscribe(logger.Session("forensics-recorder"), client, StreamOptions{
+		RetryInterval:    r.RetryInterval,
+		MaxRetryInterval: r.MaxRetryInterval,
+		OnDisconnect: func(stream string, err error) {
+			r.addDrop(Drop{Stream: stream, At: time.Now(), Error: err.Error()})
+		},
+	}, r.Record)
+	if err != nil {
+		return err
+	}
+	r.streams = streams
+	return nil
+}
+
+func (r *Recorder) Stop() {
+	if r.streams != nil {
+		r.streams.Stop()
+	}
+}
+
+func (r *Recorder) addDrop(drop Drop) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.drops = append(r.drops, drop)
+	if len(r.drops) > r.limit {
+		r.drops = r.drops[len(r.drops)-r.limit:]
+	}
+}
+
+// Drops lists the most recent times a stream dropped.
+func (r *Recorder) Drops() []Drop {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]Drop{}, r.drops...)
+}
+
+func (r *Recorder) Record(event models.Event) {
+	guid := GuidFor(event)
+	if guid == "" || (r.Filter != nil && !r.Filter(guid)) {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	recorded := append(r.events[guid], Event{
+		ReceivedAt: time.Now(),
+		Type:       event.EventType(),
+		Summary:    Summarize(event),
+	})
+	if len(recorded) > r.limit {
+		recorded = recorded[len(recorded)-r.limit:]
+	}
+	r.events[guid] = recorded
+}
+
+func (r *Recorder) EventsFor(guid string) []Event {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]Event{}, r.events[guid]...)
+}
+
+// GuidsWithPrefix lists, sorted, the GUIDs starting with prefix that events
+// were recorded for, e.g. the extra resources a spec derived from its GUID.
+func (r *Recorder) GuidsWithPrefix(prefix string) []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	guids := []string{}
+	for guid := range r.events {
+		if strings.HasPrefix(guid, prefix) {
+			guids = append(guids, guid)
+		}
+	}
+	sort.Strings(guids)
+	return guids
+}
+
+// Forget drops the events recorded for guid once nothing will ask for them.
+func (r *Recorder) Forget(guid string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.events, guid)
+}
+
+// GuidFor returns the task or process GUID an event is about.
+func GuidFor(event models.Event) string {
+	switch event := event.(type) {
+	case *models.DesiredLRPCreatedEvent:
+		return event.DesiredLrp.ProcessGuid
+	case *models.DesiredLRPChangedEvent:
+		return event.After.ProcessGuid
+	case *models.DesiredLRPRemovedEvent:
+		return event.DesiredLrp.ProcessGuid
+	case *models.ActualLRPInstanceCreatedEvent:
+		return event.ActualLrp.ProcessGuid
+	case *models.ActualLRPInstanceChangedEvent:
+		return event.ProcessGuid
+	case *models.ActualLRPInstanceRemovedEvent:
+		return event.ActualLrp.ProcessGuid
+	case *models.ActualLRPCrashedEvent:
+		return event.ProcessGuid
+	case *models.TaskCreatedEvent:
+		return event.Task.TaskGuid
+	case *models.TaskChangedEvent:
+		return event.After.TaskGuid
+	case *models.TaskRemovedEvent:
+		return event.Task.TaskGuid
+	}
+	return ""
+}
+
+// Summarize describes an event in one line, leading with what changed.
+func Summarize(event models.Event) string {
+	switch event := event.(type) {
+	case *models.DesiredLRPCreatedEvent:
+		return fmt.Sprintf("instances=%d", event.DesiredLrp.Instances)
+	case *models.DesiredLRPChangedEvent:
+		return fmt.Sprintf("instances=%d -> %d", event.Before.Instances, event.After.Instances)
+	case *models.DesiredLRPRemovedEvent:
+		return ""
+	case *models.ActualLRPInstanceCreatedEvent:
+		return fmt.Sprintf("index=%d state=%s", event.ActualLrp.Index, event.ActualLrp.State)
+	case *models.ActualLRPInstanceChangedEvent:
+		summary := fmt.Sprintf("index=%d %s -> %s", event.Index, event.Before.State, event.After.State)
+		if event.CellId != "" {
+			summary += " cell=" + event.CellId
+		}
+		if event.After.PlacementError != "" {
+			summary += fmt.Sprintf(" placement_error=%q", event.After.PlacementError)
+		}
+		if event.After.CrashCount != event.Before.CrashCount {
+			summary += fmt.Sprintf(" crash_count=%d crash_reason=%q", event.After.CrashCount, event.After.CrashReason)
+		}
+		return summary
+	case *models.ActualLRPInstanceRemovedEvent:
+		return fmt.Sprintf("index=%d state=%s", event.ActualLrp.Index, event.ActualLrp.State)
+	case *models.ActualLRPCrashedEvent:
+		return fmt.Sprintf("index=%d crash_count=%d crash_reason=%q", event.Index, event.CrashCount, event.CrashReason)
+	case *models.TaskCreatedEvent:
+		return fmt.Sprintf("state=%s", event.Task.State)
+	case *models.TaskChangedEvent:
+		summary := fmt.Sprintf("%s -> %s", event.Before.State, event.After.State)
+		if event.After.CellId != "" {
+			summary += " cell=" + event.After.CellId
+		}
+		if event.After.Failed {
+			summary += fmt.Sprintf(" failure_reason=%q", event.After.FailureReason)
+		}
+		return summary
+	case *models.TaskRemovedEvent:
+		return fmt.Sprintf("state=%s", event.Task.State)
+	}
+	return ""
+}
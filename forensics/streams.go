@@ -0,0 +1,129 @@
+package forensics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/events"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+type StreamOptions struct {
+	// RetryInterval is how long to wait before resubscribing to a stream that
+	// dropped.  It doubles after every failed attempt up to MaxRetryInterval,
+	// and is reset once a subscription succeeds.
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+
+	// OnDisconnect, if set, is told whenever a stream drops or cannot be
+	// subscribed to, with the stream's name, "instance" or "task".
+	OnDisconnect func(stream string, err error)
+}
+
+// Follow hands every event on a stream to handle until ctx is done.  The
+// stream is read from source, or subscribed to if source is nil, and is
+// resubscribed to whenever it drops; events sent while it was down are lost.
+func Follow(ctx context.Context, logger lager.Logger, stream string, source events.EventSource, subscribe func() (events.EventSource, error), options StreamOptions, handle func(models.Event)) {
+	logger = logger.Session(stream)
+	if options.RetryInterval == 0 {
+		options.RetryInterval = DefaultRetryInterval
+	}
+	if options.MaxRetryInterval == 0 {
+		options.MaxRetryInterval = DefaultMaxRetryInterval
+	}
+
+	retryInterval := options.RetryInterval
+	for {
+		var err error
+		if source == nil {
+			source, err = subscribe()
+		}
+		if err == nil {
+			retryInterval = options.RetryInterval
+			err = consumeUntilDone(ctx, source, handle)
+			source = nil
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		logger.Error("stream-dropped", err, lager.Data{"retry_in": retryInterval.String()})
+		if options.OnDisconnect != nil {
+			options.OnDisconnect(stream, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+		retryInterval *= 2
+		if retryInterval > options.MaxRetryInterval {
+			retryInterval = options.MaxRetryInterval
+		}
+	}
+}
+
+// consumeUntilDone reads source until it fails or ctx is done, and always
+// closes it.
+func consumeUntilDone(ctx context.Context, source events.EventSource, handle func(models.Event)) error {
+	stop := context.AfterFunc(ctx, func() { source.Close() })
+	defer stop()
+	defer source.Close()
+
+	for {
+		event, err := source.Next()
+		if err != nil {
+			return err
+		}
+		handle(event)
+	}
+}
+
+// Streams follows the instance and task event streams for a recorder.
+type Streams struct {
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Subscribe subscribes to the instance and task event streams, failing if
+// either subscription does, then follows both until Stop is called.  The two
+// streams call handle concurrently.
+func Subscribe(logger lager.Logger, client bbs.Client, options StreamOptions, handle func(models.Event)) (*Streams, error) {
+	instanceEvents, err := client.SubscribeToInstanceEvents(logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to instance events: %s", err.Error())
+	}
+	taskEvents, err := client.SubscribeToTaskEvents(logger)
+	if err != nil {
+		instanceEvents.Close()
+		return nil, fmt.Errorf("failed to subscribe to task events: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	streams := &Streams{cancel: cancel}
+	streams.wg.Add(2)
+	go func() {
+		defer streams.wg.Done()
+		Follow(ctx, logger, "instance", instanceEvents, func() (events.EventSource, error) {
+			return client.SubscribeToInstanceEvents(logger)
+		}, options, handle)
+	}()
+	go func() {
+		defer streams.wg.Done()
+		Follow(ctx, logger, "task", taskEvents, func() (events.EventSource, error) {
+			return client.SubscribeToTaskEvents(logger)
+		}, options, handle)
+	}()
+	return streams, nil
+}
+
+// Stop closes the streams and waits for the events already received to be
+// handled.
+func (s *Streams) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
@@ -0,0 +1,256 @@
+package grace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+// SideChannelResponse is what Grace serves on every port other than $PORT.
+const SideChannelResponse = "grace side-channel"
+
+// StatusError is returned when Grace answers with anything other than 200.
+type StatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Body       string
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("grace %s %s: unexpected status %d: %s", e.Method, e.Path, e.StatusCode, e.Body)
+}
+
+// Env is the environment of the Grace process as name/value pairs.
+type Env [][]string
+
+func (e Env) Get(name string) (string, bool) {
+	for _, pair := range e {
+		if len(pair) == 2 && pair[0] == name {
+			return pair[1], true
+		}
+	}
+	return "", false
+}
+
+// Client talks to one Grace instance, or to any instance behind a route.
+type Client struct {
+	baseURL       string
+	httpClient    *http.Client
+	attempts      int
+	retryInterval time.Duration
+	logger        lager.Logger
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sets the client requests go through, e.g. one with a cookie
+// jar for sticky sessions or with TLS config for the container proxy.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries retries requests that fail or get a non-200 response, up to
+// attempts times in total.
+func WithRetries(attempts int, interval time.Duration) Option {
+	return func(c *Client) {
+		c.attempts = attempts
+		c.retryInterval = interval
+	}
+}
+
+// WithLogger logs every attempt that fails, e.g. so that a spec's log shows
+// the retries that led up to it failing.
+func WithLogger(logger lager.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger.Session("grace")
+	}
+}
+
+// NewClient returns a Client for the Grace reachable at baseURL, e.g.
+// http://some-route or https://10.0.0.1:61001.
+func NewClient(baseURL string, options ...Option) *Client {
+	client := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		attempts:   1,
+	}
+	for _, option := range options {
+		option(client)
+	}
+	return client
+}
+
+// ForRoute addresses Grace through the router at hostname.
+func ForRoute(hostname string, options ...Option) *Client {
+	return NewClient("http://"+hostname, options...)
+}
+
+func (c *Client) URL() string {
+	return c.baseURL
+}
+
+// Ready reports whether Grace is serving requests.
+func (c *Client) Ready() bool {
+	_, err := c.get("/env")
+	return err == nil
+}
+
+// Env returns the environment Grace was started with.
+func (c *Client) Env() (Env, error) {
+	body, err := c.get("/env?json=true")
+	if err != nil {
+		return nil, err
+	}
+	env := Env{}
+	err = json.Unmarshal(body, &env)
+	if err != nil {
+		return nil, fmt.Errorf("grace returned malformed env: %s", err.Error())
+	}
+	return env, nil
+}
+
+func (c *Client) Index() (int, error) {
+	return c.getInt("/index")
+}
+
+func (c *Client) Counter() (int, error) {
+	return c.getInt("/counter")
+}
+
+func (c *Client) IncrementCounter() error {
+	_, err := c.do(http.MethodPost, "/counter")
+	return err
+}
+
+// StartedAt returns when the Grace process started, in Unix nanoseconds.
+func (c *Client) StartedAt() (int64, error) {
+	body, err := c.get("/started-at")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(body)), 10, 64)
+}
+
+// Exit makes the Grace process exit with status.
+func (c *Client) Exit(status int) error {
+	_, err := c.do(http.MethodPost, fmt.Sprintf("/exit/%d", status))
+	return err
+}
+
+// DeleteFile removes filename from Grace's working directory, e.g. the file
+// named by -upFile that a monitor action checks for.
+func (c *Client) DeleteFile(filename string) error {
+	_, err := c.do(http.MethodDelete, "/file/"+url.PathEscape(filename))
+	return err
+}
+
+// Curl asks Grace to GET target from inside its container and returns the
+// status code Grace answered with: the target's status, or 500 if Grace could
+// not reach it.
+func (c *Client) Curl(target string) (int, error) {
+	resp, err := c.httpClient.Get(c.baseURL + "/curl?url=" + url.QueryEscape(target))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// Sleep makes a request that Grace holds open for duration.
+func (c *Client) Sleep(duration time.Duration) error {
+	_, err := c.get("/sleep/" + duration.String())
+	return err
+}
+
+// Stick sets a sticky session cookie so that the router keeps sending this
+// client's requests to the same instance; the client needs a cookie jar.
+func (c *Client) Stick() error {
+	_, err := c.get("/stick")
+	return err
+}
+
+func (c *Client) Unstick() error {
+	_, err := c.get("/unstick")
+	return err
+}
+
+func (c *Client) MountFuseFS() error {
+	_, err := c.do(http.MethodPost, "/fuse-fs/mount")
+	return err
+}
+
+// ListFuseFS lists the contents of the FUSE filesystem mounted by
+// MountFuseFS.
+func (c *Client) ListFuseFS() (string, error) {
+	body, err := c.get("/fuse-fs/ls")
+	return string(body), err
+}
+
+func (c *Client) getInt(path string) (int, error) {
+	body, err := c.get(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(body)))
+}
+
+func (c *Client) get(path string) ([]byte, error) {
+	return c.do(http.MethodGet, path)
+}
+
+func (c *Client) do(method, path string) ([]byte, error) {
+	var err error
+	for attempt := 1; attempt <= c.attempts; attempt++ {
+		var body []byte
+		body, err = c.doOnce(method, path)
+		if err == nil {
+			return body, nil
+		}
+		if c.logger != nil {
+			c.logger.Info("request-failed", lager.Data{
+				"url":      c.baseURL,
+				"method":   method,
+				"path":     path,
+				"attempt":  attempt,
+				"attempts": c.attempts,
+				"error":    err.Error(),
+			})
+		}
+		if attempt < c.attempts {
+			time.Sleep(c.retryInterval)
+		}
+	}
+	return nil, err
+}
+
+func (c *Client) doOnce(method, path string) ([]byte, error) {
+	req, err := http.NewRequest(method, c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, StatusError{Method: method, Path: path, StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return body, nil
+}
@@ -0,0 +1,162 @@
+package grace_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/vizzini/grace"
+	"code.cloudfoundry.org/vizzini/grace/fakegrace"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		server *fakegrace.Server
+		client *grace.Client
+	)
+
+	BeforeEach(func() {
+		server = fakegrace.NewServer(2, grace.Env{
+			{"PORT", "8080"},
+			{"OVERRIDE", "DAQUIRI"},
+		})
+		client = grace.NewClient(server.URL())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("reads the environment", func() {
+		Expect(client.Ready()).To(BeTrue())
+
+		env, err := client.Env()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(env).To(ContainElement([]string{"OVERRIDE", "DAQUIRI"}))
+
+		value, ok := env.Get("PORT")
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal("8080"))
+		_, ok = env.Get("MISSING")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("reads the index and start time", func() {
+		Expect(client.Index()).To(Equal(2))
+
+		startedAt, err := client.StartedAt()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(time.Unix(0, startedAt)).To(BeTemporally("~", time.Now(), time.Minute))
+	})
+
+	It("increments the counter", func() {
+		Expect(client.Counter()).To(Equal(0))
+		Expect(client.IncrementCounter()).To(Succeed())
+		Expect(client.IncrementCounter()).To(Succeed())
+		Expect(client.Counter()).To(Equal(2))
+	})
+
+	It("makes Grace exit", func() {
+		Expect(client.Exit(17)).To(Succeed())
+		status, exited := server.ExitStatus()
+		Expect(exited).To(BeTrue())
+		Expect(status).To(Equal(17))
+		Expect(client.Ready()).To(BeFalse())
+	})
+
+	It("deletes files", func() {
+		server.CreateFile("up")
+		Expect(client.DeleteFile("up")).To(Succeed())
+		Expect(server.HasFile("up")).To(BeFalse())
+	})
+
+	It("reports the status Grace got from curling a URL", func() {
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer target.Close()
+
+		Expect(client.Curl(target.URL)).To(Equal(http.StatusBadRequest))
+
+		target.Close()
+		Expect(client.Curl(target.URL)).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("holds sleep requests open", func() {
+		start := time.Now()
+		Expect(client.Sleep(50 * time.Millisecond)).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+	})
+
+	It("sets and clears the sticky session cookie", func() {
+		jar, err := cookiejar.New(nil)
+		Expect(err).NotTo(HaveOccurred())
+		client = grace.NewClient(server.URL(), grace.WithHTTPClient(&http.Client{Jar: jar}))
+
+		Expect(client.Stick()).To(Succeed())
+		Expect(jar.Cookies(mustParse(server.URL()))).To(ContainElement(HaveField("Name", fakegrace.StickyCookie)))
+		Expect(client.Unstick()).To(Succeed())
+		Expect(jar.Cookies(mustParse(server.URL()))).To(BeEmpty())
+	})
+
+	It("mounts and lists the FUSE filesystem", func() {
+		_, err := client.ListFuseFS()
+		Expect(err).To(HaveOccurred())
+
+		Expect(client.MountFuseFS()).To(Succeed())
+		Expect(client.ListFuseFS()).To(ContainSubstring("fuse-fs-works.txt"))
+	})
+
+	Describe("errors", func() {
+		It("returns a StatusError for non-200 responses", func() {
+			server.FailNextRequests(1)
+
+			_, err := client.Index()
+			var statusError grace.StatusError
+			Expect(errors.As(err, &statusError)).To(BeTrue())
+			Expect(statusError.StatusCode).To(Equal(http.StatusServiceUnavailable))
+			Expect(statusError.Path).To(Equal("/index"))
+		})
+
+		It("retries when asked to", func() {
+			server.FailNextRequests(2)
+			client = grace.NewClient(server.URL(), grace.WithRetries(3, time.Millisecond))
+			Expect(client.Index()).To(Equal(2))
+
+			server.FailNextRequests(3)
+			_, err := client.Index()
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("logs every attempt that fails", func() {
+			logger := lagertest.NewTestLogger("grace")
+			server.FailNextRequests(2)
+			client = grace.NewClient(server.URL(), grace.WithRetries(3, time.Millisecond), grace.WithLogger(logger))
+			Expect(client.Index()).To(Equal(2))
+
+			failures := logger.LogMessages()
+			Expect(failures).To(Equal([]string{"grace.grace.request-failed", "grace.grace.request-failed"}))
+			Expect(logger.Logs()[1].Data).To(HaveKeyWithValue("attempt", BeEquivalentTo(2)))
+		})
+
+		It("fails when Grace cannot be reached", func() {
+			server.Close()
+			_, err := client.Env()
+			Expect(err).To(HaveOccurred())
+			Expect(client.Ready()).To(BeFalse())
+		})
+	})
+})
+
+func mustParse(rawURL string) *url.URL {
+	parsed, err := url.Parse(rawURL)
+	Expect(err).NotTo(HaveOccurred())
+	return parsed
+}
@@ -0,0 +1 @@
+package fakegrace // import "code.cloudfoundry.org/vizzini/grace/fakegrace"
@@ -0,0 +1,41 @@
+package fakegrace
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// RouterTransport sends every request to one Server, the way the router
+// would, and remembers which hosts were asked for.
+type RouterTransport struct {
+	grace *url.URL
+
+	lock  sync.Mutex
+	hosts []string
+}
+
+// RouterTransport returns a transport that routes every request to s.
+func (s *Server) RouterTransport() *RouterTransport {
+	// httptest servers always have a valid URL
+	graceURL, _ := url.Parse(s.URL())
+	return &RouterTransport{grace: graceURL}
+}
+
+func (t *RouterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.lock.Lock()
+	t.hosts = append(t.hosts, req.URL.Host)
+	t.lock.Unlock()
+
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.grace.Scheme
+	req.URL.Host = t.grace.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// Hosts lists the host of every request, in order.
+func (t *RouterTransport) Hosts() []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return append([]string{}, t.hosts...)
+}
@@ -0,0 +1,197 @@
+package fakegrace
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/vizzini/grace"
+)
+
+const StickyCookie = "JSESSIONID"
+
+// Server is an in-process stand-in for the Grace test app that serves the
+// same endpoints and records what was asked of it.
+type Server struct {
+	httpServer *httptest.Server
+
+	lock        sync.Mutex
+	index       int
+	env         grace.Env
+	counter     int
+	startedAt   time.Time
+	exitStatus  *int
+	files       map[string]bool
+	fuseMounted bool
+	failures    int
+}
+
+func NewServer(index int, env grace.Env) *Server {
+	server := &Server{
+		index:     index,
+		env:       env,
+		startedAt: time.Now(),
+		files:     map[string]bool{},
+	}
+	server.httpServer = httptest.NewServer(http.HandlerFunc(server.serveHTTP))
+	return server
+}
+
+func (s *Server) URL() string {
+	return s.httpServer.URL
+}
+
+func (s *Server) Close() {
+	s.httpServer.Close()
+}
+
+// FailNextRequests makes the next n requests get a 503, like a router with
+// no registered backend.
+func (s *Server) FailNextRequests(n int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failures = n
+}
+
+// ExitStatus returns the status Grace was asked to exit with, if any.  Until
+// Restart is called, an exited Server answers every request with a 503.
+func (s *Server) ExitStatus() (int, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.exitStatus == nil {
+		return 0, false
+	}
+	return *s.exitStatus, true
+}
+
+func (s *Server) Restart() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.exitStatus = nil
+	s.startedAt = time.Now()
+}
+
+func (s *Server) CreateFile(filename string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.files[filename] = true
+}
+
+func (s *Server) HasFile(filename string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.files[filename]
+}
+
+func (s *Server) FuseMounted() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.fuseMounted
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	s.lock.Lock()
+	if s.failures > 0 || s.exitStatus != nil {
+		if s.failures > 0 {
+			s.failures--
+		}
+		s.lock.Unlock()
+		http.Error(w, "no backend", http.StatusServiceUnavailable)
+		return
+	}
+	s.lock.Unlock()
+
+	path := req.URL.Path
+	switch {
+	case path == "/env":
+		s.handleEnv(w, req)
+	case path == "/index":
+		s.withLock(func() { fmt.Fprintf(w, "%d", s.index) })
+	case path == "/counter":
+		s.handleCounter(w, req)
+	case path == "/started-at":
+		s.withLock(func() { fmt.Fprintf(w, "%d", s.startedAt.UnixNano()) })
+	case strings.HasPrefix(path, "/exit/") && req.Method == http.MethodPost:
+		status, err := strconv.Atoi(strings.TrimPrefix(path, "/exit/"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.withLock(func() { s.exitStatus = &status })
+	case strings.HasPrefix(path, "/file/") && req.Method == http.MethodDelete:
+		filename := strings.TrimPrefix(path, "/file/")
+		s.withLock(func() { delete(s.files, filename) })
+	case path == "/curl":
+		s.handleCurl(w, req)
+	case strings.HasPrefix(path, "/sleep/"):
+		duration, err := time.ParseDuration(strings.TrimPrefix(path, "/sleep/"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		time.Sleep(duration)
+	case path == "/stick":
+		http.SetCookie(w, &http.Cookie{Name: StickyCookie, Value: strconv.Itoa(s.index)})
+	case path == "/unstick":
+		http.SetCookie(w, &http.Cookie{Name: StickyCookie, Value: "", MaxAge: -1})
+	case path == "/fuse-fs/mount" && req.Method == http.MethodPost:
+		s.withLock(func() { s.fuseMounted = true })
+	case path == "/fuse-fs/ls":
+		s.withLock(func() {
+			if !s.fuseMounted {
+				http.Error(w, "fuse-fs is not mounted", http.StatusInternalServerError)
+				return
+			}
+			fmt.Fprintln(w, "fuse-fs-works.txt")
+		})
+	case path == "/":
+		fmt.Fprint(w, "Hello from Grace")
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (s *Server) withLock(f func()) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	f()
+}
+
+func (s *Server) handleEnv(w http.ResponseWriter, req *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if req.URL.Query().Get("json") == "true" {
+		json.NewEncoder(w).Encode(s.env)
+		return
+	}
+	for _, pair := range s.env {
+		fmt.Fprintln(w, strings.Join(pair, "="))
+	}
+}
+
+func (s *Server) handleCounter(w http.ResponseWriter, req *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if req.Method == http.MethodPost {
+		s.counter++
+		return
+	}
+	fmt.Fprintf(w, "%d", s.counter)
+}
+
+func (s *Server) handleCurl(w http.ResponseWriter, req *http.Request) {
+	resp, err := http.Get(req.URL.Query().Get("url"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp.Body.Close()
+	w.WriteHeader(resp.StatusCode)
+}
@@ -0,0 +1,13 @@
+package grace_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestGrace(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Grace Suite")
+}
@@ -0,0 +1 @@
+package grace // import "code.cloudfoundry.org/vizzini/grace"
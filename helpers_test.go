@@ -3,13 +3,17 @@ package vizzini_test
 import (
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strconv"
 	"time"
 
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/lager/v3"
-	"code.cloudfoundry.org/routing-info/cfroutes"
+	"code.cloudfoundry.org/vizzini/eventstream"
+	"code.cloudfoundry.org/vizzini/fixtures"
+	"code.cloudfoundry.org/vizzini/grace"
+	"code.cloudfoundry.org/vizzini/tracing"
 
 	. "code.cloudfoundry.org/vizzini/matchers"
 	"github.com/onsi/ginkgo/v2"
@@ -20,6 +24,54 @@ const HealthyCheckInterval = 30 * time.Second
 const ConvergerInterval = 30 * time.Second
 const CrashRestartTimeout = 30 * time.Second
 
+//Waiting
+
+// The Wait helpers follow the BBS event streams rather than polling it, and
+// only fall back to polling if a stream fails.  On timeout they fail the spec
+// with every event they received for the GUID.
+
+func waitOptions(optionalTimeout ...time.Duration) eventstream.WaitOptions {
+	options := eventstream.WaitOptions{Timeout: timeout, PollInterval: 500 * time.Millisecond}
+	if len(optionalTimeout) == 1 {
+		options.Timeout = optionalTimeout[0]
+	}
+	return options
+}
+
+func WaitForTaskState(guid string, state models.Task_State, optionalTimeout ...time.Duration) {
+	end := tracer.Span(traceID, "wait for task state", tracing.GuidKey.String(guid), tracing.StateKey.String(state.String()))
+	err := eventstream.WaitForTaskState(logger, bbsClient, traceID, guid, state, waitOptions(optionalTimeout...))
+	end(err)
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+}
+
+func WaitForActualLRPState(guid string, index int, state string, optionalTimeout ...time.Duration) {
+	end := tracer.Span(traceID, "wait for actual lrp state", tracing.GuidKey.String(guid), tracing.IndexKey.Int(index), tracing.StateKey.String(state))
+	err := eventstream.WaitForActualLRPState(logger, bbsClient, traceID, guid, int32(index), state, waitOptions(optionalTimeout...))
+	end(err)
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+}
+
+// WaitForActualLRP waits until the ActualLRP the matcher is for matches, e.g.
+//
+//	WaitForActualLRP(MatchActualLRP(guid, 0).WithState(models.ActualLRPStateCrashed).WithCrashCount(3))
+func WaitForActualLRP(matcher *ActualLRPMatcher, optionalTimeout ...time.Duration) {
+	end := tracer.Span(traceID, "wait for actual lrp", tracing.GuidKey.String(matcher.ProcessGuid), tracing.IndexKey.Int(matcher.Index))
+	err := eventstream.WaitForActualLRP(logger, bbsClient, traceID, matcher.ProcessGuid, int32(matcher.Index), matcher.String(), func(lrp *models.ActualLRP) bool {
+		matches, _ := matcher.Match(lrp)
+		return matches
+	}, waitOptions(optionalTimeout...))
+	end(err)
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+}
+
+func WaitForDesiredLRPRemoved(guid string, optionalTimeout ...time.Duration) {
+	end := tracer.Span(traceID, "wait for desired lrp removal", tracing.GuidKey.String(guid))
+	err := eventstream.WaitForDesiredLRPRemoved(logger, bbsClient, traceID, guid, waitOptions(optionalTimeout...))
+	end(err)
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+}
+
 //Tasks
 
 func TaskGetter(logger lager.Logger, guid string) func() (*models.Task, error) {
@@ -40,7 +92,7 @@ func ClearOutTasksInDomain(domain string) {
 	for _, task := range tasks {
 		if task.State != models.Task_Completed {
 			bbsClient.CancelTask(logger, traceID, task.TaskGuid)
-			Eventually(TaskGetter(logger, task.TaskGuid)).Should(HaveTaskState(models.Task_Completed))
+			WaitForTaskState(task.TaskGuid, models.Task_Completed)
 		}
 		Expect(bbsClient.ResolvingTask(logger, traceID, task.TaskGuid)).To(Succeed())
 		Expect(bbsClient.DeleteTask(logger, traceID, task.TaskGuid)).To(Succeed())
@@ -48,23 +100,12 @@ func ClearOutTasksInDomain(domain string) {
 	Eventually(TasksByDomainGetter(logger, domain)).Should(BeEmpty())
 }
 
-func Task() *models.TaskDefinition {
-	return &models.TaskDefinition{
-		Action: models.WrapAction(&models.RunAction{
-			Path: "bash",
-			Args: []string{"-c", "echo 'some output' > /tmp/bar"},
-			User: "vcap",
-		}),
-		RootFs:        config.DefaultRootFS,
-		MemoryMb:      128,
-		DiskMb:        256,
-		CpuWeight:     100,
-		LogGuid:       guid,
-		LogSource:     "VIZ",
-		ResultFile:    "/tmp/bar",
-		Annotation:    "arbitrary-data",
-		PlacementTags: PlacementTags(),
-	}
+// Task returns the default task fixture for the spec's guid; it fails the
+// spec if options leave it invalid.
+func Task(options ...fixtures.TaskOption) *models.TaskDefinition {
+	task, err := Fixtures().Task(guid, options...)
+	Expect(err).NotTo(HaveOccurred())
+	return task
 }
 
 //LRPs
@@ -138,7 +179,9 @@ func ClearOutDesiredLRPsInDomain(domain string) {
 	for _, lrp := range lrps {
 		Expect(bbsClient.RemoveDesiredLRP(logger, traceID, lrp.ProcessGuid)).To(Succeed())
 	}
-	// Wait enough time for the Grace app to exit if it was run with -catchTerminate
+	// Wait enough time for the Grace app to exit if it was run with -catchTerminate.
+	// Waiting on the whole domain also covers ActualLRPs whose DesiredLRP is
+	// already gone, e.g. ones still stopping after an earlier spec.
 	Eventually(ActualByDomainGetter(logger, domain), timeout+8*time.Second).Should(BeEmpty())
 }
 
@@ -169,11 +212,25 @@ func IndexCounter(guid string, optionalHttpClient ...*http.Client) func() int {
 }
 
 func IndexCounterWithAttempts(guid string, attempts int, optionalHttpClient ...*http.Client) func() int {
+	options := []grace.Option{}
+	if len(optionalHttpClient) == 1 {
+		options = append(options, grace.WithHTTPClient(optionalHttpClient[0]))
+	}
+	return indexCounter(GraceClient(guid, options...), attempts)
+}
+
+// TCPIndexCounter counts the distinct instances that answer on externalPort
+// of the TCP router.
+func TCPIndexCounter(externalPort uint32, attempts int) func() int {
+	return indexCounter(TCPGraceClient(externalPort), attempts)
+}
+
+func indexCounter(graceClient *grace.Client, attempts int) func() int {
 	return func() int {
 		counts := map[int]bool{}
 		for i := 0; i < attempts; i++ {
-			index := GetIndexFromEndpointFor(guid, optionalHttpClient...)
-			if index == -1 {
+			index, err := graceClient.Index()
+			if err != nil {
 				continue
 			}
 			counts[index] = true
@@ -182,66 +239,47 @@ func IndexCounterWithAttempts(guid string, attempts int, optionalHttpClient ...*
 	}
 }
 
-func GetIndexFromEndpointFor(guid string, optionalHttpClient ...*http.Client) int {
-	httpClient := http.DefaultClient
-	if len(optionalHttpClient) == 1 {
-		httpClient = optionalHttpClient[0]
-	}
-	url := "http://" + RouteForGuid(guid) + "/index"
-	resp, err := httpClient.Get(url)
-	if err != nil {
-		return -1
-	}
-	if resp.StatusCode != http.StatusOK {
-		resp.Body.Close()
-		return -1
-	}
-	content, err := io.ReadAll(resp.Body)
-	resp.Body.Close()
-	Expect(err).NotTo(HaveOccurred())
-
-	index, err := strconv.Atoi(string(content))
-	Expect(err).NotTo(HaveOccurred())
-	return index
+// GraceClient talks to the Grace instances of the LRP with the given guid
+// through its route.  Failed requests are logged to the spec's log.
+func GraceClient(guid string, options ...grace.Option) *grace.Client {
+	options = append([]grace.Option{grace.WithLogger(logger)}, options...)
+	return grace.ForRoute(RouteForGuid(guid), options...)
 }
 
-func GraceCounterGetter(guid string) func() (int, error) {
-	return func() (int, error) {
-		resp, err := http.Get("http://" + RouteForGuid(guid) + "/counter")
-		if err != nil {
-			return 0, err
-		}
-		defer resp.Body.Close()
-		content, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return 0, err
-		}
-		return strconv.Atoi(string(content))
+// TCPGraceClient talks to the Grace instances the TCP router forwards
+// externalPort to.  Connections are not reused, so that each request may
+// reach a different instance.
+func TCPGraceClient(externalPort uint32, options ...grace.Option) *grace.Client {
+	httpClient := &http.Client{
+		Transport: &http.Transport{DisableKeepAlives: true},
+		Timeout:   5 * time.Second,
 	}
+	options = append([]grace.Option{grace.WithLogger(logger), grace.WithHTTPClient(httpClient)}, options...)
+	return grace.NewClient("http://"+TCPRouteAddress(externalPort), options...)
 }
 
-func StartedAtGetter(guid string) func() (int64, error) {
-	url := "http://" + RouteForGuid(guid) + "/started-at"
-	return func() (int64, error) {
-		resp, err := http.Get(url)
-		if err != nil {
-			return 0, err
-		}
-		if resp.StatusCode != http.StatusOK {
-			resp.Body.Close()
-			return 0, fmt.Errorf("invalid status code: %d", resp.StatusCode)
-		}
-		content, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			return 0, err
-		}
-		return strconv.ParseInt(string(content), 10, 64)
-	}
+func TCPRouteAddress(externalPort uint32) string {
+	return net.JoinHostPort(config.TCPRouterAddress, strconv.Itoa(int(externalPort)))
+}
+
+// TCPExternalPorts are the two external ports this parallel process may
+// register with the TCP router, so that parallel specs never share one.
+func TCPExternalPorts() (uint32, uint32) {
+	first := uint32(config.TCPExternalPort + 2*(ginkgo.GinkgoParallelProcess()-1))
+	return first, first + 1
 }
 
 func RouteForGuid(guid string) string {
-	return fmt.Sprintf("%s.%s", guid, config.RoutableDomainSuffix)
+	return Fixtures().RouteFor(guid, fixtures.GracePort)
+}
+
+// OverlayURLFor addresses the given instance of the LRP directly over the
+// container network, e.g. for another container to Curl.
+func OverlayURLFor(guid string, index int, containerPort uint32) string {
+	actualLRP, err := ActualGetter(logger, guid, index)()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(actualLRP.InstanceAddress).NotTo(BeEmpty(), "ActualLRP %d with ProcessGuid %s has no instance address", index, guid)
+	return "http://" + net.JoinHostPort(actualLRP.InstanceAddress, strconv.Itoa(int(containerPort)))
 }
 
 func TLSDirectAddressFor(guid string, index int, containerPort uint32) string {
@@ -259,49 +297,18 @@ func TLSDirectAddressFor(guid string, index int, containerPort uint32) string {
 	return ""
 }
 
-func DesiredLRPWithGuid(guid string) *models.DesiredLRP {
-	routingInfo := cfroutes.CFRoutes{
-		{Port: 8080, Hostnames: []string{RouteForGuid(guid)}},
-	}.RoutingInfo()
-
-	return &models.DesiredLRP{
-		ProcessGuid:   guid,
-		PlacementTags: PlacementTags(),
-		Domain:        domain,
-		Instances:     1,
-		CachedDependencies: []*models.CachedDependency{
-			&models.CachedDependency{
-				From:              config.GraceTarballURL,
-				To:                "/tmp/grace",
-				CacheKey:          "grace",
-				ChecksumAlgorithm: "sha1",
-				ChecksumValue:     config.GraceTarballChecksum,
-			},
-		},
-		Action: models.WrapAction(&models.RunAction{
-			Path: "/tmp/grace/grace",
-			User: "vcap",
-			Env: []*models.EnvironmentVariable{
-				{Name: "PORT", Value: "8080"},
-				{Name: "ACTION_LEVEL", Value: "COYOTE"},
-				{Name: "OVERRIDE", Value: "DAQUIRI"}},
-		}),
-		Monitor: models.WrapAction(&models.RunAction{
-			Path: "nc",
-			Args: []string{"-z", "0.0.0.0", "8080"},
-			User: "vcap",
-		}),
-		RootFs:     config.DefaultRootFS,
-		MemoryMb:   128,
-		DiskMb:     256,
-		CpuWeight:  100,
-		Ports:      []uint32{8080},
-		Routes:     &routingInfo,
-		LogGuid:    guid,
-		LogSource:  "VIZ",
-		MetricTags: map[string]*models.MetricTagValue{"source_id": {Static: guid}},
-		Annotation: "arbitrary-data",
-	}
+// DesiredLRPWithGuid returns the default Grace LRP fixture; it fails the spec
+// if options leave it invalid.
+func DesiredLRPWithGuid(guid string, options ...fixtures.LRPOption) *models.DesiredLRP {
+	lrp, err := Fixtures().DesiredLRP(guid, options...)
+	Expect(err).NotTo(HaveOccurred())
+	return lrp
+}
+
+// Fixtures builds DesiredLRPs and Tasks in the spec's domain for the
+// configured deployment.
+func Fixtures() fixtures.Defaults {
+	return config.Fixtures(domain)
 }
 
 func PlacementTags() []string {
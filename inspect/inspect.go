@@ -0,0 +1,314 @@
+package inspect
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/routing-info/cfroutes"
+	"code.cloudfoundry.org/routing-info/tcp_routes"
+	"code.cloudfoundry.org/vizzini/fixtures"
+	"code.cloudfoundry.org/vizzini/report"
+)
+
+type Kind string
+
+const (
+	TaskGuid    Kind = "task"
+	ProcessGuid Kind = "process"
+	Domain      Kind = "domain"
+)
+
+// Report is everything the BBS knows about a task GUID, process GUID or
+// domain.
+type Report struct {
+	Query string `json:"query"`
+
+	// Kinds says what Query turned out to name; it is empty when the BBS
+	// knows nothing about it.
+	Kinds []Kind `json:"kinds"`
+
+	Tasks       []*models.Task      `json:"tasks"`
+	DesiredLRPs []DesiredLRP        `json:"desired_lrps"`
+	ActualLRPs  []*models.ActualLRP `json:"actual_lrps"`
+
+	// Cells are the cells the tasks and ActualLRPs were placed on.  When
+	// anything failed placement, every cell is included, since any of them
+	// could have been chosen.
+	Cells []*models.CellPresence `json:"cells"`
+
+	// Errors records BBS requests that failed while inspecting.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// DesiredLRP is a DesiredLRP with its routes decoded.
+type DesiredLRP struct {
+	*models.DesiredLRP
+	HTTPRoutes cfroutes.CFRoutes    `json:"http_routes"`
+	TCPRoutes  tcp_routes.TCPRoutes `json:"tcp_routes,omitempty"`
+
+	// OtherRoutes names the routing info keys besides cf-router and
+	// tcp-router, e.g. diego-ssh.
+	OtherRoutes []string `json:"other_routes,omitempty"`
+}
+
+func (r Report) Found() bool {
+	return len(r.Kinds) > 0
+}
+
+// Inspect looks query up as a task GUID, a process GUID and a domain, and
+// gathers whatever matches.
+func Inspect(logger lager.Logger, client bbs.InternalClient, traceID, query string) Report {
+	logger = logger.Session("inspect", lager.Data{"query": query})
+	r := Report{
+		Query:       query,
+		Kinds:       []Kind{},
+		Tasks:       []*models.Task{},
+		DesiredLRPs: []DesiredLRP{},
+		ActualLRPs:  []*models.ActualLRP{},
+		Cells:       []*models.CellPresence{},
+	}
+	addError := func(what string, err error) bool {
+		if err == nil {
+			return true
+		}
+		if models.ConvertError(err).Type != models.Error_ResourceNotFound {
+			r.Errors = append(r.Errors, what+": "+err.Error())
+		}
+		return false
+	}
+
+	task, err := client.TaskByGuid(logger, traceID, query)
+	if addError("task", err) {
+		r.Kinds = append(r.Kinds, TaskGuid)
+		r.Tasks = append(r.Tasks, task)
+	}
+
+	desiredLRP, err := client.DesiredLRPByProcessGuid(logger, traceID, query)
+	if addError("desired LRP", err) {
+		r.DesiredLRPs = append(r.DesiredLRPs, decodeRoutes(desiredLRP))
+	}
+	// ActualLRPs outlive their DesiredLRP while they are being stopped
+	actualLRPs, err := client.ActualLRPs(logger, traceID, models.ActualLRPFilter{ProcessGuid: query})
+	addError("actual LRPs", err)
+	r.ActualLRPs = append(r.ActualLRPs, actualLRPs...)
+	if desiredLRP != nil || len(actualLRPs) > 0 {
+		r.Kinds = append(r.Kinds, ProcessGuid)
+	}
+
+	snapshot := report.TakeSnapshot(logger, client, traceID, query)
+	r.Errors = append(r.Errors, snapshot.Errors...)
+	if len(snapshot.Tasks)+len(snapshot.DesiredLRPs)+len(snapshot.ActualLRPs) > 0 {
+		r.Kinds = append(r.Kinds, Domain)
+		r.Tasks = append(r.Tasks, snapshot.Tasks...)
+		for _, lrp := range snapshot.DesiredLRPs {
+			r.DesiredLRPs = append(r.DesiredLRPs, decodeRoutes(lrp))
+		}
+		r.ActualLRPs = append(r.ActualLRPs, snapshot.ActualLRPs...)
+	}
+
+	sort.Slice(r.Tasks, func(i, j int) bool { return r.Tasks[i].TaskGuid < r.Tasks[j].TaskGuid })
+	sort.Slice(r.DesiredLRPs, func(i, j int) bool { return r.DesiredLRPs[i].ProcessGuid < r.DesiredLRPs[j].ProcessGuid })
+	sort.Slice(r.ActualLRPs, func(i, j int) bool {
+		if r.ActualLRPs[i].ProcessGuid == r.ActualLRPs[j].ProcessGuid {
+			return r.ActualLRPs[i].Index < r.ActualLRPs[j].Index
+		}
+		return r.ActualLRPs[i].ProcessGuid < r.ActualLRPs[j].ProcessGuid
+	})
+
+	if r.Found() {
+		cells, err := client.Cells(logger, traceID)
+		addError("cells", err)
+		r.Cells = r.placedOn(cells)
+	}
+	return r
+}
+
+func decodeRoutes(lrp *models.DesiredLRP) DesiredLRP {
+	decoded := DesiredLRP{DesiredLRP: lrp, HTTPRoutes: cfroutes.CFRoutes{}}
+	if lrp.Routes == nil {
+		return decoded
+	}
+	for key := range *lrp.Routes {
+		if key != cfroutes.CF_ROUTER && key != tcp_routes.TCP_ROUTER {
+			decoded.OtherRoutes = append(decoded.OtherRoutes, key)
+		}
+	}
+	sort.Strings(decoded.OtherRoutes)
+
+	httpRoutes, err := cfroutes.CFRoutesFromRoutingInfo(*lrp.Routes)
+	if err == nil {
+		decoded.HTTPRoutes = httpRoutes
+	}
+	tcpRoutes, err := fixtures.TCPRoutes(lrp.Routes)
+	if err == nil {
+		decoded.TCPRoutes = tcpRoutes
+	}
+	return decoded
+}
+
+func (r Report) placedOn(cells []*models.CellPresence) []*models.CellPresence {
+	cellIDs := map[string]bool{}
+	unplaced := false
+	for _, task := range r.Tasks {
+		if task.CellId != "" {
+			cellIDs[task.CellId] = true
+		}
+		unplaced = unplaced || strings.Contains(task.FailureReason, "found no compatible cell") || strings.Contains(task.FailureReason, "insufficient resources")
+	}
+	for _, lrp := range r.ActualLRPs {
+		if lrp.CellId != "" {
+			cellIDs[lrp.CellId] = true
+		}
+		unplaced = unplaced || lrp.PlacementError != ""
+	}
+
+	placed := []*models.CellPresence{}
+	for _, cell := range cells {
+		if unplaced || cellIDs[cell.CellId] {
+			placed = append(placed, cell)
+		}
+	}
+	sort.Slice(placed, func(i, j int) bool { return placed[i].CellId < placed[j].CellId })
+	return placed
+}
+
+func (r Report) String() string {
+	if !r.Found() {
+		lines := []string{fmt.Sprintf("the BBS knows nothing about %q", r.Query)}
+		for _, err := range r.Errors {
+			lines = append(lines, "error fetching "+err)
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	b := &strings.Builder{}
+	kinds := make([]string, len(r.Kinds))
+	for i, kind := range r.Kinds {
+		kinds[i] = string(kind)
+	}
+	fmt.Fprintf(b, "%s (%s)\n", r.Query, strings.Join(kinds, ", "))
+
+	if len(r.Tasks) > 0 {
+		fmt.Fprintln(b, "\nTasks")
+		w := tabwriter.NewWriter(b, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "  GUID\tDOMAIN\tSTATE\tCELL\tROOTFS\tMEMORY MB\tDISK MB\tFAILED\tFAILURE REASON")
+		for _, task := range r.Tasks {
+			definition := task.TaskDefinition
+			if definition == nil {
+				definition = &models.TaskDefinition{}
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%d\t%d\t%t\t%s\n", task.TaskGuid, task.Domain, task.State, dash(task.CellId),
+				definition.RootFs, definition.MemoryMb, definition.DiskMb, task.Failed, dash(task.FailureReason))
+		}
+		w.Flush()
+	}
+
+	if len(r.DesiredLRPs) > 0 {
+		fmt.Fprintln(b, "\nDesired LRPs")
+		w := tabwriter.NewWriter(b, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "  PROCESS GUID\tDOMAIN\tINSTANCES\tROOTFS\tMEMORY MB\tDISK MB\tPLACEMENT TAGS\tPORTS\tROUTES")
+		for _, lrp := range r.DesiredLRPs {
+			fmt.Fprintf(w, "  %s\t%s\t%d\t%s\t%d\t%d\t%s\t%s\t%s\n", lrp.ProcessGuid, lrp.Domain, lrp.Instances, lrp.RootFs,
+				lrp.MemoryMb, lrp.DiskMb, dash(strings.Join(lrp.PlacementTags, ",")), dash(joinPorts(lrp.Ports)), dash(lrp.routes()))
+		}
+		w.Flush()
+	}
+
+	if len(r.ActualLRPs) > 0 {
+		fmt.Fprintln(b, "\nActual LRPs")
+		w := tabwriter.NewWriter(b, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "  PROCESS GUID\tINDEX\tSTATE\tPRESENCE\tCELL\tADDRESS\tROUTABLE\tCRASHES\tSINCE\tREASON")
+		for _, lrp := range r.ActualLRPs {
+			routable := "-"
+			if lrp.RoutableExists() {
+				routable = fmt.Sprint(lrp.GetRoutable())
+			}
+			fmt.Fprintf(w, "  %s\t%d\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n", lrp.ProcessGuid, lrp.Index, lrp.State, lrp.Presence,
+				dash(lrp.CellId), dash(lrp.InstanceAddress), routable, lrp.CrashCount,
+				time.Unix(0, lrp.Since).UTC().Format(time.RFC3339), dash(reason(lrp)))
+		}
+		w.Flush()
+	}
+
+	if len(r.Cells) > 0 {
+		fmt.Fprintln(b, "\nCells")
+		w := tabwriter.NewWriter(b, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "  CELL\tZONE\tMEMORY MB\tDISK MB\tCONTAINERS\tROOTFS\tPLACEMENT TAGS")
+		for _, cell := range r.Cells {
+			capacity := cell.Capacity
+			if capacity == nil {
+				capacity = &models.CellCapacity{}
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%d\t%d\t%d\t%s\t%s\n", cell.CellId, dash(cell.Zone), capacity.MemoryMb, capacity.DiskMb,
+				capacity.Containers, RootFSProviders(cell.RootfsProviders), dash(strings.Join(cell.PlacementTags, ",")))
+		}
+		w.Flush()
+	}
+
+	for i, err := range r.Errors {
+		if i == 0 {
+			fmt.Fprintln(b)
+		}
+		fmt.Fprintln(b, "error fetching "+err)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (d DesiredLRP) routes() string {
+	routes := []string{}
+	for _, route := range d.HTTPRoutes {
+		routes = append(routes, fmt.Sprintf("%s->%d", strings.Join(route.Hostnames, ","), route.Port))
+	}
+	for _, route := range d.TCPRoutes {
+		routes = append(routes, fmt.Sprintf("tcp:%d->%d", route.ExternalPort, route.ContainerPort))
+	}
+	return strings.Join(append(routes, d.OtherRoutes...), " ")
+}
+
+// reason explains why an ActualLRP is not running: its placement error or
+// why it last crashed.
+func reason(lrp *models.ActualLRP) string {
+	if lrp.PlacementError != "" {
+		return "placement: " + lrp.PlacementError
+	}
+	if lrp.CrashReason != "" {
+		return "crash: " + lrp.CrashReason
+	}
+	return ""
+}
+
+// RootFSProviders lists providers the way rootfs URLs name them, e.g.
+// preloaded:cflinuxfs4,docker.
+func RootFSProviders(providers []*models.Provider) string {
+	names := []string{}
+	for _, provider := range providers {
+		if len(provider.Properties) == 0 {
+			names = append(names, provider.Name)
+		}
+		for _, property := range provider.Properties {
+			names = append(names, provider.Name+":"+property)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func joinPorts(ports []uint32) string {
+	joined := make([]string, len(ports))
+	for i, port := range ports {
+		joined[i] = fmt.Sprint(port)
+	}
+	return strings.Join(joined, ",")
+}
+
+func dash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
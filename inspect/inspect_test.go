@@ -0,0 +1,132 @@
+package inspect_test
+
+import (
+	"encoding/json"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/vizzini/fakebbs"
+	"code.cloudfoundry.org/vizzini/fixtures"
+	"code.cloudfoundry.org/vizzini/inspect"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Inspect", func() {
+	var (
+		server   *fakebbs.Server
+		client   bbs.InternalClient
+		logger   *lagertest.TestLogger
+		defaults fixtures.Defaults
+	)
+
+	BeforeEach(func() {
+		var err error
+		logger = lagertest.NewTestLogger("inspect")
+		server = fakebbs.NewServerWithStepInterval(10 * time.Millisecond)
+		server.Start()
+		client, err = server.Client()
+		Expect(err).NotTo(HaveOccurred())
+
+		defaults = fixtures.Defaults{
+			Domain:               "vizzini-inspect",
+			RootFS:               models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot),
+			GraceTarballURL:      "http://example.com/grace.tgz",
+			RoutableDomainSuffix: "example.com",
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	desireLRP := func(processGuid string, options ...fixtures.LRPOption) {
+		lrp, err := defaults.DesiredLRP(processGuid, options...)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.DesireLRP(logger, "trace-id", lrp)).To(Succeed())
+	}
+
+	It("shows a process GUID's DesiredLRP, routes, ActualLRPs and cells", func() {
+		desireLRP("some-lrp", fixtures.WithInstances(2), fixtures.WithSSH("http://example.com/lifecycle.tgz"), fixtures.WithTCPRoute("default-tcp", 61000, 8080))
+		Eventually(func() []*models.ActualLRP {
+			return inspect.Inspect(logger, client, "trace-id", "some-lrp").ActualLRPs
+		}).Should(HaveEach(HaveField("State", models.ActualLRPStateRunning)))
+		Expect(server.CrashActualLRP("some-lrp", 1, "Exited with status 3")).To(Succeed())
+
+		report := inspect.Inspect(logger, client, "trace-id", "some-lrp")
+		Expect(report.Found()).To(BeTrue())
+		Expect(report.Kinds).To(Equal([]inspect.Kind{inspect.ProcessGuid}))
+		Expect(report.Tasks).To(BeEmpty())
+		Expect(report.DesiredLRPs).To(HaveLen(1))
+		Expect(report.DesiredLRPs[0].HTTPRoutes[0].Hostnames).To(ConsistOf("some-lrp.example.com"))
+		Expect(report.DesiredLRPs[0].TCPRoutes).To(HaveLen(1))
+		Expect(report.DesiredLRPs[0].OtherRoutes).To(ConsistOf("diego-ssh"))
+		Expect(report.ActualLRPs).To(HaveLen(2))
+		Expect(report.Cells).To(ConsistOf(HaveField("CellId", fakebbs.FakeCellID)))
+		Expect(report.Errors).To(BeEmpty())
+
+		output := report.String()
+		Expect(output).To(HavePrefix("some-lrp (process)"))
+		Expect(output).To(ContainSubstring("some-lrp.example.com->8080 tcp:61000->8080 diego-ssh"))
+		Expect(output).To(ContainSubstring("crash: Exited with status 3"))
+		Expect(output).To(ContainSubstring("preloaded:cflinuxfs4,docker"))
+
+		encoded, err := json.Marshal(report)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(encoded)).To(ContainSubstring(`"process_guid":"some-lrp"`))
+		Expect(string(encoded)).To(ContainSubstring(`"http_routes":[{"hostnames":["some-lrp.example.com"],"port":8080`))
+	})
+
+	It("shows a task GUID's task and the cell it ran on", func() {
+		task, err := defaults.Task("some-task")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.DesireTask(logger, "trace-id", "some-task", defaults.Domain, task)).To(Succeed())
+		Eventually(func() []*models.Task {
+			return inspect.Inspect(logger, client, "trace-id", "some-task").Tasks
+		}).Should(ConsistOf(HaveField("State", models.Task_Completed)))
+
+		report := inspect.Inspect(logger, client, "trace-id", "some-task")
+		Expect(report.Kinds).To(Equal([]inspect.Kind{inspect.TaskGuid}))
+		Expect(report.DesiredLRPs).To(BeEmpty())
+		Expect(report.Cells).To(ConsistOf(HaveField("CellId", fakebbs.FakeCellID)))
+		Expect(report.String()).To(ContainSubstring("some-task  vizzini-inspect  Completed  " + fakebbs.FakeCellID))
+	})
+
+	It("shows everything in a domain, and every cell when something could not be placed", func() {
+		desireLRP("placed-lrp")
+		defaults.RootFS = models.PreloadedRootFS("unsupported")
+		desireLRP("unplaced-lrp")
+		server.SetCells(append(fakebbs.DefaultCells(), &models.CellPresence{CellId: "idle-cell", Capacity: &models.CellCapacity{}}))
+
+		var report inspect.Report
+		Eventually(func() []*models.ActualLRP {
+			report = inspect.Inspect(logger, client, "trace-id", "vizzini-inspect")
+			return report.ActualLRPs
+		}).Should(ContainElement(HaveField("PlacementError", "found no compatible cell")))
+
+		Expect(report.Kinds).To(Equal([]inspect.Kind{inspect.Domain}))
+		Expect(report.DesiredLRPs).To(HaveLen(2))
+		Expect(report.DesiredLRPs[0].ProcessGuid).To(Equal("placed-lrp"))
+		Expect(report.Cells).To(HaveLen(2))
+		Expect(report.String()).To(ContainSubstring("placement: found no compatible cell"))
+	})
+
+	It("says when the BBS knows nothing about the query", func() {
+		report := inspect.Inspect(logger, client, "trace-id", "unknown")
+		Expect(report.Found()).To(BeFalse())
+		Expect(report.Errors).To(BeEmpty())
+		Expect(report.Cells).To(BeEmpty())
+		Expect(report.String()).To(Equal(`the BBS knows nothing about "unknown"`))
+	})
+
+	It("records the requests that failed", func() {
+		server.Close()
+		report := inspect.Inspect(logger, client, "trace-id", "some-lrp")
+		Expect(report.Found()).To(BeFalse())
+		Expect(report.Errors).To(ContainElement(HavePrefix("task: ")))
+		Expect(report.String()).To(ContainSubstring("error fetching task: "))
+	})
+})
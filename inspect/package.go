@@ -0,0 +1 @@
+package inspect // import "code.cloudfoundry.org/vizzini/inspect"
@@ -0,0 +1,355 @@
+package load
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/vizzini/fixtures"
+	"code.cloudfoundry.org/vizzini/tracker"
+	uuid "github.com/nu7hatch/gouuid"
+)
+
+// MaxRate is the highest Rate a run can pace: one request a nanosecond.
+const MaxRate = float64(time.Second)
+
+type Options struct {
+	// Rate is the target number of requests per second across all workers.
+	// Zero sends requests as fast as the workers can make them.  Otherwise
+	// it must be at most MaxRate, and high enough to send at least one
+	// request during Duration.
+	Rate float64
+
+	Concurrency int
+	Duration    time.Duration
+	Mix         Mix
+
+	// Fixtures describes the Tasks and DesiredLRPs to desire.  Its Domain is
+	// where everything the run creates lives, and what cleanup audits.
+	Fixtures fixtures.Defaults
+
+	// LRPInstances defaults to zero so that only the BBS is exercised, not
+	// the auctioneer and cells.
+	LRPInstances int32
+
+	// CleanupTimeout bounds how long cleanup waits for each Task or
+	// DesiredLRP to go away.
+	CleanupTimeout time.Duration
+}
+
+func (o Options) validate() error {
+	problems := []string{}
+	if o.Rate < 0 || math.IsNaN(o.Rate) {
+		problems = append(problems, "rate must not be negative")
+	} else if o.Rate > MaxRate {
+		problems = append(problems, fmt.Sprintf("rate must be at most %g", MaxRate))
+	}
+	if o.Concurrency <= 0 {
+		problems = append(problems, "concurrency must be positive")
+	}
+	if o.Duration <= 0 {
+		problems = append(problems, "duration must be positive")
+	} else if o.Rate > 0 && o.Rate*o.Duration.Seconds() < 1 {
+		// this also keeps the interval between requests from overflowing
+		problems = append(problems, fmt.Sprintf("rate must send at least one request in %s", o.Duration))
+	}
+	if o.Mix.total() == 0 {
+		problems = append(problems, "the workload mix is empty")
+	}
+	if o.Fixtures.Domain == "" {
+		problems = append(problems, "a domain is required")
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid load options: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// Report is what a run measured, per Operation, plus what cleanup left
+// behind.
+type Report struct {
+	Domain      string        `json:"domain"`
+	Rate        float64       `json:"rate"`
+	Concurrency int           `json:"concurrency"`
+	Mix         string        `json:"mix"`
+	Elapsed     time.Duration `json:"elapsed"`
+
+	// Dropped counts requests the rate called for while every worker was
+	// still busy.  A non-zero count means the BBS could not keep up.
+	Dropped int `json:"dropped"`
+
+	Endpoints    []*Endpoint   `json:"endpoints"`
+	Leaks        tracker.Leaks `json:"leaks,omitempty"`
+	CleanupError string        `json:"cleanup_error,omitempty"`
+}
+
+func (r Report) Requests() int {
+	requests := 0
+	for _, endpoint := range r.Endpoints {
+		requests += endpoint.Requests
+	}
+	return requests
+}
+
+func (r Report) Errors() int {
+	errors := 0
+	for _, endpoint := range r.Endpoints {
+		errors += endpoint.Errors
+	}
+	return errors
+}
+
+func (r Report) String() string {
+	throughput := 0.0
+	if r.Elapsed > 0 {
+		throughput = float64(r.Requests()) / r.Elapsed.Seconds()
+	}
+	lines := []string{
+		fmt.Sprintf("domain %s, mix %s, concurrency %d, target rate %g/s", r.Domain, r.Mix, r.Concurrency, r.Rate),
+		fmt.Sprintf("%d requests, %d errors, %d dropped in %s (%.1f/s)", r.Requests(), r.Errors(), r.Dropped, r.Elapsed.Round(time.Millisecond), throughput),
+	}
+	for _, endpoint := range r.Endpoints {
+		lines = append(lines, endpoint.String())
+	}
+	if r.CleanupError != "" {
+		lines = append(lines, "cleanup failed: "+r.CleanupError)
+	}
+	if len(r.Leaks) > 0 {
+		lines = append(lines, fmt.Sprintf("%d resources leaked:", len(r.Leaks)), r.Leaks.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+type runner struct {
+	options Options
+	client  bbs.InternalClient
+	logger  lager.Logger
+	traceID string
+
+	lock      sync.Mutex
+	endpoints map[Operation]*Endpoint
+	lrps      []string
+	random    *rand.Rand
+}
+
+// Run sends requests in options.Mix to the BBS until options.Duration has
+// passed or ctx is done.  Everything it desires is tracked, and removed
+// again once the run is over, whether or not the run succeeded.
+func Run(ctx context.Context, logger lager.Logger, client bbs.InternalClient, traceID string, options Options) (Report, error) {
+	err := options.validate()
+	if err != nil {
+		return Report{}, err
+	}
+	// catch broken fixtures before any load is sent
+	_, err = options.Fixtures.Task("load")
+	if err != nil {
+		return Report{}, err
+	}
+	_, err = options.Fixtures.DesiredLRP("load", fixtures.WithInstances(options.LRPInstances))
+	if err != nil {
+		return Report{}, err
+	}
+
+	logger = logger.Session("load", lager.Data{"domain": options.Fixtures.Domain})
+	resourceTracker := tracker.New(client)
+	if options.CleanupTimeout > 0 {
+		resourceTracker.Timeout = options.CleanupTimeout
+	}
+
+	r := &runner{
+		options:   options,
+		client:    resourceTracker.Client(),
+		logger:    logger,
+		traceID:   traceID,
+		endpoints: map[Operation]*Endpoint{},
+		random:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	ctx, cancel := context.WithTimeout(ctx, options.Duration)
+	defer cancel()
+
+	start := time.Now()
+	dropped := r.drive(ctx)
+	report := Report{
+		Domain:      options.Fixtures.Domain,
+		Rate:        options.Rate,
+		Concurrency: options.Concurrency,
+		Mix:         options.Mix.String(),
+		Elapsed:     time.Since(start),
+		Dropped:     dropped,
+	}
+	for _, operation := range Operations {
+		if endpoint, ok := r.endpoints[operation]; ok {
+			endpoint.finish()
+			report.Endpoints = append(report.Endpoints, endpoint)
+		}
+	}
+
+	logger.Info("cleaning-up", lager.Data{"resources": len(resourceTracker.Tracked())})
+	err = resourceTracker.Cleanup(logger, traceID)
+	if err != nil {
+		report.CleanupError = err.Error()
+	}
+	report.Leaks, err = resourceTracker.Audit(logger, traceID, options.Fixtures.Domain)
+	if err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// drive hands work to the workers until ctx is done and returns how many
+// requests were dropped because no worker was free.
+func (r *runner) drive(ctx context.Context) int {
+	work := make(chan Operation)
+	wg := sync.WaitGroup{}
+	for i := 0; i < r.options.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for operation := range work {
+				r.do(operation)
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(work)
+
+	if r.options.Rate == 0 {
+		for {
+			select {
+			case <-ctx.Done():
+				return 0
+			case work <- r.pick():
+			}
+		}
+	}
+
+	dropped := 0
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / r.options.Rate))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return dropped
+		case <-ticker.C:
+			select {
+			case work <- r.pick():
+			default:
+				dropped++
+			}
+		}
+	}
+}
+
+func (r *runner) pick() Operation {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.options.Mix.pick(r.random)
+}
+
+func (r *runner) do(operation Operation) {
+	// there is nothing to update until the run has desired an LRP
+	processGuid := ""
+	if operation == UpdateDesiredLRP {
+		processGuid = r.existingLRP()
+		if processGuid == "" {
+			operation = DesireLRP
+		}
+	}
+
+	var err error
+	var latency time.Duration
+	switch operation {
+	case DesireTask:
+		latency, err = r.desireTask()
+	case DesireLRP:
+		latency, err = r.desireLRP()
+	case UpdateDesiredLRP:
+		latency, err = r.updateDesiredLRP(processGuid)
+	}
+
+	errType := ""
+	if err != nil {
+		errType = models.ConvertError(err).Type.String()
+		r.logger.Debug("request-failed", lager.Data{"operation": operation, "error": err.Error()})
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.endpoints[operation] == nil {
+		r.endpoints[operation] = newEndpoint(operation)
+	}
+	r.endpoints[operation].record(latency, errType)
+}
+
+func (r *runner) desireTask() (time.Duration, error) {
+	taskGuid, err := r.newGuid()
+	if err != nil {
+		return 0, err
+	}
+	task, err := r.options.Fixtures.Task(taskGuid)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	err = r.client.DesireTask(r.logger, r.traceID, taskGuid, r.options.Fixtures.Domain, task)
+	return time.Since(start), err
+}
+
+func (r *runner) desireLRP() (time.Duration, error) {
+	processGuid, err := r.newGuid()
+	if err != nil {
+		return 0, err
+	}
+	lrp, err := r.options.Fixtures.DesiredLRP(processGuid, fixtures.WithInstances(r.options.LRPInstances))
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	err = r.client.DesireLRP(r.logger, r.traceID, lrp)
+	latency := time.Since(start)
+	if err == nil {
+		r.lock.Lock()
+		r.lrps = append(r.lrps, processGuid)
+		r.lock.Unlock()
+	}
+	return latency, err
+}
+
+func (r *runner) existingLRP() string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if len(r.lrps) == 0 {
+		return ""
+	}
+	return r.lrps[r.random.Intn(len(r.lrps))]
+}
+
+// updateDesiredLRP changes the annotation of a DesiredLRP this run created,
+// so the update is real without rescheduling anything.
+func (r *runner) updateDesiredLRP(processGuid string) (time.Duration, error) {
+	update := &models.DesiredLRPUpdate{}
+	update.SetAnnotation(fmt.Sprintf("vizzini-load-%d", time.Now().UnixNano()))
+
+	start := time.Now()
+	err := r.client.UpdateDesiredLRP(r.logger, r.traceID, processGuid, update)
+	return time.Since(start), err
+}
+
+// newGuid uses the whole UUID; a long run desires enough resources for the
+// short GUIDs the specs use to collide.
+func (r *runner) newGuid() (string, error) {
+	u, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	return r.options.Fixtures.Domain + "-" + u.String(), nil
+}
@@ -0,0 +1,162 @@
+package load_test
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/vizzini/fakebbs"
+	"code.cloudfoundry.org/vizzini/fixtures"
+	"code.cloudfoundry.org/vizzini/load"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Run", func() {
+	var (
+		server  *fakebbs.Server
+		client  bbs.InternalClient
+		logger  *lagertest.TestLogger
+		options load.Options
+	)
+
+	BeforeEach(func() {
+		var err error
+		logger = lagertest.NewTestLogger("load")
+		server = fakebbs.NewServerWithStepInterval(10 * time.Millisecond)
+		server.Start()
+		client, err = server.Client()
+		Expect(err).NotTo(HaveOccurred())
+
+		options = load.Options{
+			Rate:        100,
+			Concurrency: 4,
+			Duration:    300 * time.Millisecond,
+			Mix:         load.Mix{load.DesireTask: 1, load.DesireLRP: 1, load.UpdateDesiredLRP: 1},
+			Fixtures: fixtures.Defaults{
+				Domain:               "vizzini-load",
+				RootFS:               models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot),
+				GraceTarballURL:      "http://example.com/grace.tgz",
+				RoutableDomainSuffix: "example.com",
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("sends the mix at the target rate and cleans up after itself", func() {
+		report, err := load.Run(context.Background(), logger, client, "trace-id", options)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(report.Requests()).To(BeNumerically("~", 30, 15))
+		Expect(report.Errors()).To(BeZero())
+		Expect(report.Endpoints).To(HaveLen(3))
+		for _, endpoint := range report.Endpoints {
+			Expect(endpoint.Requests).To(BeNumerically(">", 0), string(endpoint.Operation))
+			Expect(endpoint.Latency.Samples).To(Equal(endpoint.Requests))
+
+			inHistogram := 0
+			for _, bucket := range endpoint.Histogram {
+				inHistogram += bucket.Count
+			}
+			Expect(inHistogram).To(Equal(endpoint.Requests))
+		}
+
+		Expect(report.CleanupError).To(BeEmpty())
+		Expect(report.Leaks).To(BeEmpty())
+		Expect(client.TasksByDomain(logger, "trace-id", "vizzini-load")).To(BeEmpty())
+		Expect(client.DesiredLRPs(logger, "trace-id", models.DesiredLRPFilter{Domain: "vizzini-load"})).To(BeEmpty())
+	})
+
+	It("only sends the operations in the mix", func() {
+		options.Mix = load.Mix{load.DesireTask: 1, load.DesireLRP: 0}
+		report, err := load.Run(context.Background(), logger, client, "trace-id", options)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(report.Endpoints).To(HaveLen(1))
+		Expect(report.Endpoints[0].Operation).To(Equal(load.DesireTask))
+	})
+
+	It("sends as fast as it can without a rate, and stops when the context is done", func() {
+		options.Rate = 0
+		options.Duration = time.Minute
+		options.Mix = load.Mix{load.DesireLRP: 1}
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		report, err := load.Run(ctx, logger, client, "trace-id", options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Elapsed).To(BeNumerically("<", 5*time.Second))
+		Expect(report.Requests()).To(BeNumerically(">", 0))
+		Expect(report.Dropped).To(BeZero())
+		Expect(report.Leaks).To(BeEmpty())
+	})
+
+	It("counts errors by type", func() {
+		options.Mix = load.Mix{load.DesireTask: 1}
+		server.Close()
+
+		report, err := load.Run(context.Background(), logger, client, "trace-id", options)
+		Expect(err).To(HaveOccurred())
+
+		Expect(report.Requests()).To(BeNumerically(">", 0))
+		Expect(report.Errors()).To(Equal(report.Requests()))
+		Expect(report.Endpoints[0].ErrorsBy).To(HaveKeyWithValue("UnknownError", report.Requests()))
+		Expect(report.String()).To(ContainSubstring("error UnknownError"))
+	})
+
+	It("rejects invalid options before sending anything", func() {
+		options.Concurrency = 0
+		options.Fixtures.Domain = ""
+		_, err := load.Run(context.Background(), logger, client, "trace-id", options)
+		Expect(err).To(MatchError("invalid load options: concurrency must be positive, a domain is required"))
+
+		options.Concurrency = 1
+		options.Fixtures.Domain = "vizzini-load"
+		options.Fixtures.RootFS = ""
+		_, err = load.Run(context.Background(), logger, client, "trace-id", options)
+		Expect(err).To(MatchError(ContainSubstring("invalid task fixture")))
+	})
+
+	DescribeTable("rejects rates it cannot pace",
+		func(rate float64, problem string) {
+			options.Rate = rate
+			_, err := load.Run(context.Background(), logger, client, "trace-id", options)
+			Expect(err).To(MatchError("invalid load options: " + problem))
+		},
+		Entry("negative", -1.0, "rate must not be negative"),
+		Entry("NaN", math.NaN(), "rate must not be negative"),
+		Entry("faster than a request a nanosecond", 2e9, "rate must be at most 1e+09"),
+		Entry("infinite", math.Inf(1), "rate must be at most 1e+09"),
+		Entry("too slow to send anything", 1e-12, "rate must send at least one request in 300ms"),
+	)
+})
+
+var _ = Describe("ParseMix", func() {
+	It("reads operation=weight pairs", func() {
+		mix, err := load.ParseMix("desire_task=3, desire_lrp=1,update_desired_lrp=0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mix).To(Equal(load.Mix{load.DesireTask: 3, load.DesireLRP: 1, load.UpdateDesiredLRP: 0}))
+		Expect(mix.String()).To(Equal("desire_task=3,desire_lrp=1"))
+
+		_, err = load.ParseMix(load.DefaultMix)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	DescribeTable("rejects malformed mixes",
+		func(value, message string) {
+			_, err := load.ParseMix(value)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("missing weight", "desire_task", "expected operation=weight"),
+		Entry("unknown operation", "remove_task=1", `unknown operation "remove_task"`),
+		Entry("negative weight", "desire_task=-1", "non-negative integer"),
+		Entry("no positive weight", "desire_task=0", "at least one operation"),
+	)
+})
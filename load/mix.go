@@ -0,0 +1,95 @@
+package load
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Operation string
+
+const (
+	DesireTask       Operation = "desire_task"
+	DesireLRP        Operation = "desire_lrp"
+	UpdateDesiredLRP Operation = "update_desired_lrp"
+)
+
+var Operations = []Operation{DesireTask, DesireLRP, UpdateDesiredLRP}
+
+// Mix weights how often each Operation is picked, e.g. {DesireTask: 3,
+// DesireLRP: 1} desires three Tasks for every DesiredLRP.
+type Mix map[Operation]int
+
+const DefaultMix = "desire_task=1,desire_lrp=1,update_desired_lrp=1"
+
+// ParseMix reads a Mix written as comma-separated operation=weight pairs.
+func ParseMix(value string) (Mix, error) {
+	known := map[Operation]bool{}
+	for _, operation := range Operations {
+		known[operation] = true
+	}
+
+	mix := Mix{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, weight, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid workload mix %q: expected operation=weight", pair)
+		}
+		operation := Operation(strings.TrimSpace(name))
+		if !known[operation] {
+			return nil, fmt.Errorf("invalid workload mix %q: unknown operation %q", pair, name)
+		}
+		parsed, err := strconv.Atoi(strings.TrimSpace(weight))
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid workload mix %q: weight must be a non-negative integer", pair)
+		}
+		mix[operation] = parsed
+	}
+
+	if mix.total() == 0 {
+		return nil, fmt.Errorf("invalid workload mix %q: at least one operation needs a positive weight", value)
+	}
+	return mix, nil
+}
+
+func (m Mix) String() string {
+	pairs := []string{}
+	for _, operation := range Operations {
+		if m[operation] > 0 {
+			pairs = append(pairs, fmt.Sprintf("%s=%d", operation, m[operation]))
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m Mix) total() int {
+	total := 0
+	for _, weight := range m {
+		total += weight
+	}
+	return total
+}
+
+// pick chooses an Operation with probability proportional to its weight.
+func (m Mix) pick(random *rand.Rand) Operation {
+	operations := make([]Operation, 0, len(m))
+	for operation := range m {
+		operations = append(operations, operation)
+	}
+	sort.Slice(operations, func(i, j int) bool { return operations[i] < operations[j] })
+
+	n := random.Intn(m.total())
+	for _, operation := range operations {
+		if n < m[operation] {
+			return operation
+		}
+		n -= m[operation]
+	}
+	return operations[len(operations)-1]
+}
@@ -0,0 +1 @@
+package load // import "code.cloudfoundry.org/vizzini/load"
@@ -0,0 +1,117 @@
+package load
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/vizzini/perf"
+)
+
+// Buckets are the upper bounds of the latency histogram.  Anything slower
+// than the last bucket is counted in an overflow bucket.
+var Buckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Endpoint is what one Operation saw over a run.
+type Endpoint struct {
+	Operation Operation      `json:"operation"`
+	Requests  int            `json:"requests"`
+	Errors    int            `json:"errors"`
+	ErrorsBy  map[string]int `json:"errors_by_type,omitempty"`
+	Histogram []Bucket       `json:"histogram"`
+
+	// Latency summarizes every request, failed or not.
+	Latency perf.Durations `json:"latency"`
+
+	latencies []time.Duration
+}
+
+type Bucket struct {
+	// UpTo is the bucket's upper bound, or zero for the overflow bucket.
+	UpTo  time.Duration `json:"up_to"`
+	Count int           `json:"count"`
+}
+
+func newEndpoint(operation Operation) *Endpoint {
+	return &Endpoint{Operation: operation, ErrorsBy: map[string]int{}}
+}
+
+func (e *Endpoint) record(latency time.Duration, errType string) {
+	e.Requests++
+	e.latencies = append(e.latencies, latency)
+	if errType != "" {
+		e.Errors++
+		e.ErrorsBy[errType]++
+	}
+}
+
+// finish fills in the histogram and latency summary once recording is done.
+func (e *Endpoint) finish() {
+	e.Histogram = histogram(e.latencies)
+	if len(e.latencies) > 0 {
+		e.Latency = perf.SummarizeDurations(string(e.Operation), e.latencies)
+	}
+}
+
+func histogram(latencies []time.Duration) []Bucket {
+	buckets := make([]Bucket, len(Buckets)+1)
+	for i, upTo := range Buckets {
+		buckets[i].UpTo = upTo
+	}
+	for _, latency := range latencies {
+		i := sort.Search(len(Buckets), func(i int) bool { return latency <= Buckets[i] })
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+func (e *Endpoint) String() string {
+	lines := []string{
+		fmt.Sprintf("%s: %d requests, %d errors", e.Operation, e.Requests, e.Errors),
+	}
+	if e.Requests == 0 {
+		return lines[0]
+	}
+
+	lines = append(lines, fmt.Sprintf("  latency: min %s, p50 %s, p90 %s, p99 %s, max %s",
+		seconds(e.Latency.Min), seconds(e.Latency.Percentiles["p50"]), seconds(e.Latency.Percentiles["p90"]),
+		seconds(e.Latency.Percentiles["p99"]), seconds(e.Latency.Max)))
+
+	errTypes := make([]string, 0, len(e.ErrorsBy))
+	for errType := range e.ErrorsBy {
+		errTypes = append(errTypes, errType)
+	}
+	sort.Strings(errTypes)
+	for _, errType := range errTypes {
+		lines = append(lines, fmt.Sprintf("  error %s: %d", errType, e.ErrorsBy[errType]))
+	}
+
+	for _, bucket := range e.Histogram {
+		if bucket.Count == 0 {
+			continue
+		}
+		label := "> " + Buckets[len(Buckets)-1].String()
+		if bucket.UpTo != 0 {
+			label = "<= " + bucket.UpTo.String()
+		}
+		lines = append(lines, fmt.Sprintf("    %-9s %d", label, bucket.Count))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second)).Round(100 * time.Microsecond)
+}
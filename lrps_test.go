@@ -1,14 +1,15 @@
 package vizzini_test
 
 import (
-	"encoding/json"
-	"io"
 	"net/http"
 	"strings"
 
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/routing-info/cfroutes"
+	"code.cloudfoundry.org/vizzini/capabilities"
+	"code.cloudfoundry.org/vizzini/fixtures"
 	. "code.cloudfoundry.org/vizzini/matchers"
+	"code.cloudfoundry.org/vizzini/profiles"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -31,7 +32,7 @@ var _ = Describe("LRPs", func() {
 			It("desires the LRP", func() {
 				Eventually(LRPGetter(logger, guid)).ShouldNot(BeZero())
 				Eventually(EndpointCurler(url)).Should(Equal(http.StatusOK))
-				Eventually(ActualByDomainGetter(logger, domain)).Should(ContainElement(BeActualLRP(guid, 0)))
+				WaitForActualLRP(MatchActualLRP(guid, 0))
 
 				fetchedLRP, err := bbsClient.DesiredLRPByProcessGuid(logger, traceID, guid)
 				Expect(err).NotTo(HaveOccurred())
@@ -136,15 +137,7 @@ var _ = Describe("LRPs", func() {
 		})
 
 		It("should be possible to specify environment variables on both the DesiredLRP and the RunAction", func() {
-			resp, err := http.Get(url)
-			Expect(err).NotTo(HaveOccurred())
-			defer resp.Body.Close()
-
-			bodyBytes, err := io.ReadAll(resp.Body)
-			Expect(err).NotTo(HaveOccurred())
-
-			env := [][]string{}
-			err = json.Unmarshal(bodyBytes, &env)
+			env, err := GraceClient(guid).Env()
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(env).To(ContainElement([]string{"CONTAINER_LEVEL", "AARDVARK"}))
@@ -156,44 +149,36 @@ var _ = Describe("LRPs", func() {
 
 	Describe("Specifying declarative health check", func() {
 		BeforeEach(func() {
-			lrp.Setup = models.WrapAction(models.Serial(
-				&models.DownloadAction{
-					From:     config.GraceTarballURL,
-					To:       ".",
-					CacheKey: "grace",
-					User:     "vcap",
-				},
-				&models.DownloadAction{
-					From:     config.FileServerAddress + "/v1/static/buildpack_app_lifecycle/buildpack_app_lifecycle.tgz",
-					To:       "/tmp/lifecycle",
-					CacheKey: "buildpack-app-lifecycle",
-					User:     "vcap",
-				},
-			))
-			// check the wrong port to ensure the check definition is actually being used
-			lrp.Monitor = models.WrapAction(&models.RunAction{
-				Path: "/tmp/lifecycle/healthcheck",
-				Args: []string{"-port=8090", "-uri=/ping"},
-				User: "vcap",
-			})
-
-			lrp.CheckDefinition = &models.CheckDefinition{
-				Checks: []*models.Check{
-					{
-						HttpCheck: &models.HTTPCheck{
-							Port: 8080,
-							Path: "/ping",
-						},
+			lrp = DesiredLRPWithGuid(guid,
+				fixtures.WithSetup(models.Serial(
+					&models.DownloadAction{
+						From:     config.GraceTarballURL,
+						To:       ".",
+						CacheKey: "grace",
+						User:     "vcap",
 					},
-				},
-			}
+					&models.DownloadAction{
+						From:     config.FileServerAddress + "/v1/static/buildpack_app_lifecycle/buildpack_app_lifecycle.tgz",
+						To:       "/tmp/lifecycle",
+						CacheKey: "buildpack-app-lifecycle",
+						User:     "vcap",
+					},
+				)),
+				// check the wrong port to ensure the check definition is actually being used
+				fixtures.WithMonitor(&models.RunAction{
+					Path: "/tmp/lifecycle/healthcheck",
+					Args: []string{"-port=8090", "-uri=/ping"},
+					User: "vcap",
+				}),
+				fixtures.WithHTTPCheck(8080, "/ping"),
+			)
 
 			Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
 		})
 
 		It("should run", func() {
 
-			Eventually(ActualByDomainGetter(logger, domain)).Should(ContainElement(BeActualLRPWithState(guid, 0, models.ActualLRPStateRunning)))
+			WaitForActualLRPState(guid, 0, models.ActualLRPStateRunning)
 		})
 	})
 
@@ -224,7 +209,7 @@ var _ = Describe("LRPs", func() {
 		})
 
 		It("should run", func() {
-			Eventually(ActualByDomainGetter(logger, domain)).Should(ContainElement(BeActualLRPWithState(guid, 0, models.ActualLRPStateRunning)))
+			WaitForActualLRPState(guid, 0, models.ActualLRPStateRunning)
 		})
 	})
 
@@ -235,37 +220,27 @@ var _ = Describe("LRPs", func() {
 		)
 
 		BeforeEach(func() {
-			sidecar1Route = RouteForGuid("sidecar-1-" + guid)
-			sidecar2Route = RouteForGuid("sidecar-2-" + guid)
-			routingInfo := cfroutes.CFRoutes{
-				{Port: 8080, Hostnames: []string{RouteForGuid(guid)}},
-				{Port: 8090, Hostnames: []string{sidecar1Route}},
-				{Port: 8100, Hostnames: []string{sidecar2Route}},
-			}.RoutingInfo()
-			lrp.Routes = &routingInfo
-			lrp.Ports = []uint32{8080, 8090, 8100}
-			lrp.Sidecars = []*models.Sidecar{
-				{
-					Action: models.WrapAction(&models.RunAction{
-						Path: "/tmp/grace/grace",
-						User: "vcap",
-						Env:  []*models.EnvironmentVariable{{Name: "PORT", Value: "8090"}, {Name: "SECONDARY_PORT", Value: "9090"}},
-					}),
-				},
-				{
-					Action: models.WrapAction(&models.RunAction{
-						Path: "/tmp/grace/grace",
-						User: "vcap",
-						Env:  []*models.EnvironmentVariable{{Name: "PORT", Value: "8100"}, {Name: "SECONDARY_PORT", Value: "9100"}},
-					}),
-				},
-			}
+			sidecar1Route = Fixtures().RouteFor(guid, 8090)
+			sidecar2Route = Fixtures().RouteFor(guid, 8100)
+			lrp = DesiredLRPWithGuid(guid,
+				fixtures.WithRoutes(8080, 8090, 8100),
+				fixtures.WithSidecar(&models.RunAction{
+					Path: "/tmp/grace/grace",
+					User: "vcap",
+					Env:  []*models.EnvironmentVariable{{Name: "PORT", Value: "8090"}, {Name: "SECONDARY_PORT", Value: "9090"}},
+				}),
+				fixtures.WithSidecar(&models.RunAction{
+					Path: "/tmp/grace/grace",
+					User: "vcap",
+					Env:  []*models.EnvironmentVariable{{Name: "PORT", Value: "8100"}, {Name: "SECONDARY_PORT", Value: "9100"}},
+				}),
+			)
 
 			Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
 		})
 
 		It("should run", func() {
-			Eventually(ActualByDomainGetter(logger, domain)).Should(ContainElement(BeActualLRPWithState(guid, 0, models.ActualLRPStateRunning)))
+			WaitForActualLRPState(guid, 0, models.ActualLRPStateRunning)
 			Eventually(EndpointCurler(url)).Should(Equal(http.StatusOK))
 			Eventually(EndpointCurler("http://" + sidecar1Route + "/env")).Should(Equal(http.StatusOK))
 			Eventually(EndpointCurler("http://" + sidecar2Route + "/env")).Should(Equal(http.StatusOK))
@@ -275,41 +250,42 @@ var _ = Describe("LRPs", func() {
 
 	Describe("when a sidecar crashes", func() {
 		BeforeEach(func() {
-			lrp.Sidecars = append(lrp.Sidecars, &models.Sidecar{
-				Action: models.WrapAction(&models.RunAction{
-					User: "vcap",
-					Path: "/bin/bash",
-					Args: []string{"-c", "exit 1"},
-				}),
-			})
+			lrp = DesiredLRPWithGuid(guid, fixtures.WithSidecar(&models.RunAction{
+				User: "vcap",
+				Path: "/bin/bash",
+				Args: []string{"-c", "exit 1"},
+			}))
 
 			Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
 		})
 
 		It("gets marked as crashed (immediately)", func() {
-			Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithCrashCount(guid, 0, 1))
+			WaitForActualLRP(MatchActualLRP(guid, 0).WithCrashCount(1))
 		})
 	})
 
-	Describe("{DOCKER} Creating a Docker-based LRP", func() {
+	Describe("Creating a Docker-based LRP", Label(profiles.Docker), func() {
 		BeforeEach(func() {
-			lrp.RootFs = config.GraceBusyboxImageURL
-			lrp.Setup = models.WrapAction(&models.DownloadAction{
-				From:     config.FileServerAddress + "/v1/static/docker_app_lifecycle/docker_app_lifecycle.tgz",
-				To:       "/tmp/lifecycle",
-				CacheKey: "docker-app-lifecycle",
-				User:     "root",
-			})
-			lrp.Action = models.WrapAction(&models.RunAction{
-				Path: "/grace",
-				User: "root",
-				Env:  []*models.EnvironmentVariable{{Name: "PORT", Value: "8080"}},
-			})
-			lrp.Monitor = models.WrapAction(&models.RunAction{
-				Path: "/tmp/lifecycle/healthcheck",
-				Args: []string{"-port=8080"},
-				User: "root",
-			})
+			RequireCapabilities(capabilities.Docker)
+			lrp = DesiredLRPWithGuid(guid,
+				fixtures.WithDockerImage(config.GraceBusyboxImageURL),
+				fixtures.WithSetup(&models.DownloadAction{
+					From:     config.FileServerAddress + "/v1/static/docker_app_lifecycle/docker_app_lifecycle.tgz",
+					To:       "/tmp/lifecycle",
+					CacheKey: "docker-app-lifecycle",
+					User:     "root",
+				}),
+				fixtures.WithAction(&models.RunAction{
+					Path: "/grace",
+					User: "root",
+					Env:  []*models.EnvironmentVariable{{Name: "PORT", Value: "8080"}},
+				}),
+				fixtures.WithMonitor(&models.RunAction{
+					Path: "/tmp/lifecycle/healthcheck",
+					Args: []string{"-port=8080"},
+					User: "root",
+				}),
+			)
 		})
 		JustBeforeEach(func() {
 			Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
@@ -317,11 +293,12 @@ var _ = Describe("LRPs", func() {
 
 		It("should succeed", func() {
 			Eventually(EndpointCurler(url), 120).Should(Equal(http.StatusOK), "Docker can be quite slow to spin up...")
-			Eventually(ActualByDomainGetter(logger, domain)).Should(ContainElement(BeActualLRP(guid, 0)))
+			WaitForActualLRP(MatchActualLRP(guid, 0))
 		})
 
-		Context("with an OCI image", func() {
+		Context("with an OCI image", Label(profiles.OCI), func() {
 			BeforeEach(func() {
+				RequireCapabilities(capabilities.OCIImages)
 				lrp.RootFs = config.DiegoDockerOCIImageURL
 				lrp.Action = models.WrapAction(&models.RunAction{
 					Path: "dockerapp",
@@ -332,7 +309,7 @@ var _ = Describe("LRPs", func() {
 			})
 			It("should succeed", func() {
 				Eventually(EndpointCurler(url), 120).Should(Equal(http.StatusOK), "Docker can be quite slow to spin up...")
-				Eventually(ActualByDomainGetter(logger, domain)).Should(ContainElement(BeActualLRP(guid, 0)))
+				WaitForActualLRP(MatchActualLRP(guid, 0))
 			})
 		})
 	})
@@ -538,10 +515,10 @@ var _ = Describe("LRPs", func() {
 		Context("when the DesiredLRP is deleted after it is claimed but before it is running #86668966", func() {
 			It("should succesfully remove any ActualLRP", func() {
 				Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-				Eventually(ActualByProcessGuidGetter(logger, lrp.ProcessGuid)).Should(ContainElement(BeActualLRPWithState(lrp.ProcessGuid, 0, models.ActualLRPStateClaimed)))
+				WaitForActualLRPState(lrp.ProcessGuid, 0, models.ActualLRPStateClaimed)
 				//note: we don't wait for the ActualLRP to start running
 				Expect(bbsClient.RemoveDesiredLRP(logger, traceID, lrp.ProcessGuid)).To(Succeed())
-				Eventually(ActualByProcessGuidGetter(logger, lrp.ProcessGuid)).Should(BeEmpty())
+				WaitForDesiredLRPRemoved(lrp.ProcessGuid)
 			})
 		})
 
@@ -696,19 +673,19 @@ var _ = Describe("LRPs", func() {
 			})
 		})
 
-		Context("{SLOW} when an ActualLRP exists at the given ProcessGuid and index", func() {
+		Context("when an ActualLRP exists at the given ProcessGuid and index", Label(profiles.Slow), func() {
 			BeforeEach(func() {
 				Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
 				Eventually(EndpointCurler(url)).Should(Equal(http.StatusOK))
 			})
 
 			It("restarts the actual lrp", func() {
-				initialTime, err := StartedAtGetter(guid)()
+				initialTime, err := GraceClient(guid).StartedAt()
 				Expect(err).NotTo(HaveOccurred())
 				lrpKey := models.NewActualLRPKey(guid, 0, domain)
 				Expect(bbsClient.RetireActualLRP(logger, traceID, &lrpKey)).To(Succeed())
 				//This needs a large timeout as the converger needs to run for it to return
-				Eventually(StartedAtGetter(guid), ConvergerInterval*2).Should(BeNumerically(">", initialTime))
+				Eventually(GraceClient(guid).StartedAt, ConvergerInterval*2).Should(BeNumerically(">", initialTime))
 			})
 		})
 	})
@@ -721,13 +698,9 @@ var _ = Describe("LRPs", func() {
 
 			It("should report this fact on the UNCLAIMED ActualLRP", func() {
 				Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-				Eventually(ActualGetter(logger, guid, 0)).Should(BeUnclaimedActualLRPWithPlacementError(guid, 0))
-
-				actualLRP, err := ActualLRPByProcessGuidAndIndex(logger, guid, 0)
-				Expect(err).NotTo(HaveOccurred())
-
-				Expect(actualLRP.State).To(Equal(models.ActualLRPStateUnclaimed))
-				Expect(actualLRP.PlacementError).To(ContainSubstring("insufficient resources"))
+				WaitForActualLRP(MatchActualLRP(guid, 0).
+					WithState(models.ActualLRPStateUnclaimed).
+					WithPlacementError("insufficient resources"))
 			})
 		})
 
@@ -738,13 +711,9 @@ var _ = Describe("LRPs", func() {
 
 			It("should allow creation of the task but should (fairly quickly) mark the task as failed", func() {
 				Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-				Eventually(ActualGetter(logger, guid, 0)).Should(BeUnclaimedActualLRPWithPlacementError(guid, 0))
-
-				actualLRP, err := ActualLRPByProcessGuidAndIndex(logger, guid, 0)
-				Expect(err).NotTo(HaveOccurred())
-
-				Expect(actualLRP.State).To(Equal(models.ActualLRPStateUnclaimed))
-				Expect(actualLRP.PlacementError).To(ContainSubstring("found no compatible cell"))
+				WaitForActualLRP(MatchActualLRP(guid, 0).
+					WithState(models.ActualLRPStateUnclaimed).
+					WithPlacementError("found no compatible cell"))
 			})
 		})
 	})
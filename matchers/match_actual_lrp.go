@@ -0,0 +1,338 @@
+package matchers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"code.cloudfoundry.org/bbs/models"
+	"github.com/onsi/gomega/format"
+)
+
+// MatchActualLRP matches a models.ActualLRP (or *models.ActualLRP) with the
+// given ProcessGuid and Index.  Further expectations are added with the With*
+// methods, e.g.
+//
+//	MatchActualLRP(guid, 0).WithState(models.ActualLRPStateRunning).OnCell(cellID).WithRoutable(true)
+//
+// Each method returns a new matcher, so a partially built matcher can be
+// shared between assertions.  Failure messages only list the fields that
+// differ.
+func MatchActualLRP(processGuid string, index int) *ActualLRPMatcher {
+	return &ActualLRPMatcher{
+		ProcessGuid: processGuid,
+		Index:       index,
+	}
+}
+
+type ActualLRPMatcher struct {
+	ProcessGuid string
+	Index       int
+
+	expectations []actualLRPExpectation
+}
+
+type actualLRPExpectation struct {
+	field    string
+	expected string
+	actual   func(*models.ActualLRP) string
+	matches  func(*models.ActualLRP) bool
+}
+
+func (matcher *ActualLRPMatcher) with(expectation actualLRPExpectation) *ActualLRPMatcher {
+	expectations := make([]actualLRPExpectation, 0, len(matcher.expectations)+1)
+	expectations = append(expectations, matcher.expectations...)
+	return &ActualLRPMatcher{
+		ProcessGuid:  matcher.ProcessGuid,
+		Index:        matcher.Index,
+		expectations: append(expectations, expectation),
+	}
+}
+
+func (matcher *ActualLRPMatcher) WithState(state string) *ActualLRPMatcher {
+	return matcher.with(actualLRPExpectation{
+		field:    "State",
+		expected: state,
+		actual:   func(lrp *models.ActualLRP) string { return lrp.State },
+		matches:  func(lrp *models.ActualLRP) bool { return lrp.State == state },
+	})
+}
+
+func (matcher *ActualLRPMatcher) WithPresence(presence models.ActualLRP_Presence) *ActualLRPMatcher {
+	return matcher.with(actualLRPExpectation{
+		field:    "Presence",
+		expected: presence.String(),
+		actual:   func(lrp *models.ActualLRP) string { return lrp.Presence.String() },
+		matches:  func(lrp *models.ActualLRP) bool { return lrp.Presence == presence },
+	})
+}
+
+func (matcher *ActualLRPMatcher) OnCell(cellID string) *ActualLRPMatcher {
+	return matcher.with(actualLRPExpectation{
+		field:    "CellId",
+		expected: cellID,
+		actual:   func(lrp *models.ActualLRP) string { return lrp.CellId },
+		matches:  func(lrp *models.ActualLRP) bool { return lrp.CellId == cellID },
+	})
+}
+
+// WithRoutable expects Routable to be set to routable.  An ActualLRP whose
+// Routable was never set matches neither true nor false.
+func (matcher *ActualLRPMatcher) WithRoutable(routable bool) *ActualLRPMatcher {
+	return matcher.with(actualLRPExpectation{
+		field:    "Routable",
+		expected: fmt.Sprintf("%t", routable),
+		actual: func(lrp *models.ActualLRP) string {
+			if !lrp.RoutableExists() {
+				return "unset"
+			}
+			return fmt.Sprintf("%t", lrp.GetRoutable())
+		},
+		matches: func(lrp *models.ActualLRP) bool { return lrp.RoutableExists() && lrp.GetRoutable() == routable },
+	})
+}
+
+func (matcher *ActualLRPMatcher) WithCrashCount(crashCount int) *ActualLRPMatcher {
+	return matcher.with(actualLRPExpectation{
+		field:    "CrashCount",
+		expected: fmt.Sprintf("%d", crashCount),
+		actual:   func(lrp *models.ActualLRP) string { return fmt.Sprintf("%d", lrp.CrashCount) },
+		matches:  func(lrp *models.ActualLRP) bool { return int(lrp.CrashCount) == crashCount },
+	})
+}
+
+// ThatHasCrashed expects a CrashCount of at least one.
+func (matcher *ActualLRPMatcher) ThatHasCrashed() *ActualLRPMatcher {
+	return matcher.with(actualLRPExpectation{
+		field:    "CrashCount",
+		expected: "> 0",
+		actual:   func(lrp *models.ActualLRP) string { return fmt.Sprintf("%d", lrp.CrashCount) },
+		matches:  func(lrp *models.ActualLRP) bool { return lrp.CrashCount > 0 },
+	})
+}
+
+// WithCrashReason expects CrashReason to contain every one of substrings.
+func (matcher *ActualLRPMatcher) WithCrashReason(substrings ...string) *ActualLRPMatcher {
+	return matcher.with(actualLRPExpectation{
+		field:    "CrashReason",
+		expected: "containing " + quoteAll(substrings),
+		actual:   func(lrp *models.ActualLRP) string { return fmt.Sprintf("%q", lrp.CrashReason) },
+		matches:  func(lrp *models.ActualLRP) bool { return containsAll(lrp.CrashReason, substrings) },
+	})
+}
+
+// WithCrashReasonMatching expects CrashReason to match the regular expression
+// pattern.
+func (matcher *ActualLRPMatcher) WithCrashReasonMatching(pattern string) *ActualLRPMatcher {
+	re := regexp.MustCompile(pattern)
+	return matcher.with(actualLRPExpectation{
+		field:    "CrashReason",
+		expected: fmt.Sprintf("matching /%s/", pattern),
+		actual:   func(lrp *models.ActualLRP) string { return fmt.Sprintf("%q", lrp.CrashReason) },
+		matches:  func(lrp *models.ActualLRP) bool { return re.MatchString(lrp.CrashReason) },
+	})
+}
+
+// WithPlacementError expects PlacementError to contain substring, or just to
+// be set if substring is empty.
+func (matcher *ActualLRPMatcher) WithPlacementError(substring string) *ActualLRPMatcher {
+	expected := "set"
+	if substring != "" {
+		expected = fmt.Sprintf("containing %q", substring)
+	}
+	return matcher.with(actualLRPExpectation{
+		field:    "PlacementError",
+		expected: expected,
+		actual:   func(lrp *models.ActualLRP) string { return fmt.Sprintf("%q", lrp.PlacementError) },
+		matches: func(lrp *models.ActualLRP) bool {
+			return lrp.PlacementError != "" && strings.Contains(lrp.PlacementError, substring)
+		},
+	})
+}
+
+// WithPort expects one of the port mappings to agree with every non-zero
+// field of port, so
+//
+//	WithPort(models.PortMapping{ContainerPort: 8080})
+//
+// matches whatever host ports 8080 was given.
+func (matcher *ActualLRPMatcher) WithPort(port models.PortMapping) *ActualLRPMatcher {
+	return matcher.with(actualLRPExpectation{
+		field:    "Ports",
+		expected: "including " + describePort(&port),
+		actual:   describePorts,
+		matches: func(lrp *models.ActualLRP) bool {
+			for _, mapping := range lrp.Ports {
+				if portMatches(mapping, port) {
+					return true
+				}
+			}
+			return false
+		},
+	})
+}
+
+// WithTLSProxyPort expects containerPort to be mapped to a HostTlsProxyPort,
+// i.e. to be reachable through the envoy sidecar.
+func (matcher *ActualLRPMatcher) WithTLSProxyPort(containerPort uint32) *ActualLRPMatcher {
+	return matcher.with(actualLRPExpectation{
+		field:    "Ports",
+		expected: fmt.Sprintf("including %d with a HostTlsProxyPort", containerPort),
+		actual:   describePorts,
+		matches: func(lrp *models.ActualLRP) bool {
+			for _, mapping := range lrp.Ports {
+				if mapping.ContainerPort == containerPort && mapping.HostTlsProxyPort != 0 {
+					return true
+				}
+			}
+			return false
+		},
+	})
+}
+
+func (matcher *ActualLRPMatcher) WithInstanceAddress(address string) *ActualLRPMatcher {
+	return matcher.with(actualLRPExpectation{
+		field:    "InstanceAddress",
+		expected: address,
+		actual:   func(lrp *models.ActualLRP) string { return lrp.InstanceAddress },
+		matches:  func(lrp *models.ActualLRP) bool { return lrp.InstanceAddress == address },
+	})
+}
+
+func (matcher *ActualLRPMatcher) WithModificationTag(tag models.ModificationTag) *ActualLRPMatcher {
+	return matcher.with(actualLRPExpectation{
+		field:    "ModificationTag",
+		expected: describeTag(tag),
+		actual:   func(lrp *models.ActualLRP) string { return describeTag(lrp.ModificationTag) },
+		matches:  func(lrp *models.ActualLRP) bool { return lrp.ModificationTag.Equal(tag) },
+	})
+}
+
+// WithModificationTagAfter expects the ActualLRP to have been modified since
+// it had tag: the same Epoch, with a greater Index.
+func (matcher *ActualLRPMatcher) WithModificationTagAfter(tag models.ModificationTag) *ActualLRPMatcher {
+	return matcher.with(actualLRPExpectation{
+		field:    "ModificationTag",
+		expected: fmt.Sprintf("after %s", describeTag(tag)),
+		actual:   func(lrp *models.ActualLRP) string { return describeTag(lrp.ModificationTag) },
+		matches: func(lrp *models.ActualLRP) bool {
+			return lrp.ModificationTag.Epoch == tag.Epoch && lrp.ModificationTag.Index > tag.Index
+		},
+	})
+}
+
+// String describes the ActualLRP the matcher expects, e.g.
+// "ActualLRP guid/0 with State RUNNING, CrashCount 2".
+func (matcher *ActualLRPMatcher) String() string {
+	description := fmt.Sprintf("ActualLRP %s/%d", matcher.ProcessGuid, matcher.Index)
+	if len(matcher.expectations) == 0 {
+		return description
+	}
+	expected := make([]string, len(matcher.expectations))
+	for i, expectation := range matcher.expectations {
+		expected[i] = fmt.Sprintf("%s %s", expectation.field, expectation.expected)
+	}
+	return description + " with " + strings.Join(expected, ", ")
+}
+
+func (matcher *ActualLRPMatcher) Match(actual interface{}) (success bool, err error) {
+	lrp, err := toActualLRP(actual)
+	if err != nil {
+		return false, err
+	}
+	return len(matcher.differences(lrp)) == 0, nil
+}
+
+func (matcher *ActualLRPMatcher) FailureMessage(actual interface{}) (message string) {
+	lrp, err := toActualLRP(actual)
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("Expected ActualLRP %s/%d to have:\n%s", matcher.ProcessGuid, matcher.Index, strings.Join(matcher.differences(lrp), "\n"))
+}
+
+func (matcher *ActualLRPMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	expected := []string{}
+	for _, expectation := range matcher.expectations {
+		expected = append(expected, fmt.Sprintf("    %s: %s", expectation.field, expectation.expected))
+	}
+	if len(expected) == 0 {
+		return fmt.Sprintf("Expected not to find ActualLRP %s/%d", matcher.ProcessGuid, matcher.Index)
+	}
+	return fmt.Sprintf("Expected ActualLRP %s/%d not to have:\n%s", matcher.ProcessGuid, matcher.Index, strings.Join(expected, "\n"))
+}
+
+// differences lists the expected fields lrp does not have, one per line.  A
+// different ProcessGuid or Index makes every other field irrelevant.
+func (matcher *ActualLRPMatcher) differences(lrp *models.ActualLRP) []string {
+	if lrp.ProcessGuid != matcher.ProcessGuid || int(lrp.Index) != matcher.Index {
+		return []string{fmt.Sprintf("    ProcessGuid/Index: %s/%d (got %s/%d)", matcher.ProcessGuid, matcher.Index, lrp.ProcessGuid, lrp.Index)}
+	}
+
+	differences := []string{}
+	for _, expectation := range matcher.expectations {
+		if !expectation.matches(lrp) {
+			differences = append(differences, fmt.Sprintf("    %s: %s (got %s)", expectation.field, expectation.expected, expectation.actual(lrp)))
+		}
+	}
+	return differences
+}
+
+func toActualLRP(actual interface{}) (*models.ActualLRP, error) {
+	switch lrp := actual.(type) {
+	case models.ActualLRP:
+		return &lrp, nil
+	case *models.ActualLRP:
+		if lrp != nil {
+			return lrp, nil
+		}
+	}
+	return nil, fmt.Errorf("ActualLRPMatcher matcher expects a models.ActualLRP.  Got:\n%s", format.Object(actual, 1))
+}
+
+func portMatches(mapping *models.PortMapping, expected models.PortMapping) bool {
+	return (expected.ContainerPort == 0 || mapping.ContainerPort == expected.ContainerPort) &&
+		(expected.HostPort == 0 || mapping.HostPort == expected.HostPort) &&
+		(expected.ContainerTlsProxyPort == 0 || mapping.ContainerTlsProxyPort == expected.ContainerTlsProxyPort) &&
+		(expected.HostTlsProxyPort == 0 || mapping.HostTlsProxyPort == expected.HostTlsProxyPort)
+}
+
+func describePort(mapping *models.PortMapping) string {
+	description := fmt.Sprintf("%d->%d", mapping.ContainerPort, mapping.HostPort)
+	if mapping.ContainerTlsProxyPort != 0 || mapping.HostTlsProxyPort != 0 {
+		description += fmt.Sprintf(" (tls %d->%d)", mapping.ContainerTlsProxyPort, mapping.HostTlsProxyPort)
+	}
+	return description
+}
+
+func describePorts(lrp *models.ActualLRP) string {
+	if len(lrp.Ports) == 0 {
+		return "none"
+	}
+	ports := make([]string, len(lrp.Ports))
+	for i, mapping := range lrp.Ports {
+		ports[i] = describePort(mapping)
+	}
+	return strings.Join(ports, ", ")
+}
+
+func describeTag(tag models.ModificationTag) string {
+	return fmt.Sprintf("%s:%d", tag.Epoch, tag.Index)
+}
+
+func containsAll(s string, substrings []string) bool {
+	for _, substring := range substrings {
+		if !strings.Contains(s, substring) {
+			return false
+		}
+	}
+	return true
+}
+
+func quoteAll(substrings []string) string {
+	quoted := make([]string, len(substrings))
+	for i, substring := range substrings {
+		quoted[i] = fmt.Sprintf("%q", substring)
+	}
+	return strings.Join(quoted, " and ")
+}
@@ -0,0 +1,120 @@
+package matchers_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+
+	. "code.cloudfoundry.org/vizzini/matchers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MatchActualLRP", func() {
+	var lrp models.ActualLRP
+
+	BeforeEach(func() {
+		lrp = models.ActualLRP{
+			ActualLRPKey:         models.NewActualLRPKey("guid", 1, "domain"),
+			ActualLRPInstanceKey: models.NewActualLRPInstanceKey("instance-guid", "cell-z1-0"),
+			ActualLRPNetInfo: models.ActualLRPNetInfo{
+				Address:         "10.0.0.1",
+				InstanceAddress: "10.255.100.2",
+				Ports: []*models.PortMapping{
+					{ContainerPort: 8080, HostPort: 61000, ContainerTlsProxyPort: 61001, HostTlsProxyPort: 61002},
+				},
+			},
+			State:           models.ActualLRPStateRunning,
+			Presence:        models.ActualLRP_Ordinary,
+			CrashCount:      2,
+			CrashReason:     "APP/PROC/WEB: Exited with status 17",
+			ModificationTag: models.ModificationTag{Epoch: "epoch", Index: 3},
+		}
+		lrp.SetRoutable(true)
+	})
+
+	It("matches by process guid and index", func() {
+		Expect(lrp).To(MatchActualLRP("guid", 1))
+		Expect(&lrp).To(MatchActualLRP("guid", 1))
+		Expect(lrp).NotTo(MatchActualLRP("guid", 0))
+		Expect(lrp).NotTo(MatchActualLRP("other-guid", 1))
+	})
+
+	It("matches every field it was given", func() {
+		Expect(lrp).To(MatchActualLRP("guid", 1).
+			WithState(models.ActualLRPStateRunning).
+			WithPresence(models.ActualLRP_Ordinary).
+			OnCell("cell-z1-0").
+			WithRoutable(true).
+			WithCrashCount(2).
+			ThatHasCrashed().
+			WithCrashReason("Exited", "status 17").
+			WithCrashReasonMatching(`status \d+$`).
+			WithPort(models.PortMapping{ContainerPort: 8080}).
+			WithTLSProxyPort(8080).
+			WithInstanceAddress("10.255.100.2").
+			WithModificationTag(models.ModificationTag{Epoch: "epoch", Index: 3}).
+			WithModificationTagAfter(models.ModificationTag{Epoch: "epoch", Index: 1}),
+		)
+	})
+
+	DescribeTable("fails when a field differs",
+		func(matcher *ActualLRPMatcher) {
+			Expect(lrp).NotTo(matcher)
+		},
+		Entry("state", MatchActualLRP("guid", 1).WithState(models.ActualLRPStateCrashed)),
+		Entry("presence", MatchActualLRP("guid", 1).WithPresence(models.ActualLRP_Evacuating)),
+		Entry("cell", MatchActualLRP("guid", 1).OnCell("cell-z2-0")),
+		Entry("routable", MatchActualLRP("guid", 1).WithRoutable(false)),
+		Entry("crash count", MatchActualLRP("guid", 1).WithCrashCount(3)),
+		Entry("crash reason", MatchActualLRP("guid", 1).WithCrashReason("Exited", "status 1 ")),
+		Entry("crash reason pattern", MatchActualLRP("guid", 1).WithCrashReasonMatching(`^Instance`)),
+		Entry("placement error", MatchActualLRP("guid", 1).WithPlacementError("")),
+		Entry("port", MatchActualLRP("guid", 1).WithPort(models.PortMapping{ContainerPort: 8080, HostPort: 1})),
+		Entry("tls proxy port", MatchActualLRP("guid", 1).WithTLSProxyPort(2222)),
+		Entry("instance address", MatchActualLRP("guid", 1).WithInstanceAddress("10.255.100.1")),
+		Entry("modification tag", MatchActualLRP("guid", 1).WithModificationTag(models.ModificationTag{Epoch: "epoch", Index: 4})),
+		Entry("modification tag epoch", MatchActualLRP("guid", 1).WithModificationTagAfter(models.ModificationTag{Epoch: "other", Index: 1})),
+	)
+
+	It("does not treat an unset Routable as false", func() {
+		unset := models.ActualLRP{ActualLRPKey: models.NewActualLRPKey("guid", 1, "domain")}
+		Expect(unset).NotTo(MatchActualLRP("guid", 1).WithRoutable(false))
+	})
+
+	It("lists only the fields that differ", func() {
+		matcher := MatchActualLRP("guid", 1).
+			WithState(models.ActualLRPStateRunning).
+			WithCrashCount(0).
+			OnCell("cell-z1-0").
+			WithCrashReason("never healthy")
+
+		Expect(matcher.FailureMessage(lrp)).To(Equal(
+			"Expected ActualLRP guid/1 to have:\n" +
+				"    CrashCount: 0 (got 2)\n" +
+				`    CrashReason: containing "never healthy" (got "APP/PROC/WEB: Exited with status 17")`,
+		))
+	})
+
+	It("reports a different process guid or index on its own", func() {
+		Expect(MatchActualLRP("guid", 0).WithState(models.ActualLRPStateCrashed).FailureMessage(lrp)).To(Equal(
+			"Expected ActualLRP guid/0 to have:\n    ProcessGuid/Index: guid/0 (got guid/1)",
+		))
+	})
+
+	It("does not change the matcher it was built from", func() {
+		base := MatchActualLRP("guid", 1)
+		_ = base.WithState(models.ActualLRPStateCrashed)
+		Expect(lrp).To(base)
+	})
+
+	It("describes what it expects", func() {
+		Expect(MatchActualLRP("guid", 1).String()).To(Equal("ActualLRP guid/1"))
+		Expect(MatchActualLRP("guid", 1).WithState(models.ActualLRPStateCrashed).WithCrashCount(3).String()).To(Equal(
+			"ActualLRP guid/1 with State CRASHED, CrashCount 3",
+		))
+	})
+
+	It("errors on anything but an ActualLRP", func() {
+		_, err := MatchActualLRP("guid", 1).Match("guid")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -161,6 +161,39 @@ func (matcher *ActualLRPInstanceChangedEventMatcher) NegatedFailureMessage(actua
 
 //
 
+// MatchActualLRPInstanceKeptStateEvent matches an ActualLRPInstanceChangedEvent
+// that leaves the instance's state alone, e.g. when it becomes routable.
+func MatchActualLRPInstanceKeptStateEvent(processGuid string, index int) gomega.OmegaMatcher {
+	return &ActualLRPInstanceKeptStateEventMatcher{
+		ProcessGuid: processGuid,
+		Index:       index,
+	}
+}
+
+type ActualLRPInstanceKeptStateEventMatcher struct {
+	ProcessGuid string
+	Index       int
+}
+
+func (matcher *ActualLRPInstanceKeptStateEventMatcher) Match(actual interface{}) (success bool, err error) {
+	event, ok := actual.(*models.ActualLRPInstanceChangedEvent)
+	if !ok {
+		return false, fmt.Errorf("ActualLRPInstanceKeptStateEventMatcher matcher expects a models.ActualLRPInstanceChangedEvent.  Got:\n%s", format.Object(actual, 1))
+	}
+	actualLRP := event.ActualLRPKey
+	return actualLRP.ProcessGuid == matcher.ProcessGuid && actualLRP.Index == int32(matcher.Index) && event.Before.State == event.After.State, nil
+}
+
+func (matcher *ActualLRPInstanceKeptStateEventMatcher) FailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected\n%s\nto be a ActualLRPInstanceChangedEvent that kept its state with\n  ProcessGuid=%s\n  Index=%d", format.Object(actual, 1), matcher.ProcessGuid, matcher.Index)
+}
+
+func (matcher *ActualLRPInstanceKeptStateEventMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected\n%s\nnot to be a ActualLRPInstanceChangedEvent that kept its state with\n  ProcessGuid=%s\n  Index=%d", format.Object(actual, 1), matcher.ProcessGuid, matcher.Index)
+}
+
+//
+
 func MatchActualLRPInstanceRemovedEvent(processGuid string, index int) gomega.OmegaMatcher {
 	return &ActualLRPInstanceRemovedEventMatcher{
 		ProcessGuid: processGuid,
@@ -189,3 +222,140 @@ func (matcher *ActualLRPInstanceRemovedEventMatcher) FailureMessage(actual inter
 func (matcher *ActualLRPInstanceRemovedEventMatcher) NegatedFailureMessage(actual interface{}) (message string) {
 	return fmt.Sprintf("Expected\n%s\nnot to be a ActualLRPInstanceRemovedEvent with\n  ProcessGuid=%s\n  Index=%d", format.Object(actual, 1), matcher.ProcessGuid, matcher.Index)
 }
+
+//
+
+func MatchTaskCreatedEvent(taskGuid string) gomega.OmegaMatcher {
+	return &TaskCreatedEventMatcher{
+		TaskGuid: taskGuid,
+	}
+}
+
+type TaskCreatedEventMatcher struct {
+	TaskGuid string
+}
+
+func (matcher *TaskCreatedEventMatcher) Match(actual interface{}) (success bool, err error) {
+	event, ok := actual.(*models.TaskCreatedEvent)
+	if !ok {
+		return false, fmt.Errorf("TaskCreatedEventMatcher matcher expects a models.TaskCreatedEvent.  Got:\n%s", describeTaskEvent(actual))
+	}
+	return event.Task.TaskGuid == matcher.TaskGuid, nil
+}
+
+func (matcher *TaskCreatedEventMatcher) FailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected\n%s\nto be a TaskCreatedEvent with\n  TaskGuid=%s", describeTaskEvent(actual), matcher.TaskGuid)
+}
+
+func (matcher *TaskCreatedEventMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected\n%s\nnot to be a TaskCreatedEvent with\n  TaskGuid=%s", describeTaskEvent(actual), matcher.TaskGuid)
+}
+
+//
+
+func MatchTaskChangedEvent(taskGuid string, from, to models.Task_State) gomega.OmegaMatcher {
+	return &TaskChangedEventMatcher{
+		TaskGuid: taskGuid,
+		From:     from,
+		To:       to,
+	}
+}
+
+type TaskChangedEventMatcher struct {
+	TaskGuid string
+	From     models.Task_State
+	To       models.Task_State
+}
+
+func (matcher *TaskChangedEventMatcher) Match(actual interface{}) (success bool, err error) {
+	event, ok := actual.(*models.TaskChangedEvent)
+	if !ok {
+		return false, fmt.Errorf("TaskChangedEventMatcher matcher expects a models.TaskChangedEvent.  Got:\n%s", describeTaskEvent(actual))
+	}
+	return event.After.TaskGuid == matcher.TaskGuid && event.Before.State == matcher.From && event.After.State == matcher.To, nil
+}
+
+func (matcher *TaskChangedEventMatcher) FailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected\n%s\nto be a TaskChangedEvent with\n  TaskGuid=%s\n  State=%s -> %s", describeTaskEvent(actual), matcher.TaskGuid, matcher.From, matcher.To)
+}
+
+func (matcher *TaskChangedEventMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected\n%s\nnot to be a TaskChangedEvent with\n  TaskGuid=%s\n  State=%s -> %s", describeTaskEvent(actual), matcher.TaskGuid, matcher.From, matcher.To)
+}
+
+//
+
+// MatchTaskKeptStateEvent matches a TaskChangedEvent that leaves the task's
+// state alone, e.g. when a rejected task is put back to be placed again.
+func MatchTaskKeptStateEvent(taskGuid string) gomega.OmegaMatcher {
+	return &TaskKeptStateEventMatcher{
+		TaskGuid: taskGuid,
+	}
+}
+
+type TaskKeptStateEventMatcher struct {
+	TaskGuid string
+}
+
+func (matcher *TaskKeptStateEventMatcher) Match(actual interface{}) (success bool, err error) {
+	event, ok := actual.(*models.TaskChangedEvent)
+	if !ok {
+		return false, fmt.Errorf("TaskKeptStateEventMatcher matcher expects a models.TaskChangedEvent.  Got:\n%s", describeTaskEvent(actual))
+	}
+	return event.After.TaskGuid == matcher.TaskGuid && event.Before.State == event.After.State, nil
+}
+
+func (matcher *TaskKeptStateEventMatcher) FailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected\n%s\nto be a TaskChangedEvent that kept its state with\n  TaskGuid=%s", describeTaskEvent(actual), matcher.TaskGuid)
+}
+
+func (matcher *TaskKeptStateEventMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected\n%s\nnot to be a TaskChangedEvent that kept its state with\n  TaskGuid=%s", describeTaskEvent(actual), matcher.TaskGuid)
+}
+
+//
+
+func MatchTaskRemovedEvent(taskGuid string) gomega.OmegaMatcher {
+	return &TaskRemovedEventMatcher{
+		TaskGuid: taskGuid,
+	}
+}
+
+type TaskRemovedEventMatcher struct {
+	TaskGuid string
+}
+
+func (matcher *TaskRemovedEventMatcher) Match(actual interface{}) (success bool, err error) {
+	event, ok := actual.(*models.TaskRemovedEvent)
+	if !ok {
+		return false, fmt.Errorf("TaskRemovedEventMatcher matcher expects a models.TaskRemovedEvent.  Got:\n%s", describeTaskEvent(actual))
+	}
+	return event.Task.TaskGuid == matcher.TaskGuid, nil
+}
+
+func (matcher *TaskRemovedEventMatcher) FailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected\n%s\nto be a TaskRemovedEvent with\n  TaskGuid=%s", describeTaskEvent(actual), matcher.TaskGuid)
+}
+
+func (matcher *TaskRemovedEventMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected\n%s\nnot to be a TaskRemovedEvent with\n  TaskGuid=%s", describeTaskEvent(actual), matcher.TaskGuid)
+}
+
+// describeTaskEvent shows a task event as its type, guid and state change
+// rather than dumping the whole Task, whose definition drowns out the part
+// that failed to match.
+func describeTaskEvent(actual interface{}) string {
+	switch event := actual.(type) {
+	case *models.TaskCreatedEvent:
+		return fmt.Sprintf("    TaskCreatedEvent TaskGuid=%s State=%s", event.Task.TaskGuid, event.Task.State)
+	case *models.TaskChangedEvent:
+		description := fmt.Sprintf("    TaskChangedEvent TaskGuid=%s State=%s -> %s", event.After.TaskGuid, event.Before.State, event.After.State)
+		if event.After.Failed {
+			description += fmt.Sprintf(" FailureReason=%q", event.After.FailureReason)
+		}
+		return description
+	case *models.TaskRemovedEvent:
+		return fmt.Sprintf("    TaskRemovedEvent TaskGuid=%s State=%s", event.Task.TaskGuid, event.Task.State)
+	}
+	return format.Object(actual, 1)
+}
@@ -0,0 +1,69 @@
+package matchers_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+
+	. "code.cloudfoundry.org/vizzini/matchers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Task event matchers", func() {
+	task := func(state models.Task_State) *models.Task {
+		return &models.Task{TaskGuid: "task-guid", State: state, TaskDefinition: &models.TaskDefinition{}}
+	}
+
+	It("matches TaskCreatedEvents", func() {
+		event := &models.TaskCreatedEvent{Task: task(models.Task_Pending)}
+		Expect(event).To(MatchTaskCreatedEvent("task-guid"))
+		Expect(event).NotTo(MatchTaskCreatedEvent("other-guid"))
+
+		_, err := MatchTaskCreatedEvent("task-guid").Match(&models.TaskRemovedEvent{Task: task(models.Task_Resolving)})
+		Expect(err).To(MatchError(ContainSubstring("TaskRemovedEvent TaskGuid=task-guid State=Resolving")))
+	})
+
+	It("matches TaskChangedEvents by transition", func() {
+		failed := task(models.Task_Completed)
+		failed.Failed = true
+		failed.FailureReason = "task was cancelled"
+		event := &models.TaskChangedEvent{Before: task(models.Task_Running), After: failed}
+
+		Expect(event).To(MatchTaskChangedEvent("task-guid", models.Task_Running, models.Task_Completed))
+		Expect(event).NotTo(MatchTaskChangedEvent("task-guid", models.Task_Pending, models.Task_Completed))
+		Expect(event).NotTo(MatchTaskChangedEvent("other-guid", models.Task_Running, models.Task_Completed))
+
+		matcher := MatchTaskChangedEvent("task-guid", models.Task_Pending, models.Task_Running)
+		Expect(matcher.FailureMessage(event)).To(Equal(
+			"Expected\n    TaskChangedEvent TaskGuid=task-guid State=Running -> Completed FailureReason=\"task was cancelled\"\n" +
+				"to be a TaskChangedEvent with\n  TaskGuid=task-guid\n  State=Pending -> Running",
+		))
+	})
+
+	It("matches TaskChangedEvents that keep the state", func() {
+		Expect(&models.TaskChangedEvent{Before: task(models.Task_Pending), After: task(models.Task_Pending)}).To(MatchTaskKeptStateEvent("task-guid"))
+		Expect(&models.TaskChangedEvent{Before: task(models.Task_Pending), After: task(models.Task_Running)}).NotTo(MatchTaskKeptStateEvent("task-guid"))
+		Expect(&models.TaskChangedEvent{Before: task(models.Task_Pending), After: task(models.Task_Pending)}).NotTo(MatchTaskKeptStateEvent("other-guid"))
+	})
+
+	It("matches TaskRemovedEvents", func() {
+		event := &models.TaskRemovedEvent{Task: task(models.Task_Resolving)}
+		Expect(event).To(MatchTaskRemovedEvent("task-guid"))
+		Expect(event).NotTo(MatchTaskRemovedEvent("other-guid"))
+	})
+})
+
+var _ = Describe("ActualLRP instance event matchers", func() {
+	It("matches ActualLRPInstanceChangedEvents that keep the state", func() {
+		changed := func(before, after string) *models.ActualLRPInstanceChangedEvent {
+			return &models.ActualLRPInstanceChangedEvent{
+				ActualLRPKey: models.NewActualLRPKey("guid", 0, "domain"),
+				Before:       &models.ActualLRPInfo{State: before},
+				After:        &models.ActualLRPInfo{State: after},
+			}
+		}
+
+		Expect(changed(models.ActualLRPStateRunning, models.ActualLRPStateRunning)).To(MatchActualLRPInstanceKeptStateEvent("guid", 0))
+		Expect(changed(models.ActualLRPStateClaimed, models.ActualLRPStateRunning)).NotTo(MatchActualLRPInstanceKeptStateEvent("guid", 0))
+		Expect(changed(models.ActualLRPStateRunning, models.ActualLRPStateRunning)).NotTo(MatchActualLRPInstanceKeptStateEvent("guid", 1))
+	})
+})
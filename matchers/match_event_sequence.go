@@ -0,0 +1,133 @@
+package matchers
+
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/vizzini/forensics"
+	"github.com/onsi/gomega"
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
+)
+
+// MatchEventSequence succeeds when a []models.Event consists of exactly one
+// event per matcher, in order, with nothing in between but the events it is
+// told to be Ignoring.  Once an event fails to match, Eventually gives up
+// instead of waiting out its timeout.
+func MatchEventSequence(matchers ...types.GomegaMatcher) *EventSequenceMatcher {
+	return &EventSequenceMatcher{
+		Matchers: matchers,
+		Strict:   true,
+	}
+}
+
+// ContainEventsInOrder succeeds when a []models.Event contains an event for
+// each matcher, in order, allowing other events in between.
+func ContainEventsInOrder(matchers ...types.GomegaMatcher) gomega.OmegaMatcher {
+	return &EventSequenceMatcher{
+		Matchers: matchers,
+	}
+}
+
+type EventSequenceMatcher struct {
+	Matchers []types.GomegaMatcher
+	Strict   bool
+
+	// Ignored events may come anywhere in a strict sequence, e.g. instance
+	// changes that leave the state alone.
+	Ignored []types.GomegaMatcher
+
+	// set by Match for the failure message
+	matched    int
+	unexpected int
+}
+
+func (matcher *EventSequenceMatcher) Match(actual interface{}) (success bool, err error) {
+	events, ok := actual.([]models.Event)
+	if !ok {
+		return false, fmt.Errorf("EventSequenceMatcher matcher expects a []models.Event.  Got:\n%s", format.Object(actual, 1))
+	}
+
+	matcher.matched, matcher.unexpected = 0, -1
+	for i, event := range events {
+		if matcher.matched < len(matcher.Matchers) && matchesEvent(matcher.Matchers[matcher.matched], event) {
+			matcher.matched++
+			continue
+		}
+		if matcher.Strict && !matcher.ignores(event) {
+			matcher.unexpected = i
+			return false, nil
+		}
+	}
+	return matcher.matched == len(matcher.Matchers), nil
+}
+
+// Ignoring lets events that match any of matchers come anywhere in a strict
+// sequence, e.g. the ActualLRPInstanceChangedEvent the BBS sends when an
+// instance becomes routable.
+func (matcher *EventSequenceMatcher) Ignoring(matchers ...types.GomegaMatcher) *EventSequenceMatcher {
+	matcher.Ignored = append(matcher.Ignored, matchers...)
+	return matcher
+}
+
+func (matcher *EventSequenceMatcher) ignores(event models.Event) bool {
+	for _, ignored := range matcher.Ignored {
+		if matchesEvent(ignored, event) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchMayChangeInTheFuture tells Eventually whether more events could still
+// make the sequence match.  Events are only ever appended, so a strict
+// sequence that has seen an unexpected event can never match.
+func (matcher *EventSequenceMatcher) MatchMayChangeInTheFuture(actual interface{}) bool {
+	return !matcher.Strict || matcher.unexpected < 0
+}
+
+func (matcher *EventSequenceMatcher) FailureMessage(actual interface{}) (message string) {
+	if matcher.unexpected >= 0 {
+		return fmt.Sprintf("Expected events\n%s\nto match the sequence, but event %d was unexpected; wanted\n%s", describeEvents(actual), matcher.unexpected, describeMatchers(matcher.Matchers[matcher.matched:]))
+	}
+	return fmt.Sprintf("Expected events\n%s\nto %s the sequence, but these never arrived\n%s", describeEvents(actual), matcher.verb(), describeMatchers(matcher.Matchers[matcher.matched:]))
+}
+
+func (matcher *EventSequenceMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	return fmt.Sprintf("Expected events\n%s\nnot to %s the sequence\n%s", describeEvents(actual), matcher.verb(), describeMatchers(matcher.Matchers))
+}
+
+func (matcher *EventSequenceMatcher) verb() string {
+	if matcher.Strict {
+		return "match"
+	}
+	return "contain"
+}
+
+// matchesEvent treats an error, which the event matchers return for events of
+// the wrong type, as a mismatch.
+func matchesEvent(matcher types.GomegaMatcher, event models.Event) bool {
+	success, err := matcher.Match(event)
+	return err == nil && success
+}
+
+func describeEvents(actual interface{}) string {
+	events, _ := actual.([]models.Event)
+	if len(events) == 0 {
+		return "    <none>"
+	}
+	lines := make([]string, len(events))
+	for i, event := range events {
+		lines[i] = fmt.Sprintf("    %d: %s %s %s", i, event.EventType(), forensics.GuidFor(event), forensics.Summarize(event))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func describeMatchers(matchers []types.GomegaMatcher) string {
+	lines := make([]string, len(matchers))
+	for i, matcher := range matchers {
+		lines[i] = fmt.Sprintf("    %T %+v", matcher, matcher)
+	}
+	return strings.Join(lines, "\n")
+}
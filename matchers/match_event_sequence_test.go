@@ -0,0 +1,96 @@
+package matchers_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+
+	. "code.cloudfoundry.org/vizzini/matchers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MatchEventSequence", func() {
+	var created, claimed, running, removed models.Event
+
+	BeforeEach(func() {
+		created = &models.ActualLRPInstanceCreatedEvent{
+			ActualLrp: &models.ActualLRP{ActualLRPKey: models.NewActualLRPKey("guid", 0, "domain"), State: models.ActualLRPStateUnclaimed},
+		}
+		claimed = instanceChanged(models.ActualLRPStateUnclaimed, models.ActualLRPStateClaimed)
+		running = instanceChanged(models.ActualLRPStateClaimed, models.ActualLRPStateRunning)
+		removed = &models.ActualLRPInstanceRemovedEvent{
+			ActualLrp: &models.ActualLRP{ActualLRPKey: models.NewActualLRPKey("guid", 0, "domain"), State: models.ActualLRPStateRunning},
+		}
+	})
+
+	It("matches events in order with nothing in between", func() {
+		Expect([]models.Event{created, claimed, running, removed}).To(MatchEventSequence(
+			MatchActualLRPInstanceCreatedEvent("guid", 0),
+			MatchActualLRPInstanceChangedEvent("guid", 0, models.ActualLRPStateClaimed),
+			MatchActualLRPInstanceChangedEvent("guid", 0, models.ActualLRPStateRunning),
+			MatchActualLRPInstanceRemovedEvent("guid", 0),
+		))
+	})
+
+	It("fails on missing, reordered or unexpected events", func() {
+		sequence := MatchEventSequence(
+			MatchActualLRPInstanceCreatedEvent("guid", 0),
+			MatchActualLRPInstanceChangedEvent("guid", 0, models.ActualLRPStateRunning),
+		)
+
+		Expect([]models.Event{created}).NotTo(sequence)
+		Expect(sequence.FailureMessage([]models.Event{created})).To(ContainSubstring("never arrived"))
+
+		Expect([]models.Event{running, created}).NotTo(sequence)
+		Expect([]models.Event{created, claimed, running}).NotTo(sequence)
+		Expect(sequence.FailureMessage([]models.Event{created, claimed, running})).To(ContainSubstring("event 1 was unexpected"))
+	})
+
+	It("stops Eventually once an unexpected event arrives", func() {
+		sequence := &EventSequenceMatcher{Strict: true}
+		sequence.Matchers = append(sequence.Matchers, MatchActualLRPInstanceCreatedEvent("guid", 0), MatchActualLRPInstanceRemovedEvent("guid", 0))
+
+		Expect(sequence.Match([]models.Event{created})).To(BeFalse())
+		Expect(sequence.MatchMayChangeInTheFuture([]models.Event{created})).To(BeTrue())
+
+		Expect(sequence.Match([]models.Event{created, claimed})).To(BeFalse())
+		Expect(sequence.MatchMayChangeInTheFuture([]models.Event{created, claimed})).To(BeFalse())
+	})
+
+	It("skips the events it is told to ignore, and only those", func() {
+		routable := instanceChanged(models.ActualLRPStateRunning, models.ActualLRPStateRunning)
+		sequence := MatchEventSequence(
+			MatchActualLRPInstanceCreatedEvent("guid", 0),
+			MatchActualLRPInstanceChangedEvent("guid", 0, models.ActualLRPStateClaimed),
+			MatchActualLRPInstanceChangedEvent("guid", 0, models.ActualLRPStateRunning),
+			MatchActualLRPInstanceRemovedEvent("guid", 0),
+		).Ignoring(MatchActualLRPInstanceKeptStateEvent("guid", 0))
+
+		Expect([]models.Event{created, claimed, running, routable, removed}).To(sequence)
+		Expect([]models.Event{created, claimed, running, routable, routable}).NotTo(sequence)
+		Expect(sequence.MatchMayChangeInTheFuture([]models.Event{created, claimed, running, routable, routable})).To(BeTrue())
+
+		Expect([]models.Event{created, running, claimed, removed}).NotTo(sequence)
+		Expect(sequence.FailureMessage([]models.Event{created, running, claimed, removed})).To(ContainSubstring("event 1 was unexpected"))
+	})
+
+	Describe("ContainEventsInOrder", func() {
+		It("allows other events in between", func() {
+			Expect([]models.Event{created, claimed, running, removed}).To(ContainEventsInOrder(
+				MatchActualLRPInstanceCreatedEvent("guid", 0),
+				MatchActualLRPInstanceRemovedEvent("guid", 0),
+			))
+			Expect([]models.Event{removed, created}).NotTo(ContainEventsInOrder(
+				MatchActualLRPInstanceCreatedEvent("guid", 0),
+				MatchActualLRPInstanceRemovedEvent("guid", 0),
+			))
+		})
+	})
+})
+
+func instanceChanged(before, after string) *models.ActualLRPInstanceChangedEvent {
+	return &models.ActualLRPInstanceChangedEvent{
+		ActualLRPKey: models.NewActualLRPKey("guid", 0, "domain"),
+		Before:       &models.ActualLRPInfo{State: before},
+		After:        &models.ActualLRPInfo{State: after},
+	}
+}
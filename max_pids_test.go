@@ -2,7 +2,10 @@ package vizzini_test
 
 import (
 	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/vizzini/capabilities"
+	"code.cloudfoundry.org/vizzini/fixtures"
 	. "code.cloudfoundry.org/vizzini/matchers"
+	"code.cloudfoundry.org/vizzini/profiles"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -29,7 +32,7 @@ var _ = Describe("MaxPids", func() {
 				It("should start succesfully", func() {
 					lrp.MaxPids = 1024
 					Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-					Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithState(guid, 0, models.ActualLRPStateRunning))
+					WaitForActualLRPState(guid, 0, models.ActualLRPStateRunning)
 				})
 			})
 			Context("when the max pids is 0", func() {
@@ -37,7 +40,7 @@ var _ = Describe("MaxPids", func() {
 				It("should not crash, but should start succesfully", func() {
 					lrp.MaxPids = 0
 					Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-					Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPWithState(guid, 0, models.ActualLRPStateRunning))
+					WaitForActualLRPState(guid, 0, models.ActualLRPStateRunning)
 				})
 			})
 		})
@@ -46,12 +49,12 @@ var _ = Describe("MaxPids", func() {
 			It("should crash when the limits is low positive integer", func() {
 				lrp.MaxPids = 1
 				Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-				Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPThatHasCrashed(guid, 0))
+				WaitForActualLRP(MatchActualLRP(guid, 0).ThatHasCrashed())
 
 				//getting all the way helps ensure the tests don't spuriously fail
 				//when we delete the DesiredLRP if the application is in the middle of restarting it looks like we need to wiat for a convergence
 				//loop to eventually clean it up.  This is likely a bug, though it's not crticial.
-				Eventually(ActualGetter(logger, guid, 0), ConvergerInterval).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateCrashed, 3))
+				WaitForActualLRP(MatchActualLRP(guid, 0).WithState(models.ActualLRPStateCrashed).WithCrashCount(3), ConvergerInterval)
 			})
 			It("should fail call to bbs when the limit is negative integer", func() {
 				lrp.MaxPids = -1
@@ -59,23 +62,26 @@ var _ = Describe("MaxPids", func() {
 			})
 		})
 	})
-	Describe("{DOCKER} with a docker-image rootfs", func() {
+	Describe("with a docker-image rootfs", Label(profiles.Docker), func() {
 		BeforeEach(func() {
-			lrp.RootFs = config.GraceBusyboxImageURL
-			lrp.Setup = nil //note: we copy nothing in, the docker image on its own should cause this failure
-			lrp.Action = models.WrapAction(&models.RunAction{
-				Path: "/grace",
-				User: "root",
-				Env:  []*models.EnvironmentVariable{{Name: "PORT", Value: "8080"}},
-			})
-			lrp.Monitor = nil
+			RequireCapabilities(capabilities.Docker)
+			//note: we copy nothing in, the docker image on its own should cause this failure
+			lrp = DesiredLRPWithGuid(guid,
+				fixtures.WithDockerImage(config.GraceBusyboxImageURL),
+				fixtures.WithAction(&models.RunAction{
+					Path: "/grace",
+					User: "root",
+					Env:  []*models.EnvironmentVariable{{Name: "PORT", Value: "8080"}},
+				}),
+				fixtures.WithoutMonitor(),
+			)
 		})
 		Context("when the max pids exceeds the required number of processes", func() {
 			Context("when the max pids is a large positive integer", func() {
 				It("should start succesfully", func() {
 					lrp.MaxPids = 1024
 					Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-					Eventually(ActualGetter(logger, guid, 0), dockerTimeout).Should(BeActualLRPWithState(guid, 0, models.ActualLRPStateRunning))
+					WaitForActualLRPState(guid, 0, models.ActualLRPStateRunning, dockerTimeout)
 				})
 			})
 			Context("when the max pids is 0", func() {
@@ -83,7 +89,7 @@ var _ = Describe("MaxPids", func() {
 				It("should not crash, but should start succesfully", func() {
 					lrp.MaxPids = 0
 					Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-					Eventually(ActualGetter(logger, guid, 0), dockerTimeout).Should(BeActualLRPWithState(guid, 0, models.ActualLRPStateRunning))
+					WaitForActualLRPState(guid, 0, models.ActualLRPStateRunning, dockerTimeout)
 				})
 			})
 		})
@@ -92,12 +98,12 @@ var _ = Describe("MaxPids", func() {
 			It("should crash when the limits is low positive integer", func() {
 				lrp.MaxPids = 1
 				Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-				Eventually(ActualGetter(logger, guid, 0)).Should(BeActualLRPThatHasCrashed(guid, 0))
+				WaitForActualLRP(MatchActualLRP(guid, 0).ThatHasCrashed())
 
 				//getting all the way helps ensure the tests don't spuriously fail
 				//when we delete the DesiredLRP if the application is in the middle of restarting it looks like we need to wiat for a convergence
 				//loop to eventually clean it up.  This is likely a bug, though it's not crticial.
-				Eventually(ActualGetter(logger, guid, 0), ConvergerInterval).Should(BeActualLRPWithStateAndCrashCount(guid, 0, models.ActualLRPStateCrashed, 3))
+				WaitForActualLRP(MatchActualLRP(guid, 0).WithState(models.ActualLRPStateCrashed).WithCrashCount(3), ConvergerInterval)
 			})
 			It("should fail call to bbs when the limit is negative integer", func() {
 				lrp.MaxPids = -1
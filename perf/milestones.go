@@ -0,0 +1,37 @@
+package perf
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/vizzini/eventstream"
+)
+
+// ActualLRPReached returns when the recorder first saw instance index of an
+// LRP change to state.
+func ActualLRPReached(timeline []eventstream.RecordedEvent, index int32, state string) (time.Time, bool) {
+	for _, recorded := range timeline {
+		event, ok := recorded.Event.(*models.ActualLRPInstanceChangedEvent)
+		if !ok || event.ActualLRPKey.Index != index || event.After == nil {
+			continue
+		}
+		if event.After.State == state {
+			return recorded.At, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// TaskReached returns when the recorder first saw a Task change to state.
+func TaskReached(timeline []eventstream.RecordedEvent, state models.Task_State) (time.Time, bool) {
+	for _, recorded := range timeline {
+		event, ok := recorded.Event.(*models.TaskChangedEvent)
+		if !ok || event.After == nil {
+			continue
+		}
+		if event.After.State == state {
+			return recorded.At, true
+		}
+	}
+	return time.Time{}, false
+}
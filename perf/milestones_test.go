@@ -0,0 +1,70 @@
+package perf_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/vizzini/eventstream"
+	"code.cloudfoundry.org/vizzini/perf"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Milestones", func() {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	at := func(seconds int) time.Time {
+		return start.Add(time.Duration(seconds) * time.Second)
+	}
+
+	instanceChanged := func(index int32, from, to string) models.Event {
+		return &models.ActualLRPInstanceChangedEvent{
+			ActualLRPKey: models.NewActualLRPKey("guid", index, "domain"),
+			Before:       &models.ActualLRPInfo{State: from},
+			After:        &models.ActualLRPInfo{State: to},
+		}
+	}
+
+	taskChanged := func(from, to models.Task_State) models.Event {
+		return &models.TaskChangedEvent{
+			Before: &models.Task{TaskGuid: "guid", State: from},
+			After:  &models.Task{TaskGuid: "guid", State: to},
+		}
+	}
+
+	It("finds when an ActualLRP instance first reached a state", func() {
+		timeline := []eventstream.RecordedEvent{
+			{Event: &models.ActualLRPInstanceCreatedEvent{ActualLrp: &models.ActualLRP{ActualLRPKey: models.NewActualLRPKey("guid", 0, "domain")}}, At: at(0)},
+			{Event: instanceChanged(1, models.ActualLRPStateUnclaimed, models.ActualLRPStateClaimed), At: at(1)},
+			{Event: instanceChanged(0, models.ActualLRPStateUnclaimed, models.ActualLRPStateClaimed), At: at(2)},
+			{Event: instanceChanged(0, models.ActualLRPStateClaimed, models.ActualLRPStateRunning), At: at(3)},
+			{Event: instanceChanged(0, models.ActualLRPStateRunning, models.ActualLRPStateClaimed), At: at(4)},
+		}
+
+		claimedAt, ok := perf.ActualLRPReached(timeline, 0, models.ActualLRPStateClaimed)
+		Expect(ok).To(BeTrue())
+		Expect(claimedAt).To(Equal(at(2)))
+
+		runningAt, ok := perf.ActualLRPReached(timeline, 0, models.ActualLRPStateRunning)
+		Expect(ok).To(BeTrue())
+		Expect(runningAt).To(Equal(at(3)))
+
+		_, ok = perf.ActualLRPReached(timeline, 1, models.ActualLRPStateRunning)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("finds when a Task first reached a state", func() {
+		timeline := []eventstream.RecordedEvent{
+			{Event: &models.TaskCreatedEvent{Task: &models.Task{TaskGuid: "guid", State: models.Task_Pending}}, At: at(0)},
+			{Event: taskChanged(models.Task_Pending, models.Task_Running), At: at(1)},
+			{Event: taskChanged(models.Task_Running, models.Task_Completed), At: at(5)},
+		}
+
+		completedAt, ok := perf.TaskReached(timeline, models.Task_Completed)
+		Expect(ok).To(BeTrue())
+		Expect(completedAt).To(Equal(at(5)))
+
+		_, ok = perf.TaskReached(timeline, models.Task_Resolving)
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -0,0 +1 @@
+package perf // import "code.cloudfoundry.org/vizzini/perf"
@@ -0,0 +1,185 @@
+package perf
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/onsi/ginkgo/v2/types"
+	"github.com/onsi/gomega/gmeasure"
+)
+
+// EntryName is the name of the Ginkgo report entry each experiment is
+// recorded under, e.g. AddReportEntry(perf.EntryName, experiment).
+const EntryName = "vizzini-perf"
+
+const JSONFileName = "vizzini-perf.json"
+
+// Percentiles are the percentiles reported for every duration.
+var Percentiles = []float64{50, 90, 95, 99}
+
+type Results struct {
+	Suite       string       `json:"suite"`
+	StartTime   time.Time    `json:"start_time"`
+	Experiments []Experiment `json:"experiments"`
+}
+
+type Experiment struct {
+	Name         string      `json:"name"`
+	Spec         string      `json:"spec"`
+	Measurements []Durations `json:"measurements"`
+}
+
+// Durations summarizes one duration measurement.  Every time is in seconds so
+// runs against different diego-release versions can be compared directly.
+type Durations struct {
+	Name        string             `json:"name"`
+	Samples     int                `json:"samples"`
+	Min         float64            `json:"min_seconds"`
+	Max         float64            `json:"max_seconds"`
+	Mean        float64            `json:"mean_seconds"`
+	StdDev      float64            `json:"stddev_seconds"`
+	Percentiles map[string]float64 `json:"percentiles_seconds"`
+}
+
+// Summarize reduces the duration measurements of experiment to their
+// percentiles.  Value measurements and notes are left out.
+func Summarize(experiment gmeasure.Experiment) Experiment {
+	summary := Experiment{
+		Name:         experiment.Name,
+		Measurements: []Durations{},
+	}
+	for _, measurement := range experiment.Measurements {
+		if measurement.Type != gmeasure.MeasurementTypeDuration || len(measurement.Durations) == 0 {
+			continue
+		}
+		summary.Measurements = append(summary.Measurements, SummarizeDurations(measurement.Name, measurement.Durations))
+	}
+	return summary
+}
+
+func SummarizeDurations(name string, durations []time.Duration) Durations {
+	sorted := append([]time.Duration{}, durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, duration := range sorted {
+		total += duration
+	}
+	mean := total.Seconds() / float64(len(sorted))
+
+	var squares float64
+	for _, duration := range sorted {
+		squares += (duration.Seconds() - mean) * (duration.Seconds() - mean)
+	}
+
+	summary := Durations{
+		Name:        name,
+		Samples:     len(sorted),
+		Min:         sorted[0].Seconds(),
+		Max:         sorted[len(sorted)-1].Seconds(),
+		Mean:        mean,
+		StdDev:      math.Sqrt(squares / float64(len(sorted))),
+		Percentiles: map[string]float64{},
+	}
+	for _, p := range Percentiles {
+		summary.Percentiles[fmt.Sprintf("p%g", p)] = Percentile(sorted, p).Seconds()
+	}
+	return summary
+}
+
+// Percentile returns the nearest-rank pth percentile of sorted, which must be
+// in ascending order.
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// Build collects every experiment recorded under EntryName in the Ginkgo
+// report for the whole suite, including those recorded on other parallel
+// processes.
+func Build(ginkgoReport types.Report) (Results, error) {
+	results := Results{
+		Suite:       ginkgoReport.SuiteDescription,
+		StartTime:   ginkgoReport.StartTime,
+		Experiments: []Experiment{},
+	}
+
+	for _, specReport := range ginkgoReport.SpecReports {
+		for _, reportEntry := range specReport.ReportEntries {
+			if reportEntry.Name != EntryName {
+				continue
+			}
+			experiment, err := decodeExperiment(reportEntry)
+			if err != nil {
+				return Results{}, fmt.Errorf("failed to decode experiment for %q: %s", specReport.FullText(), err.Error())
+			}
+			summary := Summarize(experiment)
+			summary.Spec = specReport.FullText()
+			results.Experiments = append(results.Experiments, summary)
+		}
+	}
+
+	return results, nil
+}
+
+// decodeExperiment reads a gmeasure.Experiment back out of a report entry.
+// Entries recorded on other parallel processes arrive as JSON, so local ones
+// go through the same encoding.
+func decodeExperiment(reportEntry types.ReportEntry) (gmeasure.Experiment, error) {
+	wrapped, err := json.Marshal(reportEntry.Value)
+	if err != nil {
+		return gmeasure.Experiment{}, err
+	}
+	var value struct {
+		AsJSON string
+	}
+	err = json.Unmarshal(wrapped, &value)
+	if err != nil {
+		return gmeasure.Experiment{}, err
+	}
+
+	var experiment gmeasure.Experiment
+	err = json.Unmarshal([]byte(value.AsJSON), &experiment)
+	return experiment, err
+}
+
+// Write puts the summarized experiments in ginkgoReport into dir.  Nothing is
+// written if no experiments ran.
+func Write(dir string, ginkgoReport types.Report) error {
+	results, err := Build(ginkgoReport)
+	if err != nil {
+		return err
+	}
+	if len(results.Experiments) == 0 {
+		return nil
+	}
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create report directory: %s", err.Error())
+	}
+
+	contents, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode perf results: %s", err.Error())
+	}
+	err = os.WriteFile(filepath.Join(dir, JSONFileName), contents, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write perf results: %s", err.Error())
+	}
+	return nil
+}
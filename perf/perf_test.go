@@ -0,0 +1,124 @@
+package perf_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/vizzini/perf"
+	"github.com/onsi/ginkgo/v2/types"
+	"github.com/onsi/gomega/gmeasure"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Perf", func() {
+	seconds := func(values ...int) []time.Duration {
+		durations := []time.Duration{}
+		for _, value := range values {
+			durations = append(durations, time.Duration(value)*time.Second)
+		}
+		return durations
+	}
+
+	Describe("Percentile", func() {
+		It("returns the nearest-rank percentile", func() {
+			sorted := seconds(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+			Expect(perf.Percentile(sorted, 50)).To(Equal(5 * time.Second))
+			Expect(perf.Percentile(sorted, 90)).To(Equal(9 * time.Second))
+			Expect(perf.Percentile(sorted, 95)).To(Equal(10 * time.Second))
+			Expect(perf.Percentile(sorted, 0)).To(Equal(time.Second))
+			Expect(perf.Percentile(sorted[:1], 99)).To(Equal(time.Second))
+			Expect(perf.Percentile(nil, 50)).To(BeZero())
+		})
+	})
+
+	Describe("SummarizeDurations", func() {
+		It("reports the spread in seconds without reordering its input", func() {
+			durations := seconds(4, 2, 6, 8)
+			summary := perf.SummarizeDurations("desire-to-running", durations)
+
+			Expect(summary.Name).To(Equal("desire-to-running"))
+			Expect(summary.Samples).To(Equal(4))
+			Expect(summary.Min).To(Equal(2.0))
+			Expect(summary.Max).To(Equal(8.0))
+			Expect(summary.Mean).To(Equal(5.0))
+			Expect(summary.StdDev).To(BeNumerically("~", 2.236, 0.001))
+			Expect(summary.Percentiles).To(Equal(map[string]float64{"p50": 4, "p90": 8, "p95": 8, "p99": 8}))
+			Expect(durations).To(Equal(seconds(4, 2, 6, 8)))
+		})
+	})
+
+	Describe("Build and Write", func() {
+		var ginkgoReport types.Report
+
+		BeforeEach(func() {
+			local := gmeasure.NewExperiment("Task scheduling")
+			local.RecordDuration("desire-to-completed", time.Second)
+			local.RecordDuration("desire-to-completed", 3*time.Second)
+			local.RecordValue("ignored", 1)
+			local.RecordNote("also ignored")
+
+			// experiments recorded on another parallel process arrive JSON-encoded
+			remote := gmeasure.NewExperiment("LRP scheduling")
+			remote.RecordDuration("desire-to-claimed", 2*time.Second)
+			encoded, err := json.Marshal(types.WrapEntryValue(remote))
+			Expect(err).NotTo(HaveOccurred())
+			var decoded types.ReportEntryValue
+			Expect(json.Unmarshal(encoded, &decoded)).To(Succeed())
+
+			ginkgoReport = types.Report{
+				SuiteDescription: "Vizzini Suite",
+				SpecReports: types.SpecReports{
+					{
+						ContainerHierarchyTexts: []string{"Scheduling latency"},
+						LeafNodeText:            "tasks",
+						ReportEntries: types.ReportEntries{
+							{Name: "unrelated", Value: types.WrapEntryValue("ignored")},
+							{Name: perf.EntryName, Value: types.WrapEntryValue(local)},
+						},
+					},
+					{
+						ContainerHierarchyTexts: []string{"Scheduling latency"},
+						LeafNodeText:            "lrps",
+						ReportEntries:           types.ReportEntries{{Name: perf.EntryName, Value: decoded}},
+					},
+				},
+			}
+		})
+
+		It("summarizes the duration measurements of every experiment", func() {
+			results, err := perf.Build(ginkgoReport)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results.Experiments).To(HaveLen(2))
+
+			Expect(results.Experiments[0].Name).To(Equal("Task scheduling"))
+			Expect(results.Experiments[0].Spec).To(Equal("Scheduling latency tasks"))
+			Expect(results.Experiments[0].Measurements).To(HaveLen(1))
+			Expect(results.Experiments[0].Measurements[0].Name).To(Equal("desire-to-completed"))
+			Expect(results.Experiments[0].Measurements[0].Mean).To(Equal(2.0))
+
+			Expect(results.Experiments[1].Name).To(Equal("LRP scheduling"))
+			Expect(results.Experiments[1].Measurements[0].Percentiles["p99"]).To(Equal(2.0))
+		})
+
+		It("writes the results as JSON", func() {
+			dir := filepath.Join(GinkgoT().TempDir(), "reports")
+			Expect(perf.Write(dir, ginkgoReport)).To(Succeed())
+
+			contents, err := os.ReadFile(filepath.Join(dir, perf.JSONFileName))
+			Expect(err).NotTo(HaveOccurred())
+			var written perf.Results
+			Expect(json.Unmarshal(contents, &written)).To(Succeed())
+			Expect(written.Experiments[1].Measurements[0].Name).To(Equal("desire-to-claimed"))
+		})
+
+		It("writes nothing when no experiments ran", func() {
+			dir := filepath.Join(GinkgoT().TempDir(), "reports")
+			Expect(perf.Write(dir, types.Report{})).To(Succeed())
+			Expect(filepath.Join(dir, perf.JSONFileName)).NotTo(BeAnExistingFile())
+		})
+	})
+})
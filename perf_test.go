@@ -0,0 +1,85 @@
+package vizzini_test
+
+import (
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/vizzini/eventstream"
+	. "code.cloudfoundry.org/vizzini/matchers"
+	"code.cloudfoundry.org/vizzini/perf"
+	"code.cloudfoundry.org/vizzini/profiles"
+	"github.com/onsi/gomega/gmeasure"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// Run these on their own (the perf profile) so that other specs do not
+// compete for the cells being measured.  Set report_dir to keep the results.
+var _ = Describe("Scheduling latency", Serial, Label(profiles.Perf), func() {
+	const pollInterval = 50 * time.Millisecond
+
+	// sample desires a fresh resource with its own recorder so its timeline
+	// holds nothing but that resource's events
+	sample := func(name string, measure func(experiment *gmeasure.Experiment, recorder *eventstream.EventRecorder, sampleGuid string)) {
+		experiment := gmeasure.NewExperiment(name)
+		AddReportEntry(perf.EntryName, experiment)
+
+		experiment.Sample(func(int) {
+			sampleGuid := NewGuid()
+			recorder := eventstream.NewEventRecorder(sampleGuid)
+			Expect(recorder.Start(logger, bbsClient)).To(Succeed())
+			defer recorder.Stop()
+
+			measure(experiment, recorder, sampleGuid)
+		}, gmeasure.SamplingConfig{N: config.PerfSamples})
+	}
+
+	It("measures how long an LRP takes to be claimed, to run and to become routable", func() {
+		sample("LRP scheduling", func(experiment *gmeasure.Experiment, recorder *eventstream.EventRecorder, processGuid string) {
+			desiredAt := time.Now()
+			Expect(bbsClient.DesireLRP(logger, traceID, DesiredLRPWithGuid(processGuid))).To(Succeed())
+			Eventually(EndpointCurler("http://" + RouteForGuid(processGuid) + "/env")).WithPolling(pollInterval).Should(Equal(http.StatusOK))
+			routableAt := time.Now()
+
+			// the route can answer before the recorder has received the events
+			Eventually(recorder.EventsForGetter(processGuid)).Should(ContainEventsInOrder(
+				MatchActualLRPInstanceChangedEvent(processGuid, 0, models.ActualLRPStateClaimed),
+				MatchActualLRPInstanceChangedEvent(processGuid, 0, models.ActualLRPStateRunning),
+			))
+			timeline := recorder.TimelineFor(processGuid)
+			claimedAt, _ := perf.ActualLRPReached(timeline, 0, models.ActualLRPStateClaimed)
+			runningAt, _ := perf.ActualLRPReached(timeline, 0, models.ActualLRPStateRunning)
+
+			experiment.RecordDuration("desire-to-claimed", claimedAt.Sub(desiredAt))
+			experiment.RecordDuration("claimed-to-running", runningAt.Sub(claimedAt))
+			experiment.RecordDuration("running-to-routable", routableAt.Sub(runningAt))
+			experiment.RecordDuration("desire-to-routable", routableAt.Sub(desiredAt))
+
+			Expect(bbsClient.RemoveDesiredLRP(logger, traceID, processGuid)).To(Succeed())
+			Eventually(ActualByProcessGuidGetter(logger, processGuid)).Should(BeEmpty())
+		})
+	})
+
+	It("measures how long a Task takes to start and to complete", func() {
+		sample("Task scheduling", func(experiment *gmeasure.Experiment, recorder *eventstream.EventRecorder, taskGuid string) {
+			desiredAt := time.Now()
+			Expect(bbsClient.DesireTask(logger, traceID, taskGuid, domain, Task())).To(Succeed())
+			Eventually(recorder.EventsForGetter(taskGuid)).WithPolling(pollInterval).Should(ContainEventsInOrder(
+				MatchTaskChangedEvent(taskGuid, models.Task_Pending, models.Task_Running),
+				MatchTaskChangedEvent(taskGuid, models.Task_Running, models.Task_Completed),
+			))
+			timeline := recorder.TimelineFor(taskGuid)
+			runningAt, _ := perf.TaskReached(timeline, models.Task_Running)
+			completedAt, _ := perf.TaskReached(timeline, models.Task_Completed)
+
+			experiment.RecordDuration("desire-to-running", runningAt.Sub(desiredAt))
+			experiment.RecordDuration("running-to-completed", completedAt.Sub(runningAt))
+			experiment.RecordDuration("desire-to-completed", completedAt.Sub(desiredAt))
+
+			Expect(bbsClient.ResolvingTask(logger, traceID, taskGuid)).To(Succeed())
+			Expect(bbsClient.DeleteTask(logger, traceID, taskGuid)).To(Succeed())
+		})
+	})
+})
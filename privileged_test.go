@@ -4,7 +4,9 @@ import (
 	"time"
 
 	"code.cloudfoundry.org/bbs/models"
-	. "code.cloudfoundry.org/vizzini/matchers"
+	"code.cloudfoundry.org/vizzini/capabilities"
+	vizziniconfig "code.cloudfoundry.org/vizzini/config"
+	"code.cloudfoundry.org/vizzini/profiles"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -16,7 +18,8 @@ var _ = Describe("Privileged", func() {
 	var containerPrivileged bool
 
 	BeforeEach(func() {
-		if timeout == DefaultEventuallyTimeout {
+		// a default_eventually_timeout left at its default is far longer than these tasks need
+		if timeout == time.Duration(vizziniconfig.Defaults().DefaultEventuallyTimeout) {
 			SetDefaultEventuallyTimeout(time.Second * 15)
 		}
 	})
@@ -31,7 +34,7 @@ var _ = Describe("Privileged", func() {
 		})
 
 		Expect(bbsClient.DesireTask(logger, traceID, guid, domain, task)).To(Succeed())
-		Eventually(TaskGetter(logger, guid)).Should(HaveTaskState(models.Task_Completed))
+		WaitForTaskState(guid, models.Task_Completed)
 	})
 
 	AfterEach(func() {
@@ -40,11 +43,9 @@ var _ = Describe("Privileged", func() {
 		Expect(bbsClient.DeleteTask(logger, traceID, guid)).To(Succeed())
 	})
 
-	Context("with a privileged container", func() {
+	Context("with a privileged container", Label(profiles.Privileged), func() {
 		BeforeEach(func() {
-			if !config.EnablePrivilegedContainerTests {
-				Skip("privileged container tests are disabled")
-			}
+			RequireCapabilities(capabilities.Privileged)
 
 			containerPrivileged = true
 		})
@@ -0,0 +1 @@
+package profiles // import "code.cloudfoundry.org/vizzini/profiles"
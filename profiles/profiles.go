@@ -0,0 +1,174 @@
+package profiles
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/onsi/ginkgo/v2/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Labels that specs are tagged with, so that runs can pick specs by what
+// they need rather than by regexes over their names.
+const (
+	Slow       = "slow"
+	Perf       = "perf"
+	Docker     = "docker"
+	OCI        = "oci"
+	Privileged = "privileged"
+	Proxy      = "proxy"
+	SSH        = "ssh"
+	Routing    = "routing"
+	Fuse       = "fuse"
+	Networking = "networking"
+)
+
+// Labels lists every label a spec may carry; profiles may not name others.
+var Labels = []string{Slow, Perf, Docker, OCI, Privileged, Proxy, SSH, Routing, Fuse, Networking}
+
+// Profiles maps a profile name, e.g. smoke, to a Ginkgo label filter such as
+// "!slow && !docker".
+type Profiles map[string]string
+
+// Load reads profiles from the YAML file at path and checks that every filter
+// parses and only names known labels.
+func Load(path string) (Profiles, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles: %s", err.Error())
+	}
+
+	profiles := Profiles{}
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	err = decoder.Decode(&profiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse profiles %s: %s", path, err.Error())
+	}
+
+	for _, name := range profiles.Names() {
+		err = validateFilter(profiles[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid profile %q in %s: %s", name, path, err.Error())
+		}
+	}
+	return profiles, nil
+}
+
+func (p Profiles) Names() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Filter returns the label filter of the named profile.
+func (p Profiles) Filter(name string) (string, error) {
+	filter, ok := p[name]
+	if !ok {
+		return "", fmt.Errorf("unknown profile %q, expected one of %s", name, strings.Join(p.Names(), ", "))
+	}
+	return filter, nil
+}
+
+// Combine joins label filters so that a spec must pass all of them.  Empty
+// filters are left out.
+func Combine(filters ...string) string {
+	nonEmpty := []string{}
+	for _, filter := range filters {
+		if strings.TrimSpace(filter) != "" {
+			nonEmpty = append(nonEmpty, filter)
+		}
+	}
+	if len(nonEmpty) == 1 {
+		return nonEmpty[0]
+	}
+	for i, filter := range nonEmpty {
+		nonEmpty[i] = "(" + filter + ")"
+	}
+	return strings.Join(nonEmpty, " && ")
+}
+
+var (
+	regexpLabel  = regexp.MustCompile(`/[^/]*/`)
+	filterSyntax = regexp.MustCompile(`[&|!(),]+`)
+	whitespace   = regexp.MustCompile(`\s+`)
+)
+
+func validateFilter(filter string) error {
+	_, err := types.ParseLabelFilter(filter)
+	if err != nil {
+		return err
+	}
+
+	known := map[string]bool{}
+	for _, label := range Labels {
+		known[label] = true
+	}
+	// regexes in a filter can match anything, so only bare labels are checked
+	words := filterSyntax.ReplaceAllString(regexpLabel.ReplaceAllString(filter, " "), " ")
+	for _, word := range whitespace.Split(strings.TrimSpace(words), -1) {
+		if word != "" && !known[strings.ToLower(word)] {
+			return fmt.Errorf("unknown label %q, expected one of %s", word, strings.Join(Labels, ", "))
+		}
+	}
+	return nil
+}
+
+// Summary says what a run selected and which labels the specs that ran
+// carried.
+type Summary struct {
+	Profile     string
+	LabelFilter string
+	Labels      []string
+
+	// Specs counts the specs that ran; Unlabelled counts those among them
+	// without any label.
+	Specs      int
+	Unlabelled int
+}
+
+// Summarize describes the run in ginkgoReport, which was selected by profile.
+func Summarize(profile string, ginkgoReport types.Report) Summary {
+	summary := Summary{Profile: profile, LabelFilter: ginkgoReport.SuiteConfig.LabelFilter, Labels: []string{}}
+	ran := map[string]bool{}
+	for _, spec := range ginkgoReport.SpecReports {
+		if spec.LeafNodeType != types.NodeTypeIt || spec.State.Is(types.SpecStateSkipped|types.SpecStatePending) {
+			continue
+		}
+		summary.Specs++
+		labels := spec.Labels()
+		if len(labels) == 0 {
+			summary.Unlabelled++
+		}
+		for _, label := range labels {
+			ran[label] = true
+		}
+	}
+	for label := range ran {
+		summary.Labels = append(summary.Labels, label)
+	}
+	sort.Strings(summary.Labels)
+	return summary
+}
+
+func (s Summary) String() string {
+	profile := s.Profile
+	if profile == "" {
+		profile = "none"
+	}
+	filter := s.LabelFilter
+	if filter == "" {
+		filter = "none"
+	}
+	labels := strings.Join(s.Labels, ", ")
+	if labels == "" {
+		labels = "none"
+	}
+	return fmt.Sprintf("Profile: %s\nLabel filter: %s\nRan %d specs with labels: %s (%d without any label)", profile, filter, s.Specs, labels, s.Unlabelled)
+}
@@ -0,0 +1,87 @@
+package profiles_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/vizzini/profiles"
+	"github.com/onsi/ginkgo/v2/types"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Profiles", func() {
+	load := func(contents string) (profiles.Profiles, error) {
+		path := filepath.Join(GinkgoT().TempDir(), "profiles.yml")
+		Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+		return profiles.Load(path)
+	}
+
+	It("loads the profiles that ship with Vizzini", func() {
+		shipped, err := profiles.Load("../profiles.yml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(shipped.Names()).To(ContainElements("smoke", "full", "no-docker"))
+
+		filter, err := shipped.Filter("no-docker")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filter).To(ContainSubstring("!docker"))
+
+		filter, err = shipped.Filter("smoke")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filter).To(ContainSubstring("!routing"))
+		Expect(filter).To(ContainSubstring("!networking"))
+	})
+
+	It("names the known profiles when asked for an unknown one", func() {
+		loaded, err := load("b: slow\na: '!slow'\n")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = loaded.Filter("c")
+		Expect(err).To(MatchError(`unknown profile "c", expected one of a, b`))
+	})
+
+	It("rejects filters that do not parse", func() {
+		_, err := load("broken: '!slow &&'")
+		Expect(err).To(MatchError(ContainSubstring(`invalid profile "broken"`)))
+	})
+
+	It("rejects filters with unknown labels, but not regexes", func() {
+		_, err := load("typo: '!slow && !dokcer'")
+		Expect(err).To(MatchError(ContainSubstring(`unknown label "dokcer"`)))
+
+		_, err = load("regex: '/^(docker|oci)$/ || SSH'")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("combines filters so that every one must match", func() {
+		Expect(profiles.Combine("!slow", "")).To(Equal("!slow"))
+		Expect(profiles.Combine("!slow", "docker || oci")).To(Equal("(!slow) && (docker || oci)"))
+		Expect(profiles.Combine()).To(Equal(""))
+	})
+
+	It("sums up the labels of the specs that ran", func() {
+		spec := func(state types.SpecState, containerLabels, labels []string) types.SpecReport {
+			return types.SpecReport{
+				LeafNodeType:             types.NodeTypeIt,
+				State:                    state,
+				ContainerHierarchyLabels: [][]string{containerLabels},
+				LeafNodeLabels:           labels,
+			}
+		}
+		summary := profiles.Summarize("smoke", types.Report{
+			SuiteConfig: types.SuiteConfig{LabelFilter: "!slow"},
+			SpecReports: types.SpecReports{
+				spec(types.SpecStatePassed, []string{"ssh"}, nil),
+				spec(types.SpecStateFailed, nil, []string{"routing"}),
+				spec(types.SpecStatePassed, nil, nil),
+				spec(types.SpecStateSkipped, nil, []string{"slow"}),
+				{LeafNodeType: types.NodeTypeBeforeSuite, State: types.SpecStatePassed},
+			},
+		})
+
+		Expect(summary.Labels).To(Equal([]string{"routing", "ssh"}))
+		Expect(summary.Specs).To(Equal(3))
+		Expect(summary.Unlabelled).To(Equal(1))
+		Expect(summary.String()).To(Equal("Profile: smoke\nLabel filter: !slow\nRan 3 specs with labels: routing, ssh (1 without any label)"))
+	})
+})
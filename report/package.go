@@ -0,0 +1 @@
+package report // import "code.cloudfoundry.org/vizzini/report"
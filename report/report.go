@@ -0,0 +1,162 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/onsi/ginkgo/v2/reporters"
+	"github.com/onsi/ginkgo/v2/types"
+)
+
+// EntryName is the name of the Ginkgo report entry each spec records its
+// Entry under.
+const EntryName = "vizzini"
+
+const (
+	JSONFileName  = "vizzini-report.json"
+	JUnitFileName = "vizzini-junit.xml"
+)
+
+// Entry is what a spec attaches to its Ginkgo report with AddReportEntry.
+type Entry struct {
+	GUID      string    `json:"guid"`
+	Domain    string    `json:"domain"`
+	TraceID   string    `json:"trace_id,omitempty"`
+	LogFile   string    `json:"log_file,omitempty"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Snapshot  *Snapshot `json:"snapshot,omitempty"`
+}
+
+type Report struct {
+	Suite       string    `json:"suite"`
+	LabelFilter string    `json:"label_filter,omitempty"`
+	Succeeded   bool      `json:"succeeded"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	Specs       []Spec    `json:"specs"`
+}
+
+type Spec struct {
+	Name            string    `json:"name"`
+	Kind            string    `json:"kind"`
+	Labels          []string  `json:"labels,omitempty"`
+	State           string    `json:"state"`
+	ParallelProcess int       `json:"parallel_process"`
+	GUID            string    `json:"guid,omitempty"`
+	Domain          string    `json:"domain,omitempty"`
+	TraceID         string    `json:"trace_id,omitempty"`
+	LogFile         string    `json:"log_file,omitempty"`
+	StartTime       time.Time `json:"start_time"`
+	EndTime         time.Time `json:"end_time"`
+	Failure         *Failure  `json:"failure,omitempty"`
+	Snapshot        *Snapshot `json:"snapshot,omitempty"`
+}
+
+type Failure struct {
+	Message  string `json:"message"`
+	Location string `json:"location"`
+}
+
+// Build collects the per-spec entries out of the Ginkgo report for the whole
+// suite, including those recorded on other parallel processes.
+func Build(ginkgoReport types.Report) (Report, error) {
+	report := Report{
+		Suite:       ginkgoReport.SuiteDescription,
+		LabelFilter: ginkgoReport.SuiteConfig.LabelFilter,
+		Succeeded:   ginkgoReport.SuiteSucceeded,
+		StartTime:   ginkgoReport.StartTime,
+		EndTime:     ginkgoReport.EndTime,
+		Specs:       []Spec{},
+	}
+
+	for _, specReport := range ginkgoReport.SpecReports {
+		spec := Spec{
+			Name:            specReport.FullText(),
+			Kind:            specReport.LeafNodeType.String(),
+			Labels:          specReport.Labels(),
+			State:           specReport.State.String(),
+			ParallelProcess: specReport.ParallelProcess,
+			StartTime:       specReport.StartTime,
+			EndTime:         specReport.EndTime,
+		}
+		if spec.Name == "" {
+			spec.Name = spec.Kind
+		}
+		if specReport.Failed() {
+			spec.Failure = &Failure{
+				Message:  specReport.Failure.Message,
+				Location: specReport.Failure.Location.String(),
+			}
+		}
+
+		for _, reportEntry := range specReport.ReportEntries {
+			if reportEntry.Name != EntryName {
+				continue
+			}
+			entry, err := decodeEntry(reportEntry)
+			if err != nil {
+				return Report{}, fmt.Errorf("failed to decode report entry for %q: %s", spec.Name, err.Error())
+			}
+			spec.GUID = entry.GUID
+			spec.Domain = entry.Domain
+			spec.TraceID = entry.TraceID
+			spec.LogFile = entry.LogFile
+			spec.StartTime = entry.StartTime
+			spec.EndTime = entry.EndTime
+			spec.Snapshot = entry.Snapshot
+		}
+
+		report.Specs = append(report.Specs, spec)
+	}
+
+	return report, nil
+}
+
+// decodeEntry reads an Entry back out of a report entry.  Entries recorded
+// on other parallel processes arrive as JSON, so local ones go through the
+// same encoding.
+func decodeEntry(reportEntry types.ReportEntry) (Entry, error) {
+	wrapped, err := json.Marshal(reportEntry.Value)
+	if err != nil {
+		return Entry{}, err
+	}
+	var value struct {
+		AsJSON string
+	}
+	err = json.Unmarshal(wrapped, &value)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	var entry Entry
+	err = json.Unmarshal([]byte(value.AsJSON), &entry)
+	return entry, err
+}
+
+// Write puts a JSON report and a JUnit report for ginkgoReport into dir.
+func Write(dir string, ginkgoReport types.Report) error {
+	report, err := Build(ginkgoReport)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create report directory: %s", err.Error())
+	}
+
+	contents, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %s", err.Error())
+	}
+	err = os.WriteFile(filepath.Join(dir, JSONFileName), contents, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write report: %s", err.Error())
+	}
+
+	return reporters.GenerateJUnitReport(ginkgoReport, filepath.Join(dir, JUnitFileName))
+}
@@ -0,0 +1,132 @@
+package report_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/vizzini/report"
+	"github.com/onsi/ginkgo/v2/types"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Report", func() {
+	var (
+		startTime    time.Time
+		ginkgoReport types.Report
+	)
+
+	entryFor := func(entry report.Entry) types.ReportEntry {
+		return types.ReportEntry{Name: report.EntryName, Value: types.WrapEntryValue(entry)}
+	}
+
+	BeforeEach(func() {
+		startTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		// entries recorded on another parallel process arrive JSON-encoded
+		remoteValue := types.WrapEntryValue(report.Entry{GUID: "vizzini-2-bbbb", Domain: "vizzini-2"})
+		encoded, err := json.Marshal(remoteValue)
+		Expect(err).NotTo(HaveOccurred())
+		var decoded types.ReportEntryValue
+		Expect(json.Unmarshal(encoded, &decoded)).To(Succeed())
+
+		ginkgoReport = types.Report{
+			SuiteDescription: "Vizzini Suite",
+			SuiteConfig:      types.SuiteConfig{LabelFilter: "!slow"},
+			SuiteSucceeded:   false,
+			StartTime:        startTime,
+			EndTime:          startTime.Add(time.Minute),
+			SpecReports: types.SpecReports{
+				{
+					ContainerHierarchyTexts: []string{"Tasks"},
+					LeafNodeText:            "succeeds",
+					LeafNodeType:            types.NodeTypeIt,
+					State:                   types.SpecStatePassed,
+					ParallelProcess:         1,
+					ReportEntries: types.ReportEntries{
+						{Name: "unrelated", Value: types.WrapEntryValue("ignored")},
+						entryFor(report.Entry{
+							GUID:      "vizzini-1-aaaa",
+							Domain:    "vizzini-1",
+							TraceID:   "01234567-89ab-cdef-0123-456789abcdef",
+							LogFile:   "logs/vizzini-1-aaaa.log",
+							StartTime: startTime,
+							EndTime:   startTime.Add(time.Second),
+						}),
+					},
+				},
+				{
+					ContainerHierarchyTexts: []string{"LRPs"},
+					LeafNodeText:            "fails",
+					LeafNodeLabels:          []string{"routing"},
+					LeafNodeType:            types.NodeTypeIt,
+					State:                   types.SpecStateFailed,
+					ParallelProcess:         2,
+					Failure: types.Failure{
+						Message:  "Expected RUNNING",
+						Location: types.CodeLocation{FileName: "lrps_test.go", LineNumber: 42},
+					},
+					ReportEntries: types.ReportEntries{
+						{Name: report.EntryName, Value: decoded},
+					},
+				},
+				{
+					LeafNodeType: types.NodeTypeBeforeSuite,
+					State:        types.SpecStatePassed,
+				},
+			},
+		}
+	})
+
+	Describe("Build", func() {
+		It("attaches each spec's entry, from any parallel process", func() {
+			built, err := report.Build(ginkgoReport)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(built.Suite).To(Equal("Vizzini Suite"))
+			Expect(built.LabelFilter).To(Equal("!slow"))
+			Expect(built.Succeeded).To(BeFalse())
+			Expect(built.Specs).To(HaveLen(3))
+
+			Expect(built.Specs[0].Name).To(Equal("Tasks succeeds"))
+			Expect(built.Specs[0].State).To(Equal("passed"))
+			Expect(built.Specs[0].GUID).To(Equal("vizzini-1-aaaa"))
+			Expect(built.Specs[0].Domain).To(Equal("vizzini-1"))
+			Expect(built.Specs[0].TraceID).To(Equal("01234567-89ab-cdef-0123-456789abcdef"))
+			Expect(built.Specs[0].LogFile).To(Equal("logs/vizzini-1-aaaa.log"))
+			Expect(built.Specs[0].EndTime).To(Equal(startTime.Add(time.Second)))
+			Expect(built.Specs[0].Failure).To(BeNil())
+
+			Expect(built.Specs[1].GUID).To(Equal("vizzini-2-bbbb"))
+			Expect(built.Specs[1].ParallelProcess).To(Equal(2))
+			Expect(built.Specs[1].Labels).To(Equal([]string{"routing"}))
+			Expect(built.Specs[1].Failure).To(Equal(&report.Failure{
+				Message:  "Expected RUNNING",
+				Location: "lrps_test.go:42",
+			}))
+
+			Expect(built.Specs[2].Name).To(Equal("BeforeSuite"))
+			Expect(built.Specs[2].GUID).To(BeEmpty())
+		})
+	})
+
+	Describe("Write", func() {
+		It("writes a JSON and a JUnit report", func() {
+			dir := filepath.Join(GinkgoT().TempDir(), "reports")
+			Expect(report.Write(dir, ginkgoReport)).To(Succeed())
+
+			contents, err := os.ReadFile(filepath.Join(dir, report.JSONFileName))
+			Expect(err).NotTo(HaveOccurred())
+			var written report.Report
+			Expect(json.Unmarshal(contents, &written)).To(Succeed())
+			Expect(written.Specs[1].GUID).To(Equal("vizzini-2-bbbb"))
+
+			junit, err := os.ReadFile(filepath.Join(dir, report.JUnitFileName))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(junit)).To(ContainSubstring("Expected RUNNING"))
+		})
+	})
+})
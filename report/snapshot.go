@@ -0,0 +1,70 @@
+package report
+
+import (
+	"sort"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+// Snapshot is the BBS state of a spec's domains at the moment it failed.
+type Snapshot struct {
+	Tasks       []*models.Task       `json:"tasks"`
+	DesiredLRPs []*models.DesiredLRP `json:"desired_lrps"`
+	ActualLRPs  []*models.ActualLRP  `json:"actual_lrps"`
+
+	// CellIDs lists every cell the snapshotted tasks and LRPs were placed on,
+	// which is where their logs live.
+	CellIDs []string `json:"cell_ids"`
+
+	// Errors records BBS requests that failed while taking the snapshot.
+	Errors []string `json:"errors,omitempty"`
+}
+
+func TakeSnapshot(logger lager.Logger, client bbs.InternalClient, traceID string, domains ...string) *Snapshot {
+	logger = logger.Session("snapshot")
+	snapshot := &Snapshot{
+		Tasks:       []*models.Task{},
+		DesiredLRPs: []*models.DesiredLRP{},
+		ActualLRPs:  []*models.ActualLRP{},
+		CellIDs:     []string{},
+	}
+	cellIDs := map[string]bool{}
+
+	for _, domain := range domains {
+		tasks, err := client.TasksByDomain(logger, traceID, domain)
+		if err != nil {
+			snapshot.Errors = append(snapshot.Errors, "tasks in "+domain+": "+err.Error())
+		}
+		for _, task := range tasks {
+			if task.CellId != "" {
+				cellIDs[task.CellId] = true
+			}
+		}
+		snapshot.Tasks = append(snapshot.Tasks, tasks...)
+
+		desiredLRPs, err := client.DesiredLRPs(logger, traceID, models.DesiredLRPFilter{Domain: domain})
+		if err != nil {
+			snapshot.Errors = append(snapshot.Errors, "desired LRPs in "+domain+": "+err.Error())
+		}
+		snapshot.DesiredLRPs = append(snapshot.DesiredLRPs, desiredLRPs...)
+
+		actualLRPs, err := client.ActualLRPs(logger, traceID, models.ActualLRPFilter{Domain: domain})
+		if err != nil {
+			snapshot.Errors = append(snapshot.Errors, "actual LRPs in "+domain+": "+err.Error())
+		}
+		for _, lrp := range actualLRPs {
+			if lrp.CellId != "" {
+				cellIDs[lrp.CellId] = true
+			}
+		}
+		snapshot.ActualLRPs = append(snapshot.ActualLRPs, actualLRPs...)
+	}
+
+	for cellID := range cellIDs {
+		snapshot.CellIDs = append(snapshot.CellIDs, cellID)
+	}
+	sort.Strings(snapshot.CellIDs)
+	return snapshot
+}
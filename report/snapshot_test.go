@@ -0,0 +1,63 @@
+package report_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/vizzini/fakebbs"
+	"code.cloudfoundry.org/vizzini/report"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TakeSnapshot", func() {
+	var server *fakebbs.Server
+
+	BeforeEach(func() {
+		server = fakebbs.NewServerWithStepInterval(10 * time.Millisecond)
+		server.Start()
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("captures the tasks and LRPs in the given domains and the cells they ran on", func() {
+		logger := lagertest.NewTestLogger("report")
+		client, err := server.Client()
+		Expect(err).NotTo(HaveOccurred())
+
+		rootFS := models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot)
+		Expect(client.DesireTask(logger, "trace-id", "task-guid", "snapshotted", &models.TaskDefinition{
+			RootFs:   rootFS,
+			MemoryMb: 32,
+			Action:   models.WrapAction(&models.RunAction{Path: "true", User: "vcap"}),
+		})).To(Succeed())
+		Expect(client.DesireLRP(logger, "trace-id", &models.DesiredLRP{
+			ProcessGuid: "lrp-guid",
+			Domain:      "snapshotted",
+			Instances:   1,
+			RootFs:      rootFS,
+			MemoryMb:    32,
+			Action:      models.WrapAction(&models.RunAction{Path: "true", User: "vcap"}),
+		})).To(Succeed())
+		Expect(client.DesireTask(logger, "trace-id", "other-task-guid", "ignored", &models.TaskDefinition{
+			RootFs:   rootFS,
+			MemoryMb: 32,
+			Action:   models.WrapAction(&models.RunAction{Path: "true", User: "vcap"}),
+		})).To(Succeed())
+
+		Eventually(func() []string {
+			return report.TakeSnapshot(logger, client, "trace-id", "snapshotted").CellIDs
+		}).Should(Equal([]string{fakebbs.FakeCellID}))
+
+		snapshot := report.TakeSnapshot(logger, client, "trace-id", "snapshotted")
+		Expect(snapshot.Errors).To(BeEmpty())
+		Expect(snapshot.Tasks).To(HaveLen(1))
+		Expect(snapshot.Tasks[0].TaskGuid).To(Equal("task-guid"))
+		Expect(snapshot.DesiredLRPs).To(HaveLen(1))
+		Expect(snapshot.ActualLRPs).To(HaveLen(1))
+	})
+})
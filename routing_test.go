@@ -8,13 +8,15 @@ import (
 
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/routing-info/cfroutes"
+	"code.cloudfoundry.org/vizzini/grace"
+	"code.cloudfoundry.org/vizzini/profiles"
 
 	. "code.cloudfoundry.org/vizzini/matchers"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
 
-var _ = Describe("Routing Related Tests", func() {
+var _ = Describe("Routing Related Tests", Label(profiles.Routing), func() {
 	var lrp *models.DesiredLRP
 
 	Describe("sticky sessions", func() {
@@ -36,16 +38,12 @@ var _ = Describe("Routing Related Tests", func() {
 		})
 
 		It("should only route to the stuck instance", func() {
-			resp, err := httpClient.Get("http://" + RouteForGuid(guid) + "/stick")
-			Expect(err).NotTo(HaveOccurred())
-			resp.Body.Close()
+			Expect(GraceClient(guid, grace.WithHTTPClient(httpClient)).Stick()).To(Succeed())
 
 			//for some reason this isn't always 1!  it's sometimes 2....
 			Expect(IndexCounter(guid, httpClient)()).To(BeNumerically("<", 3))
 
-			resp, err = httpClient.Get("http://" + RouteForGuid(guid) + "/unstick")
-			Expect(err).NotTo(HaveOccurred())
-			resp.Body.Close()
+			Expect(GraceClient(guid, grace.WithHTTPClient(httpClient)).Unstick()).To(Succeed())
 
 			Expect(IndexCounter(guid, httpClient)()).To(Equal(3))
 		})
@@ -79,7 +77,7 @@ var _ = Describe("Routing Related Tests", func() {
 				Succeed())
 
 			By("verifying that the new route is hooked up to the port")
-			Eventually(EndpointContentCurler("http://" + newRoute)).Should(Equal("grace side-channel"))
+			Eventually(EndpointContentCurler("http://" + newRoute)).Should(Equal(grace.SideChannelResponse))
 
 			By("verifying that the original route is fine")
 			Expect(EndpointContentCurler(primaryURL)()).To(ContainSubstring("DAQUIRI"), "something on the original endpoint that's not in the new one")
@@ -94,8 +92,8 @@ var _ = Describe("Routing Related Tests", func() {
 
 				Succeed())
 
-			Eventually(EndpointContentCurler("http://" + veryNewRoute)).Should(Equal("grace side-channel"))
-			Expect(EndpointContentCurler("http://" + newRoute)()).To(Equal("grace side-channel"))
+			Eventually(EndpointContentCurler("http://" + veryNewRoute)).Should(Equal(grace.SideChannelResponse))
+			Expect(EndpointContentCurler("http://" + newRoute)()).To(Equal(grace.SideChannelResponse))
 			Expect(EndpointContentCurler(primaryURL)()).To(ContainSubstring("DAQUIRI"), "something on the original endpoint that's not in the new one")
 
 			By("tearing down the new port")
@@ -125,7 +123,7 @@ var _ = Describe("Routing Related Tests", func() {
 			))
 		})
 
-		It("{SLOW} should only route to running containers", func() {
+		It("should only route to running containers", Label(profiles.Slow), func() {
 			done := make(chan struct{})
 			badCodes := []int{}
 			attempts := 0
@@ -225,9 +223,7 @@ var _ = Describe("Routing Related Tests", func() {
 			It("finish outstanding requests", func() {
 				errCh := make(chan error, 1)
 				go func() {
-					resp, err := http.Get("http://" + RouteForGuid(guid) + "/sleep/8s")
-					resp.Body.Close()
-					errCh <- err
+					errCh <- GraceClient(guid).Sleep(8 * time.Second)
 				}()
 
 				dlu := &models.DesiredLRPUpdate{Routes: lrp.Routes}
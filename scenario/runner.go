@@ -0,0 +1,370 @@
+package scenario
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/vizzini/checks"
+	"code.cloudfoundry.org/vizzini/fixtures"
+	"code.cloudfoundry.org/vizzini/grace"
+)
+
+const (
+	DefaultTimeout      = 2 * time.Minute
+	DefaultPollInterval = time.Second
+)
+
+// Runner runs scenarios against a deployment.  It does not clean up what a
+// scenario desires, so give it a tracker's client.
+type Runner struct {
+	Logger  lager.Logger
+	Client  bbs.InternalClient
+	TraceID string
+
+	// Fixtures are what desire steps start from.  Everything lives in its
+	// Domain.
+	Fixtures fixtures.Defaults
+
+	// GuidPrefix is prepended, with a dash, to the names scenarios give their
+	// tasks and LRPs to make their GUIDs.
+	GuidPrefix string
+
+	// Timeout bounds each step that waits, unless the step sets its own.
+	Timeout      time.Duration
+	PollInterval time.Duration
+
+	// HTTPClient reaches routes through the router; nil uses
+	// http.DefaultClient.
+	HTTPClient *http.Client
+
+	// BeforeStep, if set, is called with each step before it runs, e.g. to
+	// hand it to Ginkgo's By.
+	BeforeStep func(step Step)
+}
+
+// Guid returns the GUID of the task or LRP a scenario calls name.
+func (r Runner) Guid(name string) string {
+	if r.GuidPrefix == "" {
+		return name
+	}
+	return r.GuidPrefix + "-" + name
+}
+
+// Run runs scenario's steps in order and stops at the first that fails.
+func (r Runner) Run(scenario Scenario) error {
+	r = r.withDefaults()
+	for i, step := range scenario.Steps {
+		if r.BeforeStep != nil {
+			r.BeforeStep(step)
+		}
+		timeout := step.Timeout
+		if timeout == 0 {
+			timeout = r.Timeout
+		}
+		err := r.runStep(step, timeout)
+		if err != nil {
+			return fmt.Errorf("step %d (%s): %s", i+1, step.Kind(), err.Error())
+		}
+	}
+	return nil
+}
+
+func (r Runner) runStep(step Step, timeout time.Duration) error {
+	switch {
+	case step.Desire != nil && step.Desire.LRP != "":
+		return r.desireLRP(step.Desire)
+	case step.Desire != nil:
+		return r.desireTask(step.Desire)
+	case step.Update != nil:
+		return r.update(step.Update)
+	case step.WaitForState != nil && step.WaitForState.LRP != "":
+		return r.waitForLRPState(step.WaitForState, timeout)
+	case step.WaitForState != nil:
+		return r.waitForTaskState(step.WaitForState, timeout)
+	case step.Curl != nil:
+		return r.curl(step.Curl, timeout)
+	case step.MakeGraceExit != nil:
+		return r.makeGraceExit(step.MakeGraceExit, timeout)
+	case step.ExpectCrashCount != nil:
+		return r.expectCrashCount(step.ExpectCrashCount, timeout)
+	case step.Cancel != nil:
+		err := r.Client.CancelTask(r.Logger, r.TraceID, r.Guid(step.Cancel.Task))
+		if err != nil {
+			return fmt.Errorf("failed to cancel task: %s", err.Error())
+		}
+		return nil
+	}
+	return fmt.Errorf("step has no action")
+}
+
+func (r Runner) desireLRP(d *Desire) error {
+	options := []fixtures.LRPOption{}
+	if d.Instances != nil {
+		options = append(options, fixtures.WithInstances(*d.Instances))
+	}
+	if d.DockerImage != "" {
+		options = append(options, fixtures.WithDockerImage(d.DockerImage))
+	}
+	if d.Privileged {
+		options = append(options, fixtures.WithPrivileged())
+	}
+	lrp, err := r.Fixtures.DesiredLRP(r.Guid(d.LRP), options...)
+	if err != nil {
+		return err
+	}
+
+	err = d.Run.applyTo(lrp.Action)
+	if err != nil {
+		return err
+	}
+	d.applyResources(&lrp.MemoryMb, &lrp.DiskMb)
+	err = lrp.Validate()
+	if err != nil {
+		return fmt.Errorf("invalid desired LRP: %s", err.Error())
+	}
+
+	err = r.Client.DesireLRP(r.Logger, r.TraceID, lrp)
+	if err != nil {
+		return fmt.Errorf("failed to desire LRP: %s", err.Error())
+	}
+	return nil
+}
+
+func (r Runner) desireTask(d *Desire) error {
+	options := []fixtures.TaskOption{}
+	if d.DockerImage != "" {
+		options = append(options, fixtures.WithDockerImage(d.DockerImage))
+	}
+	if d.Privileged {
+		options = append(options, fixtures.WithPrivileged())
+	}
+	taskGuid := r.Guid(d.Task)
+	task, err := r.Fixtures.Task(taskGuid, options...)
+	if err != nil {
+		return err
+	}
+
+	err = d.Run.applyTo(task.Action)
+	if err != nil {
+		return err
+	}
+	d.applyResources(&task.MemoryMb, &task.DiskMb)
+	err = task.Validate()
+	if err != nil {
+		return fmt.Errorf("invalid task: %s", err.Error())
+	}
+
+	err = r.Client.DesireTask(r.Logger, r.TraceID, taskGuid, r.Fixtures.Domain, task)
+	if err != nil {
+		return fmt.Errorf("failed to desire task: %s", err.Error())
+	}
+	return nil
+}
+
+func (d Desire) applyResources(memoryMB, diskMB *int32) {
+	if d.MemoryMB != nil {
+		*memoryMB = *d.MemoryMB
+	}
+	if d.DiskMB != nil {
+		*diskMB = *d.DiskMB
+	}
+}
+
+// applyTo changes the fixture's run action in place.
+func (r *Run) applyTo(action *models.Action) error {
+	if r == nil {
+		return nil
+	}
+	if action == nil || action.RunAction == nil {
+		return fmt.Errorf("the fixture has no run action to change")
+	}
+
+	run := action.RunAction
+	if r.Path != "" {
+		run.Path = r.Path
+	}
+	if r.Args != nil {
+		run.Args = r.Args
+	}
+	if r.User != "" {
+		run.User = r.User
+	}
+	names := make([]string, 0, len(r.Env))
+	for name := range r.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		run.Env = append(run.Env, &models.EnvironmentVariable{Name: name, Value: r.Env[name]})
+	}
+	return nil
+}
+
+func (r Runner) update(u *Update) error {
+	update := &models.DesiredLRPUpdate{}
+	if u.Instances != nil {
+		update.SetInstances(*u.Instances)
+	}
+	if u.Annotation != nil {
+		update.SetAnnotation(*u.Annotation)
+	}
+	err := r.Client.UpdateDesiredLRP(r.Logger, r.TraceID, r.Guid(u.LRP), update)
+	if err != nil {
+		return fmt.Errorf("failed to update LRP: %s", err.Error())
+	}
+	return nil
+}
+
+func (r Runner) waitForLRPState(w *WaitForState, timeout time.Duration) error {
+	processGuid := r.Guid(w.LRP)
+	description := fmt.Sprintf("LRP %q to be %s", w.LRP, w.State)
+	if w.Index != nil {
+		description = fmt.Sprintf("LRP %q instance %d to be %s", w.LRP, *w.Index, w.State)
+	}
+
+	return r.poll(timeout, description, func() (bool, string, error) {
+		actuals, err := r.Client.ActualLRPs(r.Logger, r.TraceID, models.ActualLRPFilter{ProcessGuid: processGuid, Index: w.Index})
+		if err != nil {
+			return false, "", err
+		}
+		seen := describeActuals(actuals)
+		if w.Index != nil {
+			return len(actuals) == 1 && actuals[0].State == w.State, seen, nil
+		}
+
+		desired, err := r.Client.DesiredLRPByProcessGuid(r.Logger, r.TraceID, processGuid)
+		if err != nil {
+			return false, seen, err
+		}
+		inState := 0
+		for _, actual := range actuals {
+			if actual.Index < desired.Instances && actual.State == w.State {
+				inState++
+			}
+		}
+		return inState == int(desired.Instances), seen, nil
+	})
+}
+
+func (r Runner) waitForTaskState(w *WaitForState, timeout time.Duration) error {
+	state, _ := taskState(w.State)
+	return r.poll(timeout, fmt.Sprintf("task %q to be %s", w.Task, state), func() (bool, string, error) {
+		task, err := r.Client.TaskByGuid(r.Logger, r.TraceID, r.Guid(w.Task))
+		if err != nil {
+			return false, "", err
+		}
+		seen := task.State.String()
+		if task.Failed {
+			seen = fmt.Sprintf("%s, failed: %s", seen, task.FailureReason)
+		}
+		if task.State != state {
+			return false, seen, nil
+		}
+		if w.Failed != nil && task.Failed != *w.Failed {
+			return false, seen, fmt.Errorf("expected failed to be %t, but the task is %s", *w.Failed, seen)
+		}
+		return true, seen, nil
+	})
+}
+
+func (r Runner) curl(c *Curl, timeout time.Duration) error {
+	url := "http://" + r.Fixtures.RouteFor(r.Guid(c.LRP), fixtures.GracePort) + c.Path
+	description := fmt.Sprintf("GET %s to return %d", url, c.Status)
+	if c.BodyContains != "" {
+		description += fmt.Sprintf(" with a body containing %q", c.BodyContains)
+	}
+
+	return r.poll(timeout, description, func() (bool, string, error) {
+		resp, err := r.HTTPClient.Get(url)
+		if err != nil {
+			return false, err.Error(), nil
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return false, err.Error(), nil
+		}
+		seen := fmt.Sprintf("%d %q", resp.StatusCode, truncate(string(body), 200))
+		return resp.StatusCode == c.Status && strings.Contains(string(body), c.BodyContains), seen, nil
+	})
+}
+
+func (r Runner) makeGraceExit(m *MakeGraceExit, timeout time.Duration) error {
+	route := r.Fixtures.RouteFor(r.Guid(m.LRP), fixtures.GracePort)
+	graceClient := grace.ForRoute(route, grace.WithHTTPClient(r.HTTPClient))
+	err := r.poll(timeout, "route "+route+" to serve", func() (bool, string, error) {
+		return graceClient.Ready(), "", nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = graceClient.Exit(m.Status)
+	if err != nil {
+		return fmt.Errorf("failed to make grace exit: %s", err.Error())
+	}
+	return nil
+}
+
+func (r Runner) expectCrashCount(e *ExpectCrashCount, timeout time.Duration) error {
+	description := fmt.Sprintf("LRP %q instance %d to have crashed %d times", e.LRP, e.Index, e.Count)
+	if e.State != "" {
+		description += " and be " + e.State
+	}
+
+	return r.poll(timeout, description, func() (bool, string, error) {
+		actuals, err := r.Client.ActualLRPs(r.Logger, r.TraceID, models.ActualLRPFilter{ProcessGuid: r.Guid(e.LRP), Index: &e.Index})
+		if err != nil {
+			return false, "", err
+		}
+		if len(actuals) != 1 {
+			return false, describeActuals(actuals), nil
+		}
+		actual := actuals[0]
+		seen := fmt.Sprintf("crash count %d, %s", actual.CrashCount, actual.State)
+		return actual.CrashCount == e.Count && (e.State == "" || actual.State == e.State), seen, nil
+	})
+}
+
+func describeActuals(actuals []*models.ActualLRP) string {
+	if len(actuals) == 0 {
+		return "no ActualLRPs"
+	}
+	sort.Slice(actuals, func(i, j int) bool { return actuals[i].Index < actuals[j].Index })
+	states := make([]string, len(actuals))
+	for i, actual := range actuals {
+		states[i] = fmt.Sprintf("%d: %s", actual.Index, actual.State)
+	}
+	return strings.Join(states, ", ")
+}
+
+func (r Runner) poll(timeout time.Duration, description string, done func() (bool, string, error)) error {
+	return checks.Poll(timeout, r.PollInterval, description, done)
+}
+
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+	return s[:length] + "..."
+}
+
+func (r Runner) withDefaults() Runner {
+	if r.Timeout == 0 {
+		r.Timeout = DefaultTimeout
+	}
+	if r.PollInterval == 0 {
+		r.PollInterval = DefaultPollInterval
+	}
+	if r.HTTPClient == nil {
+		r.HTTPClient = http.DefaultClient
+	}
+	return r
+}
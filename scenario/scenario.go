@@ -0,0 +1,399 @@
+package scenario
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario is a spec written as data: a list of steps that desire tasks and
+// LRPs, change them, and wait for what Diego should do with them.
+type Scenario struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Steps       []Step `yaml:"steps"`
+
+	// Path is the file the scenario was loaded from.
+	Path string `yaml:"-"`
+}
+
+// Step does exactly one thing.  Timeout bounds steps that wait, and defaults
+// to the runner's timeout.
+type Step struct {
+	Desire           *Desire           `yaml:"desire"`
+	Update           *Update           `yaml:"update"`
+	WaitForState     *WaitForState     `yaml:"wait_for_state"`
+	Curl             *Curl             `yaml:"curl"`
+	MakeGraceExit    *MakeGraceExit    `yaml:"make_grace_exit"`
+	ExpectCrashCount *ExpectCrashCount `yaml:"expect_crash_count"`
+	Cancel           *Cancel           `yaml:"cancel"`
+
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Desire desires a task or an LRP built from the default fixtures, with the
+// given fields changed.  Task and LRP name it within the scenario; the runner
+// turns the name into a GUID unique to the run.
+type Desire struct {
+	Task string `yaml:"task"`
+	LRP  string `yaml:"lrp"`
+
+	Instances   *int32 `yaml:"instances"`
+	DockerImage string `yaml:"docker_image"`
+	MemoryMB    *int32 `yaml:"memory_mb"`
+	DiskMB      *int32 `yaml:"disk_mb"`
+	Privileged  bool   `yaml:"privileged"`
+	Run         *Run   `yaml:"run"`
+}
+
+// Run changes the fixture's run action: Grace for LRPs, a bash command that
+// writes a result file for tasks.  Unset fields keep the fixture's values.
+type Run struct {
+	Path string            `yaml:"path"`
+	Args []string          `yaml:"args"`
+	User string            `yaml:"user"`
+	Env  map[string]string `yaml:"env"`
+}
+
+// Update changes a desired LRP's instance count or annotation.
+type Update struct {
+	LRP        string  `yaml:"lrp"`
+	Instances  *int32  `yaml:"instances"`
+	Annotation *string `yaml:"annotation"`
+}
+
+// WaitForState waits for a task, or an LRP's ActualLRPs, to reach State.
+// LRP states are UNCLAIMED, CLAIMED, RUNNING and CRASHED; without Index every
+// desired instance must be in State.  Task states are Pending, Running,
+// Completed and Resolving, and Failed checks how a completed task ended.
+type WaitForState struct {
+	Task  string `yaml:"task"`
+	LRP   string `yaml:"lrp"`
+	State string `yaml:"state"`
+
+	Index  *int32 `yaml:"index"`
+	Failed *bool  `yaml:"failed"`
+}
+
+// Curl waits for a GET of Path on the LRP's Grace route to return Status
+// (default 200) and, if set, a body containing BodyContains.
+type Curl struct {
+	LRP          string `yaml:"lrp"`
+	Path         string `yaml:"path"`
+	Status       int    `yaml:"status"`
+	BodyContains string `yaml:"body_contains"`
+}
+
+// MakeGraceExit waits for the LRP's route to serve, then asks the Grace
+// instance behind it to exit with Status.
+type MakeGraceExit struct {
+	LRP    string `yaml:"lrp"`
+	Status int    `yaml:"status"`
+}
+
+// ExpectCrashCount waits for the ActualLRP at Index to have crashed Count
+// times, and, if set, to be in State.
+type ExpectCrashCount struct {
+	LRP   string `yaml:"lrp"`
+	Index int32  `yaml:"index"`
+	Count int32  `yaml:"count"`
+	State string `yaml:"state"`
+}
+
+// Cancel cancels a task.
+type Cancel struct {
+	Task string `yaml:"task"`
+}
+
+var lrpStates = []string{
+	models.ActualLRPStateUnclaimed,
+	models.ActualLRPStateClaimed,
+	models.ActualLRPStateRunning,
+	models.ActualLRPStateCrashed,
+}
+
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Kind names the step's action as it is written in YAML.
+func (s Step) Kind() string {
+	kinds := s.kinds()
+	if len(kinds) != 1 {
+		return strings.Join(kinds, "+")
+	}
+	return kinds[0]
+}
+
+func (s Step) kinds() []string {
+	kinds := []string{}
+	if s.Desire != nil {
+		kinds = append(kinds, "desire")
+	}
+	if s.Update != nil {
+		kinds = append(kinds, "update")
+	}
+	if s.WaitForState != nil {
+		kinds = append(kinds, "wait_for_state")
+	}
+	if s.Curl != nil {
+		kinds = append(kinds, "curl")
+	}
+	if s.MakeGraceExit != nil {
+		kinds = append(kinds, "make_grace_exit")
+	}
+	if s.ExpectCrashCount != nil {
+		kinds = append(kinds, "expect_crash_count")
+	}
+	if s.Cancel != nil {
+		kinds = append(kinds, "cancel")
+	}
+	return kinds
+}
+
+// String describes the step for spec output, e.g. with Ginkgo's By.
+func (s Step) String() string {
+	switch {
+	case s.Desire != nil && s.Desire.LRP != "":
+		return fmt.Sprintf("desiring LRP %q", s.Desire.LRP)
+	case s.Desire != nil:
+		return fmt.Sprintf("desiring task %q", s.Desire.Task)
+	case s.Update != nil:
+		return fmt.Sprintf("updating LRP %q", s.Update.LRP)
+	case s.WaitForState != nil && s.WaitForState.LRP != "":
+		return fmt.Sprintf("waiting for LRP %q to be %s", s.WaitForState.LRP, s.WaitForState.State)
+	case s.WaitForState != nil:
+		return fmt.Sprintf("waiting for task %q to be %s", s.WaitForState.Task, s.WaitForState.State)
+	case s.Curl != nil:
+		return fmt.Sprintf("curling %s on LRP %q", s.Curl.Path, s.Curl.LRP)
+	case s.MakeGraceExit != nil:
+		return fmt.Sprintf("making LRP %q exit with status %d", s.MakeGraceExit.LRP, s.MakeGraceExit.Status)
+	case s.ExpectCrashCount != nil:
+		return fmt.Sprintf("expecting LRP %q instance %d to have crashed %d times", s.ExpectCrashCount.LRP, s.ExpectCrashCount.Index, s.ExpectCrashCount.Count)
+	case s.Cancel != nil:
+		return fmt.Sprintf("cancelling task %q", s.Cancel.Task)
+	}
+	return "doing nothing"
+}
+
+// Parse reads a scenario from YAML.  Unknown keys are errors, so that a typo
+// does not silently turn into a default.
+func Parse(contents []byte) (Scenario, error) {
+	scenario := Scenario{}
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	decoder.KnownFields(true)
+	err := decoder.Decode(&scenario)
+	if err != nil {
+		return Scenario{}, fmt.Errorf("invalid scenario YAML: %s", err.Error())
+	}
+	scenario.setDefaults()
+	return scenario, scenario.Validate()
+}
+
+// Load reads and validates the scenario in path.
+func Load(path string) (Scenario, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return Scenario{}, fmt.Errorf("failed to read scenario: %s", err.Error())
+	}
+	scenario, err := Parse(contents)
+	if err != nil {
+		return Scenario{}, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	scenario.Path = path
+	return scenario, nil
+}
+
+// LoadDir loads every .yml and .yaml file in dir, sorted by file name.
+// Scenario names must be unique, since they become spec names.
+func LoadDir(dir string) ([]Scenario, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("failed to read scenarios: %s", err.Error())
+	}
+
+	paths := []string{}
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	scenarios := []Scenario{}
+	loadedFrom := map[string]string{}
+	errs := []error{}
+	for _, path := range paths {
+		scenario, err := Load(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if other, ok := loadedFrom[scenario.Name]; ok {
+			errs = append(errs, fmt.Errorf("%s: scenario %q is already defined in %s", path, scenario.Name, other))
+			continue
+		}
+		loadedFrom[scenario.Name] = path
+		scenarios = append(scenarios, scenario)
+	}
+	return scenarios, errors.Join(errs...)
+}
+
+func (s *Scenario) setDefaults() {
+	for _, step := range s.Steps {
+		if step.Curl != nil {
+			if step.Curl.Path == "" {
+				step.Curl.Path = "/"
+			}
+			if step.Curl.Status == 0 {
+				step.Curl.Status = 200
+			}
+		}
+	}
+}
+
+// Validate checks every step on its own, and that each step only refers to
+// tasks and LRPs desired by an earlier step.
+func (s Scenario) Validate() error {
+	if s.Name == "" {
+		return errors.New("scenario has no name")
+	}
+	if len(s.Steps) == 0 {
+		return fmt.Errorf("scenario %q has no steps", s.Name)
+	}
+
+	tasks := map[string]bool{}
+	lrps := map[string]bool{}
+	for i, step := range s.Steps {
+		err := step.validate(tasks, lrps)
+		if err != nil {
+			return fmt.Errorf("scenario %q: step %d (%s): %s", s.Name, i+1, step.Kind(), err.Error())
+		}
+	}
+	return nil
+}
+
+func (s Step) validate(tasks, lrps map[string]bool) error {
+	kinds := s.kinds()
+	if len(kinds) == 0 {
+		return errors.New("step has no action")
+	}
+	if len(kinds) > 1 {
+		return fmt.Errorf("step has more than one action: %s", strings.Join(kinds, ", "))
+	}
+	if s.Timeout < 0 {
+		return errors.New("timeout must not be negative")
+	}
+
+	switch {
+	case s.Desire != nil:
+		return s.Desire.validate(tasks, lrps)
+	case s.Update != nil:
+		if s.Update.Instances != nil && *s.Update.Instances < 0 {
+			return errors.New("instances must not be negative")
+		}
+		return refersTo("lrp", s.Update.LRP, lrps)
+	case s.WaitForState != nil:
+		return s.WaitForState.validate(tasks, lrps)
+	case s.Curl != nil:
+		return refersTo("lrp", s.Curl.LRP, lrps)
+	case s.MakeGraceExit != nil:
+		return refersTo("lrp", s.MakeGraceExit.LRP, lrps)
+	case s.ExpectCrashCount != nil:
+		if s.ExpectCrashCount.State != "" && !oneOf(s.ExpectCrashCount.State, lrpStates) {
+			return fmt.Errorf("unknown LRP state %q, expected one of %s", s.ExpectCrashCount.State, strings.Join(lrpStates, ", "))
+		}
+		return refersTo("lrp", s.ExpectCrashCount.LRP, lrps)
+	case s.Cancel != nil:
+		return refersTo("task", s.Cancel.Task, tasks)
+	}
+	return nil
+}
+
+func (d Desire) validate(tasks, lrps map[string]bool) error {
+	name, kind := d.LRP, "lrp"
+	if d.Task != "" {
+		name, kind = d.Task, "task"
+	}
+	switch {
+	case d.Task != "" && d.LRP != "":
+		return errors.New("desire either a task or an lrp, not both")
+	case name == "":
+		return errors.New("desire needs a task or lrp name")
+	case !namePattern.MatchString(name):
+		return fmt.Errorf("%s name %q may only contain letters, digits, '-' and '_'", kind, name)
+	case tasks[name] || lrps[name]:
+		return fmt.Errorf("%q is already desired", name)
+	case d.Task != "" && d.Instances != nil:
+		return errors.New("tasks have no instances")
+	case d.Instances != nil && *d.Instances < 0:
+		return errors.New("instances must not be negative")
+	}
+
+	if d.Task != "" {
+		tasks[name] = true
+	} else {
+		lrps[name] = true
+	}
+	return nil
+}
+
+func (w WaitForState) validate(tasks, lrps map[string]bool) error {
+	if w.Task != "" && w.LRP != "" {
+		return errors.New("wait for either a task or an lrp, not both")
+	}
+	if w.Task != "" {
+		if _, ok := taskState(w.State); !ok {
+			return fmt.Errorf("unknown task state %q, expected one of Pending, Running, Completed, Resolving", w.State)
+		}
+		if w.Index != nil {
+			return errors.New("tasks have no index")
+		}
+		return refersTo("task", w.Task, tasks)
+	}
+
+	if !oneOf(w.State, lrpStates) {
+		return fmt.Errorf("unknown LRP state %q, expected one of %s", w.State, strings.Join(lrpStates, ", "))
+	}
+	if w.Failed != nil {
+		return errors.New("only tasks can fail")
+	}
+	return refersTo("lrp", w.LRP, lrps)
+}
+
+// taskState looks up a task state by its name, ignoring case.
+func taskState(name string) (models.Task_State, bool) {
+	for stateName, value := range models.Task_State_value {
+		if strings.EqualFold(stateName, name) && value != int32(models.Task_Invalid) {
+			return models.Task_State(value), true
+		}
+	}
+	return models.Task_Invalid, false
+}
+
+func refersTo(kind, name string, desired map[string]bool) error {
+	if name == "" {
+		return fmt.Errorf("missing %s name", kind)
+	}
+	if !desired[name] {
+		return fmt.Errorf("no earlier step desires %s %q", kind, name)
+	}
+	return nil
+}
+
+func oneOf(value string, values []string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
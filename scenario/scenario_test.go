@@ -0,0 +1,246 @@
+package scenario_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/vizzini/fakebbs"
+	"code.cloudfoundry.org/vizzini/fixtures"
+	"code.cloudfoundry.org/vizzini/grace"
+	"code.cloudfoundry.org/vizzini/grace/fakegrace"
+	"code.cloudfoundry.org/vizzini/scenario"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Parse", func() {
+	It("reads every kind of step", func() {
+		parsed, err := scenario.Parse([]byte(`
+name: everything
+steps:
+  - desire: {lrp: grace, instances: 2, memory_mb: 64, run: {args: [-upFile=up], env: {FOO: bar}}}
+  - desire: {task: sleeper, docker_image: "docker:///busybox"}
+  - update: {lrp: grace, instances: 3, annotation: updated}
+  - wait_for_state: {lrp: grace, state: RUNNING, index: 1}
+    timeout: 90s
+  - curl: {lrp: grace}
+  - make_grace_exit: {lrp: grace, status: 3}
+  - expect_crash_count: {lrp: grace, count: 1}
+  - cancel: {task: sleeper}
+  - wait_for_state: {task: sleeper, state: completed, failed: true}
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed.Name).To(Equal("everything"))
+		Expect(parsed.Steps).To(HaveLen(9))
+
+		Expect(*parsed.Steps[0].Desire.Instances).To(BeEquivalentTo(2))
+		Expect(parsed.Steps[0].Desire.Run.Env).To(Equal(map[string]string{"FOO": "bar"}))
+		Expect(parsed.Steps[3].Timeout).To(Equal(90 * time.Second))
+		Expect(parsed.Steps[4].Curl).To(Equal(&scenario.Curl{LRP: "grace", Path: "/", Status: 200}))
+
+		kinds := []string{}
+		for _, step := range parsed.Steps {
+			kinds = append(kinds, step.Kind())
+		}
+		Expect(kinds).To(Equal([]string{"desire", "desire", "update", "wait_for_state", "curl", "make_grace_exit", "expect_crash_count", "cancel", "wait_for_state"}))
+		Expect(parsed.Steps[3].String()).To(Equal(`waiting for LRP "grace" to be RUNNING`))
+	})
+
+	DescribeTable("rejecting invalid scenarios",
+		func(yaml string, message string) {
+			_, err := scenario.Parse([]byte(yaml))
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("unknown keys", "name: x\nsteps:\n  - desire: {lrp: grace, instance: 2}", "field instance not found"),
+		Entry("no name", "steps:\n  - desire: {lrp: grace}", "scenario has no name"),
+		Entry("no steps", "name: x", `scenario "x" has no steps`),
+		Entry("a step with no action", "name: x\nsteps:\n  - timeout: 1s", "step 1 (): step has no action"),
+		Entry("a step with two actions", "name: x\nsteps:\n  - desire: {lrp: grace}\n    cancel: {task: grace}", "step has more than one action: desire, cancel"),
+		Entry("an undesired name", "name: x\nsteps:\n  - curl: {lrp: grace}", `step 1 (curl): no earlier step desires lrp "grace"`),
+		Entry("a task used as an LRP", "name: x\nsteps:\n  - desire: {task: t}\n  - update: {lrp: t}", `step 2 (update): no earlier step desires lrp "t"`),
+		Entry("a name desired twice", "name: x\nsteps:\n  - desire: {task: t}\n  - desire: {lrp: t}", `"t" is already desired`),
+		Entry("an unusable name", "name: x\nsteps:\n  - desire: {lrp: a/b}", `lrp name "a/b" may only contain`),
+		Entry("an unknown LRP state", "name: x\nsteps:\n  - desire: {lrp: l}\n  - wait_for_state: {lrp: l, state: running}", `unknown LRP state "running"`),
+		Entry("an unknown task state", "name: x\nsteps:\n  - desire: {task: t}\n  - wait_for_state: {task: t, state: Done}", `unknown task state "Done"`),
+		Entry("instances on a task", "name: x\nsteps:\n  - desire: {task: t, instances: 2}", "tasks have no instances"),
+	)
+})
+
+var _ = Describe("LoadDir", func() {
+	It("loads the scenarios that ship with Vizzini", func() {
+		scenarios, err := scenario.LoadDir("../scenarios")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(scenarios).NotTo(BeEmpty())
+		for _, s := range scenarios {
+			Expect(s.Path).To(HavePrefix("../scenarios/"))
+		}
+	})
+
+	It("reports every broken file and duplicate name", func() {
+		dir := GinkgoT().TempDir()
+		write := func(name, contents string) {
+			Expect(os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644)).To(Succeed())
+		}
+		write("a.yml", "name: same\nsteps:\n  - desire: {task: t}")
+		write("b.yaml", "name: same\nsteps:\n  - desire: {task: t}")
+		write("c.yml", "name: broken")
+		write("ignored.json", "{}")
+
+		scenarios, err := scenario.LoadDir(dir)
+		Expect(scenarios).To(HaveLen(1))
+		Expect(err).To(MatchError(ContainSubstring(`b.yaml: scenario "same" is already defined in ` + filepath.Join(dir, "a.yml"))))
+		Expect(err).To(MatchError(ContainSubstring(`c.yml: scenario "broken" has no steps`)))
+	})
+
+	It("fails when the directory does not exist", func() {
+		_, err := scenario.LoadDir("/does/not/exist")
+		Expect(err).To(MatchError(HavePrefix("failed to read scenarios: ")))
+	})
+})
+
+var _ = Describe("Runner", func() {
+	var (
+		stepInterval time.Duration
+		server       *fakebbs.Server
+		client       bbs.InternalClient
+		graceServer  *fakegrace.Server
+		transport    *fakegrace.RouterTransport
+		runner       scenario.Runner
+		steps        []string
+	)
+
+	BeforeEach(func() {
+		stepInterval = 10 * time.Millisecond
+		steps = nil
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		server = fakebbs.NewServerWithStepInterval(stepInterval)
+		server.Start()
+		client, err = server.Client()
+		Expect(err).NotTo(HaveOccurred())
+
+		graceServer = fakegrace.NewServer(0, grace.Env{})
+		transport = graceServer.RouterTransport()
+
+		runner = scenario.Runner{
+			Logger:  lagertest.NewTestLogger("scenario"),
+			Client:  client,
+			TraceID: "trace-id",
+			Fixtures: fixtures.Defaults{
+				Domain:               "vizzini-scenario",
+				RootFS:               models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot),
+				GraceTarballURL:      "http://example.com/grace.tgz",
+				RoutableDomainSuffix: "example.com",
+			},
+			GuidPrefix:   "some-prefix",
+			Timeout:      5 * time.Second,
+			PollInterval: 10 * time.Millisecond,
+			HTTPClient:   &http.Client{Transport: transport},
+			BeforeStep: func(step scenario.Step) {
+				steps = append(steps, step.Kind())
+			},
+		}
+	})
+
+	AfterEach(func() {
+		graceServer.Close()
+		server.Close()
+	})
+
+	run := func(yaml string) error {
+		parsed, err := scenario.Parse([]byte(yaml))
+		Expect(err).NotTo(HaveOccurred())
+		return runner.Run(parsed)
+	}
+
+	It("desires, scales, curls and crashes an LRP", func() {
+		runner.BeforeStep = func(step scenario.Step) {
+			steps = append(steps, step.Kind())
+			// the fake BBS does not notice Grace exiting, so crash it by hand
+			if step.ExpectCrashCount != nil {
+				Expect(server.CrashActualLRP("some-prefix-grace", 0, "Exited with status 3")).To(Succeed())
+			}
+		}
+
+		Expect(run(`
+name: lrp
+steps:
+  - desire: {lrp: grace, instances: 2, memory_mb: 64, run: {env: {FOO: bar}}}
+  - wait_for_state: {lrp: grace, state: RUNNING}
+  - update: {lrp: grace, instances: 3, annotation: scaled}
+  - wait_for_state: {lrp: grace, state: RUNNING}
+  - curl: {lrp: grace, path: /index, body_contains: "0"}
+  - make_grace_exit: {lrp: grace, status: 3}
+  - expect_crash_count: {lrp: grace, count: 1, state: RUNNING}
+`)).To(Succeed())
+		Expect(steps).To(Equal([]string{"desire", "wait_for_state", "update", "wait_for_state", "curl", "make_grace_exit", "expect_crash_count"}))
+
+		lrp, err := client.DesiredLRPByProcessGuid(runner.Logger, "trace-id", "some-prefix-grace")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lrp.Domain).To(Equal("vizzini-scenario"))
+		Expect(lrp.Instances).To(BeEquivalentTo(3))
+		Expect(lrp.Annotation).To(Equal("scaled"))
+		Expect(lrp.MemoryMb).To(BeEquivalentTo(64))
+		Expect(lrp.Action.RunAction.Env).To(ContainElement(&models.EnvironmentVariable{Name: "FOO", Value: "bar"}))
+
+		status, exited := graceServer.ExitStatus()
+		Expect(exited).To(BeTrue())
+		Expect(status).To(Equal(3))
+		Expect(transport.Hosts()).To(HaveEach("some-prefix-grace.example.com"))
+	})
+
+	It("says which step failed and what it last saw", func() {
+		err := run(`
+name: stuck
+steps:
+  - desire: {lrp: grace}
+  - wait_for_state: {lrp: grace, state: CRASHED}
+    timeout: 100ms
+`)
+		Expect(err).To(MatchError(And(
+			HavePrefix(`step 2 (wait_for_state): timed out after 100ms waiting for LRP "grace" to be CRASHED (last saw 0: `),
+			Not(ContainSubstring("desire")),
+		)))
+	})
+
+	Context("when tasks stay pending", func() {
+		BeforeEach(func() {
+			stepInterval = time.Hour
+		})
+
+		It("cancels a task and checks how it completed", func() {
+			Expect(run(`
+name: cancel
+steps:
+  - desire: {task: sleeper, run: {args: ["-c", "sleep 1000"]}}
+  - wait_for_state: {task: sleeper, state: Pending}
+  - cancel: {task: sleeper}
+  - wait_for_state: {task: sleeper, state: Completed, failed: true}
+`)).To(Succeed())
+
+			task, err := client.TaskByGuid(runner.Logger, "trace-id", "some-prefix-sleeper")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(task.Domain).To(Equal("vizzini-scenario"))
+			Expect(task.Action.RunAction.Args).To(Equal([]string{"-c", "sleep 1000"}))
+		})
+
+		It("fails as soon as a task completes the wrong way", func() {
+			err := run(`
+name: cancel
+steps:
+  - desire: {task: sleeper}
+  - cancel: {task: sleeper}
+  - wait_for_state: {task: sleeper, state: Completed, failed: false}
+`)
+			Expect(err).To(MatchError(ContainSubstring("expected failed to be false, but the task is Completed, failed: task was cancelled")))
+		})
+	})
+})
@@ -0,0 +1,45 @@
+package vizzini_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/vizzini/scenario"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// Scenarios are specs written in YAML, one per file in scenario_dir.  The
+// config is loaded before the spec tree is built, so the files can be read
+// here.
+var _ = Describe("Scenarios", func() {
+	if config.ScenarioDir == "" {
+		return
+	}
+
+	scenarios, err := scenario.LoadDir(config.ScenarioDir)
+	if err != nil {
+		It("loads the scenarios in "+config.ScenarioDir, func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+	}
+
+	for _, s := range scenarios {
+		s := s
+		It(s.Name, func() {
+			runner := scenario.Runner{
+				Logger:       logger,
+				Client:       bbsClient,
+				TraceID:      traceID,
+				Fixtures:     Fixtures(),
+				GuidPrefix:   guid,
+				Timeout:      timeout,
+				PollInterval: 500 * time.Millisecond,
+				BeforeStep: func(step scenario.Step) {
+					By(step.String())
+				},
+			}
+			Expect(runner.Run(s)).To(Succeed(), "scenario %s failed", s.Path)
+		})
+	}
+})
@@ -5,7 +5,7 @@ import (
 	"net/http"
 
 	"code.cloudfoundry.org/bbs/models"
-	. "code.cloudfoundry.org/vizzini/matchers"
+	"code.cloudfoundry.org/vizzini/fixtures"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -32,39 +32,34 @@ var _ = Describe("Security groups", func() {
 
 		BeforeEach(func() {
 			allowedCallerGuid, disallowedCallerGuid = NewGuid(), NewGuid()
-			allowedCaller, disallowedCaller = DesiredLRPWithGuid(allowedCallerGuid), DesiredLRPWithGuid(disallowedCallerGuid)
+			allowedCaller = DesiredLRPWithGuid(allowedCallerGuid, fixtures.WithEgressRule(&models.SecurityGroupRule{
+				Protocol:     models.AllProtocol,
+				Destinations: []string{"0.0.0.0/0"},
+			}))
+			disallowedCaller = DesiredLRPWithGuid(disallowedCallerGuid)
 
 			Expect(bbsClient.DesireLRP(logger, traceID, disallowedCaller)).To(Succeed())
-			Eventually(ActualGetter(logger, disallowedCallerGuid, 0)).Should(BeActualLRPWithState(disallowedCallerGuid, 0, models.ActualLRPStateRunning))
-			Eventually(EndpointCurler("http://" + RouteForGuid(disallowedCallerGuid) + "/env")).Should(Equal(http.StatusOK))
-
-			allowedCaller.EgressRules = []*models.SecurityGroupRule{
-				{
-					Protocol:     models.AllProtocol,
-					Destinations: []string{"0.0.0.0/0"},
-				},
-			}
+			WaitForActualLRPState(disallowedCallerGuid, 0, models.ActualLRPStateRunning)
+			Eventually(GraceClient(disallowedCallerGuid).Ready).Should(BeTrue())
 
 			Expect(bbsClient.DesireLRP(logger, traceID, allowedCaller)).To(Succeed())
-			Eventually(ActualGetter(logger, allowedCallerGuid, 0)).Should(BeActualLRPWithState(allowedCallerGuid, 0, models.ActualLRPStateRunning))
-			Eventually(EndpointCurler("http://" + RouteForGuid(allowedCallerGuid) + "/env")).Should(Equal(http.StatusOK))
+			WaitForActualLRPState(allowedCallerGuid, 0, models.ActualLRPStateRunning)
+			Eventually(GraceClient(allowedCallerGuid).Ready).Should(BeTrue())
 		})
 
 		It("should allow access to an internal IP", func() {
-			endpoint := "/curl?url=http://" + gorouterLBIP + ":80"
-			urlToProxyThroughDisallowedCaller := "http://" + RouteForGuid(disallowedCallerGuid) + endpoint
-			urlToProxyThroughAllowedCaller := "http://" + RouteForGuid(allowedCallerGuid) + endpoint
+			target := "http://" + gorouterLBIP + ":80"
 
 			By("verifiying that without egress rules, this network call is disallowed")
-			resp, err := http.Get(urlToProxyThroughDisallowedCaller)
+			status, err := GraceClient(disallowedCallerGuid).Curl(target)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+			Expect(status).To(Equal(http.StatusInternalServerError))
 
 			By("asserting that opening up the security group rule allows us to call into the internal IP")
-			resp, err = http.Get(urlToProxyThroughAllowedCaller)
+			status, err = GraceClient(allowedCallerGuid).Curl(target)
 			Expect(err).NotTo(HaveOccurred())
 			// Any reply from the gorouter indicates that the application security group is in place
-			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(status).To(Equal(http.StatusBadRequest))
 		})
 	})
 
@@ -74,33 +69,27 @@ var _ = Describe("Security groups", func() {
 
 		BeforeEach(func() {
 			allowedTaskGuid, disallowedTaskGuid = NewGuid(), NewGuid()
-			allowedTask, disallowedTask = Task(), Task()
-			allowedTask.ResultFile, disallowedTask.ResultFile = "", ""
 
 			// Test whether the process can establish a tcp connection on port 80 to the internal IP
-			ncAction := models.WrapAction(&models.RunAction{
+			ncAction := fixtures.WithAction(&models.RunAction{
 				Path: "bash",
 				Args: []string{"-c", "nc -w 2 " + gorouterLBIP + " 80"},
 				User: "vcap",
 			})
 
-			disallowedTask.Action = ncAction
-			allowedTask.Action = ncAction
-
-			allowedTask.EgressRules = []*models.SecurityGroupRule{
-				{
-					Protocol:     models.AllProtocol,
-					Destinations: []string{"0.0.0.0/0"},
-				},
-			}
+			disallowedTask = Task(ncAction, fixtures.WithResultFile(""))
+			allowedTask = Task(ncAction, fixtures.WithResultFile(""), fixtures.WithEgressRule(&models.SecurityGroupRule{
+				Protocol:     models.AllProtocol,
+				Destinations: []string{"0.0.0.0/0"},
+			}))
 		})
 
 		It("should allow access to an internal IP", func() {
 			Expect(bbsClient.DesireTask(logger, traceID, allowedTaskGuid, domain, allowedTask)).To(Succeed())
 			Expect(bbsClient.DesireTask(logger, traceID, disallowedTaskGuid, domain, disallowedTask)).To(Succeed())
 
-			Eventually(TaskGetter(logger, allowedTaskGuid)).Should(HaveTaskState(models.Task_Completed))
-			Eventually(TaskGetter(logger, disallowedTaskGuid)).Should(HaveTaskState(models.Task_Completed))
+			WaitForTaskState(allowedTaskGuid, models.Task_Completed)
+			WaitForTaskState(disallowedTaskGuid, models.Task_Completed)
 
 			By("verifiying that without egress rules, this network call is disallowed")
 			task, err := bbsClient.TaskByGuid(logger, traceID, disallowedTaskGuid)
@@ -0,0 +1 @@
+package speclog // import "code.cloudfoundry.org/vizzini/speclog"
@@ -0,0 +1,72 @@
+package speclog
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+// Log captures everything one spec logs through its Logger, so that it can
+// be printed when the spec fails or saved next to the spec's report.
+type Log struct {
+	buffer *lockedBuffer
+	logger lager.Logger
+}
+
+// New starts a Log whose Logger is a session tagged with tags, e.g. the
+// spec's GUID and domain, so that every line carries them.
+func New(component string, tags lager.Data) *Log {
+	buffer := &lockedBuffer{}
+	logger := lager.NewLogger(component)
+	logger.RegisterSink(lager.NewPrettySink(buffer, lager.DEBUG))
+	return &Log{
+		buffer: buffer,
+		logger: logger.Session("spec", tags),
+	}
+}
+
+func (l *Log) Logger() lager.Logger {
+	return l.logger
+}
+
+// String returns what has been logged so far in lager's pretty format: one
+// JSON object per line, with a human-readable timestamp and log level.
+func (l *Log) String() string {
+	return l.buffer.String()
+}
+
+// WriteFile saves what has been logged so far to path, creating its
+// directory if need be.
+func (l *Log) WriteFile(path string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		err = os.WriteFile(path, []byte(l.String()), 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write spec log: %s", err.Error())
+	}
+	return nil
+}
+
+// lockedBuffer lets specs read the log while background goroutines, e.g.
+// event stream readers, are still writing to it.
+type lockedBuffer struct {
+	lock   sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buffer.String()
+}
@@ -0,0 +1,13 @@
+package speclog_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSpeclog(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Speclog Suite")
+}
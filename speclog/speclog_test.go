@@ -0,0 +1,68 @@
+package speclog_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/vizzini/speclog"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Log", func() {
+	var log *speclog.Log
+
+	BeforeEach(func() {
+		log = speclog.New("vizzini", lager.Data{"guid": "vizzini-1-aaaa", "domain": "vizzini-1"})
+	})
+
+	lines := func() []map[string]interface{} {
+		decoded := []map[string]interface{}{}
+		for _, line := range strings.Split(strings.TrimSpace(log.String()), "\n") {
+			var fields map[string]interface{}
+			Expect(json.Unmarshal([]byte(line), &fields)).To(Succeed())
+			decoded = append(decoded, fields)
+		}
+		return decoded
+	}
+
+	It("tags every line with the spec's GUID and domain", func() {
+		log.Logger().Info("desiring", lager.Data{"instances": 2})
+		log.Logger().Session("grace").Error("request-failed", errors.New("boom"))
+
+		logged := lines()
+		Expect(logged).To(HaveLen(2))
+		Expect(logged[0]).To(HaveKeyWithValue("message", "vizzini.spec.desiring"))
+		Expect(logged[1]).To(HaveKeyWithValue("message", "vizzini.spec.grace.request-failed"))
+		Expect(logged[1]["data"]).To(HaveKeyWithValue("error", "boom"))
+		for _, line := range logged {
+			Expect(line["data"]).To(And(
+				HaveKeyWithValue("guid", "vizzini-1-aaaa"),
+				HaveKeyWithValue("domain", "vizzini-1"),
+			))
+		}
+	})
+
+	It("only holds what was logged through its own logger", func() {
+		other := speclog.New("vizzini", lager.Data{"guid": "vizzini-1-bbbb"})
+		other.Logger().Info("elsewhere")
+		Expect(log.String()).To(BeEmpty())
+	})
+
+	It("saves the log to a file", func() {
+		log.Logger().Info("desiring")
+		path := filepath.Join(GinkgoT().TempDir(), "logs", "vizzini-1-aaaa.log")
+		Expect(log.WriteFile(path)).To(Succeed())
+
+		contents, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal(log.String()))
+
+		Expect(log.WriteFile(filepath.Join(path, "nested.log"))).To(MatchError(HavePrefix("failed to write spec log: ")))
+	})
+})
@@ -12,17 +12,29 @@ import (
 	"github.com/onsi/gomega/ghttp"
 
 	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/vizzini/capabilities"
+	"code.cloudfoundry.org/vizzini/eventstream"
+	"code.cloudfoundry.org/vizzini/fixtures"
 	. "code.cloudfoundry.org/vizzini/matchers"
+	"code.cloudfoundry.org/vizzini/profiles"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/types"
 )
 
 var _ = Describe("Tasks", func() {
 	var task *models.TaskDefinition
+	var taskEvents *eventstream.EventRecorder
 
 	BeforeEach(func() {
 		task = Task()
+		taskEvents = eventstream.NewEventRecorder(guid)
+		Expect(taskEvents.Start(logger, bbsClient)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		taskEvents.Stop()
 	})
 
 	Describe("Creating Tasks", func() {
@@ -32,7 +44,7 @@ var _ = Describe("Tasks", func() {
 			})
 
 			It("runs the task", func() {
-				Eventually(TaskGetter(logger, guid)).Should(HaveTaskState(models.Task_Completed))
+				WaitForTaskState(guid, models.Task_Completed)
 
 				task, err := bbsClient.TaskByGuid(logger, traceID, guid)
 				Expect(err).NotTo(HaveOccurred())
@@ -43,6 +55,15 @@ var _ = Describe("Tasks", func() {
 
 				Expect(bbsClient.ResolvingTask(logger, traceID, guid)).To(Succeed())
 				Expect(bbsClient.DeleteTask(logger, traceID, guid)).To(Succeed())
+
+				By("emitting an event for every transition")
+				Eventually(taskEvents.Events).Should(MatchTaskEventSequence(guid,
+					MatchTaskCreatedEvent(guid),
+					MatchTaskChangedEvent(guid, models.Task_Pending, models.Task_Running),
+					MatchTaskChangedEvent(guid, models.Task_Running, models.Task_Completed),
+					MatchTaskChangedEvent(guid, models.Task_Completed, models.Task_Resolving),
+					MatchTaskRemovedEvent(guid),
+				))
 			})
 		})
 
@@ -77,7 +98,7 @@ var _ = Describe("Tasks", func() {
 				By("even when the domain is different")
 				Expect(bbsClient.DesireTask(logger, traceID, guid, otherDomain, task)).NotTo(Succeed())
 
-				Eventually(TaskGetter(logger, guid)).Should(HaveTaskState(models.Task_Completed))
+				WaitForTaskState(guid, models.Task_Completed)
 				Expect(bbsClient.ResolvingTask(logger, traceID, guid)).To(Succeed())
 				Expect(bbsClient.DeleteTask(logger, traceID, guid)).To(Succeed())
 			})
@@ -127,7 +148,7 @@ var _ = Describe("Tasks", func() {
 			})
 
 			It("should be marked as failed and should not return the result file", func() {
-				Eventually(TaskGetter(logger, guid)).Should(HaveTaskState(models.Task_Completed))
+				WaitForTaskState(guid, models.Task_Completed)
 
 				task, err := bbsClient.TaskByGuid(logger, traceID, guid)
 				Expect(err).NotTo(HaveOccurred())
@@ -161,7 +182,7 @@ var _ = Describe("Tasks", func() {
 
 		It("should be possible to specify environment variables on both the Task and the RunAction", func() {
 			Expect(bbsClient.DesireTask(logger, traceID, guid, domain, task)).To(Succeed())
-			Eventually(TaskGetter(logger, guid)).Should(HaveTaskState(models.Task_Completed))
+			WaitForTaskState(guid, models.Task_Completed)
 
 			task, err := bbsClient.TaskByGuid(logger, traceID, guid)
 			Expect(err).NotTo(HaveOccurred())
@@ -176,22 +197,26 @@ var _ = Describe("Tasks", func() {
 		})
 	})
 
-	Describe("{DOCKER} Creating a Docker-based Task", func() {
+	Describe("Creating a Docker-based Task", Label(profiles.Docker), func() {
 		BeforeEach(func() {
-			task.RootFs = "docker:///cloudfoundry/busybox-alice"
-			task.Action = models.WrapAction(&models.RunAction{
-				Path: "sh",
-				Args: []string{"-c", "echo 'down-the-rabbit-hole' > payload && chmod 0400 payload"},
-				User: "alice",
-			})
-			task.ResultFile = "/home/alice/payload"
+			RequireCapabilities(capabilities.Docker)
+			task = Task(
+				fixtures.WithDockerImage("docker:///cloudfoundry/busybox-alice"),
+				fixtures.WithAction(&models.RunAction{
+					Path: "sh",
+					Args: []string{"-c", "echo 'down-the-rabbit-hole' > payload && chmod 0400 payload"},
+					User: "alice",
+				}),
+				fixtures.WithResultFile("/home/alice/payload"),
+			)
 		})
 		JustBeforeEach(func() {
 			Expect(bbsClient.DesireTask(logger, traceID, guid, domain, task)).To(Succeed())
 		})
 
 		It("should succeed", func() {
-			Eventually(TaskGetter(logger, guid), 120).Should(HaveTaskState(models.Task_Completed), "Docker can be quite slow to spin up....")
+			// Docker can be quite slow to spin up
+			WaitForTaskState(guid, models.Task_Completed, 120*time.Second)
 
 			task, err := bbsClient.TaskByGuid(logger, traceID, guid)
 			Expect(err).NotTo(HaveOccurred())
@@ -201,18 +226,22 @@ var _ = Describe("Tasks", func() {
 			Expect(bbsClient.ResolvingTask(logger, traceID, guid)).To(Succeed())
 			Expect(bbsClient.DeleteTask(logger, traceID, guid)).To(Succeed())
 		})
-		Context("with an OCI image", func() {
+		Context("with an OCI image", Label(profiles.OCI), func() {
 			BeforeEach(func() {
-				task.RootFs = config.DiegoDockerOCIImageURL
-				task.Action = models.WrapAction(&models.RunAction{
-					Path: "sh",
-					Args: []string{"-c", "echo 'down-the-rabbit-hole' > /payload && chmod 0400 /payload"},
-					User: "root",
-				})
-				task.ResultFile = "/payload"
+				RequireCapabilities(capabilities.OCIImages)
+				task = Task(
+					fixtures.WithDockerImage(config.DiegoDockerOCIImageURL),
+					fixtures.WithAction(&models.RunAction{
+						Path: "sh",
+						Args: []string{"-c", "echo 'down-the-rabbit-hole' > /payload && chmod 0400 /payload"},
+						User: "root",
+					}),
+					fixtures.WithResultFile("/payload"),
+				)
 			})
 			It("should succeed", func() {
-				Eventually(TaskGetter(logger, guid), 120).Should(HaveTaskState(models.Task_Completed), "Docker can be quite slow to spin up....")
+				// Docker can be quite slow to spin up
+				WaitForTaskState(guid, models.Task_Completed, 120*time.Second)
 
 				task, err := bbsClient.TaskByGuid(logger, traceID, guid)
 				Expect(err).NotTo(HaveOccurred())
@@ -234,30 +263,30 @@ var _ = Describe("Tasks", func() {
 
 				lrp := DesiredLRPWithGuid(lrpGuid)
 				Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
-				Eventually(EndpointCurler("http://" + RouteForGuid(lrpGuid) + "/env")).Should(Equal(http.StatusOK))
+				Eventually(GraceClient(lrpGuid).Ready).Should(BeTrue())
 
 				incrementCounterRoute := "http://" + RouteForGuid(lrpGuid) + "/counter"
 
-				task.EgressRules = []*models.SecurityGroupRule{
-					{
+				task = Task(
+					fixtures.WithEgressRule(&models.SecurityGroupRule{
 						Protocol:     models.AllProtocol,
 						Destinations: []string{"0.0.0.0/0"},
-					},
-				}
-				task.Action = models.WrapAction(&models.RunAction{
-					Path: "bash",
-					Args: []string{"-c", fmt.Sprintf("while true; do curl %s -X POST -d 'body'; sleep 0.05; done", incrementCounterRoute)},
-					User: "vcap",
-				})
+					}),
+					fixtures.WithAction(&models.RunAction{
+						Path: "bash",
+						Args: []string{"-c", fmt.Sprintf("while true; do curl %s -X POST -d 'body'; sleep 0.05; done", incrementCounterRoute)},
+						User: "vcap",
+					}),
+				)
 
 				Expect(bbsClient.DesireTask(logger, traceID, guid, domain, task)).To(Succeed())
 			})
 
 			It("should cancel the task immediately", func() {
-				Eventually(TaskGetter(logger, guid)).Should(HaveTaskState(models.Task_Running))
+				WaitForTaskState(guid, models.Task_Running)
 
 				By("verifying the counter is being incremented")
-				Eventually(GraceCounterGetter(lrpGuid)).Should(BeNumerically(">", 2))
+				Eventually(GraceClient(lrpGuid).Counter).Should(BeNumerically(">", 2))
 
 				Expect(bbsClient.CancelTask(logger, traceID, guid)).To(Succeed())
 
@@ -269,17 +298,26 @@ var _ = Describe("Tasks", func() {
 				Expect(task.FailureReason).To(Equal("task was cancelled"))
 
 				By("actually shutting down the container immediately, it should stop incrementing the counter")
-				counterAfterCancel, err := GraceCounterGetter(lrpGuid)()
+				counterAfterCancel, err := GraceClient(lrpGuid).Counter()
 				Expect(err).NotTo(HaveOccurred())
 
 				time.Sleep(2 * time.Second)
 
-				counterAfterSomeTime, err := GraceCounterGetter(lrpGuid)()
+				counterAfterSomeTime, err := GraceClient(lrpGuid).Counter()
 				Expect(err).NotTo(HaveOccurred())
 				Expect(counterAfterSomeTime).To(BeNumerically("<", counterAfterCancel+20))
 
 				Expect(bbsClient.ResolvingTask(logger, traceID, guid)).To(Succeed())
 				Expect(bbsClient.DeleteTask(logger, traceID, guid)).To(Succeed())
+
+				By("emitting the cancellation as a transition straight from running to completed")
+				Eventually(taskEvents.Events).Should(MatchTaskEventSequence(guid,
+					MatchTaskCreatedEvent(guid),
+					MatchTaskChangedEvent(guid, models.Task_Pending, models.Task_Running),
+					MatchTaskChangedEvent(guid, models.Task_Running, models.Task_Completed),
+					MatchTaskChangedEvent(guid, models.Task_Completed, models.Task_Resolving),
+					MatchTaskRemovedEvent(guid),
+				))
 			})
 		})
 
@@ -292,7 +330,7 @@ var _ = Describe("Tasks", func() {
 		Context("when the task is already completed", func() {
 			BeforeEach(func() {
 				Expect(bbsClient.DesireTask(logger, traceID, guid, domain, task)).To(Succeed())
-				Eventually(TaskGetter(logger, guid)).Should(HaveTaskState(models.Task_Completed))
+				WaitForTaskState(guid, models.Task_Completed)
 			})
 
 			It("should fail", func() {
@@ -316,7 +354,7 @@ var _ = Describe("Tasks", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(task.TaskGuid).To(Equal(guid))
 
-				Eventually(TaskGetter(logger, guid)).Should(HaveTaskState(models.Task_Completed))
+				WaitForTaskState(guid, models.Task_Completed)
 				Expect(bbsClient.ResolvingTask(logger, traceID, guid)).To(Succeed())
 				Expect(bbsClient.DeleteTask(logger, traceID, guid)).To(Succeed())
 			})
@@ -336,13 +374,13 @@ var _ = Describe("Tasks", func() {
 
 		BeforeEach(func() {
 			Expect(bbsClient.DesireTask(logger, traceID, guid, domain, task)).To(Succeed())
-			Eventually(TaskGetter(logger, guid)).Should(HaveTaskState(models.Task_Completed))
+			WaitForTaskState(guid, models.Task_Completed)
 
 			otherGuids = []string{NewGuid(), NewGuid()}
 			for _, otherGuid := range otherGuids {
 				otherTask := Task()
 				Expect(bbsClient.DesireTask(logger, traceID, otherGuid, otherDomain, otherTask)).To(Succeed())
-				Eventually(TaskGetter(logger, otherGuid)).Should(HaveTaskState(models.Task_Completed))
+				WaitForTaskState(otherGuid, models.Task_Completed)
 			}
 		})
 
@@ -393,12 +431,20 @@ var _ = Describe("Tasks", func() {
 		Context("when the task is in the completed state", func() {
 			It("should be deleted", func() {
 				Expect(bbsClient.DesireTask(logger, traceID, guid, domain, task)).To(Succeed())
-				Eventually(TaskGetter(logger, guid)).Should(HaveTaskState(models.Task_Completed))
+				WaitForTaskState(guid, models.Task_Completed)
 
 				Expect(bbsClient.ResolvingTask(logger, traceID, guid)).To(Succeed())
 				Expect(bbsClient.DeleteTask(logger, traceID, guid)).To(Succeed())
 				_, err := bbsClient.TaskByGuid(logger, traceID, guid)
 				Expect(err).To(HaveOccurred())
+
+				Eventually(taskEvents.Events).Should(MatchTaskEventSequence(guid,
+					MatchTaskCreatedEvent(guid),
+					MatchTaskChangedEvent(guid, models.Task_Pending, models.Task_Running),
+					MatchTaskChangedEvent(guid, models.Task_Running, models.Task_Completed),
+					MatchTaskChangedEvent(guid, models.Task_Completed, models.Task_Resolving),
+					MatchTaskRemovedEvent(guid),
+				))
 			})
 		})
 
@@ -410,16 +456,26 @@ var _ = Describe("Tasks", func() {
 					User: "vcap",
 				})
 				Expect(bbsClient.DesireTask(logger, traceID, guid, domain, task)).To(Succeed())
-				Eventually(TaskGetter(logger, guid)).Should(HaveTaskState(models.Task_Running))
+				WaitForTaskState(guid, models.Task_Running)
 				err := bbsClient.ResolvingTask(logger, traceID, guid)
 				Expect(models.ConvertError(err).Type).To(Equal(models.Error_InvalidStateTransition))
 
 				_, err = bbsClient.TasksByDomain(logger, traceID, domain)
 				Expect(err).NotTo(HaveOccurred())
 
-				Eventually(TaskGetter(logger, guid)).Should(HaveTaskState(models.Task_Completed))
+				WaitForTaskState(guid, models.Task_Completed)
 				Expect(bbsClient.ResolvingTask(logger, traceID, guid)).To(Succeed())
 				Expect(bbsClient.DeleteTask(logger, traceID, guid)).To(Succeed())
+
+				By("never emitting the rejected transition to resolving")
+				Eventually(taskEvents.Events).Should(MatchTaskEventSequence(guid,
+					MatchTaskCreatedEvent(guid),
+					MatchTaskChangedEvent(guid, models.Task_Pending, models.Task_Running),
+					MatchTaskChangedEvent(guid, models.Task_Running, models.Task_Completed),
+					MatchTaskChangedEvent(guid, models.Task_Completed, models.Task_Resolving),
+					MatchTaskRemovedEvent(guid),
+				))
+				Expect(taskEvents.Events()).NotTo(ContainElement(MatchTaskChangedEvent(guid, models.Task_Running, models.Task_Resolving)))
 			})
 		})
 
@@ -481,6 +537,14 @@ var _ = Describe("Tasks", func() {
 					_, err := bbsClient.TaskByGuid(logger, traceID, guid)
 					return err == nil
 				}).Should(BeFalse(), "Eventually, the task should be resolved")
+
+				Eventually(taskEvents.Events).Should(MatchTaskEventSequence(guid,
+					MatchTaskCreatedEvent(guid),
+					MatchTaskChangedEvent(guid, models.Task_Pending, models.Task_Running),
+					MatchTaskChangedEvent(guid, models.Task_Running, models.Task_Completed),
+					MatchTaskChangedEvent(guid, models.Task_Completed, models.Task_Resolving),
+					MatchTaskRemovedEvent(guid),
+				))
 			})
 		})
 
@@ -496,6 +560,14 @@ var _ = Describe("Tasks", func() {
 					_, err := bbsClient.TaskByGuid(logger, traceID, guid)
 					return err == nil
 				}).Should(BeFalse(), "Eventually, the task should be resolved")
+
+				Eventually(taskEvents.Events).Should(MatchTaskEventSequence(guid,
+					MatchTaskCreatedEvent(guid),
+					MatchTaskChangedEvent(guid, models.Task_Pending, models.Task_Running),
+					MatchTaskChangedEvent(guid, models.Task_Running, models.Task_Completed),
+					MatchTaskChangedEvent(guid, models.Task_Completed, models.Task_Resolving),
+					MatchTaskRemovedEvent(guid),
+				))
 			})
 		})
 
@@ -526,6 +598,14 @@ var _ = Describe("Tasks", func() {
 					_, err := bbsClient.TaskByGuid(logger, traceID, guid)
 					return err == nil
 				}).Should(BeFalse(), "Eventually, the task should be resolved")
+
+				Eventually(taskEvents.Events).Should(MatchTaskEventSequence(guid,
+					MatchTaskCreatedEvent(guid),
+					MatchTaskChangedEvent(guid, models.Task_Pending, models.Task_Running),
+					MatchTaskChangedEvent(guid, models.Task_Running, models.Task_Completed),
+					MatchTaskChangedEvent(guid, models.Task_Completed, models.Task_Resolving),
+					MatchTaskRemovedEvent(guid),
+				))
 			})
 		})
 
@@ -542,6 +622,14 @@ var _ = Describe("Tasks", func() {
 					_, err := bbsClient.TaskByGuid(logger, traceID, guid)
 					return err == nil
 				}).Should(BeFalse(), "Eventually, the task should be resolved")
+
+				By("failing the task without it ever running")
+				Eventually(taskEvents.Events).Should(MatchTaskEventSequence(guid,
+					MatchTaskCreatedEvent(guid),
+					MatchTaskChangedEvent(guid, models.Task_Pending, models.Task_Completed),
+					MatchTaskChangedEvent(guid, models.Task_Completed, models.Task_Resolving),
+					MatchTaskRemovedEvent(guid),
+				))
 			})
 		})
 	})
@@ -554,7 +642,7 @@ var _ = Describe("Tasks", func() {
 
 			It("should allow creation of the task but should mark the task as failed", func() {
 				Expect(bbsClient.DesireTask(logger, traceID, guid, domain, task)).To(Succeed())
-				Eventually(TaskGetter(logger, guid), taskFailureTimeout).Should(HaveTaskState(models.Task_Completed))
+				WaitForTaskState(guid, models.Task_Completed, taskFailureTimeout)
 
 				retreivedTask, err := bbsClient.TaskByGuid(logger, traceID, guid)
 				Expect(err).NotTo(HaveOccurred())
@@ -564,6 +652,13 @@ var _ = Describe("Tasks", func() {
 
 				Expect(bbsClient.ResolvingTask(logger, traceID, guid)).To(Succeed())
 				Expect(bbsClient.DeleteTask(logger, traceID, guid)).To(Succeed())
+
+				Eventually(taskEvents.Events).Should(MatchTaskEventSequence(guid,
+					MatchTaskCreatedEvent(guid),
+					MatchTaskChangedEvent(guid, models.Task_Pending, models.Task_Completed),
+					MatchTaskChangedEvent(guid, models.Task_Completed, models.Task_Resolving),
+					MatchTaskRemovedEvent(guid),
+				))
 			})
 		})
 
@@ -574,7 +669,7 @@ var _ = Describe("Tasks", func() {
 
 			It("should allow creation of the task but should mark the task as failed", func() {
 				Expect(bbsClient.DesireTask(logger, traceID, guid, domain, task)).To(Succeed())
-				Eventually(TaskGetter(logger, guid), taskFailureTimeout).Should(HaveTaskState(models.Task_Completed))
+				WaitForTaskState(guid, models.Task_Completed, taskFailureTimeout)
 
 				retreivedTask, err := bbsClient.TaskByGuid(logger, traceID, guid)
 				Expect(err).NotTo(HaveOccurred())
@@ -584,7 +679,20 @@ var _ = Describe("Tasks", func() {
 
 				Expect(bbsClient.ResolvingTask(logger, traceID, guid)).To(Succeed())
 				Expect(bbsClient.DeleteTask(logger, traceID, guid)).To(Succeed())
+
+				Eventually(taskEvents.Events).Should(MatchTaskEventSequence(guid,
+					MatchTaskCreatedEvent(guid),
+					MatchTaskChangedEvent(guid, models.Task_Pending, models.Task_Completed),
+					MatchTaskChangedEvent(guid, models.Task_Completed, models.Task_Resolving),
+					MatchTaskRemovedEvent(guid),
+				))
 			})
 		})
 	})
 })
+
+// MatchTaskEventSequence matches guid's task events strictly in order,
+// skipping only changes that keep the task's state.
+func MatchTaskEventSequence(guid string, matchers ...types.GomegaMatcher) types.GomegaMatcher {
+	return MatchEventSequence(matchers...).Ignoring(MatchTaskKeptStateEvent(guid))
+}
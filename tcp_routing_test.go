@@ -0,0 +1,78 @@
+package vizzini_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/routing-info/tcp_routes"
+	"code.cloudfoundry.org/vizzini/fixtures"
+	"code.cloudfoundry.org/vizzini/profiles"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TCP Routing", Label(profiles.Routing), func() {
+	var (
+		lrp                       *models.DesiredLRP
+		externalPort, updatedPort uint32
+	)
+
+	BeforeEach(func() {
+		if config.TCPRouterAddress == "" {
+			Skip("tcp_router_address is not configured")
+		}
+		externalPort, updatedPort = TCPExternalPorts()
+
+		lrp = DesiredLRPWithGuid(guid, fixtures.WithTCPRoute(config.TCPRouterGroupGuid, externalPort, fixtures.GracePort))
+		lrp.Instances = 3
+		Expect(bbsClient.DesireLRP(logger, traceID, lrp)).To(Succeed())
+	})
+
+	It("balances connections across every instance", func() {
+		Eventually(TCPIndexCounter(externalPort, 30)).Should(Equal(3))
+	})
+
+	It("stops routing to instances that are scaled down", func() {
+		Eventually(TCPIndexCounter(externalPort, 30)).Should(Equal(3))
+
+		update := &models.DesiredLRPUpdate{}
+		update.SetInstances(1)
+		Expect(bbsClient.UpdateDesiredLRP(logger, traceID, guid, update)).To(Succeed())
+
+		Eventually(TCPIndexCounter(externalPort, 10)).Should(Equal(1))
+		Consistently(TCPIndexCounter(externalPort, 10), 5*time.Second).Should(Equal(1))
+		Expect(TCPGraceClient(externalPort).Index()).To(Equal(0))
+	})
+
+	It("moves the route when it is updated", func() {
+		Eventually(TCPGraceClient(externalPort).Ready).Should(BeTrue())
+
+		By("replacing the external port")
+		routes := fixtures.ReplaceTCPRoutes(lrp.Routes, tcp_routes.TCPRoutes{
+			{RouterGroupGuid: config.TCPRouterGroupGuid, ExternalPort: updatedPort, ContainerPort: fixtures.GracePort},
+		})
+		Expect(bbsClient.UpdateDesiredLRP(logger, traceID, guid, &models.DesiredLRPUpdate{Routes: routes})).To(Succeed())
+
+		By("reaching the instances through the new port")
+		Eventually(TCPGraceClient(updatedPort).Ready).Should(BeTrue())
+		Eventually(TCPIndexCounter(updatedPort, 30)).Should(Equal(3))
+
+		By("no longer reaching them through the old port")
+		Eventually(TCPGraceClient(externalPort).Ready).Should(BeFalse())
+
+		By("unregistering every TCP route")
+		routes = fixtures.ReplaceTCPRoutes(lrp.Routes, nil)
+		Expect(bbsClient.UpdateDesiredLRP(logger, traceID, guid, &models.DesiredLRPUpdate{Routes: routes})).To(Succeed())
+		Eventually(TCPGraceClient(updatedPort).Ready).Should(BeFalse())
+	})
+
+	It("removes the route when the LRP is deleted", func() {
+		Eventually(TCPGraceClient(externalPort).Ready).Should(BeTrue())
+
+		Expect(bbsClient.RemoveDesiredLRP(logger, traceID, guid)).To(Succeed())
+		WaitForDesiredLRPRemoved(guid)
+
+		Eventually(TCPGraceClient(externalPort).Ready).Should(BeFalse())
+	})
+})
@@ -10,21 +10,24 @@ import (
 
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/tlsconfig"
+	"code.cloudfoundry.org/vizzini/capabilities"
 	. "code.cloudfoundry.org/vizzini/matchers"
+	"code.cloudfoundry.org/vizzini/profiles"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
 
-var _ = Describe("TLS Proxy", func() {
+var _ = Describe("TLS Proxy", Label(profiles.Proxy), func() {
 	var (
 		lrp       *models.DesiredLRP
 		actualLRP models.ActualLRP
 	)
 
 	BeforeEach(func() {
-		if !config.EnableContainerProxyTests {
-			Skip("container proxy tests are disabled")
+		RequireCapabilities(capabilities.ContainerProxy)
+		if config.ProxyCAPath == "" {
+			Skip("proxy_ca_path is not configured")
 		}
 
 		lrp = DesiredLRPWithGuid(guid)
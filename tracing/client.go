@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+// Client returns a BBS client that records a span for every call that
+// desires, changes or removes something, within the trace whose ID the call
+// was made with.  Calls made with any other trace ID are not traced.
+func (t *Tracer) Client(client bbs.InternalClient) bbs.InternalClient {
+	return &tracingClient{InternalClient: client, tracer: t}
+}
+
+type tracingClient struct {
+	bbs.InternalClient
+	tracer *Tracer
+}
+
+func (c *tracingClient) DesireLRP(logger lager.Logger, traceID string, lrp *models.DesiredLRP) error {
+	end := c.tracer.Span(traceID, "desire lrp", GuidKey.String(lrp.ProcessGuid), DomainKey.String(lrp.Domain))
+	err := c.InternalClient.DesireLRP(logger, traceID, lrp)
+	end(err)
+	return err
+}
+
+func (c *tracingClient) UpdateDesiredLRP(logger lager.Logger, traceID string, processGuid string, update *models.DesiredLRPUpdate) error {
+	end := c.tracer.Span(traceID, "update lrp", GuidKey.String(processGuid))
+	err := c.InternalClient.UpdateDesiredLRP(logger, traceID, processGuid, update)
+	end(err)
+	return err
+}
+
+func (c *tracingClient) RemoveDesiredLRP(logger lager.Logger, traceID string, processGuid string) error {
+	end := c.tracer.Span(traceID, "remove lrp", GuidKey.String(processGuid))
+	err := c.InternalClient.RemoveDesiredLRP(logger, traceID, processGuid)
+	end(err)
+	return err
+}
+
+func (c *tracingClient) RetireActualLRP(logger lager.Logger, traceID string, key *models.ActualLRPKey) error {
+	end := c.tracer.Span(traceID, "retire actual lrp", GuidKey.String(key.ProcessGuid), IndexKey.Int(int(key.Index)))
+	err := c.InternalClient.RetireActualLRP(logger, traceID, key)
+	end(err)
+	return err
+}
+
+func (c *tracingClient) DesireTask(logger lager.Logger, traceID string, guid, domain string, definition *models.TaskDefinition) error {
+	end := c.tracer.Span(traceID, "desire task", GuidKey.String(guid), DomainKey.String(domain))
+	err := c.InternalClient.DesireTask(logger, traceID, guid, domain, definition)
+	end(err)
+	return err
+}
+
+func (c *tracingClient) CancelTask(logger lager.Logger, traceID string, taskGuid string) error {
+	end := c.tracer.Span(traceID, "cancel task", GuidKey.String(taskGuid))
+	err := c.InternalClient.CancelTask(logger, traceID, taskGuid)
+	end(err)
+	return err
+}
+
+func (c *tracingClient) DeleteTask(logger lager.Logger, traceID string, taskGuid string) error {
+	end := c.tracer.Span(traceID, "delete task", GuidKey.String(taskGuid))
+	err := c.InternalClient.DeleteTask(logger, traceID, taskGuid)
+	end(err)
+	return err
+}
@@ -0,0 +1,206 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/onsi/ginkgo/v2/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const ServiceName = "vizzini"
+
+// Attribute keys set on Vizzini's spans.
+const (
+	GuidKey     = attribute.Key("vizzini.guid")
+	DomainKey   = attribute.Key("vizzini.domain")
+	IndexKey    = attribute.Key("vizzini.index")
+	StateKey    = attribute.Key("vizzini.state")
+	LocationKey = attribute.Key("vizzini.location")
+)
+
+// Options say where spans are exported.  With neither set spans are still
+// created, so that every trace still has its own ID, but they go nowhere.
+type Options struct {
+	// OTLPEndpoint is the URL of an OTLP/HTTP collector, e.g.
+	// http://localhost:4318.
+	OTLPEndpoint string
+
+	// File is appended to with one JSON-encoded span per line, so parallel
+	// processes can share it.
+	File string
+}
+
+// Tracer starts traces and remembers the ones still running by ID, so that a
+// BBS call can be traced within the trace whose ID it was made with.
+type Tracer struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+	file     *os.File
+
+	lock   sync.Mutex
+	traces map[string]*Trace
+}
+
+func New(ctx context.Context, options Options) (*Tracer, error) {
+	t := &Tracer{traces: map[string]*Trace{}}
+	providerOptions := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", ServiceName))),
+	}
+
+	if options.OTLPEndpoint != "" {
+		exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(options.OTLPEndpoint))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %s", err.Error())
+		}
+		providerOptions = append(providerOptions, sdktrace.WithBatcher(exporter))
+	}
+
+	if options.File != "" {
+		file, err := os.OpenFile(options.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %s", err.Error())
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to create file exporter: %s", err.Error())
+		}
+		t.file = file
+		// written as each span ends, so a crashed run still leaves its spans
+		providerOptions = append(providerOptions, sdktrace.WithSyncer(exporter))
+	}
+
+	t.provider = sdktrace.NewTracerProvider(providerOptions...)
+	t.tracer = t.provider.Tracer("code.cloudfoundry.org/vizzini")
+	return t, nil
+}
+
+// Shutdown exports every span that has ended and closes the exporters.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	err := t.provider.Shutdown(ctx)
+	if t.file != nil {
+		closeErr := t.file.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to shut down tracing: %s", err.Error())
+	}
+	return nil
+}
+
+// Start begins a new trace, e.g. for a spec.
+func (t *Tracer) Start(name string, attributes ...attribute.KeyValue) *Trace {
+	ctx, span := t.tracer.Start(context.Background(), name, trace.WithNewRoot(), trace.WithAttributes(attributes...))
+	started := &Trace{
+		tracer: t,
+		ctx:    ctx,
+		span:   span,
+		id:     RequestID(span.SpanContext().TraceID()),
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.traces[started.id] = started
+	return started
+}
+
+// Span starts a span within the running trace with the given ID, and returns
+// the function that ends it.  Spans for any other ID are not recorded.
+func (t *Tracer) Span(traceID, name string, attributes ...attribute.KeyValue) func(error) {
+	t.lock.Lock()
+	running := t.traces[traceID]
+	t.lock.Unlock()
+
+	if running == nil {
+		return func(error) {}
+	}
+	_, span := t.tracer.Start(running.ctx, name, trace.WithAttributes(attributes...))
+	return func(err error) {
+		End(span, err)
+	}
+}
+
+func (t *Tracer) forget(id string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.traces, id)
+}
+
+// RequestID formats traceID as a UUID.  The BBS client sends its trace ID in
+// the X-Vcap-Request-Id header, and the BBS logs a UUID found there as the
+// trace-id of the request, so its logs can be searched for this value with
+// the dashes taken out.
+func RequestID(traceID trace.TraceID) string {
+	id := traceID.String()
+	return id[0:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:32]
+}
+
+// Trace is a root span and the spans within it.
+type Trace struct {
+	tracer *Tracer
+	ctx    context.Context
+	span   trace.Span
+	id     string
+}
+
+// ID is the trace ID to pass to the BBS with every call made for this trace.
+func (t *Trace) ID() string {
+	return t.id
+}
+
+// TraceID is the trace ID as exporters show it, without dashes.
+func (t *Trace) TraceID() string {
+	return t.span.SpanContext().TraceID().String()
+}
+
+// RecordSteps adds a span for every By in events, each ending when the next
+// one starts or at end.  Ginkgo only reports when a step started, so steps
+// are recorded once the spec is over rather than as they happen.
+func (t *Trace) RecordSteps(events types.SpecEvents, end time.Time) {
+	steps := types.SpecEvents{}
+	for _, event := range events {
+		if event.SpecEventType == types.SpecEventByStart {
+			steps = append(steps, event)
+		}
+	}
+
+	for i, step := range steps {
+		stepEnd := end
+		if i+1 < len(steps) {
+			stepEnd = steps[i+1].TimelineLocation.Time
+		}
+		_, span := t.tracer.tracer.Start(t.ctx, "step: "+step.Message,
+			trace.WithTimestamp(step.TimelineLocation.Time),
+			trace.WithAttributes(LocationKey.String(step.CodeLocation.String())),
+		)
+		span.End(trace.WithTimestamp(stepEnd))
+	}
+}
+
+// End ends the trace, marking it failed if err is not nil.  Calls made with
+// its ID afterwards are no longer traced.
+func (t *Trace) End(err error) {
+	t.tracer.forget(t.id)
+	End(t.span, err)
+}
+
+// End ends span, recording err on it if there was one.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
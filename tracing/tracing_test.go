@@ -0,0 +1,148 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/vizzini/fakebbs"
+	"code.cloudfoundry.org/vizzini/tracing"
+	"github.com/onsi/ginkgo/v2/types"
+	"go.opentelemetry.io/otel/trace"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// exportedSpan is the part of a span in the trace file these specs look at.
+type exportedSpan struct {
+	Name        string
+	SpanContext struct {
+		TraceID string
+		SpanID  string
+	}
+	Parent struct {
+		SpanID string
+	}
+	StartTime time.Time
+	EndTime   time.Time
+	Status    struct {
+		Code        string
+		Description string
+	}
+}
+
+var _ = Describe("Tracing", func() {
+	var (
+		path   string
+		tracer *tracing.Tracer
+	)
+
+	BeforeEach(func() {
+		var err error
+		path = filepath.Join(GinkgoT().TempDir(), "spans.json")
+		tracer, err = tracing.New(context.Background(), tracing.Options{File: path})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	exported := func() map[string]exportedSpan {
+		Expect(tracer.Shutdown(context.Background())).To(Succeed())
+		file, err := os.Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		spans := map[string]exportedSpan{}
+		decoder := json.NewDecoder(file)
+		for {
+			var span exportedSpan
+			err := decoder.Decode(&span)
+			if err == io.EOF {
+				return spans
+			}
+			Expect(err).NotTo(HaveOccurred())
+			spans[span.Name] = span
+		}
+	}
+
+	It("formats trace IDs as the UUIDs the BBS expects as request IDs", func() {
+		traceID, err := trace.TraceIDFromHex("0123456789abcdef0123456789abcdef")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tracing.RequestID(traceID)).To(Equal("01234567-89ab-cdef-0123-456789abcdef"))
+
+		first, second := tracer.Start("first"), tracer.Start("second")
+		Expect(first.ID()).To(MatchRegexp(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`))
+		Expect(first.ID()).NotTo(Equal(second.ID()))
+		first.End(nil)
+		second.End(nil)
+	})
+
+	It("traces BBS calls and steps within the trace whose ID they were made with", func() {
+		server := fakebbs.NewServer()
+		server.Start()
+		defer server.Close()
+		internalClient, err := server.Client()
+		Expect(err).NotTo(HaveOccurred())
+		client := tracer.Client(internalClient)
+		logger := lagertest.NewTestLogger("tracing")
+
+		spec := tracer.Start("some spec", tracing.GuidKey.String("some-guid"))
+		definition := &models.TaskDefinition{
+			RootFs:   models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot),
+			MemoryMb: 128,
+			Action:   models.WrapAction(&models.RunAction{Path: "true", User: "vcap"}),
+		}
+		Expect(client.DesireTask(logger, spec.ID(), "some-task", "tracing", definition)).To(Succeed())
+		Expect(client.CancelTask(logger, "some-other-trace", "some-task")).To(Succeed())
+
+		start := time.Now()
+		spec.RecordSteps(types.SpecEvents{
+			{SpecEventType: types.SpecEventByStart, Message: "desiring", TimelineLocation: types.TimelineLocation{Time: start}},
+			{SpecEventType: types.SpecEventByEnd, Message: "desiring", TimelineLocation: types.TimelineLocation{Time: start.Add(time.Second)}},
+			{SpecEventType: types.SpecEventByStart, Message: "asserting", TimelineLocation: types.TimelineLocation{Time: start.Add(2 * time.Second)}},
+		}, start.Add(3*time.Second))
+		spec.End(errors.New("boom"))
+
+		spans := exported()
+		Expect(spans).To(HaveLen(4))
+		Expect(spans).NotTo(HaveKey("cancel task"))
+
+		root := spans["some spec"]
+		Expect(root.SpanContext.TraceID).To(Equal(spec.TraceID()))
+		Expect(root.Status.Code).To(Equal("Error"))
+		Expect(root.Status.Description).To(Equal("boom"))
+		for _, name := range []string{"desire task", "step: desiring", "step: asserting"} {
+			Expect(spans[name].SpanContext.TraceID).To(Equal(spec.TraceID()), name)
+			Expect(spans[name].Parent.SpanID).To(Equal(root.SpanContext.SpanID), name)
+		}
+
+		Expect(spans["step: desiring"].StartTime).To(BeTemporally("==", start))
+		Expect(spans["step: desiring"].EndTime).To(BeTemporally("==", start.Add(2*time.Second)))
+		Expect(spans["step: asserting"].EndTime).To(BeTemporally("==", start.Add(3*time.Second)))
+	})
+
+	It("stops tracing calls once the trace has ended", func() {
+		server := fakebbs.NewServer()
+		server.Start()
+		defer server.Close()
+		internalClient, err := server.Client()
+		Expect(err).NotTo(HaveOccurred())
+		client := tracer.Client(internalClient)
+
+		spec := tracer.Start("some spec")
+		spec.End(nil)
+		Expect(client.RemoveDesiredLRP(lagertest.NewTestLogger("tracing"), spec.ID(), "missing")).NotTo(Succeed())
+
+		Expect(exported()).To(HaveLen(1))
+	})
+
+	It("fails when the trace file cannot be opened", func() {
+		_, err := tracing.New(context.Background(), tracing.Options{File: "/does/not/exist/spans.json"})
+		Expect(err).To(MatchError(HavePrefix("failed to open trace file: ")))
+	})
+})
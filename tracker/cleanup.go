@@ -0,0 +1,99 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+var errTimedOut = errors.New("timed out")
+
+// deleteTask cancels taskGuid if it has not completed, then resolves and
+// deletes it.  A Task that no longer exists counts as deleted.
+func deleteTask(logger lager.Logger, client bbs.InternalClient, traceID, taskGuid string, timeout, pollInterval time.Duration) error {
+	task, err := client.TaskByGuid(logger, traceID, taskGuid)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if task.State == models.Task_Pending || task.State == models.Task_Running {
+		// the task may complete on its own before the cancel lands, so only the
+		// state it ends up in matters
+		client.CancelTask(logger, traceID, taskGuid)
+		err = poll(timeout, pollInterval, func() (bool, error) {
+			task, err = client.TaskByGuid(logger, traceID, taskGuid)
+			if err != nil {
+				return false, err
+			}
+			return task.State == models.Task_Completed || task.State == models.Task_Resolving, nil
+		})
+		if isNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("task did not complete after cancelling: %s", err.Error())
+		}
+	}
+
+	if task.State == models.Task_Completed {
+		err = client.ResolvingTask(logger, traceID, taskGuid)
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+
+	err = client.DeleteTask(logger, traceID, taskGuid)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// removeDesiredLRP removes processGuid and waits until none of its
+// ActualLRPs are left.
+func removeDesiredLRP(logger lager.Logger, client bbs.InternalClient, traceID, processGuid string, timeout, pollInterval time.Duration) error {
+	err := client.RemoveDesiredLRP(logger, traceID, processGuid)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+
+	err = poll(timeout, pollInterval, func() (bool, error) {
+		actualLRPs, err := client.ActualLRPs(logger, traceID, models.ActualLRPFilter{ProcessGuid: processGuid})
+		if err != nil {
+			return false, err
+		}
+		return len(actualLRPs) == 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("actual LRPs were not removed: %s", err.Error())
+	}
+	return nil
+}
+
+func poll(timeout, pollInterval time.Duration, done func() (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := done()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errTimedOut
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+func isNotFound(err error) bool {
+	return err != nil && models.ConvertError(err).Type == models.Error_ResourceNotFound
+}
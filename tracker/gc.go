@@ -0,0 +1,146 @@
+package tracker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+const (
+	DefaultDomainPrefix = "vizzini-"
+	DefaultMinAge       = time.Hour
+)
+
+type GCOptions struct {
+	// DomainPrefix limits collection to domains Vizzini created.
+	DomainPrefix string
+
+	// MinAge protects domains with a Task or ActualLRP that changed more
+	// recently, so a run in progress is never swept.  DesiredLRPs carry no
+	// timestamp, so a domain with neither Tasks nor ActualLRPs, e.g. a load
+	// run's zero-instance DesiredLRPs, has no known age and is never swept
+	// either.
+	MinAge time.Duration
+
+	Timeout      time.Duration
+	PollInterval time.Duration
+}
+
+// StaleDomain is a domain whose resources were left behind, e.g. by a CI run
+// that crashed before its AfterSuite.
+type StaleDomain struct {
+	Domain       string
+	Tasks        []string
+	DesiredLRPs  []string
+	LastActivity time.Time
+}
+
+func (d StaleDomain) String() string {
+	return fmt.Sprintf("%s: %d tasks, %d desired LRPs, last activity %s", d.Domain, len(d.Tasks), len(d.DesiredLRPs), d.LastActivity.UTC().Format(time.RFC3339))
+}
+
+// FindStaleDomains returns the domains starting with options.DomainPrefix
+// that still have Tasks or DesiredLRPs, are not fresh, and have seen no
+// activity for options.MinAge.
+func FindStaleDomains(logger lager.Logger, client bbs.InternalClient, traceID string, options GCOptions) ([]StaleDomain, error) {
+	freshDomains, err := client.Domains(logger, traceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch domains: %s", err.Error())
+	}
+	fresh := map[string]bool{}
+	for _, domain := range freshDomains {
+		fresh[domain] = true
+	}
+
+	candidates := map[string]*StaleDomain{}
+	candidate := func(domain string) *StaleDomain {
+		if !strings.HasPrefix(domain, options.DomainPrefix) || fresh[domain] {
+			return nil
+		}
+		if candidates[domain] == nil {
+			candidates[domain] = &StaleDomain{Domain: domain}
+		}
+		return candidates[domain]
+	}
+	touch := func(stale *StaleDomain, nanos int64) {
+		if at := time.Unix(0, nanos); nanos > 0 && at.After(stale.LastActivity) {
+			stale.LastActivity = at
+		}
+	}
+
+	tasks, err := client.Tasks(logger, traceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tasks: %s", err.Error())
+	}
+	for _, task := range tasks {
+		if stale := candidate(task.Domain); stale != nil {
+			stale.Tasks = append(stale.Tasks, task.TaskGuid)
+			touch(stale, task.UpdatedAt)
+		}
+	}
+
+	lrps, err := client.DesiredLRPs(logger, traceID, models.DesiredLRPFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch desired LRPs: %s", err.Error())
+	}
+	for _, lrp := range lrps {
+		if stale := candidate(lrp.Domain); stale != nil {
+			stale.DesiredLRPs = append(stale.DesiredLRPs, lrp.ProcessGuid)
+		}
+	}
+
+	actualLRPs, err := client.ActualLRPs(logger, traceID, models.ActualLRPFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch actual LRPs: %s", err.Error())
+	}
+	for _, actualLRP := range actualLRPs {
+		if stale := candidates[actualLRP.Domain]; stale != nil {
+			touch(stale, actualLRP.Since)
+		}
+	}
+
+	staleDomains := []StaleDomain{}
+	for _, stale := range candidates {
+		if stale.LastActivity.IsZero() {
+			logger.Info("skipping-domain-of-unknown-age", lager.Data{"domain": stale.Domain, "desired-lrps": len(stale.DesiredLRPs)})
+			continue
+		}
+		if time.Since(stale.LastActivity) < options.MinAge {
+			continue
+		}
+		sort.Strings(stale.Tasks)
+		sort.Strings(stale.DesiredLRPs)
+		staleDomains = append(staleDomains, *stale)
+	}
+	sort.Slice(staleDomains, func(i, j int) bool { return staleDomains[i].Domain < staleDomains[j].Domain })
+	return staleDomains, nil
+}
+
+// Sweep deletes the Tasks and removes the DesiredLRPs of a stale domain.
+func Sweep(logger lager.Logger, client bbs.InternalClient, traceID string, stale StaleDomain, options GCOptions) error {
+	logger = logger.Session("sweep", lager.Data{"domain": stale.Domain})
+
+	failures := []string{}
+	for _, taskGuid := range stale.Tasks {
+		err := deleteTask(logger, client, traceID, taskGuid, options.Timeout, options.PollInterval)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("task %s: %s", taskGuid, err.Error()))
+		}
+	}
+	for _, processGuid := range stale.DesiredLRPs {
+		err := removeDesiredLRP(logger, client, traceID, processGuid, options.Timeout, options.PollInterval)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("desired LRP %s: %s", processGuid, err.Error()))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to sweep %s:\n%s", stale.Domain, strings.Join(failures, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package tracker_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/vizzini/fakebbs"
+	"code.cloudfoundry.org/vizzini/tracker"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GC", func() {
+	var (
+		server  *fakebbs.Server
+		client  bbs.InternalClient
+		logger  *lagertest.TestLogger
+		options tracker.GCOptions
+	)
+
+	BeforeEach(func() {
+		var err error
+		logger = lagertest.NewTestLogger("gc")
+		server = fakebbs.NewServerWithStepInterval(10 * time.Millisecond)
+		server.Start()
+		client, err = server.Client()
+		Expect(err).NotTo(HaveOccurred())
+
+		options = tracker.GCOptions{
+			DomainPrefix: tracker.DefaultDomainPrefix,
+			Timeout:      5 * time.Second,
+			PollInterval: 10 * time.Millisecond,
+		}
+
+		Expect(client.DesireLRP(logger, "trace-id", desiredLRP("stale-lrp", "vizzini-1"))).To(Succeed())
+		Expect(client.DesireTask(logger, "trace-id", "stale-task", "vizzini-1", taskDefinition())).To(Succeed())
+		Expect(client.DesireLRP(logger, "trace-id", desiredLRP("fresh-lrp", "vizzini-2"))).To(Succeed())
+		Expect(client.UpsertDomain(logger, "trace-id", "vizzini-2", 0)).To(Succeed())
+		Expect(client.DesireLRP(logger, "trace-id", desiredLRP("other-lrp", "cf-apps"))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("finds vizzini domains that are not fresh", func() {
+		staleDomains, err := tracker.FindStaleDomains(logger, client, "trace-id", options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(staleDomains).To(HaveLen(1))
+		Expect(staleDomains[0].Domain).To(Equal("vizzini-1"))
+		Expect(staleDomains[0].Tasks).To(ConsistOf("stale-task"))
+		Expect(staleDomains[0].DesiredLRPs).To(ConsistOf("stale-lrp"))
+		Expect(staleDomains[0].LastActivity).To(BeTemporally("~", time.Now(), time.Minute))
+		Expect(staleDomains[0].String()).To(HavePrefix("vizzini-1: 1 tasks, 1 desired LRPs"))
+	})
+
+	It("leaves domains with recent activity alone", func() {
+		options.MinAge = time.Hour
+		Expect(tracker.FindStaleDomains(logger, client, "trace-id", options)).To(BeEmpty())
+	})
+
+	It("leaves domains with only zero-instance desired LRPs alone, since their age is unknown", func() {
+		idle := desiredLRP("idle-lrp", "vizzini-load-1")
+		idle.Instances = 0
+		Expect(client.DesireLRP(logger, "trace-id", idle)).To(Succeed())
+
+		staleDomains, err := tracker.FindStaleDomains(logger, client, "trace-id", options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(staleDomains).To(HaveLen(1))
+		Expect(staleDomains[0].Domain).To(Equal("vizzini-1"))
+	})
+
+	It("sweeps stale domains", func() {
+		staleDomains, err := tracker.FindStaleDomains(logger, client, "trace-id", options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tracker.Sweep(logger, client, "trace-id", staleDomains[0], options)).To(Succeed())
+
+		Expect(client.TasksByDomain(logger, "trace-id", "vizzini-1")).To(BeEmpty())
+		Expect(client.DesiredLRPs(logger, "trace-id", models.DesiredLRPFilter{Domain: "vizzini-1"})).To(BeEmpty())
+		Expect(client.DesiredLRPs(logger, "trace-id", models.DesiredLRPFilter{})).To(HaveLen(2))
+	})
+})
@@ -0,0 +1 @@
+package tracker // import "code.cloudfoundry.org/vizzini/tracker"
@@ -0,0 +1,256 @@
+package tracker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+)
+
+const (
+	DefaultTimeout      = 2 * time.Minute
+	DefaultPollInterval = 500 * time.Millisecond
+)
+
+type Kind string
+
+const (
+	Task       Kind = "task"
+	DesiredLRP Kind = "desired LRP"
+)
+
+// Resource is a Task or DesiredLRP that was desired through the tracking
+// client.
+type Resource struct {
+	Kind      Kind
+	Guid      string
+	Domain    string
+	Spec      string
+	CreatedAt time.Time
+}
+
+// Leak is a Resource still in the BBS after the spec that created it was
+// cleaned up.
+type Leak struct {
+	Resource
+	Reason string
+}
+
+func (l Leak) String() string {
+	return fmt.Sprintf("%s %s in domain %s (%s) after %q", l.Kind, l.Guid, l.Domain, l.Reason, l.Spec)
+}
+
+type Leaks []Leak
+
+func (l Leaks) String() string {
+	lines := make([]string, len(l))
+	for i, leak := range l {
+		lines[i] = leak.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+type resourceKey struct {
+	kind Kind
+	guid string
+}
+
+// Tracker records every Task and DesiredLRP desired through its Client so
+// that a spec can clean up exactly what it created, and so that anything
+// left behind is reported instead of silently swept away.
+type Tracker struct {
+	Timeout      time.Duration
+	PollInterval time.Duration
+
+	client bbs.InternalClient
+
+	lock      sync.Mutex
+	spec      string
+	resources map[resourceKey]Resource
+	leaks     Leaks
+}
+
+func New(client bbs.InternalClient) *Tracker {
+	return &Tracker{
+		Timeout:      DefaultTimeout,
+		PollInterval: DefaultPollInterval,
+		client:       client,
+		resources:    map[resourceKey]Resource{},
+	}
+}
+
+// Client returns a BBS client that tracks what it desires and stops tracking
+// what it removes or deletes.
+func (t *Tracker) Client() bbs.InternalClient {
+	return &trackingClient{InternalClient: t.client, tracker: t}
+}
+
+// SetSpec names the spec that resources tracked from now on belong to.
+func (t *Tracker) SetSpec(spec string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.spec = spec
+}
+
+func (t *Tracker) Track(kind Kind, guid, domain string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	key := resourceKey{kind, guid}
+	if _, ok := t.resources[key]; ok {
+		return
+	}
+	t.resources[key] = Resource{
+		Kind:      kind,
+		Guid:      guid,
+		Domain:    domain,
+		Spec:      t.spec,
+		CreatedAt: time.Now(),
+	}
+}
+
+func (t *Tracker) Untrack(kind Kind, guid string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.resources, resourceKey{kind, guid})
+}
+
+// Tracked returns the resources that have not been cleaned up, oldest first.
+func (t *Tracker) Tracked() []Resource {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	resources := make([]Resource, 0, len(t.resources))
+	for _, resource := range t.resources {
+		resources = append(resources, resource)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].CreatedAt.Equal(resources[j].CreatedAt) {
+			return resources[i].Guid < resources[j].Guid
+		}
+		return resources[i].CreatedAt.Before(resources[j].CreatedAt)
+	})
+	return resources
+}
+
+// Cleanup cancels, resolves and deletes every tracked Task, then removes
+// every tracked DesiredLRP and waits for its ActualLRPs to go away.
+// Resources that could not be cleaned up stay tracked.
+func (t *Tracker) Cleanup(logger lager.Logger, traceID string) error {
+	logger = logger.Session("cleanup")
+
+	failures := []string{}
+	for _, kind := range []Kind{Task, DesiredLRP} {
+		for _, resource := range t.Tracked() {
+			if resource.Kind != kind {
+				continue
+			}
+
+			var err error
+			if kind == Task {
+				err = deleteTask(logger, t.client, traceID, resource.Guid, t.Timeout, t.PollInterval)
+			} else {
+				err = removeDesiredLRP(logger, t.client, traceID, resource.Guid, t.Timeout, t.PollInterval)
+			}
+			if err != nil {
+				logger.Error("failed-to-clean-up", err, lager.Data{"kind": kind, "guid": resource.Guid})
+				failures = append(failures, fmt.Sprintf("%s %s: %s", kind, resource.Guid, err.Error()))
+				continue
+			}
+			t.Untrack(kind, resource.Guid)
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to clean up %d resources:\n%s", len(failures), strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// Audit lists the Tasks and DesiredLRPs left in domains.  Each one is a Leak:
+// either it is still tracked because cleanup failed, or it was never tracked
+// because it was desired through some other client.  Leaks are remembered so
+// that Leaks can report them at the end of the suite.
+func (t *Tracker) Audit(logger lager.Logger, traceID string, domains ...string) (Leaks, error) {
+	found := Leaks{}
+	for _, domain := range domains {
+		tasks, err := t.client.TasksByDomain(logger, traceID, domain)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch tasks in %s: %s", domain, err.Error())
+		}
+		for _, task := range tasks {
+			found = append(found, t.leak(Task, task.TaskGuid, domain))
+		}
+
+		lrps, err := t.client.DesiredLRPs(logger, traceID, models.DesiredLRPFilter{Domain: domain})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch desired LRPs in %s: %s", domain, err.Error())
+		}
+		for _, lrp := range lrps {
+			found = append(found, t.leak(DesiredLRP, lrp.ProcessGuid, domain))
+		}
+	}
+
+	t.lock.Lock()
+	t.leaks = append(t.leaks, found...)
+	t.lock.Unlock()
+	return found, nil
+}
+
+func (t *Tracker) leak(kind Kind, guid, domain string) Leak {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if resource, ok := t.resources[resourceKey{kind, guid}]; ok {
+		return Leak{Resource: resource, Reason: "not cleaned up"}
+	}
+	return Leak{
+		Resource: Resource{Kind: kind, Guid: guid, Domain: domain, Spec: t.spec},
+		Reason:   "never tracked",
+	}
+}
+
+// Leaks returns every Leak found by Audit so far.
+func (t *Tracker) Leaks() Leaks {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return append(Leaks{}, t.leaks...)
+}
+
+type trackingClient struct {
+	bbs.InternalClient
+	tracker *Tracker
+}
+
+// DesireLRP tracks lrp even if the BBS rejects it, since a timed-out request
+// may still have gone through; cleanup ignores resources that do not exist.
+func (c *trackingClient) DesireLRP(logger lager.Logger, traceID string, lrp *models.DesiredLRP) error {
+	c.tracker.Track(DesiredLRP, lrp.ProcessGuid, lrp.Domain)
+	return c.InternalClient.DesireLRP(logger, traceID, lrp)
+}
+
+func (c *trackingClient) RemoveDesiredLRP(logger lager.Logger, traceID string, processGuid string) error {
+	err := c.InternalClient.RemoveDesiredLRP(logger, traceID, processGuid)
+	if err == nil {
+		c.tracker.Untrack(DesiredLRP, processGuid)
+	}
+	return err
+}
+
+func (c *trackingClient) DesireTask(logger lager.Logger, traceID string, guid, domain string, definition *models.TaskDefinition) error {
+	c.tracker.Track(Task, guid, domain)
+	return c.InternalClient.DesireTask(logger, traceID, guid, domain, definition)
+}
+
+func (c *trackingClient) DeleteTask(logger lager.Logger, traceID string, taskGuid string) error {
+	err := c.InternalClient.DeleteTask(logger, traceID, taskGuid)
+	if err == nil {
+		c.tracker.Untrack(Task, taskGuid)
+	}
+	return err
+}
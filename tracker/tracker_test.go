@@ -0,0 +1,136 @@
+package tracker_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/vizzini/fakebbs"
+	"code.cloudfoundry.org/vizzini/tracker"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func desiredLRP(processGuid, domain string) *models.DesiredLRP {
+	return &models.DesiredLRP{
+		ProcessGuid: processGuid,
+		Domain:      domain,
+		Instances:   1,
+		RootFs:      models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot),
+		MemoryMb:    128,
+		Action:      models.WrapAction(&models.RunAction{Path: "true", User: "vcap"}),
+	}
+}
+
+func taskDefinition() *models.TaskDefinition {
+	return &models.TaskDefinition{
+		RootFs:   models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot),
+		MemoryMb: 32,
+		Action:   models.WrapAction(&models.RunAction{Path: "true", User: "vcap"}),
+	}
+}
+
+var _ = Describe("Tracker", func() {
+	var (
+		server          *fakebbs.Server
+		rawClient       bbs.InternalClient
+		client          bbs.InternalClient
+		logger          *lagertest.TestLogger
+		resourceTracker *tracker.Tracker
+	)
+
+	BeforeEach(func() {
+		var err error
+		logger = lagertest.NewTestLogger("tracker")
+		server = fakebbs.NewServerWithStepInterval(10 * time.Millisecond)
+		server.Start()
+		rawClient, err = server.Client()
+		Expect(err).NotTo(HaveOccurred())
+
+		resourceTracker = tracker.New(rawClient)
+		resourceTracker.Timeout = 5 * time.Second
+		resourceTracker.PollInterval = 10 * time.Millisecond
+		resourceTracker.SetSpec("some spec")
+		client = resourceTracker.Client()
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("tracks what is desired through its client", func() {
+		Expect(client.DesireLRP(logger, "trace-id", desiredLRP("lrp-guid", "vizzini-1"))).To(Succeed())
+		Expect(client.DesireTask(logger, "trace-id", "task-guid", "vizzini-1", taskDefinition())).To(Succeed())
+
+		tracked := resourceTracker.Tracked()
+		Expect(tracked).To(HaveLen(2))
+		Expect(tracked[0].Kind).To(Equal(tracker.DesiredLRP))
+		Expect(tracked[0].Guid).To(Equal("lrp-guid"))
+		Expect(tracked[0].Domain).To(Equal("vizzini-1"))
+		Expect(tracked[1].Kind).To(Equal(tracker.Task))
+		Expect(tracked[1].Spec).To(Equal("some spec"))
+	})
+
+	It("stops tracking what is removed or deleted through its client", func() {
+		Expect(client.DesireLRP(logger, "trace-id", desiredLRP("lrp-guid", "vizzini-1"))).To(Succeed())
+		Expect(client.RemoveDesiredLRP(logger, "trace-id", "lrp-guid")).To(Succeed())
+
+		Expect(client.DesireTask(logger, "trace-id", "task-guid", "vizzini-1", taskDefinition())).To(Succeed())
+		Eventually(func() (*models.Task, error) {
+			return client.TaskByGuid(logger, "trace-id", "task-guid")
+		}).Should(HaveField("State", models.Task_Completed))
+		Expect(client.ResolvingTask(logger, "trace-id", "task-guid")).To(Succeed())
+		Expect(client.DeleteTask(logger, "trace-id", "task-guid")).To(Succeed())
+
+		Expect(resourceTracker.Tracked()).To(BeEmpty())
+	})
+
+	It("cleans up only what it tracked", func() {
+		Expect(client.DesireLRP(logger, "trace-id", desiredLRP("mine-lrp", "vizzini-1"))).To(Succeed())
+		Expect(client.DesireTask(logger, "trace-id", "mine-task", "vizzini-1", taskDefinition())).To(Succeed())
+		Expect(rawClient.DesireLRP(logger, "trace-id", desiredLRP("theirs-lrp", "vizzini-2"))).To(Succeed())
+
+		Expect(resourceTracker.Cleanup(logger, "trace-id")).To(Succeed())
+		Expect(resourceTracker.Tracked()).To(BeEmpty())
+
+		Expect(rawClient.TasksByDomain(logger, "trace-id", "vizzini-1")).To(BeEmpty())
+		Expect(rawClient.DesiredLRPs(logger, "trace-id", models.DesiredLRPFilter{Domain: "vizzini-1"})).To(BeEmpty())
+		Expect(rawClient.ActualLRPs(logger, "trace-id", models.ActualLRPFilter{ProcessGuid: "mine-lrp"})).To(BeEmpty())
+		Expect(rawClient.DesiredLRPs(logger, "trace-id", models.DesiredLRPFilter{Domain: "vizzini-2"})).To(HaveLen(1))
+	})
+
+	It("ignores tracked resources that were never created", func() {
+		invalid := desiredLRP("invalid-lrp", "vizzini-1")
+		invalid.RootFs = ""
+		Expect(client.DesireLRP(logger, "trace-id", invalid)).NotTo(Succeed())
+
+		Expect(resourceTracker.Cleanup(logger, "trace-id")).To(Succeed())
+		Expect(resourceTracker.Tracked()).To(BeEmpty())
+	})
+
+	It("reports leaked resources", func() {
+		Expect(rawClient.DesireLRP(logger, "trace-id", desiredLRP("untracked-lrp", "vizzini-1"))).To(Succeed())
+
+		leaks, err := resourceTracker.Audit(logger, "trace-id", "vizzini-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(leaks).To(HaveLen(1))
+		Expect(leaks[0].Guid).To(Equal("untracked-lrp"))
+		Expect(leaks[0].Reason).To(Equal("never tracked"))
+		Expect(leaks[0].Spec).To(Equal("some spec"))
+
+		resourceTracker.SetSpec("another spec")
+		Expect(client.DesireTask(logger, "trace-id", "tracked-task", "vizzini-1", taskDefinition())).To(Succeed())
+		leaks, err = resourceTracker.Audit(logger, "trace-id", "vizzini-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(leaks).To(ContainElement(And(
+			HaveField("Guid", "tracked-task"),
+			HaveField("Reason", "not cleaned up"),
+			HaveField("Spec", "another spec"),
+		)))
+
+		Expect(resourceTracker.Leaks()).To(HaveLen(3))
+		Expect(resourceTracker.Leaks().String()).To(ContainSubstring(`desired LRP untracked-lrp in domain vizzini-1 (never tracked) after "some spec"`))
+	})
+})
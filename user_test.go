@@ -1,9 +1,12 @@
 package vizzini_test
 
 import (
-	. "code.cloudfoundry.org/vizzini/matchers"
+	"time"
 
 	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/vizzini/capabilities"
+	"code.cloudfoundry.org/vizzini/fixtures"
+	"code.cloudfoundry.org/vizzini/profiles"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -12,22 +15,24 @@ import (
 var _ = Describe("Users", func() {
 	var task *models.TaskDefinition
 
-	Context("{DOCKER} with an existing 'alice' user in the rootfs", func() {
+	Context("with an existing 'alice' user in the rootfs", Label(profiles.Docker), func() {
 		BeforeEach(func() {
-			task = Task()
-			task.RootFs = "docker:///cloudfoundry/busybox-alice"
-			task.Action = models.WrapAction(&models.RunAction{
-				Path: "sh",
-				Args: []string{"-c", "whoami > /tmp/output"},
-				User: "alice",
-			})
-			task.ResultFile = "/tmp/output"
+			RequireCapabilities(capabilities.Docker)
+			task = Task(
+				fixtures.WithDockerImage("docker:///cloudfoundry/busybox-alice"),
+				fixtures.WithAction(&models.RunAction{
+					Path: "sh",
+					Args: []string{"-c", "whoami > /tmp/output"},
+					User: "alice",
+				}),
+				fixtures.WithResultFile("/tmp/output"),
+			)
 
 			Expect(bbsClient.DesireTask(logger, traceID, guid, domain, task)).To(Succeed())
 		})
 
 		It("runs an action as alice", func() {
-			Eventually(TaskGetter(logger, guid), 120).Should(HaveTaskState(models.Task_Completed))
+			WaitForTaskState(guid, models.Task_Completed, 120*time.Second)
 
 			task, err := bbsClient.TaskByGuid(logger, traceID, guid)
 			Expect(err).NotTo(HaveOccurred())
@@ -1,11 +1,16 @@
 package vizzini_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net"
-	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,9 +18,19 @@ import (
 	. "github.com/onsi/gomega"
 
 	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/lager/v3"
 	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/vizzini/capabilities"
 	vizziniconfig "code.cloudfoundry.org/vizzini/config"
+	"code.cloudfoundry.org/vizzini/fakebbs"
+	"code.cloudfoundry.org/vizzini/forensics"
+	"code.cloudfoundry.org/vizzini/perf"
+	"code.cloudfoundry.org/vizzini/profiles"
+	"code.cloudfoundry.org/vizzini/report"
+	"code.cloudfoundry.org/vizzini/speclog"
+	"code.cloudfoundry.org/vizzini/tracing"
+	"code.cloudfoundry.org/vizzini/tracker"
 	uuid "github.com/nu7hatch/gouuid"
 	"github.com/onsi/say"
 )
@@ -32,28 +47,67 @@ var (
 	sshHost       string
 	sshPort       string
 
-	config vizziniconfig.VizziniConfig
+	config            vizziniconfig.VizziniConfig
+	fakeBBS           *fakebbs.Server
+	suiteCapabilities *capabilities.Set
+	failureSnapshot   *report.Snapshot
+	eventRecorder     *forensics.Recorder
+	resourceTracker   *tracker.Tracker
+	tracer            *tracing.Tracer
+	specTrace         *tracing.Trace
+
+	// logger is the current spec's logger while a spec runs, and suiteLogger
+	// otherwise.
+	suiteLogger lager.Logger
+	specLog     *speclog.Log
 )
 
-func init() {
+var configFlags = vizziniconfig.RegisterFlags(flag.CommandLine)
+var configSources vizziniconfig.Sources
+
+// labelFilter is the profile's label filter combined with --label-filter.
+var labelFilter string
+
+func TestVizziniSuite(t *testing.T) {
 	var err error
-	config, err = vizziniconfig.NewVizziniConfig()
+	config, configSources, err = vizziniconfig.Load(os.Getenv("VIZZINI_CONFIG_PATH"), os.LookupEnv, configFlags)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	if config.BBSAddress == "" {
-		log.Fatal("i need a bbs address to talk to Diego...")
+	if config.FakeBBS && config.DefaultRootFS == "" {
+		config.DefaultRootFS = models.PreloadedRootFS(fakebbs.DefaultPreloadedRoot)
+	}
+	if config.FakeBBS && config.GraceTarballURL == "" {
+		config.GraceTarballURL = fakebbs.PlaceholderGraceTarballURL
+		config.GraceTarballChecksum = fakebbs.PlaceholderGraceTarballChecksum
 	}
 
-	if config.SSHAddress == "" {
-		log.Fatal("i need an SSH address to talk to Diego...")
+	// report every config problem up front rather than one failed spec at a time
+	if err := config.Validate(); err != nil {
+		log.Fatal(err)
 	}
-}
 
-func TestVizziniSuite(t *testing.T) {
+	suiteConfig, reporterConfig := GinkgoConfiguration()
+	if err := config.ValidateTCPExternalPorts(suiteConfig.ParallelTotal); err != nil {
+		log.Fatal(err)
+	}
+	if config.Profile != "" {
+		loaded, err := profiles.Load(config.ProfilesPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		profileFilter, err := loaded.Filter(config.Profile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// --label-filter narrows the profile further rather than replacing it
+		suiteConfig.LabelFilter = profiles.Combine(profileFilter, suiteConfig.LabelFilter)
+	}
+	labelFilter = suiteConfig.LabelFilter
+
 	RegisterFailHandler(Fail)
-	RunSpecs(t, "Vizzini Suite")
+	RunSpecs(t, "Vizzini Suite", suiteConfig, reporterConfig)
 }
 
 func NewGuid() string {
@@ -62,64 +116,217 @@ func NewGuid() string {
 	return domain + "-" + u.String()[:8]
 }
 
-const DefaultEventuallyTimeout = 120 * time.Second
-
 var taskFailureTimeout time.Duration
 
+// traceID is passed with every BBS call.  Each spec, and the suite setup and
+// teardown, get a trace of their own, so the BBS logs the calls they make
+// under an ID that appears nowhere else.
 var traceID = "vizzini-trace-id"
 
-var _ = BeforeSuite(func() {
-	var err error
-	timeout = DefaultEventuallyTimeout
-	dockerTimeout = 120 * time.Second
+// The capabilities are probed once, by the first process, since every
+// process runs against the same deployment.
+var _ = SynchronizedBeforeSuite(func() []byte {
+	setUpProcess()
+
+	probeTrace := tracer.Start("probe capabilities", tracing.DomainKey.String(domain))
+	defer probeTrace.End(nil)
+	traceID = probeTrace.ID()
 
-	timeoutArg := os.Getenv("DEFAULT_EVENTUALLY_TIMEOUT")
-	if timeoutArg != "" {
-		timeout, err = time.ParseDuration(timeoutArg)
-		Expect(err).NotTo(HaveOccurred(), "invalid value '"+timeoutArg+"' for DEFAULT_EVENTUALLY_TIMEOUT")
-		fmt.Fprintf(GinkgoWriter, "Setting Default Eventually Timeout to %s\n", timeout)
+	probed, err := capabilities.Probe(logger, bbsClient, traceID, capabilities.ProbeOptions{
+		Domain:      domain,
+		GuidPrefix:  domain + "-capabilities",
+		RootFS:      config.DefaultRootFS,
+		OCIImageURL: config.DiegoDockerOCIImageURL,
+		Overrides:   capabilityOverrides(),
+		Timeout:     timeout,
+	})
+	Expect(err).NotTo(HaveOccurred())
+	encoded, err := json.Marshal(probed)
+	Expect(err).NotTo(HaveOccurred())
+	return encoded
+}, func(encoded []byte) {
+	// the first process has set itself up already
+	if GinkgoParallelProcess() != 1 {
+		setUpProcess()
 	}
 
+	suiteCapabilities = capabilities.NewSet()
+	Expect(json.Unmarshal(encoded, suiteCapabilities)).To(Succeed())
+	fmt.Fprintln(GinkgoWriter, "Deployment capabilities:")
+	suiteCapabilities.Report(GinkgoWriter)
+})
+
+// setUpProcess gives a parallel process its own domain, BBS client, tracer
+// and event recorder.
+func setUpProcess() {
+	var err error
+	timeout = time.Duration(config.DefaultEventuallyTimeout)
+	dockerTimeout = time.Duration(config.DockerTimeout)
+
+	fmt.Fprintln(GinkgoWriter, "Vizzini config (key, source, value):")
+	configSources.Report(GinkgoWriter, config)
+	fmt.Fprintf(GinkgoWriter, "Profile %q selects specs with label filter %q\n", config.Profile, labelFilter)
+
 	SetDefaultEventuallyTimeout(timeout)
 	SetDefaultEventuallyPollingInterval(500 * time.Millisecond)
 	SetDefaultConsistentlyPollingInterval(200 * time.Millisecond)
 	domain = fmt.Sprintf("vizzini-%d", GinkgoParallelProcess())
 	otherDomain = fmt.Sprintf("vizzini-other-%d", GinkgoParallelProcess())
 
-	rootfsURI, err := url.Parse(config.DefaultRootFS)
+	if config.FakeBBS {
+		fakeBBS = fakebbs.NewServer()
+		fakeBBS.Start()
+	}
+
+	logger = lagertest.NewTestLogger("vizzini")
+	suiteLogger = logger
+	tracer, err = tracing.New(context.Background(), tracing.Options{
+		OTLPEndpoint: config.TraceOTLPEndpoint,
+		File:         config.TraceFile,
+	})
 	Expect(err).NotTo(HaveOccurred())
-	Expect(rootfsURI.Scheme).To(Equal("preloaded"))
-	Expect(rootfsURI.Opaque).NotTo(BeEmpty())
+	suiteTrace := tracer.Start("BeforeSuite", tracing.DomainKey.String(domain))
+	defer suiteTrace.End(nil)
+	traceID = suiteTrace.ID()
 
-	bbsClient = initializeBBSClient()
+	resourceTracker = tracker.New(initializeBBSClient())
+	// Wait enough time for the Grace app to exit if it was run with -catchTerminate
+	resourceTracker.Timeout = timeout + 8*time.Second
+	bbsClient = tracer.Client(resourceTracker.Client())
 
-	sshHost, sshPort, err = net.SplitHostPort(config.SSHAddress)
-	Expect(err).NotTo(HaveOccurred())
+	eventRecorder = forensics.NewRecorder(forensics.DefaultEventsPerGuid)
+	eventRecorder.Filter = func(eventGuid string) bool {
+		return strings.HasPrefix(eventGuid, domain+"-")
+	}
+	Expect(eventRecorder.Start(logger, bbsClient)).To(Succeed())
+
+	if config.SSHAddress != "" {
+		sshHost, sshPort, err = net.SplitHostPort(config.SSHAddress)
+		Expect(err).NotTo(HaveOccurred())
+	}
 
 	// conservative taskFailureTimeout since tasks retries happen during convergence
 	taskFailureTimeout = ConvergerInterval * time.Duration(config.MaxTaskRetries+1)
+}
 
-	logger = lagertest.NewTestLogger("vizzini")
-})
+// capabilityOverrides turns enable_* settings that were set explicitly into
+// overrides, so deployments that already configure them skip the probes.
+func capabilityOverrides() map[capabilities.Capability]bool {
+	overrides := map[capabilities.Capability]bool{}
+	if configSources["enable_container_proxy_tests"] != vizziniconfig.SourceDefault {
+		overrides[capabilities.ContainerProxy] = config.EnableContainerProxyTests
+	}
+	if configSources["enable_privileged_container_tests"] != vizziniconfig.SourceDefault {
+		overrides[capabilities.Privileged] = config.EnablePrivilegedContainerTests
+	}
+	return overrides
+}
+
+// RequireCapabilities skips the current spec unless the deployment supports
+// every capability in needs.
+func RequireCapabilities(needs ...capabilities.Capability) {
+	if reason, missing := suiteCapabilities.Missing(needs...); missing {
+		Skip(reason)
+	}
+}
 
 var _ = BeforeEach(func() {
 	startTime = time.Now()
 	guid = NewGuid()
+	resourceTracker.SetSpec(CurrentSpecReport().FullText())
+	specTrace = tracer.Start(CurrentSpecReport().FullText(), tracing.GuidKey.String(guid), tracing.DomainKey.String(domain))
+	traceID = specTrace.ID()
+	specLog = speclog.New("vizzini", lager.Data{"guid": guid, "domain": domain, "trace_id": traceID})
+	logger = specLog.Logger()
+})
+
+// snapshot the BBS before any AfterEach cleans up after the failed spec
+var _ = JustAfterEach(func() {
+	failureSnapshot = nil
+	if CurrentSpecReport().Failed() {
+		failureSnapshot = report.TakeSnapshot(logger, bbsClient, traceID, domain, otherDomain)
+		for _, specGuid := range specGuids() {
+			AddReportEntry("forensics", forensics.Collect(logger, bbsClient, traceID, specGuid, eventRecorder), ReportEntryVisibilityFailureOrVerbose)
+		}
+	}
 })
 
 var _ = AfterEach(func() {
 	defer func() {
 		endTime := time.Now()
-		fmt.Fprint(GinkgoWriter, say.F("{{cyan}}\n%s\nThis test referenced GUID %s\nStart time: %s (%d)\nEnd time: %s (%d)\n{{/}}", CurrentSpecReport().FullText(), guid, startTime, startTime.Unix(), endTime, endTime.Unix()))
+		logger = suiteLogger
+		logFile := ""
+		if config.LogDir != "" {
+			logFile = filepath.Join(config.LogDir, guid+".log")
+			if err := specLog.WriteFile(logFile); err != nil {
+				fmt.Fprintln(GinkgoWriter, err.Error())
+				logFile = ""
+			}
+		}
+		// passing specs' logs only bloat the report; log_dir keeps them
+		if CurrentSpecReport().Failed() {
+			AddReportEntry("spec log", specLog.String(), ReportEntryVisibilityFailureOrVerbose)
+		}
+
+		fmt.Fprint(GinkgoWriter, say.F("{{cyan}}\n%s\nThis test referenced GUID %s (run `vizzini inspect <guid>` to see what the BBS still knows about it)\nTrace ID: %s (BBS logs show it as trace-id %s)\nStart time: %s (%d)\nEnd time: %s (%d)\n{{/}}", CurrentSpecReport().FullText(), guid, specTrace.ID(), specTrace.TraceID(), startTime, startTime.Unix(), endTime, endTime.Unix()))
+		AddReportEntry(report.EntryName, report.Entry{
+			GUID:      guid,
+			Domain:    domain,
+			TraceID:   specTrace.ID(),
+			LogFile:   logFile,
+			StartTime: startTime,
+			EndTime:   endTime,
+			Snapshot:  failureSnapshot,
+		}, ReportEntryVisibilityNever)
+
+		specReport := CurrentSpecReport()
+		specTrace.RecordSteps(specReport.SpecEvents, endTime)
+		var failure error
+		if specReport.Failed() {
+			failure = errors.New(specReport.Failure.Message)
+		}
+		specTrace.End(failure)
 	}()
 
-	for _, domain := range []string{domain, otherDomain} {
-		ClearOutTasksInDomain(domain)
-		ClearOutDesiredLRPsInDomain(domain)
+	for _, specGuid := range specGuids() {
+		eventRecorder.Forget(specGuid)
+	}
+
+	// a failed cleanup must not stop the leaks from being recorded and
+	// cleared out, so its error only fails the spec at the end
+	cleanupErr := resourceTracker.Cleanup(logger, traceID)
+	leaks, auditErr := resourceTracker.Audit(logger, traceID, domain, otherDomain)
+	if cleanupErr != nil || auditErr != nil || len(leaks) > 0 {
+		// keep the next spec isolated from whatever this one left behind
+		for _, domain := range []string{domain, otherDomain} {
+			ClearOutTasksInDomain(domain)
+			ClearOutDesiredLRPsInDomain(domain)
+		}
 	}
+	Expect(cleanupErr).NotTo(HaveOccurred())
+	Expect(auditErr).NotTo(HaveOccurred())
 })
 
+// specGuids are the spec's guid and the GUIDs derived from it, e.g.
+// guid+"-source", that the recorder saw events for.
+func specGuids() []string {
+	return append([]string{guid}, eventRecorder.GuidsWithPrefix(guid+"-")...)
+}
+
 var _ = AfterSuite(func() {
+	if tracer != nil {
+		suiteTrace := tracer.Start("AfterSuite", tracing.DomainKey.String(domain))
+		traceID = suiteTrace.ID()
+		defer func() {
+			suiteTrace.End(nil)
+			Expect(tracer.Shutdown(context.Background())).To(Succeed())
+		}()
+	}
+
+	if resourceTracker != nil && len(resourceTracker.Leaks()) > 0 {
+		AddReportEntry("leaked resources", resourceTracker.Leaks(), ReportEntryVisibilityAlways)
+	}
+
 	for _, domain := range []string{domain, otherDomain} {
 		bbsClient.UpsertDomain(logger, traceID, domain, 5*time.Minute) //leave the domain around forever so that Diego cleans up if need be
 	}
@@ -128,10 +335,36 @@ var _ = AfterSuite(func() {
 		ClearOutDesiredLRPsInDomain(domain)
 		ClearOutTasksInDomain(domain)
 	}
+
+	if eventRecorder != nil {
+		eventRecorder.Stop()
+	}
+
+	if fakeBBS != nil {
+		fakeBBS.Close()
+	}
+})
+
+var _ = ReportAfterSuite("Vizzini profile", func(ginkgoReport Report) {
+	fmt.Fprintln(GinkgoWriter, profiles.Summarize(config.Profile, ginkgoReport))
+})
+
+var _ = ReportAfterSuite("Vizzini report", func(ginkgoReport Report) {
+	if config.ReportDir == "" {
+		return
+	}
+	Expect(report.Write(config.ReportDir, ginkgoReport)).To(Succeed())
+	Expect(perf.Write(config.ReportDir, ginkgoReport)).To(Succeed())
 })
 
 func initializeBBSClient() bbs.InternalClient {
-	bbsClient, err := bbs.NewSecureSkipVerifyClient(config.BBSAddress, config.BBSClientCertPath, config.BBSClientKeyPath, 0, 0)
+	if config.FakeBBS {
+		bbsClient, err := fakeBBS.Client()
+		Expect(err).NotTo(HaveOccurred())
+		return bbsClient
+	}
+
+	bbsClient, err := config.BBSClient()
 	Expect(err).NotTo(HaveOccurred())
 	return bbsClient
 }